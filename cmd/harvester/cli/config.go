@@ -17,6 +17,7 @@ const (
 	defaultSpireSocketPath       = "/tmp/spire-server/private/api.sock"
 	defaultBundleUpdatesInterval = "30s"
 	defaultLogLevel              = "INFO"
+	defaultDataDir               = "./.data"
 )
 
 type Config struct {
@@ -28,6 +29,11 @@ type harvesterConfig struct {
 	ServerAddress         string `hcl:"server_address"`
 	BundleUpdatesInterval string `hcl:"bundle_updates_interval"`
 	LogLevel              string `hcl:"log_level"`
+	DataDir               string `hcl:"data_dir"`
+
+	// SpireVersion is the version of the SPIRE Server reported to Galadriel Server, which cannot be
+	// queried from the SPIRE Server API.
+	SpireVersion string `hcl:"spire_version"`
 }
 
 // ParseConfig reads a configuration from the Reader and parses it
@@ -62,6 +68,8 @@ func NewHarvesterConfig(c *Config) (*harvester.Config, error) {
 	hc.SpireAddress = spireAddr
 	hc.ServerAddress = c.Harvester.ServerAddress
 	hc.BundleUpdatesInterval = buInt
+	hc.SpireVersion = c.Harvester.SpireVersion
+	hc.DataDir = c.Harvester.DataDir
 
 	hc.Logger = logrus.WithField(telemetry.SubsystemName, telemetry.Harvester)
 
@@ -96,4 +104,8 @@ func (c *Config) setDefaults() {
 	if c.Harvester.LogLevel == "" {
 		c.Harvester.LogLevel = defaultLogLevel
 	}
+
+	if c.Harvester.DataDir == "" {
+		c.Harvester.DataDir = defaultDataDir
+	}
 }
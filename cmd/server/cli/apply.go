@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/apply"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Args:  cobra.ExactArgs(0),
+	Short: "Reconciles the trust domains and relationships with a configuration file",
+	Long: `Reconciles the trust domains and relationships with a configuration file. Trust domains and
+relationships in the file are created or updated, and the ones that are not in the file are deleted.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return fmt.Errorf("cannot get file flag: %v", err)
+		}
+		if input == "" {
+			return fmt.Errorf("the configuration file is required")
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("cannot get dry-run flag: %v", err)
+		}
+
+		f, err := os.Open(input)
+		if err != nil {
+			return fmt.Errorf("failed to open configuration file: %v", err)
+		}
+		defer f.Close()
+
+		state, err := apply.ParseConfig(f)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+
+		plan, err := c.Apply(state, dryRun)
+		if err != nil {
+			return err
+		}
+
+		return p.print(plan, func() error {
+			printPlan(p, plan)
+			return nil
+		})
+	},
+}
+
+var actionSymbols = map[apply.Action]string{
+	apply.ActionCreate: "+",
+	apply.ActionUpdate: "~",
+	apply.ActionDelete: "-",
+}
+
+func printPlan(p *printer, plan *apply.Plan) {
+	if len(plan.Changes) == 0 {
+		p.message("No changes: the datastore matches the configuration")
+		return
+	}
+
+	counts := make(map[apply.Action]int)
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+
+		line := fmt.Sprintf("%s %s %s", actionSymbols[change.Action], change.Resource, change.Name)
+		if change.Detail != "" {
+			line += fmt.Sprintf(" (%s)", change.Detail)
+		}
+		p.message("%s", line)
+	}
+
+	verb := "Planned"
+	if plan.Applied {
+		verb = "Applied"
+	}
+	p.message("\n%s: %d to create, %d to update, %d to delete",
+		verb, counts[apply.ActionCreate], counts[apply.ActionUpdate], counts[apply.ActionDelete])
+}
+
+func init() {
+	applyCmd.Flags().StringP("file", "f", "", "The configuration file declaring the trust domains and relationships.")
+	applyCmd.Flags().Bool("dry-run", false, "Prints the changes without making them.")
+
+	RootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/backup"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Args:  cobra.ExactArgs(0),
+	Short: "Exports the trust domains, relationships, bundles and join tokens to a JSON document",
+	Long: `Exports the trust domains, relationships, bundles and join tokens to a JSON document. The document is
+written to stdout as YAML with the yaml output format, but always written as JSON to a file, as the import command
+reads JSON documents.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return fmt.Errorf("cannot get file flag: %v", err)
+		}
+
+		excludeTokens, err := cmd.Flags().GetBool("exclude-tokens")
+		if err != nil {
+			return fmt.Errorf("cannot get exclude-tokens flag: %v", err)
+		}
+
+		if file == "" {
+			if file, err = deprecatedOutputFile(cmd); err != nil {
+				return err
+			}
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+
+		doc, err := c.Export(excludeTokens)
+		if err != nil {
+			return err
+		}
+
+		if file == "" {
+			// the document has no table format, so it is printed as JSON unless YAML is asked for
+			return p.print(doc, func() error {
+				return p.printJSON(doc)
+			})
+		}
+
+		docBytes, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal backup document: %v", err)
+		}
+
+		// the document might contain join tokens, so it is only readable by the owner
+		if err := os.WriteFile(file, append(docBytes, '\n'), 0600); err != nil {
+			return fmt.Errorf("failed to write backup document: %v", err)
+		}
+
+		p.message("Backup exported to %q", file)
+		return nil
+	},
+}
+
+// deprecatedOutputFile returns the file set with the -o/--output flag, which set the file of the document before
+// it set the output format. A value of the flag which is not an output format is still read as the file, so the
+// scripts written before keep working, and the output format is reset to the default.
+func deprecatedOutputFile(cmd *cobra.Command) (string, error) {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", fmt.Errorf("cannot get output flag: %v", err)
+	}
+	if isOutputFormat(output) {
+		return "", nil
+	}
+
+	cmd.PrintErrln("Setting the file of the document with --output is deprecated, use --file instead")
+	if err := cmd.Flags().Set("output", outputTable); err != nil {
+		return "", fmt.Errorf("cannot set output flag: %v", err)
+	}
+	return output, nil
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Args:  cobra.ExactArgs(0),
+	Short: "Imports a JSON document written by the export command",
+	Long: `Imports a JSON document written by the export command. Entities in the document are created,
+or updated if they already exist. Entities that are not in the document are left untouched.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return fmt.Errorf("cannot get file flag: %v", err)
+		}
+		if input == "" {
+			return fmt.Errorf("the backup document file is required")
+		}
+
+		docBytes, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("failed to read backup document: %v", err)
+		}
+
+		var doc backup.Document
+		if err := json.Unmarshal(docBytes, &doc); err != nil {
+			return fmt.Errorf("failed to unmarshal backup document: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+
+		result, err := c.Import(&doc)
+		if err != nil {
+			return err
+		}
+
+		return p.print(result, func() error {
+			p.message("Backup imported: %d entities created, %d updated", result.Created, result.Updated)
+			return nil
+		})
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("file", "f", "", "File to write the document to. If not set the document is written to stdout.")
+	exportCmd.Flags().Bool("exclude-tokens", false, "Leaves the join tokens out of the document.")
+
+	importCmd.Flags().StringP("file", "f", "", "The document to import.")
+
+	RootCmd.AddCommand(exportCmd)
+	RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecatedOutputFile(t *testing.T) {
+	newCmd := func(output string) (*cobra.Command, *bytes.Buffer) {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("output", outputTable, "")
+		require.NoError(t, cmd.Flags().Set("output", output))
+
+		var errOut bytes.Buffer
+		cmd.SetErr(&errOut)
+		return cmd, &errOut
+	}
+
+	// an output format is not a file
+	cmd, errOut := newCmd(outputYAML)
+	file, err := deprecatedOutputFile(cmd)
+	require.NoError(t, err)
+	assert.Empty(t, file)
+	assert.Empty(t, errOut.String())
+
+	// any other value is the file set before the flag set the output format
+	cmd, errOut = newCmd("backup.json")
+	file, err = deprecatedOutputFile(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "backup.json", file)
+	assert.Contains(t, errOut.String(), "deprecated, use --file instead")
+
+	p, err := newPrinter(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, outputTable, p.format)
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/spf13/cobra"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle <list | approve | reject>",
+	Short: "Reviews the quarantined bundles",
+}
+
+var bundleListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the quarantined bundles.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		quarantined, err := c.ListQuarantinedBundles()
+		if err != nil {
+			return err
+		}
+
+		return p.print(quarantined, func() error {
+			if len(quarantined) == 0 {
+				p.message("No quarantined bundles found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(quarantined))
+			for _, qb := range quarantined {
+				rows = append(rows, []string{
+					qb.TrustDomainName.String(),
+					formatTime(qb.UpdatedAt),
+					formatTime(qb.ReleaseAt),
+					hex.EncodeToString(qb.Digest),
+					formatViolations(qb.Reasons),
+				})
+			}
+
+			return p.table([]string{"TRUST DOMAIN", "QUARANTINED AT", "RELEASE AT", "DIGEST", "REASONS"}, rows)
+		})
+	},
+}
+
+var bundleApproveCmd = &cobra.Command{
+	Use:   "approve",
+	Args:  cobra.ExactArgs(0),
+	Short: "Approves the quarantined bundle of a trust domain, which is distributed right away",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+
+		trustDomain, err := trustDomainFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := c.ApproveBundle(trustDomain); err != nil {
+			return err
+		}
+
+		p.message("Quarantined bundle of trust domain %q approved", trustDomain)
+		return nil
+	},
+}
+
+var bundleRejectCmd = &cobra.Command{
+	Use:   "reject",
+	Args:  cobra.ExactArgs(0),
+	Short: "Rejects the quarantined bundle of a trust domain, which keeps being rejected if posted again",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+
+		trustDomain, err := trustDomainFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := c.RejectBundle(trustDomain); err != nil {
+			return err
+		}
+
+		p.message("Quarantined bundle of trust domain %q rejected", trustDomain)
+		return nil
+	},
+}
+
+func trustDomainFlag(cmd *cobra.Command) (spiffeid.TrustDomain, error) {
+	td, err := cmd.Flags().GetString("trustDomain")
+	if err != nil {
+		return spiffeid.TrustDomain{}, fmt.Errorf("cannot get trust domain flag: %v", err)
+	}
+
+	return spiffeid.TrustDomainFromString(td)
+}
+
+func init() {
+	bundleApproveCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain whose quarantined bundle is approved.")
+	bundleRejectCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain whose quarantined bundle is rejected.")
+
+	bundleCmd.AddCommand(bundleListCmd)
+	bundleCmd.AddCommand(bundleApproveCmd)
+	bundleCmd.AddCommand(bundleRejectCmd)
+
+	RootCmd.AddCommand(bundleCmd)
+}
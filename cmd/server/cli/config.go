@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/telemetry"
 	"github.com/HewlettPackard/galadriel/pkg/common/util"
 	"github.com/HewlettPackard/galadriel/pkg/server"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/jobs"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
 	"github.com/hashicorp/hcl"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -18,6 +24,14 @@ const (
 	defaultPort       = 8085
 	defaultAddress    = "0.0.0.0"
 	defaultLogLevel   = "INFO"
+
+	defaultFederationPort = 8443
+	defaultPeeringPort    = 8444
+
+	defaultMetricsAddress = "127.0.0.1"
+	defaultMetricsPort    = 8088
+
+	defaultDBMigrationMode = string(datastore.MigrationModeAuto)
 )
 
 type Config struct {
@@ -30,6 +44,106 @@ type serverConfig struct {
 	SocketPath    string `hcl:"socket_path"`
 	LogLevel      string `hcl:"log_level"`
 	DBConnString  string `hcl:"db_conn_string"`
+
+	// DBMigrationMode is either "auto", to migrate the DB schema on startup, or "check", to refuse
+	// to start if the DB schema is out of date.
+	DBMigrationMode string `hcl:"db_migration_mode"`
+
+	// FederationEndpoint enables the SPIFFE federation bundle endpoint.
+	FederationEndpoint *federationEndpointConfig `hcl:"federation_endpoint"`
+
+	// Peering enables the peering with other Galadriel Servers.
+	Peering *peeringConfig `hcl:"peering"`
+
+	// BundlePolicy overrides the default validation policy of the bundles.
+	BundlePolicy *bundlePolicyConfig `hcl:"bundle_policy"`
+
+	// BundleMonitor overrides the default thresholds of the warnings about expiring and stale bundles.
+	BundleMonitor *bundleMonitorConfig `hcl:"bundle_monitor"`
+
+	// RelationshipRules constrain which trust domains can be federated.
+	RelationshipRules *relationshipRulesConfig `hcl:"relationship_rules"`
+
+	// Jobs override the default schedule and retention of the maintenance jobs.
+	Jobs []*jobConfig `hcl:"job"`
+
+	// Metrics enables the Prometheus metrics endpoint.
+	Metrics *metricsConfig `hcl:"metrics"`
+}
+
+type federationEndpointConfig struct {
+	ListenAddress string `hcl:"listen_address"`
+	ListenPort    int    `hcl:"listen_port"`
+	CertFile      string `hcl:"cert_file"`
+	KeyFile       string `hcl:"key_file"`
+
+	// PublicURL is the URL the endpoint is reached at, e.g., https://galadriel.example.org:8443.
+	PublicURL string `hcl:"public_url"`
+}
+
+type peeringConfig struct {
+	ListenAddress string `hcl:"listen_address"`
+	ListenPort    int    `hcl:"listen_port"`
+
+	// CertFile and KeyFile are the certificate presented to the peers, valid for the host of the peering
+	// endpoint, and CAFile holds the CA certificates the certificates of the peers are verified with.
+	CertFile string `hcl:"cert_file"`
+	KeyFile  string `hcl:"key_file"`
+	CAFile   string `hcl:"ca_file"`
+}
+
+type bundlePolicyConfig struct {
+	MaxAuthorities      int      `hcl:"max_authorities"`
+	MinRSAKeySize       int      `hcl:"min_rsa_key_size"`
+	AllowedCurves       []string `hcl:"allowed_curves"`
+	MaxAddedAuthorities int      `hcl:"max_added_authorities"`
+
+	// QuarantineDelay is a duration, e.g., "24h".
+	QuarantineDelay string `hcl:"quarantine_delay"`
+}
+
+// bundleMonitorConfig holds durations, e.g., "168h".
+type bundleMonitorConfig struct {
+	ExpiryWarning    string `hcl:"expiry_warning"`
+	StalenessWarning string `hcl:"staleness_warning"`
+}
+
+// relationshipRulesConfig holds a version of the relationship rules, e.g.:
+//
+//	relationship_rules {
+//	  version = "2023-06-01"
+//
+//	  rule "prod-dev" {
+//	    effect    = "deny"
+//	    condition = "a.labels.env == 'prod' && b.labels.env == 'dev'"
+//	  }
+//	}
+type relationshipRulesConfig struct {
+	Version string                    `hcl:"version"`
+	Rules   []*relationshipRuleConfig `hcl:"rule"`
+}
+
+type relationshipRuleConfig struct {
+	Name      string `hcl:",key"`
+	Effect    string `hcl:"effect"`
+	Condition string `hcl:"condition"`
+}
+
+// jobConfig overrides the settings of a maintenance job with durations, e.g.:
+//
+//	job "prune_bundle_rejections" {
+//	  schedule  = "6h"
+//	  retention = "2160h"
+//	}
+type jobConfig struct {
+	Name      string `hcl:",key"`
+	Schedule  string `hcl:"schedule"`
+	Retention string `hcl:"retention"`
+}
+
+type metricsConfig struct {
+	ListenAddress string `hcl:"listen_address"`
+	ListenPort    int    `hcl:"listen_port"`
 }
 
 // ParseConfig reads a configuration from the Reader and parses it
@@ -70,9 +184,153 @@ func NewServerConfig(c *Config) (*server.Config, error) {
 
 	sc.DBConnString = c.Server.DBConnString
 
+	sc.DBMigrationMode, err = datastore.ParseMigrationMode(c.Server.DBMigrationMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if fe := c.Server.FederationEndpoint; fe != nil {
+		if fe.CertFile == "" || fe.KeyFile == "" {
+			return nil, errors.New("federation_endpoint requires cert_file and key_file")
+		}
+
+		addrPort := fmt.Sprintf("%s:%d", fe.ListenAddress, fe.ListenPort)
+		sc.FederationAddress, err = net.ResolveTCPAddr("tcp", addrPort)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.FederationCertFile = fe.CertFile
+		sc.FederationKeyFile = fe.KeyFile
+
+		if fe.PublicURL != "" {
+			u, err := url.Parse(fe.PublicURL)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return nil, fmt.Errorf("federation_endpoint public_url %q must be an https URL", fe.PublicURL)
+			}
+			sc.FederationPublicURL = fe.PublicURL
+		}
+	}
+
+	if p := c.Server.Peering; p != nil {
+		if p.CertFile == "" || p.KeyFile == "" || p.CAFile == "" {
+			return nil, errors.New("peering requires cert_file, key_file and ca_file")
+		}
+
+		addrPort := fmt.Sprintf("%s:%d", p.ListenAddress, p.ListenPort)
+		sc.PeeringAddress, err = net.ResolveTCPAddr("tcp", addrPort)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.PeeringCertFile = p.CertFile
+		sc.PeeringKeyFile = p.KeyFile
+		sc.PeeringCAFile = p.CAFile
+	}
+
+	if bp := c.Server.BundlePolicy; bp != nil {
+		quarantineDelay, err := time.ParseDuration(bp.QuarantineDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle_policy quarantine_delay %q: %w", bp.QuarantineDelay, err)
+		}
+
+		sc.BundlePolicy, err = bundles.NewPolicy(bundles.PolicyConfig{
+			MaxAuthorities:      bp.MaxAuthorities,
+			MinRSAKeySize:       bp.MinRSAKeySize,
+			AllowedCurves:       bp.AllowedCurves,
+			MaxAddedAuthorities: bp.MaxAddedAuthorities,
+			QuarantineDelay:     quarantineDelay,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle_policy: %w", err)
+		}
+	}
+
+	if bm := c.Server.BundleMonitor; bm != nil {
+		sc.BundleExpiryWarning, err = parseThreshold("expiry_warning", bm.ExpiryWarning)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.BundleStalenessWarning, err = parseThreshold("staleness_warning", bm.StalenessWarning)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rr := c.Server.RelationshipRules; rr != nil {
+		rules := make([]relationships.RuleConfig, 0, len(rr.Rules))
+		for _, r := range rr.Rules {
+			rules = append(rules, relationships.RuleConfig{Name: r.Name, Effect: r.Effect, Condition: r.Condition})
+		}
+
+		sc.RelationshipRules, err = relationships.NewRuleSet(rr.Version, rules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relationship_rules: %w", err)
+		}
+	}
+
+	for _, j := range c.Server.Jobs {
+		if _, ok := sc.Jobs[j.Name]; ok {
+			return nil, fmt.Errorf("invalid job: job %q is configured more than once", j.Name)
+		}
+
+		var s jobs.Settings
+		if s.Schedule, err = parseJobDuration(j.Name, "schedule", j.Schedule); err != nil {
+			return nil, err
+		}
+		if s.Retention, err = parseJobDuration(j.Name, "retention", j.Retention); err != nil {
+			return nil, err
+		}
+		if err := jobs.ValidateSettings(j.Name, s); err != nil {
+			return nil, fmt.Errorf("invalid job: %w", err)
+		}
+
+		if sc.Jobs == nil {
+			sc.Jobs = make(map[string]jobs.Settings)
+		}
+		sc.Jobs[j.Name] = s
+	}
+
+	if m := c.Server.Metrics; m != nil {
+		addrPort := fmt.Sprintf("%s:%d", m.ListenAddress, m.ListenPort)
+		sc.MetricsAddress, err = net.ResolveTCPAddr("tcp", addrPort)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return sc, nil
 }
 
+// parseThreshold parses a positive duration of the bundle_monitor block.
+func parseThreshold(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bundle_monitor %s %q: %w", name, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid bundle_monitor %s %q: it must be positive", name, value)
+	}
+	return d, nil
+}
+
+// parseJobDuration parses a positive duration of a job block, which is zero if it is not set.
+func parseJobDuration(job, name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s of job %q: %w", name, job, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s of job %q: it must be positive", name, job)
+	}
+	return d, nil
+}
+
 func newConfig(configBytes []byte) (*Config, error) {
 	var config Config
 
@@ -105,4 +363,70 @@ func (c *Config) setDefaults() {
 	if c.Server.LogLevel == "" {
 		c.Server.LogLevel = defaultLogLevel
 	}
+
+	if c.Server.DBMigrationMode == "" {
+		c.Server.DBMigrationMode = defaultDBMigrationMode
+	}
+
+	if fe := c.Server.FederationEndpoint; fe != nil {
+		if fe.ListenAddress == "" {
+			fe.ListenAddress = defaultAddress
+		}
+
+		if fe.ListenPort == 0 {
+			fe.ListenPort = defaultFederationPort
+		}
+	}
+
+	if p := c.Server.Peering; p != nil {
+		if p.ListenAddress == "" {
+			p.ListenAddress = defaultAddress
+		}
+
+		if p.ListenPort == 0 {
+			p.ListenPort = defaultPeeringPort
+		}
+	}
+
+	if bp := c.Server.BundlePolicy; bp != nil {
+		if bp.MaxAuthorities == 0 {
+			bp.MaxAuthorities = bundles.DefaultMaxAuthorities
+		}
+
+		if bp.MinRSAKeySize == 0 {
+			bp.MinRSAKeySize = bundles.DefaultMinRSAKeySize
+		}
+
+		if bp.AllowedCurves == nil {
+			bp.AllowedCurves = bundles.DefaultAllowedCurves
+		}
+
+		if bp.MaxAddedAuthorities == 0 {
+			bp.MaxAddedAuthorities = bundles.DefaultMaxAddedAuthorities
+		}
+
+		if bp.QuarantineDelay == "" {
+			bp.QuarantineDelay = bundles.DefaultQuarantineDelay.String()
+		}
+	}
+
+	if bm := c.Server.BundleMonitor; bm != nil {
+		if bm.ExpiryWarning == "" {
+			bm.ExpiryWarning = bundles.DefaultExpiryWarning.String()
+		}
+
+		if bm.StalenessWarning == "" {
+			bm.StalenessWarning = bundles.DefaultStalenessWarning.String()
+		}
+	}
+
+	if m := c.Server.Metrics; m != nil {
+		if m.ListenAddress == "" {
+			m.ListenAddress = defaultMetricsAddress
+		}
+
+		if m.ListenPort == 0 {
+			m.ListenPort = defaultMetricsPort
+		}
+	}
 }
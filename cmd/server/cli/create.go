@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/cmd/server/util"
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
@@ -30,24 +31,46 @@ var createTrustDomainCmd = &cobra.Command{
 			return err
 		}
 
-		c := util.NewServerClient(defaultSocketPath)
+		publishBundle, err := cmd.Flags().GetBool("publish-bundle")
+		if err != nil {
+			return fmt.Errorf("cannot get publish-bundle flag: %v", err)
+		}
 
-		if err := c.CreateTrustDomain(&entity.TrustDomain{Name: trustDomain}); err != nil {
+		publishOIDC, err := cmd.Flags().GetBool("publish-oidc")
+		if err != nil {
+			return fmt.Errorf("cannot get publish-oidc flag: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Trust Domain created: %q\n", trustDomain.String())
+		c := util.NewServerClient(defaultSocketPath)
 
-		return nil
+		created, err := c.CreateTrustDomain(&entity.TrustDomain{Name: trustDomain, PublishBundle: publishBundle, PublishOIDC: publishOIDC})
+		if err != nil {
+			return err
+		}
+
+		return p.print(created, func() error {
+			p.message("Trust Domain created: %q", created.Name.String())
+			return nil
+		})
 	},
 }
 
 var createRelationshipCmd = &cobra.Command{
 	Use:   "relationship",
-	Short: "Registers a bidirectional federation relationship between two trust domains.",
+	Short: "Registers a federation relationship between two trust domains.",
 	Args:  cobra.ExactArgs(0),
 
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
 		c := util.NewServerClient(defaultSocketPath)
 
 		tdA, err := cmd.Flags().GetString("trustDomainA")
@@ -69,15 +92,43 @@ var createRelationshipCmd = &cobra.Command{
 			return err
 		}
 
-		if err := c.CreateRelationship(&entity.Relationship{
+		dir, err := cmd.Flags().GetString("direction")
+		if err != nil {
+			return fmt.Errorf("cannot get direction flag: %v", err)
+		}
+		direction := entity.RelationshipDirection(dir)
+		if err := direction.Validate(); err != nil {
+			return err
+		}
+
+		expiresIn, err := cmd.Flags().GetDuration("expires-in")
+		if err != nil {
+			return fmt.Errorf("cannot get expires-in flag: %v", err)
+		}
+		if expiresIn < 0 {
+			return fmt.Errorf("expires-in must be positive")
+		}
+
+		rel := &entity.Relationship{
 			TrustDomainAName: trustDomain1,
 			TrustDomainBName: trustDomain2,
-		}); err != nil {
+			Direction:        direction,
+		}
+		if expiresIn > 0 {
+			rel.NotAfter = time.Now().Add(expiresIn)
+		}
+		created, err := c.CreateRelationship(rel)
+		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Relationship created between trust domain %q and trust domain %q\n", trustDomain1.String(), trustDomain2.String())
-		return nil
+		return p.print(created, func() error {
+			p.message("Relationship created between trust domain %q and trust domain %q: %s", trustDomain1.String(), trustDomain2.String(), created.DirectionString())
+			if !created.NotAfter.IsZero() {
+				p.message("The relationship expires at %s", formatTime(created.NotAfter))
+			}
+			return nil
+		})
 	},
 }
 
@@ -86,9 +137,13 @@ func init() {
 	createCmd.AddCommand(createTrustDomainCmd)
 
 	createTrustDomainCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain name.")
+	createTrustDomainCmd.PersistentFlags().Bool("publish-bundle", false, "Serves the trust domain bundle from the SPIFFE federation bundle endpoint.")
+	createTrustDomainCmd.PersistentFlags().Bool("publish-oidc", false, "Serves the JWT authorities of the trust domain as an OIDC discovery document.")
 
 	createRelationshipCmd.PersistentFlags().StringP("trustDomainA", "a", "", "A trust domain name to participate in a relationship.")
 	createRelationshipCmd.PersistentFlags().StringP("trustDomainB", "b", "", "A trust domain name to participate in a relationship.")
+	createRelationshipCmd.PersistentFlags().Duration("expires-in", 0, "How long the relationship is in effect, e.g. 720h. If not set, it does not expire.")
+	createRelationshipCmd.PersistentFlags().String("direction", string(entity.RelationshipBidirectional), "Which trust domains trust their peer: bidirectional, a_trusts_b or b_trusts_a.")
 
 	RootCmd.AddCommand(createCmd)
 }
@@ -17,6 +17,11 @@ var tokenCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(0),
 	Short: "Generates a join token for provided trust domain",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
 		c := util.NewServerClient(defaultSocketPath)
 
 		td, err := cmd.Flags().GetString("trustDomain")
@@ -34,8 +39,10 @@ var tokenCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Println("Join Token: " + at.Token)
-		return nil
+		return p.print(at, func() error {
+			p.message("Join Token: %s", at.Token)
+			return nil
+		})
 	},
 }
 
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spf13/cobra"
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group <create | list | delete | add | remove>",
+	Short: "Manages the federation groups, whose members are federated with each other",
+}
+
+var groupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Args:  cobra.ExactArgs(0),
+	Short: "Creates a federation group.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := groupNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		mode, err := cmd.Flags().GetString("mode")
+		if err != nil {
+			return fmt.Errorf("cannot get mode flag: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		group, err := c.CreateFederationGroup(&entity.FederationGroup{Name: name, Mode: entity.FederationGroupMode(mode)})
+		if err != nil {
+			return err
+		}
+
+		return p.print(group, func() error {
+			p.message("Federation group %q created in %s mode", group.Name, group.Mode)
+			return nil
+		})
+	},
+}
+
+var groupListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the federation groups along with their members.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		groups, err := c.ListFederationGroups()
+		if err != nil {
+			return err
+		}
+
+		return p.print(groups, func() error {
+			if len(groups) == 0 {
+				p.message("No federation groups found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(groups))
+			for _, g := range groups {
+				members := make([]string, len(g.Members))
+				for i, m := range g.Members {
+					members[i] = m.TrustDomainName.String()
+					if m.Hub {
+						members[i] += " (hub)"
+					}
+				}
+				rows = append(rows, []string{g.Name, string(g.Mode), strings.Join(members, ", ")})
+			}
+
+			return p.table([]string{"NAME", "MODE", "MEMBERS"}, rows)
+		})
+	},
+}
+
+var groupDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Args:  cobra.ExactArgs(0),
+	Short: "Deletes a federation group. Its members are no longer federated through it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := groupNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.DeleteFederationGroup(name); err != nil {
+			return err
+		}
+
+		p.message("Federation group %q deleted", name)
+		return nil
+	},
+}
+
+var groupAddCmd = &cobra.Command{
+	Use:   "add",
+	Args:  cobra.ExactArgs(0),
+	Short: "Adds a trust domain to a federation group.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := groupNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		trustDomain, err := trustDomainFlag(cmd)
+		if err != nil {
+			return err
+		}
+		hub, err := cmd.Flags().GetBool("hub")
+		if err != nil {
+			return fmt.Errorf("cannot get hub flag: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.AddFederationGroupMember(name, trustDomain, hub); err != nil {
+			return err
+		}
+
+		p.message("Trust domain %q added to federation group %q", trustDomain, name)
+		return nil
+	},
+}
+
+var groupRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Args:  cobra.ExactArgs(0),
+	Short: "Removes a trust domain from a federation group.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := groupNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		trustDomain, err := trustDomainFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.RemoveFederationGroupMember(name, trustDomain); err != nil {
+			return err
+		}
+
+		p.message("Trust domain %q removed from federation group %q", trustDomain, name)
+		return nil
+	},
+}
+
+func groupNameFlag(cmd *cobra.Command) (string, error) {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return "", fmt.Errorf("cannot get name flag: %v", err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("the name of the federation group is required")
+	}
+
+	return name, nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{groupCreateCmd, groupDeleteCmd, groupAddCmd, groupRemoveCmd} {
+		cmd.PersistentFlags().StringP("name", "n", "", "The name of the federation group.")
+	}
+	groupCreateCmd.PersistentFlags().String("mode", string(entity.FederationGroupMesh), "How the members trust each other: mesh, or hub_and_spoke where the members only trust the hub.")
+	groupAddCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain added to the group.")
+	groupAddCmd.PersistentFlags().Bool("hub", false, "Whether the trust domain is the hub of a hub_and_spoke group.")
+	groupRemoveCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain removed from the group.")
+
+	groupCmd.AddCommand(groupCreateCmd)
+	groupCmd.AddCommand(groupListCmd)
+	groupCmd.AddCommand(groupDeleteCmd)
+	groupCmd.AddCommand(groupAddCmd)
+	groupCmd.AddCommand(groupRemoveCmd)
+
+	RootCmd.AddCommand(groupCmd)
+}
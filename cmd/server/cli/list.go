@@ -1,15 +1,21 @@
 package cli
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
-	Use:   "list <trustdomains | relationships>",
-	Short: "Lists trust domains and relationships",
+	Use:   "list <trustdomains | relationships | rejections | jobs>",
+	Short: "Lists trust domains, relationships, rejected bundles and maintenance jobs",
 }
 
 var listTrustDomainCmd = &cobra.Command{
@@ -17,24 +23,44 @@ var listTrustDomainCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(0),
 	Short: "Lists all the Trust Domains.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		wide, err := cmd.Flags().GetBool("wide")
+		if err != nil {
+			return err
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
 		c := util.NewServerClient(defaultSocketPath)
 		trustDomains, err := c.ListTrustDomains()
 		if err != nil {
 			return err
 		}
 
-		if len(trustDomains) == 0 {
-			fmt.Println("No trust domains found")
-			return nil
-		}
+		return p.print(trustDomains, func() error {
+			if len(trustDomains) == 0 {
+				p.message("No trust domains found")
+				return nil
+			}
 
-		for _, m := range trustDomains {
-			fmt.Printf("ID: %s\n", m.ID.UUID)
-			fmt.Printf("Trust Domain: %s\n", m.Name)
-			fmt.Println()
-		}
+			header := []string{"NAME", "DESCRIPTION", "LABELS", "BUNDLE DIGEST", "BUNDLE UPDATED", "BUNDLE EXPIRES", "BUNDLE HEALTH"}
+			if wide {
+				header = append(header, "ID", "PEER ID", "HARVESTER LAST SEEN", "HARVESTER ADDRESS", "HARVESTER VERSION", "SPIRE VERSION")
+			}
 
-		return nil
+			rows := make([][]string, 0, len(trustDomains))
+			for _, td := range trustDomains {
+				row := append([]string{td.Name.String(), td.Description, entity.FormatLabels(td.Labels)}, bundleHealthCells(td.BundleHealth)...)
+				if wide {
+					row = append(row, td.ID.UUID.String(), nullUUID(td.PeerID))
+					row = append(row, harvesterSessionCells(td.HarvesterSession)...)
+				}
+				rows = append(rows, row)
+			}
+
+			return p.table(header, rows)
+		})
 	},
 }
 
@@ -43,31 +69,175 @@ var listRelationshipsCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(0),
 	Short: "Lists all the relationships.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
 		c := util.NewServerClient(defaultSocketPath)
 		rels, err := c.ListRelationships()
 		if err != nil {
 			return err
 		}
 
-		if len(rels) == 0 {
-			fmt.Println("No relationships found")
-			return nil
+		return p.print(rels, func() error {
+			if len(rels) == 0 {
+				p.message("No relationships found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(rels))
+			for _, r := range rels {
+				rows = append(rows, []string{
+					r.ID.UUID.String(),
+					r.TrustDomainAName.String(),
+					r.TrustDomainBName.String(),
+					strconv.FormatBool(r.TrustDomainAConsent),
+					strconv.FormatBool(r.TrustDomainBConsent),
+					r.DirectionString(),
+					formatTime(r.NotBefore),
+					formatTime(r.NotAfter),
+					strconv.FormatBool(!r.ExpiredAt.IsZero()),
+				})
+			}
+
+			return p.table([]string{"ID", "TRUST DOMAIN A", "TRUST DOMAIN B", "A CONSENT", "B CONSENT", "DIRECTION", "NOT BEFORE", "NOT AFTER", "EXPIRED"}, rows)
+		})
+	},
+}
+
+var listRejectionsCmd = &cobra.Command{
+	Use:   "rejections",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the bundles rejected by the bundle validation policy.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
 		}
 
-		for _, r := range rels {
-			fmt.Printf("ID: %s\n", r.ID.UUID)
-			fmt.Printf("Trust Domain A: %s\n", r.TrustDomainAName.String())
-			fmt.Printf("Trust Domain B: %s\n", r.TrustDomainBName.String())
-			fmt.Println()
+		c := util.NewServerClient(defaultSocketPath)
+		rejections, err := c.ListBundleRejections()
+		if err != nil {
+			return err
 		}
 
-		return nil
+		return p.print(rejections, func() error {
+			if len(rejections) == 0 {
+				p.message("No rejected bundles found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(rejections))
+			for _, r := range rejections {
+				rows = append(rows, []string{
+					r.TrustDomainName.String(),
+					formatTime(r.CreatedAt),
+					hex.EncodeToString(r.Digest),
+					formatViolations(r.Violations),
+				})
+			}
+
+			return p.table([]string{"TRUST DOMAIN", "REJECTED AT", "DIGEST", "VIOLATIONS"}, rows)
+		})
 	},
 }
 
+var listJobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the last run of each maintenance job.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		statuses, err := c.ListJobs()
+		if err != nil {
+			return err
+		}
+
+		return p.print(statuses, func() error {
+			if len(statuses) == 0 {
+				p.message("No job runs found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(statuses))
+			for _, js := range statuses {
+				result := js.Result
+				if js.Error != "" {
+					result = "failed: " + js.Error
+				}
+				rows = append(rows, []string{
+					js.Name,
+					js.Schedule,
+					formatTime(js.StartedAt),
+					js.FinishedAt.Sub(js.StartedAt).Round(time.Millisecond).String(),
+					formatTime(js.NextRunAt),
+					result,
+				})
+			}
+
+			return p.table([]string{"NAME", "SCHEDULE", "LAST RUN", "DURATION", "NEXT RUN", "RESULT"}, rows)
+		})
+	},
+}
+
+// bundleHealthCells returns the digest of the bundle of a trust domain, when it was last updated and when it
+// expires, along with whether it is reported as stale or expiring.
+func bundleHealthCells(h *entity.BundleHealth) []string {
+	if h == nil {
+		return []string{"", "", "", "no bundle"}
+	}
+
+	var health []string
+	if h.Stale {
+		health = append(health, "stale")
+	}
+	if h.Expiring {
+		health = append(health, "expiring")
+	}
+	if len(health) == 0 {
+		health = append(health, "ok")
+	}
+
+	return []string{hex.EncodeToString(h.Digest), formatTime(h.UpdatedAt), formatTime(h.EarliestExpiry), strings.Join(health, ",")}
+}
+
+// harvesterSessionCells returns when the harvester of a trust domain was last seen, the address it called from
+// and the versions it reported.
+func harvesterSessionCells(s *entity.HarvesterSession) []string {
+	if s == nil {
+		return []string{"never", "", "", ""}
+	}
+	return []string{formatTime(s.UpdatedAt), s.RemoteAddress, s.HarvesterVersion, s.SPIREVersion}
+}
+
+func formatViolations(violations []entity.BundleViolation) string {
+	formatted := make([]string, len(violations))
+	for i, v := range violations {
+		formatted[i] = fmt.Sprintf("%s: %s", v.Code, v.Message)
+	}
+	return strings.Join(formatted, "; ")
+}
+
+func nullUUID(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
+
 func init() {
+	listTrustDomainCmd.Flags().Bool("wide", false, "Also prints the IDs, peers and harvester sessions of the trust domains in the table output.")
+
 	listCmd.AddCommand(listTrustDomainCmd)
 	listCmd.AddCommand(listRelationshipsCmd)
+	listCmd.AddCommand(listRejectionsCmd)
+	listCmd.AddCommand(listJobsCmd)
 
 	RootCmd.AddCommand(listCmd)
 }
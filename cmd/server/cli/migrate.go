@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/telemetry"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <up|down|status|version>",
+	Short: "Manages the database schema",
+	Long: `Manages the database schema. The database is read from the server configuration.
+Migrations are serialized with any server that is starting up, or other migrate commands.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Args:  cobra.ExactArgs(0),
+	Short: "Migrates the database schema to the version supported by this server",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		if err := m.Up(cmd.Context()); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+
+		return printSchemaStatus(cmd, m)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Args:  cobra.ExactArgs(0),
+	Short: "Reverts the last migrations applied to the database schema",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, err := cmd.Flags().GetInt("steps")
+		if err != nil {
+			return fmt.Errorf("cannot get steps flag: %v", err)
+		}
+
+		m, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		if err := m.Down(cmd.Context(), steps); err != nil {
+			return fmt.Errorf("failed to revert schema migrations: %w", err)
+		}
+
+		return printSchemaStatus(cmd, m)
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Args:  cobra.ExactArgs(0),
+	Short: "Shows whether the database schema is up to date",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		return printSchemaStatus(cmd, m)
+	},
+}
+
+var migrateVersionCmd = &cobra.Command{
+	Use:   "version",
+	Args:  cobra.ExactArgs(0),
+	Short: "Prints the version of the database schema",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		status, err := m.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		return p.print(status, func() error {
+			p.message("%d", status.Version)
+			return nil
+		})
+	},
+}
+
+func newMigrator(cmd *cobra.Command) (*datastore.Migrator, error) {
+	config, err := LoadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := config.Logger.WithField(telemetry.SubsystemName, telemetry.Datastore)
+
+	return datastore.NewMigrator(logger, config.DBConnString)
+}
+
+func printSchemaStatus(cmd *cobra.Command, m *datastore.Migrator) error {
+	p, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+
+	status, err := m.Status(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	return p.print(status, func() error {
+		p.message("Schema version: %d", status.Version)
+		p.message("Supported version: %d", status.SupportedVersion)
+
+		switch {
+		case status.Dirty:
+			p.message("Status: dirty, the last migration failed and the schema must be fixed manually")
+		case status.Version < status.SupportedVersion:
+			p.message("Status: %d migrations pending", status.SupportedVersion-status.Version)
+		case status.Version > status.SupportedVersion:
+			p.message("Status: the schema is newer than the version supported by this server")
+		default:
+			p.message("Status: up to date")
+		}
+		return nil
+	})
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateVersionCmd)
+
+	migrateCmd.PersistentFlags().StringP("config", "c", defaultConfigPath, "config file path")
+	migrateDownCmd.Flags().Int("steps", 1, "The number of migrations to revert.")
+
+	RootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// The formats of the output of the commands, set by the global --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printer prints the results of a command in the format set by the --output flag. The JSON and YAML formats
+// print the results with the schema of the management API, so scripts can parse them, while the table format
+// prints them in aligned columns for people to read.
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+func newPrinter(cmd *cobra.Command) (*printer, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get output flag: %v", err)
+	}
+
+	if !isOutputFormat(format) {
+		return nil, fmt.Errorf("invalid output format %q, expected one of: table, json, yaml", format)
+	}
+
+	return &printer{format: format, out: cmd.OutOrStdout()}, nil
+}
+
+func isOutputFormat(format string) bool {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return true
+	default:
+		return false
+	}
+}
+
+// print prints the value as JSON or YAML, or with the table function in the table format.
+func (p *printer) print(v any, table func() error) error {
+	switch p.format {
+	case outputJSON:
+		return p.printJSON(v)
+	case outputYAML:
+		return p.printYAML(v)
+	default:
+		return table()
+	}
+}
+
+// message prints a message in the table format only, as the JSON and YAML formats only print results. The
+// commands without results print nothing in these formats, their exit status tells whether they succeeded.
+func (p *printer) message(format string, args ...any) {
+	if p.format == outputTable {
+		fmt.Fprintf(p.out, format+"\n", args...)
+	}
+}
+
+// table prints the rows in aligned columns under the header.
+func (p *printer) table(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = cell(c)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func (p *printer) printJSON(v any) error {
+	b, err := json.MarshalIndent(emptyIfNil(v), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %v", err)
+	}
+
+	_, err = fmt.Fprintln(p.out, string(b))
+	return err
+}
+
+// printYAML prints the value converted from its JSON encoding, so it has the same fields, in the same order,
+// as in the JSON format.
+func (p *printer) printYAML(v any) error {
+	b, err := json.Marshal(emptyIfNil(v))
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %v", err)
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return fmt.Errorf("failed to convert output to YAML: %v", err)
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(p.out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("failed to marshal output: %v", err)
+	}
+
+	return enc.Close()
+}
+
+// blockStyle clears the flow style and quotes the node was decoded with from JSON, so it is printed in the block
+// style. The strings that would be read as another type are still quoted.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+// emptyIfNil returns an empty list in place of a nil slice, so an empty result is printed as an empty list
+// rather than null.
+func emptyIfNil(v any) any {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		return []any{}
+	}
+	return v
+}
+
+// cell returns the value printed in a table cell, on a single line and with a dash for empty values.
+func cell(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// formatTime returns the time printed in a table cell, or an empty string for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func init() {
+	RootCmd.PersistentFlags().StringP("output", "o", outputTable, "The output format: table, json or yaml.")
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spf13/cobra"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPrinter(t *testing.T, format string) (*printer, *bytes.Buffer) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", format, "")
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	p, err := newPrinter(cmd)
+	require.NoError(t, err)
+	return p, &out
+}
+
+func TestNewPrinterInvalidFormat(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", "xml", "")
+
+	_, err := newPrinter(cmd)
+	assert.EqualError(t, err, `invalid output format "xml", expected one of: table, json, yaml`)
+}
+
+func TestPrinter(t *testing.T) {
+	rels := []*entity.Relationship{
+		{
+			TrustDomainAName:    spiffeid.RequireTrustDomainFromString("a.test"),
+			TrustDomainBName:    spiffeid.RequireTrustDomainFromString("b.test"),
+			TrustDomainAConsent: true,
+			Direction:           entity.RelationshipBidirectional,
+			NotAfter:            time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	table := func(p *printer) func() error {
+		return func() error {
+			p.message("Relationships:")
+			return p.table([]string{"TRUST DOMAIN A", "TRUST DOMAIN B", "NOT BEFORE"}, [][]string{
+				{rels[0].TrustDomainAName.String(), rels[0].TrustDomainBName.String(), formatTime(rels[0].NotBefore)},
+			})
+		}
+	}
+
+	p, out := newTestPrinter(t, outputTable)
+	require.NoError(t, p.print(rels, table(p)))
+	assert.Equal(t, "Relationships:\n"+
+		"TRUST DOMAIN A   TRUST DOMAIN B   NOT BEFORE\n"+
+		"a.test           b.test           -\n", out.String())
+
+	// the structured formats only print the results, with the schema of the API
+	p, out = newTestPrinter(t, outputJSON)
+	require.NoError(t, p.print(rels, table(p)))
+	assert.Contains(t, out.String(), "[\n  {\n")
+	assert.Contains(t, out.String(), `  "trust_domain_a_name": "a.test",`)
+	assert.Contains(t, out.String(), `  "not_after": "2023-05-01T12:00:00Z",`)
+	assert.NotContains(t, out.String(), "Relationships:")
+
+	// the YAML format has the fields of the JSON format, in the same order
+	p, out = newTestPrinter(t, outputYAML)
+	require.NoError(t, p.print(rels, table(p)))
+	assert.Contains(t, out.String(), "- ID: null\n")
+	assert.Contains(t, out.String(), "  trust_domain_a_name: a.test\n  trust_domain_b_name: b.test\n  trust_domain_a_consent: true\n")
+	assert.Contains(t, out.String(), `  not_after: "2023-05-01T12:00:00Z"`)
+	assert.NotContains(t, out.String(), "Relationships:")
+}
+
+func TestPrinterEmptyResults(t *testing.T) {
+	var rels []*entity.Relationship
+
+	p, out := newTestPrinter(t, outputJSON)
+	require.NoError(t, p.print(rels, nil))
+	assert.Equal(t, "[]\n", out.String())
+
+	p, out = newTestPrinter(t, outputYAML)
+	require.NoError(t, p.print(rels, nil))
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestPrinterYAMLQuoting(t *testing.T) {
+	td := &entity.TrustDomain{
+		Name:        spiffeid.RequireTrustDomainFromString("a.test"),
+		Description: "first line\nsecond line",
+		Labels:      map[string]string{"tier": "1", "prod": "true"},
+	}
+
+	p, out := newTestPrinter(t, outputYAML)
+	require.NoError(t, p.print(td, nil))
+
+	// the strings that would be read as another type are quoted
+	assert.Contains(t, out.String(), "labels:\n  prod: \"true\"\n  tier: \"1\"\n")
+	assert.Contains(t, out.String(), "description: |-\n  first line\n  second line\n")
+}
+
+func TestCell(t *testing.T) {
+	assert.Equal(t, "-", cell(""))
+	assert.Equal(t, "first line second line", cell("first line\nsecond\tline"))
+}
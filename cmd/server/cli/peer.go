@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spf13/cobra"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+var peerCmd = &cobra.Command{
+	Use:   "peer <create | list | delete | share | unshare>",
+	Short: "Manages the peer Galadriel Servers, which exchange the bundles of the trust domains they share with each other",
+}
+
+var peerCreateCmd = &cobra.Command{
+	Use:   "create",
+	Args:  cobra.ExactArgs(0),
+	Short: "Creates a peer. The host of its URL must match the certificate it presents.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := peerNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		url, err := cmd.Flags().GetString("url")
+		if err != nil {
+			return fmt.Errorf("cannot get url flag: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		peer, err := c.CreatePeer(&entity.Peer{Name: name, URL: url})
+		if err != nil {
+			return err
+		}
+
+		return p.print(peer, func() error {
+			p.message("Peer %q created with URL %s", peer.Name, peer.URL)
+			return nil
+		})
+	},
+}
+
+var peerListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the peers along with the trust domains shared with them and imported from them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		peers, err := c.ListPeers()
+		if err != nil {
+			return err
+		}
+
+		return p.print(peers, func() error {
+			if len(peers) == 0 {
+				p.message("No peers found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(peers))
+			for _, peer := range peers {
+				rows = append(rows, []string{peer.Name, peer.URL, joinTrustDomains(peer.SharedTrustDomains), joinTrustDomains(peer.TrustDomains)})
+			}
+
+			return p.table([]string{"NAME", "URL", "SHARED TRUST DOMAINS", "IMPORTED TRUST DOMAINS"}, rows)
+		})
+	},
+}
+
+var peerDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Args:  cobra.ExactArgs(0),
+	Short: "Deletes a peer, along with the trust domains imported from it and their relationships.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := peerNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.DeletePeer(name); err != nil {
+			return err
+		}
+
+		p.message("Peer %q deleted", name)
+		return nil
+	},
+}
+
+var peerShareCmd = &cobra.Command{
+	Use:   "share",
+	Args:  cobra.ExactArgs(0),
+	Short: "Shares a trust domain with a peer, which imports it along with its bundle.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := peerNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		trustDomain, err := trustDomainFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.SharePeerTrustDomain(name, trustDomain); err != nil {
+			return err
+		}
+
+		p.message("Trust domain %q shared with peer %q", trustDomain, name)
+		return nil
+	},
+}
+
+var peerUnshareCmd = &cobra.Command{
+	Use:   "unshare",
+	Args:  cobra.ExactArgs(0),
+	Short: "Stops sharing a trust domain with a peer.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := peerNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		trustDomain, err := trustDomainFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.UnsharePeerTrustDomain(name, trustDomain); err != nil {
+			return err
+		}
+
+		p.message("Trust domain %q no longer shared with peer %q", trustDomain, name)
+		return nil
+	},
+}
+
+func peerNameFlag(cmd *cobra.Command) (string, error) {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return "", fmt.Errorf("cannot get name flag: %v", err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("the name of the peer is required")
+	}
+
+	return name, nil
+}
+
+func joinTrustDomains(trustDomains []spiffeid.TrustDomain) string {
+	names := make([]string, len(trustDomains))
+	for i, td := range trustDomains {
+		names[i] = td.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{peerCreateCmd, peerDeleteCmd, peerShareCmd, peerUnshareCmd} {
+		cmd.PersistentFlags().StringP("name", "n", "", "The name of the peer.")
+	}
+	peerCreateCmd.PersistentFlags().String("url", "", "The https URL of the peering endpoint of the peer, e.g. https://galadriel.example.org:8444.")
+	peerShareCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain shared with the peer.")
+	peerUnshareCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain no longer shared with the peer.")
+
+	peerCmd.AddCommand(peerCreateCmd)
+	peerCmd.AddCommand(peerListCmd)
+	peerCmd.AddCommand(peerDeleteCmd)
+	peerCmd.AddCommand(peerShareCmd)
+	peerCmd.AddCommand(peerUnshareCmd)
+
+	RootCmd.AddCommand(peerCmd)
+}
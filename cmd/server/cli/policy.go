@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spf13/cobra"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy <create | list | delete | test>",
+	Short: "Manages the federation policies, which federate trust domains selected by their labels, and tests the relationship rules",
+}
+
+var policyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Args:  cobra.ExactArgs(0),
+	Short: "Creates a federation policy.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := policyNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+		selector, err := selectorFlag(cmd, "selector")
+		if err != nil {
+			return err
+		}
+		peerSelector, err := selectorFlag(cmd, "peer-selector")
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		policy, err := c.CreateFederationPolicy(&entity.FederationPolicy{Name: name, Selector: selector, PeerSelector: peerSelector})
+		if err != nil {
+			return err
+		}
+
+		return p.print(policy, func() error {
+			p.message("Federation policy %q created, federating %s with %s", policy.Name, policy.Selector, policy.PeerSelector)
+			return nil
+		})
+	},
+}
+
+var policyListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the federation policies.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		policies, err := c.ListFederationPolicies()
+		if err != nil {
+			return err
+		}
+
+		return p.print(policies, func() error {
+			if len(policies) == 0 {
+				p.message("No federation policies found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(policies))
+			for _, fp := range policies {
+				rows = append(rows, []string{fp.Name, fp.Selector.String(), fp.PeerSelector.String()})
+			}
+
+			return p.table([]string{"NAME", "SELECTOR", "PEER SELECTOR"}, rows)
+		})
+	},
+}
+
+var policyDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Args:  cobra.ExactArgs(0),
+	Short: "Deletes a federation policy. The trust domains it selected are no longer federated through it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := policyNameFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.DeleteFederationPolicy(name); err != nil {
+			return err
+		}
+
+		p.message("Federation policy %q deleted", name)
+		return nil
+	},
+}
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test",
+	Args:  cobra.ExactArgs(0),
+	Short: "Evaluates the relationship rules for a relationship between two trust domains, without creating it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tdA, err := cmd.Flags().GetString("trustDomainA")
+		if err != nil {
+			return fmt.Errorf("cannot get trust domain A flag: %v", err)
+		}
+		trustDomainA, err := spiffeid.TrustDomainFromString(tdA)
+		if err != nil {
+			return err
+		}
+
+		tdB, err := cmd.Flags().GetString("trustDomainB")
+		if err != nil {
+			return fmt.Errorf("cannot get trust domain B flag: %v", err)
+		}
+		trustDomainB, err := spiffeid.TrustDomainFromString(tdB)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		decision, err := c.TestRelationship(trustDomainA, trustDomainB)
+		if err != nil {
+			return err
+		}
+
+		return p.print(decision, func() error {
+			if decision.Allowed {
+				p.message("Relationship between %q and %q allowed: %s", trustDomainA, trustDomainB, decision.Reason)
+			} else {
+				p.message("Relationship between %q and %q denied: %s", trustDomainA, trustDomainB, decision.Reason)
+			}
+			if decision.Version != "" {
+				p.message("Relationship rules version: %s", decision.Version)
+			}
+			return nil
+		})
+	},
+}
+
+func policyNameFlag(cmd *cobra.Command) (string, error) {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return "", fmt.Errorf("cannot get name flag: %v", err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("the name of the federation policy is required")
+	}
+
+	return name, nil
+}
+
+func selectorFlag(cmd *cobra.Command, flag string) (entity.LabelSelector, error) {
+	value, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get %s flag: %v", flag, err)
+	}
+
+	selector, err := entity.ParseLabelSelector(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", flag, err)
+	}
+
+	return selector, nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{policyCreateCmd, policyDeleteCmd} {
+		cmd.PersistentFlags().StringP("name", "n", "", "The name of the federation policy.")
+	}
+	policyCreateCmd.PersistentFlags().String("selector", "", "Comma-separated key=value labels of the trust domains the policy applies to.")
+	policyCreateCmd.PersistentFlags().String("peer-selector", "", "Comma-separated key=value labels of the trust domains they are federated with.")
+	policyTestCmd.PersistentFlags().StringP("trustDomainA", "a", "", "A trust domain name of the relationship.")
+	policyTestCmd.PersistentFlags().StringP("trustDomainB", "b", "", "A trust domain name of the relationship.")
+
+	policyCmd.AddCommand(policyCreateCmd)
+	policyCmd.AddCommand(policyListCmd)
+	policyCmd.AddCommand(policyDeleteCmd)
+	policyCmd.AddCommand(policyTestCmd)
+
+	RootCmd.AddCommand(policyCmd)
+}
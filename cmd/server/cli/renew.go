@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/spf13/cobra"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+var renewCmd = &cobra.Command{
+	Use:   "renew <relationship>",
+	Short: "Renews relationships",
+}
+
+var renewRelationshipCmd = &cobra.Command{
+	Use:   "relationship",
+	Args:  cobra.ExactArgs(0),
+	Short: "Extends the validity of a relationship, bringing it back into effect if it expired.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tdA, err := cmd.Flags().GetString("trustDomainA")
+		if err != nil {
+			return fmt.Errorf("cannot get trust domain A flag: %v", err)
+		}
+		trustDomainA, err := spiffeid.TrustDomainFromString(tdA)
+		if err != nil {
+			return err
+		}
+
+		tdB, err := cmd.Flags().GetString("trustDomainB")
+		if err != nil {
+			return fmt.Errorf("cannot get trust domain B flag: %v", err)
+		}
+		trustDomainB, err := spiffeid.TrustDomainFromString(tdB)
+		if err != nil {
+			return err
+		}
+
+		expiresIn, err := cmd.Flags().GetDuration("expires-in")
+		if err != nil {
+			return fmt.Errorf("cannot get expires-in flag: %v", err)
+		}
+		if expiresIn <= 0 {
+			return fmt.Errorf("expires-in must be positive")
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		rel, err := c.RenewRelationship(trustDomainA, trustDomainB, time.Now().Add(expiresIn))
+		if err != nil {
+			return err
+		}
+
+		return p.print(rel, func() error {
+			p.message("Relationship between trust domain %q and trust domain %q renewed until %s", trustDomainA, trustDomainB, formatTime(rel.NotAfter))
+			return nil
+		})
+	},
+}
+
+func init() {
+	renewCmd.AddCommand(renewRelationshipCmd)
+
+	renewRelationshipCmd.PersistentFlags().StringP("trustDomainA", "a", "", "A trust domain name participating in the relationship.")
+	renewRelationshipCmd.PersistentFlags().StringP("trustDomainB", "b", "", "The other trust domain name participating in the relationship.")
+	renewRelationshipCmd.PersistentFlags().Duration("expires-in", 0, "How long the relationship is in effect from now, e.g. 720h.")
+
+	RootCmd.AddCommand(renewCmd)
+}
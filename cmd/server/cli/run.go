@@ -26,6 +26,12 @@ func NewRunCmd() *cobra.Command {
 				return err
 			}
 
+			devMode, err := cmd.Flags().GetBool("dev")
+			if err != nil {
+				return fmt.Errorf("cannot read flag dev: %w", err)
+			}
+			config.DevMode = devMode
+
 			s := server.New(config)
 
 			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -82,6 +88,7 @@ func LoadConfig(cmd *cobra.Command) (*server.Config, error) {
 func init() {
 	runCmd := NewRunCmd()
 	runCmd.Flags().StringP("config", "c", defaultConfigPath, "config file path")
+	runCmd.Flags().Bool("dev", false, "run in development mode, using an in-memory datastore instead of Postgres")
 
 	RootCmd.AddCommand(runCmd)
 }
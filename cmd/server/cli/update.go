@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/endpoints"
+	"github.com/spf13/cobra"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update <trustdomain>",
+	Short: "Allows updating trust domains",
+}
+
+var updateTrustDomainCmd = &cobra.Command{
+	Use:   "trustdomain",
+	Args:  cobra.ExactArgs(0),
+	Short: "Updates a trust domain. Only the flags that are set are changed.",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		td, err := cmd.Flags().GetString("trustDomain")
+		if err != nil {
+			return fmt.Errorf("cannot get trust domain flag: %v", err)
+		}
+
+		trustDomain, err := spiffeid.TrustDomainFromString(td)
+		if err != nil {
+			return err
+		}
+
+		req := &endpoints.UpdateTrustDomainRequest{Name: trustDomain}
+
+		if cmd.Flags().Changed("description") {
+			description, err := cmd.Flags().GetString("description")
+			if err != nil {
+				return fmt.Errorf("cannot get description flag: %v", err)
+			}
+			req.Description = &description
+		}
+
+		if cmd.Flags().Changed("publish-bundle") {
+			publishBundle, err := cmd.Flags().GetBool("publish-bundle")
+			if err != nil {
+				return fmt.Errorf("cannot get publish-bundle flag: %v", err)
+			}
+			req.PublishBundle = &publishBundle
+		}
+
+		if cmd.Flags().Changed("publish-oidc") {
+			publishOIDC, err := cmd.Flags().GetBool("publish-oidc")
+			if err != nil {
+				return fmt.Errorf("cannot get publish-oidc flag: %v", err)
+			}
+			req.PublishOIDC = &publishOIDC
+		}
+
+		if cmd.Flags().Changed("labels") {
+			value, err := cmd.Flags().GetString("labels")
+			if err != nil {
+				return fmt.Errorf("cannot get labels flag: %v", err)
+			}
+
+			labels, err := entity.ParseLabels(value)
+			if err != nil {
+				return err
+			}
+			req.Labels = &labels
+		}
+
+		if cmd.Flags().Changed("onboarding-bundle") {
+			path, err := cmd.Flags().GetString("onboarding-bundle")
+			if err != nil {
+				return fmt.Errorf("cannot get onboarding-bundle flag: %v", err)
+			}
+
+			req.OnboardingBundle, err = os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read onboarding bundle: %v", err)
+			}
+		}
+
+		if cmd.Flags().Changed("bundle-endpoint-url") || cmd.Flags().Changed("bundle-endpoint-profile") || cmd.Flags().Changed("bundle-endpoint-spiffe-id") {
+			endpoint, err := bundleEndpointFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			req.BundleEndpoint = endpoint
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+
+		updated, err := c.UpdateTrustDomain(req)
+		if err != nil {
+			return err
+		}
+
+		return p.print(updated, func() error {
+			p.message("Trust Domain updated: %q", updated.Name.String())
+			return nil
+		})
+	},
+}
+
+func bundleEndpointFromFlags(cmd *cobra.Command) (*endpoints.BundleEndpoint, error) {
+	url, err := cmd.Flags().GetString("bundle-endpoint-url")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get bundle-endpoint-url flag: %v", err)
+	}
+
+	profile, err := cmd.Flags().GetString("bundle-endpoint-profile")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get bundle-endpoint-profile flag: %v", err)
+	}
+
+	id, err := cmd.Flags().GetString("bundle-endpoint-spiffe-id")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get bundle-endpoint-spiffe-id flag: %v", err)
+	}
+
+	endpoint := &endpoints.BundleEndpoint{URL: url, Profile: profile}
+	if id != "" {
+		endpoint.SPIFFEID, err = spiffeid.FromString(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle endpoint SPIFFE ID: %v", err)
+		}
+	}
+
+	return endpoint, nil
+}
+
+func init() {
+	updateCmd.AddCommand(updateTrustDomainCmd)
+
+	updateTrustDomainCmd.PersistentFlags().StringP("trustDomain", "t", "", "The trust domain name.")
+	updateTrustDomainCmd.PersistentFlags().String("description", "", "The trust domain description.")
+	updateTrustDomainCmd.PersistentFlags().Bool("publish-bundle", false, "Serves the trust domain bundle from the SPIFFE federation bundle endpoint.")
+	updateTrustDomainCmd.PersistentFlags().Bool("publish-oidc", false, "Serves the JWT authorities of the trust domain as an OIDC discovery document.")
+	updateTrustDomainCmd.PersistentFlags().String("labels", "", "Comma-separated key=value labels selected by the federation policies, replacing the current ones. An empty value removes them.")
+	updateTrustDomainCmd.PersistentFlags().String("onboarding-bundle", "", "File with the SPIFFE bundle that authenticates the bundle endpoint of the trust domain until its bundle is fetched.")
+	updateTrustDomainCmd.PersistentFlags().String("bundle-endpoint-url", "", "URL of the SPIFFE bundle endpoint the bundle of the trust domain is fetched from. An empty URL removes the endpoint.")
+	updateTrustDomainCmd.PersistentFlags().String("bundle-endpoint-profile", "", "Profile of the SPIFFE bundle endpoint: https_web or https_spiffe.")
+	updateTrustDomainCmd.PersistentFlags().String("bundle-endpoint-spiffe-id", "", "SPIFFE ID of the bundle endpoint. Required for the https_spiffe profile.")
+
+	RootCmd.AddCommand(updateCmd)
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Args:  cobra.ExactArgs(0),
+	Short: "Prints the changes of the trust domains, relationships, bundles and join tokens as they are made.",
+	Long: `Prints the changes of the trust domains, relationships, bundles and join tokens as they are made, one JSON
+event per line, or one YAML document per event with the yaml output format.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kinds, err := cmd.Flags().GetStringSlice("kinds")
+		if err != nil {
+			return fmt.Errorf("cannot get kinds flag: %v", err)
+		}
+		from, err := cmd.Flags().GetInt64("resource-version")
+		if err != nil {
+			return fmt.Errorf("cannot get resource-version flag: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		return c.Watch(cmd.Context(), from, kinds, func(e *entity.WatchEvent) error {
+			if p.format == outputYAML {
+				// each event is a document of the YAML stream
+				if _, err := fmt.Fprintln(p.out, "---"); err != nil {
+					return err
+				}
+				return p.printYAML(e)
+			}
+
+			b, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintln(p.out, string(b))
+			return err
+		})
+	},
+}
+
+func init() {
+	watchCmd.PersistentFlags().StringSlice("kinds", nil, "The kinds of the resources to watch: trust_domain, relationship, bundle or join_token. All of them if not set.")
+	watchCmd.PersistentFlags().Int64("resource-version", -1, "The resource version to resume watching after. The current version if not set.")
+
+	RootCmd.AddCommand(watchCmd)
+}
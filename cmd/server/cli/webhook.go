@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/HewlettPackard/galadriel/cmd/server/util"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook <create | list | delete | deadletters | redeliver>",
+	Short: "Manages the webhooks notified of the federation events",
+}
+
+var webhookCreateCmd = &cobra.Command{
+	Use:   "create",
+	Args:  cobra.ExactArgs(0),
+	Short: "Creates a webhook notified of the events of the given types.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString("url")
+		if err != nil {
+			return fmt.Errorf("cannot get url flag: %v", err)
+		}
+		events, err := cmd.Flags().GetStringSlice("events")
+		if err != nil {
+			return fmt.Errorf("cannot get events flag: %v", err)
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		webhook, err := c.CreateWebhook(&entity.Webhook{URL: url, EventTypes: events})
+		if err != nil {
+			return err
+		}
+
+		return p.print(webhook, func() error {
+			p.message("Webhook %s created", webhook.ID.UUID)
+			p.message("Secret: %s", webhook.Secret)
+			p.message("The secret signs the payloads of the notifications, and cannot be retrieved later.")
+			return nil
+		})
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the webhooks.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		webhooks, err := c.ListWebhooks()
+		if err != nil {
+			return err
+		}
+
+		return p.print(webhooks, func() error {
+			if len(webhooks) == 0 {
+				p.message("No webhooks found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(webhooks))
+			for _, w := range webhooks {
+				rows = append(rows, []string{w.ID.UUID.String(), w.URL, strings.Join(w.EventTypes, ", ")})
+			}
+
+			return p.table([]string{"ID", "URL", "EVENTS"}, rows)
+		})
+	},
+}
+
+var webhookDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Args:  cobra.ExactArgs(0),
+	Short: "Deletes a webhook, along with its pending and dead deliveries.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := idFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.DeleteWebhook(id); err != nil {
+			return err
+		}
+
+		p.message("Webhook %s deleted", id)
+		return nil
+	},
+}
+
+var webhookDeadLettersCmd = &cobra.Command{
+	Use:   "deadletters",
+	Args:  cobra.ExactArgs(0),
+	Short: "Lists the deliveries that failed after their last attempt.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		deliveries, err := c.ListDeadWebhookDeliveries()
+		if err != nil {
+			return err
+		}
+
+		return p.print(deliveries, func() error {
+			if len(deliveries) == 0 {
+				p.message("No dead webhook deliveries found")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(deliveries))
+			for _, d := range deliveries {
+				rows = append(rows, []string{
+					d.ID.UUID.String(),
+					d.WebhookURL,
+					d.EventType,
+					formatTime(d.CreatedAt),
+					strconv.Itoa(d.Attempts),
+					d.LastError,
+					string(d.Payload),
+				})
+			}
+
+			return p.table([]string{"ID", "WEBHOOK", "EVENT", "CREATED AT", "ATTEMPTS", "LAST ERROR", "PAYLOAD"}, rows)
+		})
+	},
+}
+
+var webhookRedeliverCmd = &cobra.Command{
+	Use:   "redeliver",
+	Args:  cobra.ExactArgs(0),
+	Short: "Schedules a dead delivery for a new series of attempts.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := idFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		c := util.NewServerClient(defaultSocketPath)
+		if err := c.RedeliverWebhookDelivery(id); err != nil {
+			return err
+		}
+
+		p.message("Webhook delivery %s scheduled for redelivery", id)
+		return nil
+	},
+}
+
+func idFlag(cmd *cobra.Command) (uuid.UUID, error) {
+	id, err := cmd.Flags().GetString("id")
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("cannot get id flag: %v", err)
+	}
+
+	return uuid.Parse(id)
+}
+
+func init() {
+	webhookCreateCmd.PersistentFlags().String("url", "", "The URL the events are posted to.")
+	webhookCreateCmd.PersistentFlags().StringSlice("events", nil, "The types of the events the webhook is notified of.")
+	webhookDeleteCmd.PersistentFlags().String("id", "", "The ID of the webhook.")
+	webhookRedeliverCmd.PersistentFlags().String("id", "", "The ID of the dead delivery.")
+
+	webhookCmd.AddCommand(webhookCreateCmd)
+	webhookCmd.AddCommand(webhookListCmd)
+	webhookCmd.AddCommand(webhookDeleteCmd)
+	webhookCmd.AddCommand(webhookDeadLettersCmd)
+	webhookCmd.AddCommand(webhookRedeliverCmd)
+
+	RootCmd.AddCommand(webhookCmd)
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,8 +10,17 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/apply"
+	"github.com/HewlettPackard/galadriel/pkg/server/backup"
+	"github.com/HewlettPackard/galadriel/pkg/server/endpoints"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/google/uuid"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 )
 
@@ -24,19 +34,79 @@ const (
 
 var (
 	createTrustDomainURL  = fmt.Sprintf(localURL, "createTrustDomain")
+	updateTrustDomainURL  = fmt.Sprintf(localURL, "updateTrustDomain")
 	listTrustDomainsURL   = fmt.Sprintf(localURL, "listTrustDomains")
 	createRelationshipURL = fmt.Sprintf(localURL, "createRelationship")
 	listRelationshipsURL  = fmt.Sprintf(localURL, "listRelationships")
+	renewRelationshipURL  = fmt.Sprintf(localURL, "renewRelationship")
+	createGroupURL        = fmt.Sprintf(localURL, "createFederationGroup")
+	listGroupsURL         = fmt.Sprintf(localURL, "listFederationGroups")
+	deleteGroupURL        = fmt.Sprintf(localURL, "deleteFederationGroup")
+	addGroupMemberURL     = fmt.Sprintf(localURL, "addFederationGroupMember")
+	removeGroupMemberURL  = fmt.Sprintf(localURL, "removeFederationGroupMember")
+	createPolicyURL       = fmt.Sprintf(localURL, "createFederationPolicy")
+	listPoliciesURL       = fmt.Sprintf(localURL, "listFederationPolicies")
+	deletePolicyURL       = fmt.Sprintf(localURL, "deleteFederationPolicy")
+	testRelationshipURL   = fmt.Sprintf(localURL, "testRelationship")
+	createPeerURL         = fmt.Sprintf(localURL, "createPeer")
+	listPeersURL          = fmt.Sprintf(localURL, "listPeers")
+	deletePeerURL         = fmt.Sprintf(localURL, "deletePeer")
+	sharePeerTDURL        = fmt.Sprintf(localURL, "sharePeerTrustDomain")
+	unsharePeerTDURL      = fmt.Sprintf(localURL, "unsharePeerTrustDomain")
+	listRejectionsURL     = fmt.Sprintf(localURL, "listBundleRejections")
+	listQuarantinedURL    = fmt.Sprintf(localURL, "listQuarantinedBundles")
+	approveBundleURL      = fmt.Sprintf(localURL, "approveBundle")
+	rejectBundleURL       = fmt.Sprintf(localURL, "rejectBundle")
 	generateTokenURL      = fmt.Sprintf(localURL, "generateToken")
+	createWebhookURL      = fmt.Sprintf(localURL, "createWebhook")
+	listWebhooksURL       = fmt.Sprintf(localURL, "listWebhooks")
+	deleteWebhookURL      = fmt.Sprintf(localURL, "deleteWebhook")
+	listDeadDeliveriesURL = fmt.Sprintf(localURL, "listDeadWebhookDeliveries")
+	redeliverURL          = fmt.Sprintf(localURL, "redeliverWebhookDelivery")
+	listJobsURL           = fmt.Sprintf(localURL, "listJobs")
+	exportURL             = fmt.Sprintf(localURL, "export")
+	importURL             = fmt.Sprintf(localURL, "import")
+	applyURL              = fmt.Sprintf(localURL, "apply")
+	watchURL              = fmt.Sprintf(localURL, "watch")
 )
 
 // ServerLocalClient represents a local client of the Galadriel Server.
 type ServerLocalClient interface {
-	CreateTrustDomain(m *entity.TrustDomain) error
+	CreateTrustDomain(m *entity.TrustDomain) (*entity.TrustDomain, error)
+	UpdateTrustDomain(req *endpoints.UpdateTrustDomainRequest) (*entity.TrustDomain, error)
 	ListTrustDomains() ([]*entity.TrustDomain, error)
-	CreateRelationship(r *entity.Relationship) error
+	CreateRelationship(r *entity.Relationship) (*entity.Relationship, error)
 	ListRelationships() ([]*entity.Relationship, error)
+	RenewRelationship(trustDomainA, trustDomainB spiffeid.TrustDomain, notAfter time.Time) (*entity.Relationship, error)
+	CreateFederationGroup(g *entity.FederationGroup) (*entity.FederationGroup, error)
+	ListFederationGroups() ([]*entity.FederationGroup, error)
+	DeleteFederationGroup(name string) error
+	AddFederationGroupMember(group string, trustDomain spiffeid.TrustDomain, hub bool) error
+	RemoveFederationGroupMember(group string, trustDomain spiffeid.TrustDomain) error
+	CreateFederationPolicy(p *entity.FederationPolicy) (*entity.FederationPolicy, error)
+	ListFederationPolicies() ([]*entity.FederationPolicy, error)
+	DeleteFederationPolicy(name string) error
+	TestRelationship(trustDomainA, trustDomainB spiffeid.TrustDomain) (*relationships.Decision, error)
+	CreatePeer(p *entity.Peer) (*entity.Peer, error)
+	ListPeers() ([]*entity.Peer, error)
+	DeletePeer(name string) error
+	SharePeerTrustDomain(peer string, trustDomain spiffeid.TrustDomain) error
+	UnsharePeerTrustDomain(peer string, trustDomain spiffeid.TrustDomain) error
+	ListBundleRejections() ([]*entity.BundleRejection, error)
+	ListQuarantinedBundles() ([]*entity.QuarantinedBundle, error)
+	ApproveBundle(trustDomain spiffeid.TrustDomain) error
+	RejectBundle(trustDomain spiffeid.TrustDomain) error
 	GenerateJoinToken(trustDomain spiffeid.TrustDomain) (*entity.JoinToken, error)
+	CreateWebhook(w *entity.Webhook) (*entity.Webhook, error)
+	ListWebhooks() ([]*entity.Webhook, error)
+	DeleteWebhook(webhookID uuid.UUID) error
+	ListDeadWebhookDeliveries() ([]*entity.WebhookDelivery, error)
+	RedeliverWebhookDelivery(deliveryID uuid.UUID) error
+	ListJobs() ([]*entity.JobStatus, error)
+	Export(excludeTokens bool) (*backup.Document, error)
+	Import(doc *backup.Document) (*backup.ImportResult, error)
+	Apply(state *apply.State, dryRun bool) (*apply.Plan, error)
+	Watch(ctx context.Context, from int64, kinds []string, fn func(e *entity.WatchEvent) error) error
 }
 
 // TODO: improve this adding options for the transport, dialcontext, and http.Client.
@@ -57,28 +127,62 @@ type serverClient struct {
 	client *http.Client
 }
 
-func (c serverClient) CreateTrustDomain(m *entity.TrustDomain) error {
+func (c serverClient) CreateTrustDomain(m *entity.TrustDomain) (*entity.TrustDomain, error) {
 	trustDomainBytes, err := json.Marshal(m)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	r, err := c.client.Post(createTrustDomainURL, contentType, bytes.NewReader(trustDomainBytes))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer r.Body.Close()
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if r.StatusCode != 200 {
-		return errors.New(string(body))
+		return nil, errors.New(string(body))
 	}
 
-	return nil
+	var td entity.TrustDomain
+	if err = json.Unmarshal(body, &td); err != nil {
+		return nil, err
+	}
+
+	return &td, nil
+}
+
+func (c serverClient) UpdateTrustDomain(req *endpoints.UpdateTrustDomainRequest) (*entity.TrustDomain, error) {
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.client.Post(updateTrustDomainURL, contentType, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != 200 {
+		return nil, errors.New(string(b))
+	}
+
+	var td entity.TrustDomain
+	if err = json.Unmarshal(b, &td); err != nil {
+		return nil, err
+	}
+
+	return &td, nil
 }
 
 func (c serverClient) ListTrustDomains() ([]*entity.TrustDomain, error) {
@@ -105,28 +209,33 @@ func (c serverClient) ListTrustDomains() ([]*entity.TrustDomain, error) {
 	return trustDomains, nil
 }
 
-func (c serverClient) CreateRelationship(rel *entity.Relationship) error {
+func (c serverClient) CreateRelationship(rel *entity.Relationship) (*entity.Relationship, error) {
 	relBytes, err := json.Marshal(rel)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	r, err := c.client.Post(createRelationshipURL, contentType, bytes.NewReader(relBytes))
 	if err != nil {
-		return fmt.Errorf("failed to create relationship: %v", err)
+		return nil, fmt.Errorf("failed to create relationship: %v", err)
 	}
 	defer r.Body.Close()
 
 	b, err := io.ReadAll(r.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if r.StatusCode != 200 {
-		return errors.New(string(b))
+		return nil, errors.New(string(b))
 	}
 
-	return nil
+	var created entity.Relationship
+	if err = json.Unmarshal(b, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
 }
 
 func (c serverClient) ListRelationships() ([]*entity.Relationship, error) {
@@ -154,6 +263,86 @@ func (c serverClient) ListRelationships() ([]*entity.Relationship, error) {
 	return rels, nil
 }
 
+func (c serverClient) ListBundleRejections() ([]*entity.BundleRejection, error) {
+	r, err := c.client.Get(listRejectionsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != 200 {
+		return nil, errors.New(string(b))
+	}
+
+	var rejections []*entity.BundleRejection
+	if err = json.Unmarshal(b, &rejections); err != nil {
+		return nil, err
+	}
+
+	return rejections, nil
+}
+
+func (c serverClient) ListQuarantinedBundles() ([]*entity.QuarantinedBundle, error) {
+	r, err := c.client.Get(listQuarantinedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != 200 {
+		return nil, errors.New(string(b))
+	}
+
+	var quarantined []*entity.QuarantinedBundle
+	if err = json.Unmarshal(b, &quarantined); err != nil {
+		return nil, err
+	}
+
+	return quarantined, nil
+}
+
+func (c serverClient) ApproveBundle(td spiffeid.TrustDomain) error {
+	return c.reviewBundle(approveBundleURL, td)
+}
+
+func (c serverClient) RejectBundle(td spiffeid.TrustDomain) error {
+	return c.reviewBundle(rejectBundleURL, td)
+}
+
+func (c serverClient) reviewBundle(url string, td spiffeid.TrustDomain) error {
+	b, err := json.Marshal(entity.TrustDomain{Name: td})
+	if err != nil {
+		return err
+	}
+
+	r, err := c.client.Post(url, contentType, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if r.StatusCode != 200 {
+		return errors.New(string(body))
+	}
+
+	return nil
+}
+
 func (c serverClient) GenerateJoinToken(td spiffeid.TrustDomain) (*entity.JoinToken, error) {
 	b, err := json.Marshal(entity.TrustDomain{Name: td})
 	if err != nil {
@@ -182,3 +371,362 @@ func (c serverClient) GenerateJoinToken(td spiffeid.TrustDomain) (*entity.JoinTo
 
 	return &createdToken, nil
 }
+
+func (c serverClient) Export(excludeTokens bool) (*backup.Document, error) {
+	url := exportURL
+	if excludeTokens {
+		url += "?exclude_tokens=true"
+	}
+
+	r, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != 200 {
+		return nil, errors.New(string(b))
+	}
+
+	var doc backup.Document
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func (c serverClient) Import(doc *backup.Document) (*backup.ImportResult, error) {
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.client.Post(importURL, contentType, bytes.NewReader(docBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != 200 {
+		return nil, errors.New(string(b))
+	}
+
+	var result backup.ImportResult
+	if err = json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c serverClient) Apply(state *apply.State, dryRun bool) (*apply.Plan, error) {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	url := applyURL
+	if dryRun {
+		url += "?dry_run=true"
+	}
+
+	r, err := c.client.Post(url, contentType, bytes.NewReader(stateBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != 200 {
+		return nil, errors.New(string(b))
+	}
+
+	var plan apply.Plan
+	if err = json.Unmarshal(b, &plan); err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+func (c serverClient) RenewRelationship(trustDomainA, trustDomainB spiffeid.TrustDomain, notAfter time.Time) (*entity.Relationship, error) {
+	req := &entity.Relationship{
+		TrustDomainAName: trustDomainA,
+		TrustDomainBName: trustDomainB,
+		NotAfter:         notAfter,
+	}
+
+	var renewed entity.Relationship
+	if err := c.post(renewRelationshipURL, req, &renewed); err != nil {
+		return nil, err
+	}
+
+	return &renewed, nil
+}
+
+func (c serverClient) CreateFederationGroup(g *entity.FederationGroup) (*entity.FederationGroup, error) {
+	var created entity.FederationGroup
+	if err := c.post(createGroupURL, g, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (c serverClient) ListFederationGroups() ([]*entity.FederationGroup, error) {
+	var groups []*entity.FederationGroup
+	if err := c.get(listGroupsURL, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (c serverClient) DeleteFederationGroup(name string) error {
+	return c.post(deleteGroupURL, entity.FederationGroup{Name: name}, nil)
+}
+
+func (c serverClient) AddFederationGroupMember(group string, trustDomain spiffeid.TrustDomain, hub bool) error {
+	req := entity.FederationGroup{
+		Name:    group,
+		Members: []*entity.FederationGroupMember{{TrustDomainName: trustDomain, Hub: hub}},
+	}
+
+	return c.post(addGroupMemberURL, req, nil)
+}
+
+func (c serverClient) RemoveFederationGroupMember(group string, trustDomain spiffeid.TrustDomain) error {
+	req := entity.FederationGroup{
+		Name:    group,
+		Members: []*entity.FederationGroupMember{{TrustDomainName: trustDomain}},
+	}
+
+	return c.post(removeGroupMemberURL, req, nil)
+}
+
+func (c serverClient) CreateFederationPolicy(p *entity.FederationPolicy) (*entity.FederationPolicy, error) {
+	var created entity.FederationPolicy
+	if err := c.post(createPolicyURL, p, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (c serverClient) ListFederationPolicies() ([]*entity.FederationPolicy, error) {
+	var policies []*entity.FederationPolicy
+	if err := c.get(listPoliciesURL, &policies); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (c serverClient) DeleteFederationPolicy(name string) error {
+	return c.post(deletePolicyURL, entity.FederationPolicy{Name: name}, nil)
+}
+
+func (c serverClient) TestRelationship(trustDomainA, trustDomainB spiffeid.TrustDomain) (*relationships.Decision, error) {
+	req := entity.Relationship{
+		TrustDomainAName: trustDomainA,
+		TrustDomainBName: trustDomainB,
+	}
+
+	var decision relationships.Decision
+	if err := c.post(testRelationshipURL, req, &decision); err != nil {
+		return nil, err
+	}
+
+	return &decision, nil
+}
+
+func (c serverClient) CreatePeer(p *entity.Peer) (*entity.Peer, error) {
+	var created entity.Peer
+	if err := c.post(createPeerURL, p, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (c serverClient) ListPeers() ([]*entity.Peer, error) {
+	var peers []*entity.Peer
+	if err := c.get(listPeersURL, &peers); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+func (c serverClient) DeletePeer(name string) error {
+	return c.post(deletePeerURL, entity.Peer{Name: name}, nil)
+}
+
+func (c serverClient) SharePeerTrustDomain(peer string, trustDomain spiffeid.TrustDomain) error {
+	req := entity.Peer{Name: peer, SharedTrustDomains: []spiffeid.TrustDomain{trustDomain}}
+	return c.post(sharePeerTDURL, req, nil)
+}
+
+func (c serverClient) UnsharePeerTrustDomain(peer string, trustDomain spiffeid.TrustDomain) error {
+	req := entity.Peer{Name: peer, SharedTrustDomains: []spiffeid.TrustDomain{trustDomain}}
+	return c.post(unsharePeerTDURL, req, nil)
+}
+
+func (c serverClient) CreateWebhook(w *entity.Webhook) (*entity.Webhook, error) {
+	var created entity.Webhook
+	if err := c.post(createWebhookURL, w, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (c serverClient) ListWebhooks() ([]*entity.Webhook, error) {
+	var webhooks []*entity.Webhook
+	if err := c.get(listWebhooksURL, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (c serverClient) DeleteWebhook(webhookID uuid.UUID) error {
+	return c.post(deleteWebhookURL, entity.Webhook{ID: uuid.NullUUID{UUID: webhookID, Valid: true}}, nil)
+}
+
+func (c serverClient) ListDeadWebhookDeliveries() ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	if err := c.get(listDeadDeliveriesURL, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (c serverClient) RedeliverWebhookDelivery(deliveryID uuid.UUID) error {
+	return c.post(redeliverURL, entity.WebhookDelivery{ID: uuid.NullUUID{UUID: deliveryID, Valid: true}}, nil)
+}
+
+func (c serverClient) ListJobs() ([]*entity.JobStatus, error) {
+	var statuses []*entity.JobStatus
+	if err := c.get(listJobsURL, &statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// Watch calls fn with the changes of the resources of the given kinds, or of all of them if none is given,
+// recorded after the resource version from, or from the current version if from is negative. It returns when
+// the context is canceled, fn fails or the server ends the stream.
+func (c serverClient) Watch(ctx context.Context, from int64, kinds []string, fn func(e *entity.WatchEvent) error) error {
+	query := url.Values{}
+	if from >= 0 {
+		query.Set("resourceVersion", strconv.FormatInt(from, 10))
+	}
+	if len(kinds) > 0 {
+		query.Set("kinds", strings.Join(kinds, ","))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, watchURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != 200 {
+		return readResponse(r, nil)
+	}
+
+	// events are separated by blank lines, and lines starting with a colon are heartbeats
+	reader := bufio.NewReader(r.Body)
+	var data []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		switch {
+		case errors.Is(err, io.EOF), ctx.Err() != nil:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case bytes.HasPrefix(line, []byte("data: ")):
+			data = append(data, bytes.TrimPrefix(line, []byte("data: "))...)
+		case len(line) == 0 && len(data) > 0:
+			var e entity.WatchEvent
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			if err := fn(&e); err != nil {
+				return err
+			}
+			data = nil
+		}
+	}
+}
+
+// get unmarshals the response of a GET request into res.
+func (c serverClient) get(url string, res any) error {
+	r, err := c.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	return readResponse(r, res)
+}
+
+// post sends req as the JSON body of a POST request, and unmarshals the response into res unless it is nil.
+func (c serverClient) post(url string, req, res any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := c.client.Post(url, contentType, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	return readResponse(r, res)
+}
+
+func readResponse(r *http.Response, res any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if r.StatusCode != 200 {
+		return errors.New(string(body))
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, res)
+}
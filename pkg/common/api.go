@@ -5,6 +5,16 @@ import (
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 )
 
+// Headers reporting the versions of a harvester and of its SPIRE Server to Galadriel Server.
+const (
+	HarvesterVersionHeader = "X-Galadriel-Harvester-Version"
+	SPIREVersionHeader     = "X-Galadriel-Spire-Version"
+)
+
+// ResourceVersionHeader reports the resource version of a list returned by the management API, which a watch
+// of the changes of the listed resources starts from.
+const ResourceVersionHeader = "X-Galadriel-Resource-Version"
+
 // BundlesDigests is a map of trust bundle digests keyed by trust domain.
 type BundlesDigests map[spiffeid.TrustDomain][]byte
 
@@ -14,6 +24,10 @@ type BundleUpdates map[spiffeid.TrustDomain]*entity.Bundle
 // SyncBundleRequest represents a request to send the current state of federated bundles digests.
 type SyncBundleRequest struct {
 	State BundlesDigests `json:"state"`
+
+	// DigestAlgorithm is the algorithm the digests of State are computed with. Older harvesters do not set it,
+	// their digests are computed over the X.509 authorities only.
+	DigestAlgorithm string `json:"digest_algorithm,omitempty"`
 }
 
 // SyncBundleResponse represents a response from Galadriel Server containing the
@@ -22,7 +36,8 @@ type SyncBundleResponse struct {
 	// Update conveys trust bundles that are new or updates.
 	Updates BundleUpdates `json:"updates"`
 
-	// State is the current source-of-truth map of all trust bundles.
+	// State is the current source-of-truth map of all trust bundles, with digests computed with the
+	// algorithm of the request.
 	// It essentially allows triggering deletions of trust bundles on harvesters.
 	State BundlesDigests `json:"state"`
 }
@@ -32,3 +47,12 @@ type PostBundleRequest struct {
 	// TrustBundle is the latest watched SPIRE Server trust bundle.
 	*entity.Bundle `json:"state"`
 }
+
+// PostBundleError is the response to a post bundle request whose bundle is rejected by the bundle validation
+// policy of Galadriel Server.
+type PostBundleError struct {
+	Error string `json:"error"`
+
+	// Violations are the rules of the policy broken by the bundle.
+	Violations []entity.BundleViolation `json:"violations"`
+}
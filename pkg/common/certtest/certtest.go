@@ -0,0 +1,104 @@
+// Package certtest provides the self-signed CA certificates and the bundles built with them that the tests
+// exercise the bundle handling with.
+package certtest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/require"
+)
+
+type options struct {
+	key     crypto.Signer
+	updates []func(*x509.Certificate)
+}
+
+// Option customizes the certificate created by CreateCA.
+type Option func(*options)
+
+// WithKey sets the key the certificate is created and self-signed with, instead of a new P-256 key.
+func WithKey(key crypto.Signer) Option {
+	return func(o *options) {
+		o.key = key
+	}
+}
+
+// WithNotAfter sets when the certificate expires, instead of an hour from now.
+func WithNotAfter(notAfter time.Time) Option {
+	return WithTemplate(func(c *x509.Certificate) {
+		c.NotAfter = notAfter
+	})
+}
+
+// WithValidity sets the validity period of the certificate, instead of from a minute ago to an hour from now.
+func WithValidity(notBefore, notAfter time.Time) Option {
+	return WithTemplate(func(c *x509.Certificate) {
+		c.NotBefore = notBefore
+		c.NotAfter = notAfter
+	})
+}
+
+// WithTemplate modifies the template of the certificate with the update function before it is created.
+func WithTemplate(update func(*x509.Certificate)) Option {
+	return func(o *options) {
+		o.updates = append(o.updates, update)
+	}
+}
+
+// CreateCA creates a self-signed CA certificate, with the subject "CN=CA", valid from a minute ago to an hour
+// from now unless the options set otherwise.
+func CreateCA(t testing.TB, opts ...Option) *x509.Certificate {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.key == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		o.key = key
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	for _, update := range o.updates {
+		update(template)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, o.key.Public(), o.key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// CreateBundle creates a bundle of the trust domain with a single X.509 authority, created by CreateCA with the
+// options.
+func CreateBundle(t testing.TB, td spiffeid.TrustDomain, opts ...Option) *spiffebundle.Bundle {
+	return spiffebundle.FromX509Authorities(td, []*x509.Certificate{CreateCA(t, opts...)})
+}
+
+// MarshalBundle returns the bundle in the SPIFFE bundle format.
+func MarshalBundle(t testing.TB, bundle *spiffebundle.Bundle) []byte {
+	data, err := bundle.Marshal()
+	require.NoError(t, err)
+	return data
+}
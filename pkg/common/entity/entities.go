@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,22 +17,263 @@ type TrustDomain struct {
 	Description       string               `json:"description"`
 	HarvesterSpiffeID spiffeid.ID          `json:"harvester_spiffe_id"`
 	OnboardingBundle  []byte               `json:"onboarding_bundle"`
+	PublishBundle     bool                 `json:"publish_bundle"`
+	PublishOIDC       bool                 `json:"publish_oidc"`
 	CreatedAt         time.Time            `json:"created_at"`
 	UpdatedAt         time.Time            `json:"updated_at"`
+
+	// BundleEndpointURL, BundleEndpointProfile and BundleEndpointSPIFFEID locate the SPIFFE bundle endpoint
+	// the bundle of the trust domain is fetched from, for trust domains without a harvester.
+	BundleEndpointURL      string      `json:"bundle_endpoint_url"`
+	BundleEndpointProfile  string      `json:"bundle_endpoint_profile"`
+	BundleEndpointSPIFFEID spiffeid.ID `json:"bundle_endpoint_spiffe_id"`
+
+	// Labels are key/value pairs, e.g. env=prod, selected by the federation policies.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// PeerID is the peer Galadriel Server the trust domain is imported from. It is not set for the trust
+	// domains managed by this server; imported trust domains are kept in sync with their peer.
+	PeerID uuid.NullUUID `json:"peer_id"`
+
+	// BundleHealth reports the expiry and staleness of the bundle of the trust domain. It is not stored, and
+	// only set when trust domains are listed; it is nil if the trust domain has no bundle.
+	BundleHealth *BundleHealth `json:"bundle_health,omitempty"`
+
+	// HarvesterSession reports the last calls of the harvester of the trust domain. It is not stored with the
+	// trust domain, and only set when trust domains are listed; it is nil if no harvester ever connected.
+	HarvesterSession *HarvesterSession `json:"harvester_session,omitempty"`
+}
+
+// BundleHealth reports the expiry and staleness of the bundle of a trust domain.
+type BundleHealth struct {
+	// UpdatedAt is when the bundle was last updated.
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Digest is the digest of the bundle, computed with the digest algorithm of the bundle.
+	Digest []byte `json:"digest"`
+
+	// EarliestExpiry is when the first of the X.509 authorities of the bundle expires. It is zero if the
+	// bundle has no X.509 authorities.
+	EarliestExpiry time.Time `json:"earliest_expiry"`
+
+	// Expiring is set when the earliest expiry is within the expiry warning threshold.
+	Expiring bool `json:"expiring"`
+
+	// Stale is set when the bundle was not updated within the staleness warning threshold.
+	Stale bool `json:"stale"`
 }
 
 type Relationship struct {
 	ID                  uuid.NullUUID
-	TrustDomainAID      uuid.UUID            `json:"trust_domain_a_id"`
-	TrustDomainBID      uuid.UUID            `json:"trust_domain_b_id"`
-	TrustDomainAName    spiffeid.TrustDomain `json:"trust_domain_a_name"`
-	TrustDomainBName    spiffeid.TrustDomain `json:"trust_domain_b_name"`
-	TrustDomainAConsent bool                 `json:"trust_domain_a_consent"`
-	TrustDomainBConsent bool                 `json:"trust_domain_b_consent"`
-	CreatedAt           time.Time            `json:"created_at"`
-	UpdatedAt           time.Time            `json:"updated_at"`
+	TrustDomainAID      uuid.UUID             `json:"trust_domain_a_id"`
+	TrustDomainBID      uuid.UUID             `json:"trust_domain_b_id"`
+	TrustDomainAName    spiffeid.TrustDomain  `json:"trust_domain_a_name"`
+	TrustDomainBName    spiffeid.TrustDomain  `json:"trust_domain_b_name"`
+	TrustDomainAConsent bool                  `json:"trust_domain_a_consent"`
+	TrustDomainBConsent bool                  `json:"trust_domain_b_consent"`
+	Direction           RelationshipDirection `json:"direction"`
+	CreatedAt           time.Time             `json:"created_at"`
+	UpdatedAt           time.Time             `json:"updated_at"`
+
+	// NotBefore and NotAfter bound when the relationship is in effect, they are zero if it is not bounded.
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+
+	// ExpiredAt is when the expiry of the relationship was processed, it is zero until then.
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// InEffect returns whether the relationship is in effect at the given time, i.e., within its validity window.
+func (r *Relationship) InEffect(now time.Time) bool {
+	if !r.NotBefore.IsZero() && now.Before(r.NotBefore) {
+		return false
+	}
+	return r.NotAfter.IsZero() || now.Before(r.NotAfter)
+}
+
+// Trusts returns whether the trust domain with the given ID trusts its peer in the relationship, i.e., whether
+// it receives the bundle of its peer.
+func (r *Relationship) Trusts(trustDomainID uuid.UUID) bool {
+	switch trustDomainID {
+	case r.TrustDomainAID:
+		return r.Direction.OrDefault() != RelationshipBTrustsA
+	case r.TrustDomainBID:
+		return r.Direction.OrDefault() != RelationshipATrustsB
+	default:
+		return false
+	}
 }
 
+// DirectionString describes the direction of the relationship in terms of its trust domain names,
+// e.g. "a.test trusts b.test".
+func (r *Relationship) DirectionString() string {
+	switch r.Direction.OrDefault() {
+	case RelationshipATrustsB:
+		return fmt.Sprintf("%s trusts %s", r.TrustDomainAName, r.TrustDomainBName)
+	case RelationshipBTrustsA:
+		return fmt.Sprintf("%s trusts %s", r.TrustDomainBName, r.TrustDomainAName)
+	default:
+		return fmt.Sprintf("%s and %s trust each other", r.TrustDomainAName, r.TrustDomainBName)
+	}
+}
+
+// RelationshipDirection defines which trust domains of a relationship trust their peer.
+type RelationshipDirection string
+
+const (
+	// RelationshipBidirectional means both trust domains trust each other.
+	RelationshipBidirectional RelationshipDirection = "bidirectional"
+
+	// RelationshipATrustsB means trust domain A trusts trust domain B, which does not trust A.
+	RelationshipATrustsB RelationshipDirection = "a_trusts_b"
+
+	// RelationshipBTrustsA means trust domain B trusts trust domain A, which does not trust B.
+	RelationshipBTrustsA RelationshipDirection = "b_trusts_a"
+)
+
+// OrDefault returns the direction, or RelationshipBidirectional if it is not set.
+func (d RelationshipDirection) OrDefault() RelationshipDirection {
+	if d == "" {
+		return RelationshipBidirectional
+	}
+	return d
+}
+
+// Reverse returns the direction of the same relationship with its trust domains in the opposite order.
+func (d RelationshipDirection) Reverse() RelationshipDirection {
+	switch d {
+	case RelationshipATrustsB:
+		return RelationshipBTrustsA
+	case RelationshipBTrustsA:
+		return RelationshipATrustsB
+	default:
+		return d
+	}
+}
+
+// Validate returns an error if the direction is set to an unknown direction.
+func (d RelationshipDirection) Validate() error {
+	switch d {
+	case "", RelationshipBidirectional, RelationshipATrustsB, RelationshipBTrustsA:
+		return nil
+	default:
+		return fmt.Errorf("unknown relationship direction %q, must be one of %q, %q or %q", d, RelationshipBidirectional, RelationshipATrustsB, RelationshipBTrustsA)
+	}
+}
+
+// FederationGroup federates its member trust domains with each other, as if they had relationships with one
+// another, according to the mode of the group.
+type FederationGroup struct {
+	ID        uuid.NullUUID
+	Name      string              `json:"name"`
+	Mode      FederationGroupMode `json:"mode"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+
+	// Members are the trust domains of the group. They are not stored with the group, and only set when groups
+	// are listed.
+	Members []*FederationGroupMember `json:"members,omitempty"`
+}
+
+// FederationGroupMember is the membership of a trust domain in a federation group. Hub marks the hub of a
+// hub-and-spoke group.
+type FederationGroupMember struct {
+	ID              uuid.NullUUID
+	GroupID         uuid.UUID            `json:"group_id"`
+	TrustDomainID   uuid.UUID            `json:"trust_domain_id"`
+	TrustDomainName spiffeid.TrustDomain `json:"trust_domain_name"`
+	Hub             bool                 `json:"hub"`
+	CreatedAt       time.Time            `json:"created_at"`
+}
+
+// FederationGroupMode defines which members of a federation group trust each other.
+type FederationGroupMode string
+
+const (
+	// FederationGroupMesh means every member trusts every other member.
+	FederationGroupMesh FederationGroupMode = "mesh"
+
+	// FederationGroupHubAndSpoke means the hub trusts every other member, which only trust the hub.
+	FederationGroupHubAndSpoke FederationGroupMode = "hub_and_spoke"
+)
+
+// OrDefault returns the mode, or FederationGroupMesh if it is not set.
+func (m FederationGroupMode) OrDefault() FederationGroupMode {
+	if m == "" {
+		return FederationGroupMesh
+	}
+	return m
+}
+
+// Validate returns an error if the mode is set to an unknown mode.
+func (m FederationGroupMode) Validate() error {
+	switch m {
+	case "", FederationGroupMesh, FederationGroupHubAndSpoke:
+		return nil
+	default:
+		return fmt.Errorf("unknown federation group mode %q, must be one of %q or %q", m, FederationGroupMesh, FederationGroupHubAndSpoke)
+	}
+}
+
+// Federates returns whether the members with the given hub flags trust each other within a group of this mode.
+func (m FederationGroupMode) Federates(hubA, hubB bool) bool {
+	return m.OrDefault() == FederationGroupMesh || hubA || hubB
+}
+
+// FederationPolicy federates the trust domains whose labels match its selector with the trust domains whose labels
+// match its peer selector. The selectors are evaluated whenever the federated bundles are computed, so trust domains
+// join and leave the federation as their labels change.
+type FederationPolicy struct {
+	ID           uuid.NullUUID
+	Name         string        `json:"name"`
+	Selector     LabelSelector `json:"selector"`
+	PeerSelector LabelSelector `json:"peer_selector"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// Federates returns whether the policy federates the trust domains with the given labels with each other.
+func (p *FederationPolicy) Federates(labelsA, labelsB map[string]string) bool {
+	return (p.Selector.Matches(labelsA) && p.PeerSelector.Matches(labelsB)) ||
+		(p.PeerSelector.Matches(labelsA) && p.Selector.Matches(labelsB))
+}
+
+// Validate returns an error if either selector of the policy is empty or invalid.
+func (p *FederationPolicy) Validate() error {
+	if err := p.Selector.Validate(); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	if err := p.PeerSelector.Validate(); err != nil {
+		return fmt.Errorf("invalid peer selector: %w", err)
+	}
+	return nil
+}
+
+// Peer is another Galadriel Server this server exchanges bundles with over mutual TLS. Each side shares some of
+// its trust domains with the other, and imports the trust domains the other shares, so the admins of both sides
+// approve the exchange.
+type Peer struct {
+	ID        uuid.NullUUID
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// SharedTrustDomains are the trust domains of this server shared with the peer, and TrustDomains are the
+	// trust domains imported from the peer. They are not stored with the peer, and only set when peers are listed.
+	SharedTrustDomains []spiffeid.TrustDomain `json:"shared_trust_domains,omitempty"`
+	TrustDomains       []spiffeid.TrustDomain `json:"trust_domains,omitempty"`
+}
+
+// JoinTokenUseInterval is how often the use of a join token is recorded, by updating the token, so the last use of
+// a token is known to within this interval.
+const JoinTokenUseInterval = time.Hour
+
+// HarvesterSessionInterval is how often a harvester session is updated by the calls of the same kind, from the
+// same address and with the same versions, so the time of the last call of each kind is known to within this
+// interval.
+const HarvesterSessionInterval = time.Minute
+
 type JoinToken struct {
 	ID              uuid.NullUUID
 	Token           string
@@ -56,3 +299,99 @@ type Bundle struct {
 	CreatedAt          time.Time            `json:"created_at"`
 	UpdatedAt          time.Time            `json:"updated_at"`
 }
+
+// BundleRejection records a bundle of a trust domain that was rejected by the bundle validation policy.
+type BundleRejection struct {
+	ID              uuid.NullUUID
+	TrustDomainID   uuid.UUID            `json:"trust_domain_id"`
+	TrustDomainName spiffeid.TrustDomain `json:"trust_domain_name"`
+	Digest          []byte               `json:"digest"`
+	Violations      []BundleViolation    `json:"violations"`
+	CreatedAt       time.Time            `json:"created_at"`
+}
+
+// BundleViolation is a rule of the bundle validation policy broken by a bundle.
+type BundleViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// QuarantinedBundle is a bundle update held back because it breaks the continuity with the stored bundle of
+// its trust domain. It is released at ReleaseAt, unless an admin approves or rejects it first.
+type QuarantinedBundle struct {
+	ID              uuid.NullUUID
+	TrustDomainID   uuid.UUID            `json:"trust_domain_id"`
+	TrustDomainName spiffeid.TrustDomain `json:"trust_domain_name"`
+	Data            []byte               `json:"bundle"`
+	Digest          []byte               `json:"digest"`
+	DigestAlgorithm string               `json:"digest_algorithm"`
+	Reasons         []BundleViolation    `json:"reasons"`
+	ReleaseAt       time.Time            `json:"release_at"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// HarvesterSession records the last authenticated calls of the harvester of a trust domain, along with the
+// address it called from and the versions it reported. The zero time means the call was never made.
+type HarvesterSession struct {
+	ID               uuid.NullUUID
+	TrustDomainID    uuid.UUID            `json:"trust_domain_id"`
+	TrustDomainName  spiffeid.TrustDomain `json:"trust_domain_name"`
+	LastOnboardAt    time.Time            `json:"last_onboard_at"`
+	LastPostAt       time.Time            `json:"last_post_at"`
+	LastSyncAt       time.Time            `json:"last_sync_at"`
+	RemoteAddress    string               `json:"remote_address"`
+	HarvesterVersion string               `json:"harvester_version"`
+	SPIREVersion     string               `json:"spire_version"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// JobStatus reports the last run of a maintenance job, by whichever replica of the server ran it. Result
+// summarizes what the run did and Error is set if it failed.
+type JobStatus struct {
+	Name       string    `json:"name"`
+	Schedule   string    `json:"schedule"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	NextRunAt  time.Time `json:"next_run_at"`
+	Result     string    `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Webhook is an HTTP endpoint notified of the federation events it is subscribed to. The payloads of the
+// notifications are signed with the secret of the webhook.
+type Webhook struct {
+	ID         uuid.NullUUID
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is the notification of an event to a webhook, waiting to be delivered, or dead once its
+// last delivery attempt failed.
+type WebhookDelivery struct {
+	ID            uuid.NullUUID
+	WebhookID     uuid.UUID `json:"webhook_id"`
+	WebhookURL    string    `json:"webhook_url"`
+	EventType     string    `json:"event_type"`
+	Payload       []byte    `json:"payload"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	Dead          bool      `json:"dead"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// WatchEvent is a change of a watched resource. Versions are assigned in the order the changes are committed,
+// so a watch can be resumed after the last version it received.
+type WatchEvent struct {
+	Version   int64           `json:"version"`
+	Kind      string          `json:"kind"`
+	Type      string          `json:"type"`
+	Object    json.RawMessage `json:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
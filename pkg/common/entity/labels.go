@@ -0,0 +1,112 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxLabelLength = 63
+
+var (
+	labelKeyRegexp   = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]*[a-zA-Z0-9])?$`)
+	labelValueRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9._-]*[a-zA-Z0-9])?)?$`)
+)
+
+// ValidateLabels returns an error if a key or a value of the labels is invalid. Keys are alphanumeric, and can
+// contain '.', '_', '-' and '/' other than as their first or last character. Values follow the same rules
+// without '/', and can be empty.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if len(k) > maxLabelLength || !labelKeyRegexp.MatchString(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if len(v) > maxLabelLength || !labelValueRegexp.MatchString(v) {
+			return fmt.Errorf("invalid value %q for label %q", v, k)
+		}
+	}
+	return nil
+}
+
+// ParseLabels parses comma separated key=value pairs, e.g. "env=prod,org=payments".
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return labels, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q, must be key=value", pair)
+		}
+		if _, ok := labels[k]; ok {
+			return nil, fmt.Errorf("duplicate label %q", k)
+		}
+		labels[k] = v
+	}
+
+	if err := ValidateLabels(labels); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// FormatLabels formats the labels as comma separated key=value pairs, sorted by key.
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + labels[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// LabelSelector selects the trust domains that have all of its labels.
+type LabelSelector map[string]string
+
+// ParseLabelSelector parses a selector written as comma separated key=value pairs, e.g. "env=prod,org=payments".
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	labels, err := ParseLabels(s)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := LabelSelector(labels)
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+
+	return selector, nil
+}
+
+// Matches returns whether the given labels have all the labels of the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for k, v := range s {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate returns an error if the selector is empty, as it would select every trust domain, or if its labels
+// are invalid.
+func (s LabelSelector) Validate() error {
+	if len(s) == 0 {
+		return errors.New("a selector requires at least one label")
+	}
+	return ValidateLabels(s)
+}
+
+func (s LabelSelector) String() string {
+	return FormatLabels(s)
+}
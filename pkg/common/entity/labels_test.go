@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabels(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		in       string
+		expected map[string]string
+		err      string
+	}{
+		{name: "empty", in: "", expected: map[string]string{}},
+		{name: "pairs", in: "env=prod, org=payments", expected: map[string]string{"env": "prod", "org": "payments"}},
+		{name: "empty value", in: "pci=", expected: map[string]string{"pci": ""}},
+		{name: "missing value", in: "env", err: `invalid label "env", must be key=value`},
+		{name: "duplicate key", in: "env=prod,env=dev", err: `duplicate label "env"`},
+		{name: "invalid key", in: "-env=prod", err: `invalid label key "-env"`},
+		{name: "invalid value", in: "env=prod/eu", err: `invalid value "prod/eu" for label "env"`},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := ParseLabels(tt.in)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, labels)
+			assert.Equal(t, tt.in != "", FormatLabels(labels) != "")
+		})
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	selector, err := ParseLabelSelector("org=payments,env=prod")
+	require.NoError(t, err)
+	assert.Equal(t, "env=prod,org=payments", selector.String())
+
+	assert.True(t, selector.Matches(map[string]string{"env": "prod", "org": "payments", "team": "a"}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod"}))
+	assert.False(t, selector.Matches(map[string]string{"env": "dev", "org": "payments"}))
+
+	_, err = ParseLabelSelector("")
+	require.EqualError(t, err, "a selector requires at least one label")
+}
+
+func TestFederationPolicyFederates(t *testing.T) {
+	p := &FederationPolicy{
+		Selector:     LabelSelector{"env": "prod"},
+		PeerSelector: LabelSelector{"role": "shared-services"},
+	}
+
+	prod := map[string]string{"env": "prod"}
+	shared := map[string]string{"role": "shared-services"}
+
+	assert.True(t, p.Federates(prod, shared))
+	assert.True(t, p.Federates(shared, prod))
+	assert.False(t, p.Federates(prod, prod))
+	assert.False(t, p.Federates(shared, nil))
+}
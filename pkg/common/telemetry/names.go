@@ -31,6 +31,21 @@ const (
 
 	Endpoints = "endpoints"
 
+	Datastore = "datastore"
+
+	BundleFetcher  = "bundle_fetcher"
+	BundleReleaser = "bundle_releaser"
+	BundleMonitor  = "bundle_monitor"
+
+	WebhookDispatcher = "webhook_dispatcher"
+
+	JobRunner = "job_runner"
+
+	PeeringSyncer = "peering_syncer"
+
+	LeaderElector  = "leader_elector"
+	ChangeListener = "change_listener"
+
 	MetricsServer       = "metrics_server"
 	HarvesterController = "harvester_controller"
 
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"sort"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"golang.org/x/crypto/sha3"
+)
+
+// Bundle digest algorithms.
+const (
+	// DigestAlgorithmCanonical is a SHA3-256 digest over the canonical form of the X.509 and JWT authorities of
+	// a bundle. The order of the authorities, the refresh hint and the sequence number do not change the digest.
+	DigestAlgorithmCanonical = "sha3-256-canonical"
+
+	// DigestAlgorithmX509 is a SHA3-256 digest over the PEM encoded X.509 authorities of a bundle. It is the
+	// algorithm used by older harvesters, which do not set the digest algorithm of their requests.
+	DigestAlgorithmX509 = "sha3-256-x509"
+)
+
+// GetBundleDigest returns the digest of a SPIFFE bundle computed with the given algorithm.
+// An empty algorithm stands for DigestAlgorithmX509.
+func GetBundleDigest(bundle *spiffebundle.Bundle, algorithm string) ([]byte, error) {
+	switch algorithm {
+	case DigestAlgorithmCanonical:
+		return canonicalDigest(bundle)
+	case DigestAlgorithmX509, "":
+		b, err := bundle.X509Bundle().Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal X.509 bundle: %w", err)
+		}
+		return GetDigest(b), nil
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+}
+
+func canonicalDigest(bundle *spiffebundle.Bundle) ([]byte, error) {
+	x509Authorities := bundle.X509Authorities()
+	certs := make([][]byte, 0, len(x509Authorities))
+	for _, cert := range x509Authorities {
+		certs = append(certs, cert.Raw)
+	}
+	sort.Slice(certs, func(i, j int) bool {
+		return bytes.Compare(certs[i], certs[j]) < 0
+	})
+
+	jwtAuthorities := bundle.JWTAuthorities()
+	keyIDs := make([]string, 0, len(jwtAuthorities))
+	for keyID := range jwtAuthorities {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	h := sha3.New256()
+	for _, cert := range certs {
+		writeField(h, []byte("x509"))
+		writeField(h, cert)
+	}
+	for _, keyID := range keyIDs {
+		key, err := x509.MarshalPKIXPublicKey(jwtAuthorities[keyID])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JWT authority %q: %w", keyID, err)
+		}
+		writeField(h, []byte("jwt"))
+		writeField(h, []byte(keyID))
+		writeField(h, key)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// writeField writes a length-prefixed field, so that different sequences of fields never hash the same input.
+func writeField(h hash.Hash, field []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+	h.Write(length[:])
+	h.Write(field)
+}
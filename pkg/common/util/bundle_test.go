@@ -0,0 +1,63 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBundleDigest(t *testing.T) {
+	td := spiffeid.RequireTrustDomainFromString("foo.test")
+	ca1, ca2 := certtest.CreateCA(t), certtest.CreateCA(t)
+	jwtKey := createKey(t)
+
+	bundle := spiffebundle.FromX509Authorities(td, []*x509.Certificate{ca1, ca2})
+	require.NoError(t, bundle.AddJWTAuthority("key-1", jwtKey.Public()))
+
+	canonical, err := GetBundleDigest(bundle, DigestAlgorithmCanonical)
+	require.NoError(t, err)
+	x509Digest, err := GetBundleDigest(bundle, DigestAlgorithmX509)
+	require.NoError(t, err)
+
+	// an empty algorithm is the X.509 one
+	legacy, err := GetBundleDigest(bundle, "")
+	require.NoError(t, err)
+	assert.Equal(t, x509Digest, legacy)
+
+	// the order of the authorities, the refresh hint and the sequence number are not part of the canonical digest
+	reordered := spiffebundle.FromX509Authorities(td, []*x509.Certificate{ca2, ca1})
+	require.NoError(t, reordered.AddJWTAuthority("key-1", jwtKey.Public()))
+	reordered.SetRefreshHint(time.Hour)
+	reordered.SetSequenceNumber(42)
+	digest, err := GetBundleDigest(reordered, DigestAlgorithmCanonical)
+	require.NoError(t, err)
+	assert.Equal(t, canonical, digest)
+
+	// a JWT authority rotation changes the canonical digest only
+	rotated := spiffebundle.FromX509Authorities(td, []*x509.Certificate{ca1, ca2})
+	require.NoError(t, rotated.AddJWTAuthority("key-2", createKey(t).Public()))
+	digest, err = GetBundleDigest(rotated, DigestAlgorithmCanonical)
+	require.NoError(t, err)
+	assert.NotEqual(t, canonical, digest)
+	digest, err = GetBundleDigest(rotated, DigestAlgorithmX509)
+	require.NoError(t, err)
+	assert.Equal(t, x509Digest, digest)
+
+	_, err = GetBundleDigest(bundle, "md5")
+	assert.EqualError(t, err, `unsupported digest algorithm "md5"`)
+}
+
+func createKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
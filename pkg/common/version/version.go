@@ -0,0 +1,6 @@
+// Package version holds the version of the Galadriel binaries.
+package version
+
+// Version is the version of Galadriel. It can be set at build time with
+// -ldflags "-X github.com/HewlettPackard/galadriel/pkg/common/version.Version=<version>".
+var Version = "0.1.0-dev"
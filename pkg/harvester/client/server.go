@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/HewlettPackard/galadriel/pkg/common"
 	"github.com/HewlettPackard/galadriel/pkg/common/telemetry"
+	"github.com/HewlettPackard/galadriel/pkg/common/version"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,18 +31,22 @@ type GaladrielServerClient interface {
 }
 
 type client struct {
-	c       http.Client
-	address string
-	token   string
-	logger  logrus.FieldLogger
+	c            http.Client
+	address      string
+	token        string
+	spireVersion string
+	logger       logrus.FieldLogger
 }
 
-func NewGaladrielServerClient(address, token string) (GaladrielServerClient, error) {
+// NewGaladrielServerClient creates a client of the Galadriel Server at the given address. The version of the
+// SPIRE Server, if known, is reported to Galadriel Server along with the version of the harvester.
+func NewGaladrielServerClient(address, token, spireVersion string) (GaladrielServerClient, error) {
 	return &client{
-		c:       *http.DefaultClient,
-		address: "http://" + address,
-		token:   token,
-		logger:  logrus.WithField(telemetry.SubsystemName, telemetry.GaladrielServerClient),
+		c:            *http.DefaultClient,
+		address:      "http://" + address,
+		token:        token,
+		spireVersion: spireVersion,
+		logger:       logrus.WithField(telemetry.SubsystemName, telemetry.GaladrielServerClient),
 	}, nil
 }
 
@@ -50,7 +56,7 @@ func (c *client) Connect(ctx context.Context, token string) error {
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
+	c.setHeaders(req, token)
 
 	resp, err := c.c.Do(req)
 	if err != nil {
@@ -83,8 +89,7 @@ func (c *client) SyncFederatedBundles(ctx context.Context, req *common.SyncBundl
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	// TODO: decorate all requests coming out
-	r.Header.Set("Authorization", "Bearer "+c.token)
+	c.setHeaders(r, c.token)
 	r.Header.Set("Content-Type", contentType)
 
 	res, err := c.c.Do(r)
@@ -124,8 +129,7 @@ func (c *client) PostBundle(ctx context.Context, req *common.PostBundleRequest)
 		return fmt.Errorf("failed to create push bundle request: %v", err)
 	}
 
-	// TODO: decorate all requests coming out
-	r.Header.Set("Authorization", "Bearer "+c.token)
+	c.setHeaders(r, c.token)
 	r.Header.Set("Content-Type", contentType)
 
 	res, err := c.c.Do(r)
@@ -139,6 +143,10 @@ func (c *client) PostBundle(ctx context.Context, req *common.PostBundleRequest)
 		return fmt.Errorf("failed to read response body: %v", err)
 	}
 
+	if res.StatusCode == http.StatusUnprocessableEntity {
+		return postBundleError(body)
+	}
+
 	// TODO: check right status code
 	if res.StatusCode != 200 {
 		return fmt.Errorf("push bundle request returned an error code %d: \n%s", res.StatusCode, body)
@@ -147,6 +155,32 @@ func (c *client) PostBundle(ctx context.Context, req *common.PostBundleRequest)
 	return nil
 }
 
+// setHeaders sets the headers common to all the requests: the token authenticating the harvester, and the
+// versions reported to Galadriel Server.
+func (c *client) setHeaders(r *http.Request, token string) {
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set(common.HarvesterVersionHeader, version.Version)
+	if c.spireVersion != "" {
+		r.Header.Set(common.SPIREVersionHeader, c.spireVersion)
+	}
+}
+
+// postBundleError returns the error for a bundle rejected by the validation policy of the server,
+// listing the broken rules.
+func postBundleError(body []byte) error {
+	var res common.PostBundleError
+	if err := json.Unmarshal(body, &res); err != nil || len(res.Violations) == 0 {
+		return fmt.Errorf("bundle rejected by Galadriel Server: %s", body)
+	}
+
+	violations := make([]string, len(res.Violations))
+	for i, v := range res.Violations {
+		violations[i] = fmt.Sprintf("%s (%s)", v.Message, v.Code)
+	}
+
+	return fmt.Errorf("bundle rejected by Galadriel Server: %s", strings.Join(violations, "; "))
+}
+
 func readBody(resp *http.Response) (string, error) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -1 +1,46 @@
 package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/common/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostBundleRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error":"rejected","violations":[{"code":"not_ca","message":"X.509 authority \"CN=CA\" is not a CA certificate"}]}`))
+	}))
+	defer server.Close()
+
+	c, err := NewGaladrielServerClient(strings.TrimPrefix(server.URL, "http://"), "token", "")
+	require.NoError(t, err)
+
+	err = c.PostBundle(context.Background(), &common.PostBundleRequest{Bundle: &entity.Bundle{}})
+	assert.EqualError(t, err, `bundle rejected by Galadriel Server: X.509 authority "CN=CA" is not a CA certificate (not_ca)`)
+}
+
+func TestConnectReportsVersions(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	c, err := NewGaladrielServerClient(strings.TrimPrefix(server.URL, "http://"), "token", "1.6.1")
+	require.NoError(t, err)
+
+	require.NoError(t, c.Connect(context.Background(), "token"))
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+	assert.Equal(t, version.Version, headers.Get(common.HarvesterVersionHeader))
+	assert.Equal(t, "1.6.1", headers.Get(common.SPIREVersionHeader))
+}
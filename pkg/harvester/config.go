@@ -27,6 +27,9 @@ type Config struct {
 	// How often to check for bundle rotation
 	BundleUpdatesInterval time.Duration
 
+	// Version of SPIRE Server reported to Galadriel Server, if known
+	SpireVersion string
+
 	// Directory to store runtime data
 	DataDir string
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net"
+	"path/filepath"
 	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/telemetry"
@@ -30,12 +31,18 @@ type Config struct {
 	SpireSocketPath       net.Addr
 	AccessToken           string
 	BundleUpdatesInterval time.Duration
+	SpireVersion          string
+	DataDir               string
 	Logger                logrus.FieldLogger
 }
 
+// federatedBundlesStateFile is the file in the data directory recording the trust domains whose bundle the
+// Galadriel Server sent.
+const federatedBundlesStateFile = "federated_bundles.json"
+
 func NewHarvesterController(ctx context.Context, config *Config) (*HarvesterController, error) {
 	sc := spire.NewLocalSpireServer(ctx, config.SpireSocketPath)
-	gc, err := client.NewGaladrielServerClient(config.ServerAddress, config.AccessToken)
+	gc, err := client.NewGaladrielServerClient(config.ServerAddress, config.AccessToken, config.SpireVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +71,7 @@ func (c *HarvesterController) run(ctx context.Context) {
 
 	err := util.RunTasks(ctx,
 		watcher.BuildSelfBundleWatcher(c.config.BundleUpdatesInterval, c.server, c.spire),
-		watcher.BuildFederatedBundlesWatcher(federatedBundlesInterval, c.server, c.spire),
+		watcher.BuildFederatedBundlesWatcher(federatedBundlesInterval, c.server, c.spire, filepath.Join(c.config.DataDir, federatedBundlesStateFile)),
 	)
 	if err != nil && !errors.Is(err, context.Canceled) {
 		c.logger.Error(err)
@@ -3,7 +3,12 @@ package watcher
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common"
@@ -14,6 +19,8 @@ import (
 	"github.com/HewlettPackard/galadriel/pkg/harvester/spire"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"google.golang.org/grpc/codes"
 )
 
 var logger = logrus.WithField(telemetry.SubsystemName, telemetry.HarvesterController)
@@ -52,40 +59,31 @@ func BuildSelfBundleWatcher(interval time.Duration, server client.GaladrielServe
 	}
 }
 
-func BuildFederatedBundlesWatcher(interval time.Duration, server client.GaladrielServerClient, spire spire.SpireServer) util.RunnableTask {
+// BuildFederatedBundlesWatcher builds the task syncing the federated bundles of the SPIRE Server with the Galadriel
+// Server. The trust domains whose bundle the Galadriel Server sent are recorded in the state file, so the bundles
+// the Galadriel Server stopped sending while the harvester was down are deleted once it starts again.
+func BuildFederatedBundlesWatcher(interval time.Duration, server client.GaladrielServerClient, spire spire.SpireServer, stateFile string) util.RunnableTask {
 	return func(ctx context.Context) error {
 		t := time.NewTicker(interval)
 
+		// managed holds the trust domains in the last state of the Galadriel Server, the only federated bundles
+		// the watcher deletes from the SPIRE Server
+		managed, err := loadManagedTrustDomains(stateFile)
+		if err != nil {
+			return err
+		}
+
 		for {
 			select {
 			case <-t.C:
-				req, err := buildSyncBundlesRequest(ctx, spire)
-				if err != nil {
-					logger.Errorf("Failed to build sync federated bundle request: %v", err)
-					break
-				}
-
-				res, err := server.SyncFederatedBundles(ctx, req)
-				if err != nil {
-					logger.Errorf("Failed to get federated bundles updates: %v", err)
+				synced := syncFederatedBundles(ctx, server, spire, managed)
+				if sameTrustDomains(managed, synced) {
 					break
 				}
-
-				bundles, processed := federatedBundlesUpdatesToSpiffeBundles(res)
-				updatesLen := uint32(len(res.Updates))
-				if updatesLen != processed {
-					logger.Errorf("Failed to process %d out of %d trust domains", updatesLen-processed, updatesLen)
-				}
-
-				if len(bundles) == 0 {
-					logger.Debug("No new federated bundles to set")
-					break
-				}
-
-				logger.Infof("Setting %d new federated bundle(s)", len(bundles))
-				if _, err = spire.SetFederatedBundles(ctx, bundles); err != nil {
-					logger.Errorf("%v", err)
+				if err := saveManagedTrustDomains(stateFile, synced); err != nil {
+					logger.Errorf("Failed to save the trust domains of the federated bundles: %v", err)
 				}
+				managed = synced
 			case <-ctx.Done():
 				return nil
 			}
@@ -93,6 +91,50 @@ func BuildFederatedBundlesWatcher(interval time.Duration, server client.Galadrie
 	}
 }
 
+// syncFederatedBundles sets the federated bundles updated by the Galadriel Server on the SPIRE Server, and deletes
+// the ones that were in the previous state of the Galadriel Server but are no longer. The federated bundles that
+// the Galadriel Server never sent, e.g. the SPIFFE federations configured directly in SPIRE, are left untouched.
+// It returns the state of the Galadriel Server, or the previous one if it could not be synced.
+func syncFederatedBundles(ctx context.Context, server client.GaladrielServerClient, spire spire.SpireServer, managed common.BundlesDigests) common.BundlesDigests {
+	req, err := buildSyncBundlesRequest(ctx, spire)
+	if err != nil {
+		logger.Errorf("Failed to build sync federated bundle request: %v", err)
+		return managed
+	}
+
+	res, err := server.SyncFederatedBundles(ctx, req)
+	if err != nil {
+		logger.Errorf("Failed to get federated bundles updates: %v", err)
+		return managed
+	}
+
+	bundles, processed := federatedBundlesUpdatesToSpiffeBundles(res)
+	updatesLen := uint32(len(res.Updates))
+	if updatesLen != processed {
+		logger.Errorf("Failed to process %d out of %d trust domains", updatesLen-processed, updatesLen)
+	}
+
+	if removed := removedTrustDomains(managed, req, res); len(removed) > 0 {
+		logger.Infof("Deleting %d federated bundle(s) no longer federated", len(removed))
+		deleteFederatedBundles(ctx, spire, removed)
+	}
+
+	if len(bundles) == 0 {
+		logger.Debug("No new federated bundles to set")
+	} else {
+		logger.Infof("Setting %d new federated bundle(s)", len(bundles))
+		if _, err = spire.SetFederatedBundles(ctx, bundles); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+
+	// a response without a state cannot tell which bundles are managed
+	if res.State == nil {
+		return managed
+	}
+	return res.State
+}
+
 func hasNewBundle(ctx context.Context, currentDigest []byte, spire spire.SpireServer) (newBundle *spiffebundle.Bundle, newDigest []byte, hasNew bool) {
 	spireBundle, err := spire.GetBundle(ctx)
 	if err != nil {
@@ -100,12 +142,11 @@ func hasNewBundle(ctx context.Context, currentDigest []byte, spire spire.SpireSe
 		return nil, nil, false
 	}
 
-	b, err := spireBundle.X509Bundle().Marshal()
+	spireDigest, err := util.GetBundleDigest(spireBundle, util.DigestAlgorithmCanonical)
 	if err != nil {
-		logger.Errorf("Failed to marshal spire X.509 bundle: %v", err)
+		logger.Errorf("Failed to compute spire bundle digest: %v", err)
 		return nil, nil, false
 	}
-	spireDigest := util.GetDigest(b)
 
 	if !bytes.Equal(currentDigest, spireDigest) {
 		return spireBundle, spireDigest, true
@@ -117,17 +158,18 @@ func hasNewBundle(ctx context.Context, currentDigest []byte, spire spire.SpireSe
 func buildPostBundleRequest(b *spiffebundle.Bundle) (*common.PostBundleRequest, error) {
 	bundle, err := b.Marshal()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal X.509 bundle: %v", err)
+		return nil, fmt.Errorf("failed to marshal bundle: %v", err)
 	}
 
-	x509b, err := b.X509Bundle().Marshal()
+	digest, err := util.GetBundleDigest(b, util.DigestAlgorithmCanonical)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal X.509 bundle: %v", err)
+		return nil, fmt.Errorf("failed to compute bundle digest: %v", err)
 	}
 
 	ent := entity.Bundle{
 		Data:            bundle,
-		Digest:          util.GetDigest(x509b),
+		Digest:          digest,
+		DigestAlgorithm: util.DigestAlgorithmCanonical,
 		TrustDomainName: b.TrustDomain(),
 		CreatedAt:       time.Time{},
 		UpdatedAt:       time.Time{},
@@ -150,21 +192,17 @@ func buildSyncBundlesRequest(ctx context.Context, spire spire.SpireServer) (*com
 
 	for _, b := range res.Bundles {
 		td := b.TrustDomain()
-		if err != nil {
-			logger.Errorf("Failed to marshal bundle for trust domain %s: %v", td, err)
-			continue
-		}
 
-		x509b, err := b.X509Bundle().Marshal()
+		digest, err := util.GetBundleDigest(b, util.DigestAlgorithmCanonical)
 		if err != nil {
-			logger.Errorf("Failed to marshal X.509 bundle for trust domain %s: %v", td, err)
+			logger.Errorf("Failed to compute bundle digest for trust domain %s: %v", td, err)
 			continue
 		}
 
-		digests[td] = util.GetDigest(x509b)
+		digests[td] = digest
 	}
 
-	state := &common.SyncBundleRequest{State: digests}
+	state := &common.SyncBundleRequest{State: digests, DigestAlgorithm: util.DigestAlgorithmCanonical}
 
 	return state, nil
 }
@@ -188,3 +226,102 @@ func federatedBundlesUpdatesToSpiffeBundles(res *common.SyncBundleResponse) (bun
 
 	return bundles, processed
 }
+
+// removedTrustDomains returns the trust domains whose bundle is set on the SPIRE Server and was in the previous
+// state of the Galadriel Server, but is not in its current state, e.g. because their relationship was deleted or
+// expired.
+func removedTrustDomains(managed common.BundlesDigests, req *common.SyncBundleRequest, res *common.SyncBundleResponse) []spiffeid.TrustDomain {
+	// a response without a state cannot tell which bundles were removed
+	if res.State == nil {
+		return nil
+	}
+
+	var removed []spiffeid.TrustDomain
+	for td := range req.State {
+		if _, ok := managed[td]; !ok {
+			continue
+		}
+		if _, ok := res.State[td]; !ok {
+			removed = append(removed, td)
+		}
+	}
+
+	return removed
+}
+
+// loadManagedTrustDomains reads the trust domains recorded in the state file, none if it does not exist yet.
+func loadManagedTrustDomains(stateFile string) (common.BundlesDigests, error) {
+	data, err := os.ReadFile(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read federated bundles state: %w", err)
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("failed to parse federated bundles state %q: %w", stateFile, err)
+	}
+
+	managed := make(common.BundlesDigests, len(names))
+	for _, name := range names {
+		td, err := spiffeid.TrustDomainFromString(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trust domain in federated bundles state %q: %w", stateFile, err)
+		}
+		managed[td] = nil
+	}
+
+	return managed, nil
+}
+
+// saveManagedTrustDomains records the trust domains in the state file, replacing it at once so a harvester
+// stopped while it is written does not leave it truncated.
+func saveManagedTrustDomains(stateFile string, managed common.BundlesDigests) error {
+	names := make([]string, 0, len(managed))
+	for td := range managed {
+		names = append(names, td.String())
+	}
+	sort.Strings(names)
+
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, stateFile)
+}
+
+func sameTrustDomains(a, b common.BundlesDigests) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for td := range a {
+		if _, ok := b[td]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func deleteFederatedBundles(ctx context.Context, spire spire.SpireServer, trustDomains []spiffeid.TrustDomain) {
+	statuses, err := spire.DeleteFederatedBundles(ctx, trustDomains)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return
+	}
+
+	for _, s := range statuses {
+		if s.Status != nil && s.Status.Code != codes.OK {
+			logger.Errorf("Failed to delete federated bundle of trust domain %q: %s", s.TrustDomain, s.Status.Message)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package watcher
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common"
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/HewlettPackard/galadriel/pkg/harvester/spire"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSpireServer struct {
+	mu      sync.Mutex
+	bundles map[spiffeid.TrustDomain]*spiffebundle.Bundle
+}
+
+func (s *fakeSpireServer) has(td spiffeid.TrustDomain) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.bundles[td]
+	return ok
+}
+
+func (s *fakeSpireServer) GetBundle(context.Context) (*spiffebundle.Bundle, error) {
+	return nil, nil
+}
+
+func (s *fakeSpireServer) SetFederatedBundles(_ context.Context, bundles []*spiffebundle.Bundle) ([]*spire.BatchSetFederatedBundleStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range bundles {
+		s.bundles[b.TrustDomain()] = b
+	}
+	return nil, nil
+}
+
+func (s *fakeSpireServer) GetFederatedBundles(context.Context) (*spire.ListFederatedBundlesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &spire.ListFederatedBundlesResponse{}
+	for _, b := range s.bundles {
+		res.Bundles = append(res.Bundles, b)
+	}
+	return res, nil
+}
+
+func (s *fakeSpireServer) DeleteFederatedBundles(_ context.Context, trustDomains []spiffeid.TrustDomain) ([]*spire.BatchDeleteFederatedBundleStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, td := range trustDomains {
+		delete(s.bundles, td)
+	}
+	return nil, nil
+}
+
+// fakeGaladrielServer responds with the given bundles as its state, sending the ones the SPIRE Server does not
+// have yet.
+type fakeGaladrielServer struct {
+	mu      sync.Mutex
+	bundles common.BundleUpdates
+}
+
+func (s *fakeGaladrielServer) setBundles(bundles common.BundleUpdates) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bundles = bundles
+}
+
+func (s *fakeGaladrielServer) SyncFederatedBundles(_ context.Context, req *common.SyncBundleRequest) (*common.SyncBundleResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &common.SyncBundleResponse{Updates: make(common.BundleUpdates), State: make(common.BundlesDigests)}
+	for td, b := range s.bundles {
+		res.State[td] = b.Digest
+		if _, ok := req.State[td]; !ok {
+			res.Updates[td] = b
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeGaladrielServer) PostBundle(context.Context, *common.PostBundleRequest) error {
+	return nil
+}
+
+func (s *fakeGaladrielServer) Connect(context.Context, string) error {
+	return nil
+}
+
+func TestSyncFederatedBundles(t *testing.T) {
+	ctx := context.Background()
+	federated := spiffeid.RequireTrustDomainFromString("federated.test")
+	native := spiffeid.RequireTrustDomainFromString("native.test")
+
+	// native.test is federated directly in SPIRE, without Galadriel
+	spireServer := &fakeSpireServer{bundles: map[spiffeid.TrustDomain]*spiffebundle.Bundle{
+		native: certtest.CreateBundle(t, native),
+	}}
+	federatedBundle := certtest.MarshalBundle(t, certtest.CreateBundle(t, federated))
+	server := &fakeGaladrielServer{bundles: common.BundleUpdates{
+		federated: {TrustDomainName: federated, Data: federatedBundle, Digest: []byte("digest")},
+	}}
+
+	managed := syncFederatedBundles(ctx, server, spireServer, nil)
+	assert.Contains(t, managed, federated)
+	require.Contains(t, spireServer.bundles, federated)
+	assert.Contains(t, spireServer.bundles, native)
+
+	// the bundle federated through Galadriel is deleted when it is no longer in its state, but not the native one
+	server.setBundles(common.BundleUpdates{})
+	managed = syncFederatedBundles(ctx, server, spireServer, managed)
+	assert.Empty(t, managed)
+	assert.NotContains(t, spireServer.bundles, federated)
+	assert.Contains(t, spireServer.bundles, native)
+}
+
+func TestFederatedBundlesWatcherRestart(t *testing.T) {
+	federated := spiffeid.RequireTrustDomainFromString("federated.test")
+	native := spiffeid.RequireTrustDomainFromString("native.test")
+	stateFile := filepath.Join(t.TempDir(), "federated_bundles.json")
+
+	spireServer := &fakeSpireServer{bundles: map[spiffeid.TrustDomain]*spiffebundle.Bundle{
+		native: certtest.CreateBundle(t, native),
+	}}
+	federatedBundle := certtest.MarshalBundle(t, certtest.CreateBundle(t, federated))
+	server := &fakeGaladrielServer{bundles: common.BundleUpdates{
+		federated: {TrustDomainName: federated, Data: federatedBundle, Digest: []byte("digest")},
+	}}
+
+	run := func(done func() bool) {
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- BuildFederatedBundlesWatcher(10*time.Millisecond, server, spireServer, stateFile)(ctx)
+		}()
+
+		assert.Eventually(t, done, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		require.NoError(t, <-errCh)
+	}
+
+	// the first watcher sets the bundle and records its trust domain
+	run(func() bool {
+		managed, err := loadManagedTrustDomains(stateFile)
+		return spireServer.has(federated) && err == nil && len(managed) == 1
+	})
+
+	// the bundle is removed from the Galadriel Server while the harvester is down, and deleted by a fresh
+	// watcher, which does not touch the native bundle
+	server.setBundles(common.BundleUpdates{})
+	run(func() bool {
+		return !spireServer.has(federated)
+	})
+	assert.True(t, spireServer.has(native))
+
+	managed, err := loadManagedTrustDomains(stateFile)
+	require.NoError(t, err)
+	assert.Empty(t, managed)
+}
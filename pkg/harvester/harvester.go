@@ -31,7 +31,7 @@ func (h *Harvester) Run(ctx context.Context) error {
 		return errors.New("token is required to connect the Harvester to the Galadriel Server")
 	}
 
-	galadrielClient, err := client.NewGaladrielServerClient(h.config.ServerAddress, h.config.JoinToken)
+	galadrielClient, err := client.NewGaladrielServerClient(h.config.ServerAddress, h.config.JoinToken, h.config.SpireVersion)
 	if err != nil {
 		return err
 	}
@@ -46,6 +46,8 @@ func (h *Harvester) Run(ctx context.Context) error {
 		SpireSocketPath:       h.config.SpireAddress,
 		AccessToken:           h.config.JoinToken,
 		BundleUpdatesInterval: h.config.BundleUpdatesInterval,
+		SpireVersion:          h.config.SpireVersion,
+		DataDir:               h.config.DataDir,
 		Logger:                h.config.Logger.WithField(telemetry.SubsystemName, telemetry.HarvesterController),
 	}
 	c, err := controller.NewHarvesterController(ctx, config)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	bundlev1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/bundle/v1"
 	"google.golang.org/grpc"
 )
@@ -15,6 +16,7 @@ type BundleClient interface {
 	GetBundle(context.Context) (*spiffebundle.Bundle, error)
 	BatchSetFederatedBundle(context.Context, []*spiffebundle.Bundle) ([]*BatchSetFederatedBundleStatus, error)
 	ListFederatedBundles(context.Context) (*ListFederatedBundlesResponse, error)
+	BatchDeleteFederatedBundle(context.Context, []spiffeid.TrustDomain) ([]*BatchDeleteFederatedBundleStatus, error)
 }
 
 // NewBundleClient creates a new SPIRE Bundle API client
@@ -90,3 +92,27 @@ func (c bundleClient) BatchSetFederatedBundle(ctx context.Context, bundles []*sp
 
 	return statuses, nil
 }
+
+// BatchDeleteFederatedBundle deletes federated bundles. The registration entries federating with the trust domains
+// of the deleted bundles are dissociated from them, rather than preventing the deletion.
+func (c bundleClient) BatchDeleteFederatedBundle(ctx context.Context, trustDomains []spiffeid.TrustDomain) ([]*BatchDeleteFederatedBundleStatus, error) {
+	names := make([]string, len(trustDomains))
+	for i, td := range trustDomains {
+		names[i] = td.String()
+	}
+
+	res, err := c.client.BatchDeleteFederatedBundle(ctx, &bundlev1.BatchDeleteFederatedBundleRequest{
+		TrustDomains: names,
+		Mode:         bundlev1.BatchDeleteFederatedBundleRequest_DISSOCIATE,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("client failed to delete federated bundles: %v", err)
+	}
+
+	statuses, err := protoToBatchDeleteFederatedBundleResult(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spire server bundle response: %v", err)
+	}
+
+	return statuses, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	bundlev1 "github.com/spiffe/spire-api-sdk/proto/spire/api/server/bundle/v1"
 	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
 	"google.golang.org/grpc"
@@ -85,6 +86,10 @@ func (c fakeInternalClient) BatchSetFederatedBundle(context.Context, []*spiffebu
 	return nil, errors.New("not implemented")
 }
 
+func (c fakeInternalClient) BatchDeleteFederatedBundle(context.Context, []spiffeid.TrustDomain) ([]*BatchDeleteFederatedBundleStatus, error) {
+	return nil, errors.New("not implemented")
+}
+
 func (c fakeInternalClient) ListFederatedBundles(context.Context) (*ListFederatedBundlesResponse, error) {
 	return nil, errors.New("not implemented")
 }
@@ -193,6 +193,31 @@ func protoToBatchSetFederatedBundleResult(in *bundlev1.BatchSetFederatedBundleRe
 	return out, nil
 }
 
+func protoToBatchDeleteFederatedBundleResult(in *bundlev1.BatchDeleteFederatedBundleResponse) ([]*BatchDeleteFederatedBundleStatus, error) {
+	var out []*BatchDeleteFederatedBundleStatus
+
+	for _, r := range in.GetResults() {
+		td, err := spiffeid.TrustDomainFromString(r.TrustDomain)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Status == nil {
+			return nil, errors.New("call returned no status")
+		}
+
+		out = append(out, &BatchDeleteFederatedBundleStatus{
+			TrustDomain: td,
+			Status: &Status{
+				Message: r.Status.GetMessage(),
+				Code:    codes.Code(r.Status.GetCode()),
+			},
+		})
+	}
+
+	return out, nil
+}
+
 func protoToFederatedBundles(in *bundlev1.ListFederatedBundlesResponse) ([]*spiffebundle.Bundle, error) {
 	var out []*spiffebundle.Bundle
 
@@ -9,6 +9,7 @@ import (
 	"github.com/HewlettPackard/galadriel/pkg/common/telemetry"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -18,6 +19,7 @@ type SpireServer interface {
 	GetBundle(context.Context) (*spiffebundle.Bundle, error)
 	SetFederatedBundles(context.Context, []*spiffebundle.Bundle) ([]*BatchSetFederatedBundleStatus, error)
 	GetFederatedBundles(context.Context) (*ListFederatedBundlesResponse, error)
+	DeleteFederatedBundles(context.Context, []spiffeid.TrustDomain) ([]*BatchDeleteFederatedBundleStatus, error)
 }
 
 type localSpireServer struct {
@@ -76,6 +78,16 @@ func (s *localSpireServer) SetFederatedBundles(ctx context.Context, bundles []*s
 	return res, nil
 }
 
+// DeleteFederatedBundles deletes a set of federated SPIFFE bundles from the SPIRE Server
+func (s *localSpireServer) DeleteFederatedBundles(ctx context.Context, trustDomains []spiffeid.TrustDomain) ([]*BatchDeleteFederatedBundleStatus, error) {
+	res, err := s.client.BatchDeleteFederatedBundle(ctx, trustDomains)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete federated bundles: %v", err)
+	}
+
+	return res, nil
+}
+
 type clientMaker func(*grpc.ClientConn) (client, error)
 
 func dialSocket(ctx context.Context, addr net.Addr, makeClient clientMaker) (client, error) {
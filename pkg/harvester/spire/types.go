@@ -2,6 +2,7 @@ package spire
 
 import (
 	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"google.golang.org/grpc/codes"
 )
 
@@ -16,6 +17,11 @@ type BatchSetFederatedBundleStatus struct {
 	Status *Status
 }
 
+type BatchDeleteFederatedBundleStatus struct {
+	TrustDomain spiffeid.TrustDomain
+	Status      *Status
+}
+
 type BatchGetFederatedBundleStatus struct {
 	Bundle *spiffebundle.Bundle
 }
@@ -0,0 +1,453 @@
+// Package apply reconciles the trust domains and relationships stored in a datastore with a declared State,
+// creating, updating and deleting them as needed.
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// State is the declared set of trust domains and relationships. Trust domains and relationships
+// that are not declared are deleted when the State is applied, except the trust domains imported from
+// peers and their relationships, which are left alone.
+type State struct {
+	TrustDomains  []TrustDomain  `json:"trust_domains"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+type TrustDomain struct {
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	PublishBundle bool   `json:"publish_bundle,omitempty"`
+	PublishOIDC   bool   `json:"publish_oidc,omitempty"`
+
+	// Labels are selected by the federation policies.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// BundleEndpoint is the SPIFFE bundle endpoint the bundle of a trust domain without a harvester is fetched from.
+	BundleEndpoint *BundleEndpoint `json:"bundle_endpoint,omitempty"`
+}
+
+type BundleEndpoint struct {
+	URL      string `json:"url"`
+	Profile  string `json:"profile"`
+	SPIFFEID string `json:"spiffe_id,omitempty"`
+}
+
+type Relationship struct {
+	TrustDomainA string `json:"trust_domain_a"`
+	TrustDomainB string `json:"trust_domain_b"`
+
+	// Direction defines which trust domains trust their peer. It defaults to bidirectional.
+	Direction entity.RelationshipDirection `json:"direction,omitempty"`
+}
+
+// Action is the kind of change made to a resource.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Resource is the kind of resource changed.
+type Resource string
+
+const (
+	ResourceTrustDomain  Resource = "trust_domain"
+	ResourceRelationship Resource = "relationship"
+)
+
+// Change describes a change needed to reconcile a resource with the State.
+type Change struct {
+	Action   Action   `json:"action"`
+	Resource Resource `json:"resource"`
+	Name     string   `json:"name"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Plan lists the changes needed to reconcile the datastore with the State, in the order they are made.
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Applied is false if the changes were only planned.
+	Applied bool `json:"applied"`
+}
+
+// Validate checks that the State is well-formed: trust domain names are valid and unique, and
+// relationships are unique and between two different declared trust domains.
+func (s *State) Validate() error {
+	trustDomains := make(map[string]bool, len(s.TrustDomains))
+	for _, td := range s.TrustDomains {
+		if _, err := spiffeid.TrustDomainFromString(td.Name); err != nil {
+			return fmt.Errorf("invalid trust domain name %q: %w", td.Name, err)
+		}
+		if trustDomains[td.Name] {
+			return fmt.Errorf("trust domain %q is declared more than once", td.Name)
+		}
+		if _, err := td.toEntity(); err != nil {
+			return err
+		}
+		trustDomains[td.Name] = true
+	}
+
+	relationships := make(map[string]bool, len(s.Relationships))
+	for _, r := range s.Relationships {
+		for _, name := range []string{r.TrustDomainA, r.TrustDomainB} {
+			if !trustDomains[name] {
+				return fmt.Errorf("relationship %s references trust domain %q, which is not declared", relationshipName(r.TrustDomainA, r.TrustDomainB), name)
+			}
+		}
+		if r.TrustDomainA == r.TrustDomainB {
+			return fmt.Errorf("trust domain %q cannot have a relationship with itself", r.TrustDomainA)
+		}
+		if err := r.Direction.Validate(); err != nil {
+			return fmt.Errorf("invalid relationship %s: %w", relationshipName(r.TrustDomainA, r.TrustDomainB), err)
+		}
+
+		key := relationshipKey(r.TrustDomainA, r.TrustDomainB)
+		if relationships[key] {
+			return fmt.Errorf("relationship %s is declared more than once", relationshipName(r.TrustDomainA, r.TrustDomainB))
+		}
+		relationships[key] = true
+	}
+
+	return nil
+}
+
+// step is a planned change along with the function that makes it.
+type step struct {
+	change Change
+	run    func(ctx context.Context, tx datastore.Datastore) error
+}
+
+// Apply reconciles the datastore with the State, within a single transaction. If dryRun is set,
+// the changes are planned but not made.
+func Apply(ctx context.Context, ds datastore.Datastore, state *State, dryRun bool) (*Plan, error) {
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Changes: []Change{}}
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		steps, err := planSteps(ctx, tx, state)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range steps {
+			plan.Changes = append(plan.Changes, s.change)
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		for _, s := range steps {
+			if err := s.run(ctx, tx); err != nil {
+				return fmt.Errorf("failed to %s %s %s: %w", s.change.Action, s.change.Resource, s.change.Name, err)
+			}
+		}
+		plan.Applied = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// planSteps computes the steps to reconcile the datastore with the State. Trust domains are created before
+// the relationships that reference them, and relationships are deleted before their trust domains.
+func planSteps(ctx context.Context, tx datastore.Datastore, state *State) ([]step, error) {
+	storedTrustDomains, err := tx.ListTrustDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	storedRelationships, err := tx.ListRelationshipsWithTrustDomainNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trustDomains := make(map[string]*entity.TrustDomain, len(storedTrustDomains))
+	for _, td := range storedTrustDomains {
+		trustDomains[td.Name.String()] = td
+	}
+
+	relationships := make(map[string]*entity.Relationship, len(storedRelationships))
+	for _, r := range storedRelationships {
+		relationships[relationshipKey(r.TrustDomainAName.String(), r.TrustDomainBName.String())] = r
+	}
+
+	var steps []step
+
+	declaredTrustDomains := make(map[string]bool, len(state.TrustDomains))
+	for _, declared := range state.TrustDomains {
+		declaredTrustDomains[declared.Name] = true
+
+		want, err := declared.toEntity()
+		if err != nil {
+			return nil, err
+		}
+
+		stored, ok := trustDomains[declared.Name]
+		if ok && stored.PeerID.Valid {
+			return nil, fmt.Errorf("trust domain %q is imported from a peer and cannot be declared", declared.Name)
+		}
+		if !ok {
+			steps = append(steps, step{
+				change: Change{Action: ActionCreate, Resource: ResourceTrustDomain, Name: declared.Name},
+				run: func(ctx context.Context, tx datastore.Datastore) error {
+					created, err := tx.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: want.Name})
+					if err != nil {
+						return err
+					}
+
+					// the attributes can only be set through an update
+					setAttributes(created, want)
+					_, err = tx.CreateOrUpdateTrustDomain(ctx, created)
+					return err
+				},
+			})
+			continue
+		}
+
+		if diff := trustDomainDiff(stored, want); diff != "" {
+			steps = append(steps, step{
+				change: Change{
+					Action:   ActionUpdate,
+					Resource: ResourceTrustDomain,
+					Name:     declared.Name,
+					Detail:   diff,
+				},
+				run: func(ctx context.Context, tx datastore.Datastore) error {
+					setAttributes(stored, want)
+					_, err := tx.CreateOrUpdateTrustDomain(ctx, stored)
+					return err
+				},
+			})
+		}
+	}
+
+	declaredRelationships := make(map[string]bool, len(state.Relationships))
+	for _, declared := range state.Relationships {
+		declared := declared
+		key := relationshipKey(declared.TrustDomainA, declared.TrustDomainB)
+		declaredRelationships[key] = true
+
+		if stored, ok := relationships[key]; ok {
+			stored := stored
+			want := declared.directionOf(stored)
+			if stored.Direction.OrDefault() == want {
+				continue
+			}
+
+			steps = append(steps, step{
+				change: Change{
+					Action:   ActionUpdate,
+					Resource: ResourceRelationship,
+					Name:     relationshipName(declared.TrustDomainA, declared.TrustDomainB),
+					Detail:   fmt.Sprintf("direction: %q -> %q", stored.Direction.OrDefault(), want),
+				},
+				run: func(ctx context.Context, tx datastore.Datastore) error {
+					stored.Direction = want
+					_, err := tx.CreateOrUpdateRelationship(ctx, stored)
+					return err
+				},
+			})
+			continue
+		}
+
+		steps = append(steps, step{
+			change: Change{Action: ActionCreate, Resource: ResourceRelationship, Name: relationshipName(declared.TrustDomainA, declared.TrustDomainB)},
+			run: func(ctx context.Context, tx datastore.Datastore) error {
+				tdA, err := findTrustDomain(ctx, tx, declared.TrustDomainA)
+				if err != nil {
+					return err
+				}
+				tdB, err := findTrustDomain(ctx, tx, declared.TrustDomainB)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+					TrustDomainAID: tdA.ID.UUID,
+					TrustDomainBID: tdB.ID.UUID,
+					Direction:      declared.Direction.OrDefault(),
+				})
+				return err
+			},
+		})
+	}
+
+	for _, stored := range storedRelationships {
+		stored := stored
+		if declaredRelationships[relationshipKey(stored.TrustDomainAName.String(), stored.TrustDomainBName.String())] {
+			continue
+		}
+		if isImported(trustDomains[stored.TrustDomainAName.String()]) || isImported(trustDomains[stored.TrustDomainBName.String()]) {
+			continue
+		}
+
+		steps = append(steps, step{
+			change: Change{Action: ActionDelete, Resource: ResourceRelationship, Name: relationshipName(stored.TrustDomainAName.String(), stored.TrustDomainBName.String())},
+			run: func(ctx context.Context, tx datastore.Datastore) error {
+				return tx.DeleteRelationship(ctx, stored.ID.UUID)
+			},
+		})
+	}
+
+	for _, stored := range storedTrustDomains {
+		stored := stored
+		if declaredTrustDomains[stored.Name.String()] || isImported(stored) {
+			continue
+		}
+
+		steps = append(steps, step{
+			change: Change{
+				Action:   ActionDelete,
+				Resource: ResourceTrustDomain,
+				Name:     stored.Name.String(),
+				Detail:   "its bundle and join tokens are deleted as well",
+			},
+			run: func(ctx context.Context, tx datastore.Datastore) error {
+				return tx.DeleteTrustDomain(ctx, stored.ID.UUID)
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+// isImported returns whether the trust domain is imported from a peer, in which case it is not managed by the State.
+func isImported(td *entity.TrustDomain) bool {
+	return td != nil && td.PeerID.Valid
+}
+
+func findTrustDomain(ctx context.Context, tx datastore.Datastore, name string) (*entity.TrustDomain, error) {
+	td, err := tx.FindTrustDomainByName(ctx, spiffeid.RequireTrustDomainFromString(name))
+	if err != nil {
+		return nil, err
+	}
+	if td == nil {
+		return nil, errors.New("trust domain not found")
+	}
+
+	return td, nil
+}
+
+// trustDomainDiff describes the attributes of a stored trust domain that differ from the declared ones.
+func trustDomainDiff(stored, want *entity.TrustDomain) string {
+	var diffs []string
+	if stored.Description != want.Description {
+		diffs = append(diffs, fmt.Sprintf("description: %q -> %q", stored.Description, want.Description))
+	}
+	if stored.PublishBundle != want.PublishBundle {
+		diffs = append(diffs, fmt.Sprintf("publish_bundle: %t -> %t", stored.PublishBundle, want.PublishBundle))
+	}
+	if stored.PublishOIDC != want.PublishOIDC {
+		diffs = append(diffs, fmt.Sprintf("publish_oidc: %t -> %t", stored.PublishOIDC, want.PublishOIDC))
+	}
+	if storedLabels, wantLabels := entity.FormatLabels(stored.Labels), entity.FormatLabels(want.Labels); storedLabels != wantLabels {
+		diffs = append(diffs, fmt.Sprintf("labels: %q -> %q", storedLabels, wantLabels))
+	}
+	if storedEndpoint, wantEndpoint := bundleEndpointString(stored), bundleEndpointString(want); storedEndpoint != wantEndpoint {
+		diffs = append(diffs, fmt.Sprintf("bundle_endpoint: %q -> %q", storedEndpoint, wantEndpoint))
+	}
+
+	return strings.Join(diffs, ", ")
+}
+
+// toEntity returns the declared attributes of the trust domain.
+func (td *TrustDomain) toEntity() (*entity.TrustDomain, error) {
+	name, err := spiffeid.TrustDomainFromString(td.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust domain name %q: %w", td.Name, err)
+	}
+
+	result := &entity.TrustDomain{
+		Name:          name,
+		Description:   td.Description,
+		PublishBundle: td.PublishBundle,
+		PublishOIDC:   td.PublishOIDC,
+		Labels:        td.Labels,
+	}
+
+	if err := entity.ValidateLabels(td.Labels); err != nil {
+		return nil, fmt.Errorf("invalid labels for trust domain %q: %w", td.Name, err)
+	}
+
+	if td.BundleEndpoint != nil {
+		result.BundleEndpointURL = td.BundleEndpoint.URL
+		result.BundleEndpointProfile = td.BundleEndpoint.Profile
+		if td.BundleEndpoint.SPIFFEID != "" {
+			result.BundleEndpointSPIFFEID, err = spiffeid.FromString(td.BundleEndpoint.SPIFFEID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bundle endpoint SPIFFE ID for trust domain %q: %w", td.Name, err)
+			}
+		}
+		if result.BundleEndpointURL == "" {
+			return nil, fmt.Errorf("invalid bundle endpoint for trust domain %q: url is required", td.Name)
+		}
+	}
+
+	if err := bundles.ValidateEndpoint(result); err != nil {
+		return nil, fmt.Errorf("invalid bundle endpoint for trust domain %q: %w", td.Name, err)
+	}
+
+	return result, nil
+}
+
+func bundleEndpointString(td *entity.TrustDomain) string {
+	if td.BundleEndpointURL == "" {
+		return ""
+	}
+
+	id := ""
+	if !td.BundleEndpointSPIFFEID.IsZero() {
+		id = td.BundleEndpointSPIFFEID.String()
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", td.BundleEndpointProfile, td.BundleEndpointURL, id))
+}
+
+// setAttributes sets the declared attributes on a stored trust domain.
+func setAttributes(stored, want *entity.TrustDomain) {
+	stored.Description = want.Description
+	stored.PublishBundle = want.PublishBundle
+	stored.PublishOIDC = want.PublishOIDC
+	stored.Labels = want.Labels
+	stored.BundleEndpointURL = want.BundleEndpointURL
+	stored.BundleEndpointProfile = want.BundleEndpointProfile
+	stored.BundleEndpointSPIFFEID = want.BundleEndpointSPIFFEID
+}
+
+// relationshipKey identifies a relationship regardless of the order of its trust domains.
+func relationshipKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + " " + b
+}
+
+// directionOf returns the declared direction relative to the order of the trust domains in the stored relationship,
+// which may be declared the other way around.
+func (r *Relationship) directionOf(stored *entity.Relationship) entity.RelationshipDirection {
+	direction := r.Direction.OrDefault()
+	if stored.TrustDomainAName.String() != r.TrustDomainA {
+		return direction.Reverse()
+	}
+	return direction
+}
+
+func relationshipName(a, b string) string {
+	return fmt.Sprintf("%s <-> %s", a, b)
+}
@@ -0,0 +1,249 @@
+package apply_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/apply"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const config = `
+trust_domain "foo.test" {
+  description = "Foo"
+}
+
+trust_domain "bar.test" {
+  publish_bundle = true
+  publish_oidc   = true
+  labels         = { env = "prod" }
+}
+
+trust_domain "baz.test" {
+  bundle_endpoint {
+    url     = "https://baz.test/bundle"
+    profile = "https_web"
+  }
+}
+
+relationship {
+  trust_domain_a = "foo.test"
+  trust_domain_b = "bar.test"
+}
+
+relationship {
+  trust_domain_a = "baz.test"
+  trust_domain_b = "foo.test"
+  direction      = "a_trusts_b"
+}
+`
+
+func TestParseConfig(t *testing.T) {
+	state, err := apply.ParseConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	expected := &apply.State{
+		TrustDomains: []apply.TrustDomain{
+			{Name: "foo.test", Description: "Foo"},
+			{Name: "bar.test", PublishBundle: true, PublishOIDC: true, Labels: map[string]string{"env": "prod"}},
+			{Name: "baz.test", BundleEndpoint: &apply.BundleEndpoint{URL: "https://baz.test/bundle", Profile: "https_web"}},
+		},
+		Relationships: []apply.Relationship{
+			{TrustDomainA: "foo.test", TrustDomainB: "bar.test"},
+			{TrustDomainA: "baz.test", TrustDomainB: "foo.test", Direction: entity.RelationshipATrustsB},
+		},
+	}
+	assert.Equal(t, expected, state)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		state apply.State
+		err   string
+	}{
+		{
+			name:  "invalid_name",
+			state: apply.State{TrustDomains: []apply.TrustDomain{{Name: "Foo Test"}}},
+			err:   `invalid trust domain name "Foo Test": trust domain characters are limited to lowercase letters, numbers, dots, dashes, and underscores`,
+		},
+		{
+			name:  "duplicated_trust_domain",
+			state: apply.State{TrustDomains: []apply.TrustDomain{{Name: "foo.test"}, {Name: "foo.test"}}},
+			err:   `trust domain "foo.test" is declared more than once`,
+		},
+		{
+			name: "undeclared_trust_domain",
+			state: apply.State{
+				TrustDomains:  []apply.TrustDomain{{Name: "foo.test"}},
+				Relationships: []apply.Relationship{{TrustDomainA: "foo.test", TrustDomainB: "bar.test"}},
+			},
+			err: `relationship foo.test <-> bar.test references trust domain "bar.test", which is not declared`,
+		},
+		{
+			name: "self_relationship",
+			state: apply.State{
+				TrustDomains:  []apply.TrustDomain{{Name: "foo.test"}},
+				Relationships: []apply.Relationship{{TrustDomainA: "foo.test", TrustDomainB: "foo.test"}},
+			},
+			err: `trust domain "foo.test" cannot have a relationship with itself`,
+		},
+		{
+			name: "invalid_bundle_endpoint",
+			state: apply.State{TrustDomains: []apply.TrustDomain{
+				{Name: "foo.test", BundleEndpoint: &apply.BundleEndpoint{URL: "https://foo.test/bundle", Profile: "https_spiffe"}},
+			}},
+			err: `invalid bundle endpoint for trust domain "foo.test": the https_spiffe profile requires an endpoint SPIFFE ID`,
+		},
+		{
+			name: "duplicated_relationship",
+			state: apply.State{
+				TrustDomains: []apply.TrustDomain{{Name: "foo.test"}, {Name: "bar.test"}},
+				Relationships: []apply.Relationship{
+					{TrustDomainA: "foo.test", TrustDomainB: "bar.test"},
+					{TrustDomainA: "bar.test", TrustDomainB: "foo.test"},
+				},
+			},
+			err: `relationship bar.test <-> foo.test is declared more than once`,
+		},
+		{
+			name: "invalid_direction",
+			state: apply.State{
+				TrustDomains:  []apply.TrustDomain{{Name: "foo.test"}, {Name: "bar.test"}},
+				Relationships: []apply.Relationship{{TrustDomainA: "foo.test", TrustDomainB: "bar.test", Direction: "sideways"}},
+			},
+			err: `invalid relationship foo.test <-> bar.test: unknown relationship direction "sideways", must be one of "bidirectional", "a_trusts_b" or "b_trusts_a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.state.Validate(), tt.err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	// stored state: foo.test with an outdated description and publishing its bundle, qux.test in a relationship with foo.test
+	foo, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test"), Description: "Old", PublishBundle: true})
+	require.NoError(t, err)
+	qux, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("qux.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: foo.ID.UUID, TrustDomainBID: qux.ID.UUID})
+	require.NoError(t, err)
+
+	state, err := apply.ParseConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	expectedChanges := []apply.Change{
+		{Action: apply.ActionUpdate, Resource: apply.ResourceTrustDomain, Name: "foo.test", Detail: `description: "Old" -> "Foo", publish_bundle: true -> false`},
+		{Action: apply.ActionCreate, Resource: apply.ResourceTrustDomain, Name: "bar.test"},
+		{Action: apply.ActionCreate, Resource: apply.ResourceTrustDomain, Name: "baz.test"},
+		{Action: apply.ActionCreate, Resource: apply.ResourceRelationship, Name: "foo.test <-> bar.test"},
+		{Action: apply.ActionCreate, Resource: apply.ResourceRelationship, Name: "baz.test <-> foo.test"},
+		{Action: apply.ActionDelete, Resource: apply.ResourceRelationship, Name: "foo.test <-> qux.test"},
+		{Action: apply.ActionDelete, Resource: apply.ResourceTrustDomain, Name: "qux.test", Detail: "its bundle and join tokens are deleted as well"},
+	}
+
+	// a dry run only plans the changes
+	plan, err := apply.Apply(ctx, ds, state, true)
+	require.NoError(t, err)
+	assert.Equal(t, &apply.Plan{Changes: expectedChanges}, plan)
+
+	trustDomains, err := ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	assert.Len(t, trustDomains, 2)
+
+	plan, err = apply.Apply(ctx, ds, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, &apply.Plan{Changes: expectedChanges, Applied: true}, plan)
+
+	trustDomains, err = ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	require.Len(t, trustDomains, 3)
+	assert.Equal(t, "bar.test", trustDomains[0].Name.String())
+	assert.True(t, trustDomains[0].PublishBundle)
+	assert.True(t, trustDomains[0].PublishOIDC)
+	assert.Equal(t, map[string]string{"env": "prod"}, trustDomains[0].Labels)
+	assert.Equal(t, "baz.test", trustDomains[1].Name.String())
+	assert.Equal(t, "https://baz.test/bundle", trustDomains[1].BundleEndpointURL)
+	assert.Equal(t, "https_web", trustDomains[1].BundleEndpointProfile)
+	assert.Equal(t, "foo.test", trustDomains[2].Name.String())
+	assert.Equal(t, "Foo", trustDomains[2].Description)
+	assert.False(t, trustDomains[2].PublishBundle)
+
+	relationships, err := ds.ListRelationshipsWithTrustDomainNames(ctx)
+	require.NoError(t, err)
+	assert.Len(t, relationships, 2)
+
+	// the stored state matches the declared state
+	plan, err = apply.Apply(ctx, ds, state, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+
+	// declaring a relationship the other way around keeps its direction
+	state.Relationships[1] = apply.Relationship{TrustDomainA: "foo.test", TrustDomainB: "baz.test", Direction: entity.RelationshipBTrustsA}
+	plan, err = apply.Apply(ctx, ds, state, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+
+	state.Relationships[1].Direction = entity.RelationshipATrustsB
+	plan, err = apply.Apply(ctx, ds, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, []apply.Change{
+		{Action: apply.ActionUpdate, Resource: apply.ResourceRelationship, Name: "foo.test <-> baz.test", Detail: `direction: "a_trusts_b" -> "b_trusts_a"`},
+	}, plan.Changes)
+
+	relationships, err = ds.ListRelationshipsWithTrustDomainNames(ctx)
+	require.NoError(t, err)
+	for _, r := range relationships {
+		if r.TrustDomainAName.String() == "baz.test" {
+			assert.Equal(t, "foo.test trusts baz.test", r.DirectionString())
+		}
+	}
+
+	state.TrustDomains[1].Labels = map[string]string{"env": "dev", "team": "a"}
+	plan, err = apply.Apply(ctx, ds, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, []apply.Change{
+		{Action: apply.ActionUpdate, Resource: apply.ResourceTrustDomain, Name: "bar.test", Detail: `labels: "env=prod" -> "env=dev,team=a"`},
+	}, plan.Changes)
+}
+
+func TestApplyImportedTrustDomains(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	foo, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test")})
+	require.NoError(t, err)
+	imported, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("acme.test"), PeerID: peer.ID})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: foo.ID.UUID, TrustDomainBID: imported.ID.UUID})
+	require.NoError(t, err)
+
+	// the trust domains imported from peers and their relationships are not managed by the state
+	state := &apply.State{TrustDomains: []apply.TrustDomain{{Name: "foo.test"}}}
+	plan, err := apply.Apply(ctx, ds, state, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+
+	relationships, err := ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Len(t, relationships, 1)
+
+	state.TrustDomains = append(state.TrustDomains, apply.TrustDomain{Name: "acme.test"})
+	_, err = apply.Apply(ctx, ds, state, false)
+	assert.EqualError(t, err, `trust domain "acme.test" is imported from a peer and cannot be declared`)
+}
@@ -0,0 +1,140 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// The State is declared in HCL, e.g.:
+//
+//	trust_domain "foo.test" {
+//	  description    = "Foo"
+//	  publish_bundle = true
+//	  publish_oidc   = true
+//
+//	  labels = {
+//	    env = "prod"
+//	  }
+//	}
+//
+//	trust_domain "bar.test" {
+//	  bundle_endpoint {
+//	    url       = "https://bar.test/bundle"
+//	    profile   = "https_spiffe"
+//	    spiffe_id = "spiffe://bar.test/bundle-endpoint"
+//	  }
+//	}
+//
+//	relationship {
+//	  trust_domain_a = "foo.test"
+//	  trust_domain_b = "bar.test"
+//	  direction      = "a_trusts_b"
+//	}
+
+type trustDomainConfig struct {
+	Description    string                `hcl:"description"`
+	PublishBundle  bool                  `hcl:"publish_bundle"`
+	PublishOIDC    bool                  `hcl:"publish_oidc"`
+	Labels         map[string]string     `hcl:"labels"`
+	BundleEndpoint *bundleEndpointConfig `hcl:"bundle_endpoint"`
+}
+
+type bundleEndpointConfig struct {
+	URL      string `hcl:"url"`
+	Profile  string `hcl:"profile"`
+	SPIFFEID string `hcl:"spiffe_id"`
+}
+
+type relationshipConfig struct {
+	TrustDomainA string `hcl:"trust_domain_a"`
+	TrustDomainB string `hcl:"trust_domain_b"`
+	Direction    string `hcl:"direction"`
+}
+
+// ParseConfig reads the desired State from an HCL file.
+func ParseConfig(r io.Reader) (*State, error) {
+	if r == nil {
+		return nil, errors.New("configuration is required")
+	}
+
+	configBytes, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+
+	file, err := hcl.ParseBytes(configBytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode configuration: %v", err)
+	}
+
+	root, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, errors.New("unable to decode configuration: unexpected root node")
+	}
+
+	state := &State{
+		TrustDomains:  []TrustDomain{},
+		Relationships: []Relationship{},
+	}
+
+	// blocks are decoded one by one, as HCL merges the attributes of unlabeled blocks when decoding them into a slice
+	for _, item := range root.Items {
+		name := item.Keys[0].Token.Value()
+		switch name {
+		case "trust_domain":
+			if len(item.Keys) != 2 {
+				return nil, fmt.Errorf("%s: trust_domain block requires a name, e.g., trust_domain \"example.org\" {}", item.Pos())
+			}
+
+			var c trustDomainConfig
+			if err := hcl.DecodeObject(&c, item.Val); err != nil {
+				return nil, fmt.Errorf("unable to decode trust_domain block: %v", err)
+			}
+
+			td := TrustDomain{
+				Name:          fmt.Sprint(item.Keys[1].Token.Value()),
+				Description:   c.Description,
+				PublishBundle: c.PublishBundle,
+				PublishOIDC:   c.PublishOIDC,
+				Labels:        c.Labels,
+			}
+			if c.BundleEndpoint != nil {
+				td.BundleEndpoint = &BundleEndpoint{
+					URL:      c.BundleEndpoint.URL,
+					Profile:  c.BundleEndpoint.Profile,
+					SPIFFEID: c.BundleEndpoint.SPIFFEID,
+				}
+			}
+
+			state.TrustDomains = append(state.TrustDomains, td)
+		case "relationship":
+			if len(item.Keys) != 1 {
+				return nil, fmt.Errorf("%s: relationship block does not take a name", item.Pos())
+			}
+
+			var c relationshipConfig
+			if err := hcl.DecodeObject(&c, item.Val); err != nil {
+				return nil, fmt.Errorf("unable to decode relationship block: %v", err)
+			}
+
+			state.Relationships = append(state.Relationships, Relationship{
+				TrustDomainA: c.TrustDomainA,
+				TrustDomainB: c.TrustDomainB,
+				Direction:    entity.RelationshipDirection(c.Direction),
+			})
+		default:
+			return nil, fmt.Errorf("%s: unknown block %q", item.Pos(), name)
+		}
+	}
+
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
@@ -0,0 +1,557 @@
+// Package backup exports the state of a Galadriel Server to a versioned document, and imports it back
+// into any datastore backend.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// Version is the version of the document format written by Export.
+// It must be increased on any change that older versions of Import cannot read.
+const Version = 1
+
+// Document is a backup of the state of a Galadriel Server. Entities reference trust domains by name,
+// as IDs are specific to the datastore the document was exported from.
+type Document struct {
+	Version       int            `json:"version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	TrustDomains  []TrustDomain  `json:"trust_domains"`
+	Relationships []Relationship `json:"relationships"`
+	Bundles       []Bundle       `json:"bundles"`
+	JoinTokens    []JoinToken    `json:"join_tokens,omitempty"`
+}
+
+type TrustDomain struct {
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	HarvesterSpiffeID string `json:"harvester_spiffe_id,omitempty"`
+	OnboardingBundle  []byte `json:"onboarding_bundle,omitempty"`
+	PublishBundle     bool   `json:"publish_bundle,omitempty"`
+	PublishOIDC       bool   `json:"publish_oidc,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	BundleEndpointURL      string `json:"bundle_endpoint_url,omitempty"`
+	BundleEndpointProfile  string `json:"bundle_endpoint_profile,omitempty"`
+	BundleEndpointSPIFFEID string `json:"bundle_endpoint_spiffe_id,omitempty"`
+}
+
+type Relationship struct {
+	TrustDomainA        string `json:"trust_domain_a"`
+	TrustDomainB        string `json:"trust_domain_b"`
+	TrustDomainAConsent bool   `json:"trust_domain_a_consent"`
+	TrustDomainBConsent bool   `json:"trust_domain_b_consent"`
+
+	// Direction is empty in documents exported before relationships had a direction, which means bidirectional.
+	Direction entity.RelationshipDirection `json:"direction,omitempty"`
+
+	// NotBefore and NotAfter bound the validity of the relationship, if it is bounded.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+type Bundle struct {
+	TrustDomain        string `json:"trust_domain"`
+	Data               []byte `json:"data"`
+	Digest             []byte `json:"digest"`
+	Signature          []byte `json:"signature,omitempty"`
+	DigestAlgorithm    string `json:"digest_algorithm,omitempty"`
+	SignatureAlgorithm string `json:"signature_algorithm,omitempty"`
+	SigningCert        []byte `json:"signing_cert,omitempty"`
+}
+
+type JoinToken struct {
+	TrustDomain string    `json:"trust_domain"`
+	Token       string    `json:"token"`
+	Used        bool      `json:"used"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ExportOptions customizes the exported document.
+type ExportOptions struct {
+	// ExcludeJoinTokens leaves the join tokens out of the document, as they are credentials.
+	ExcludeJoinTokens bool
+}
+
+// ImportResult summarizes the changes made by Import.
+type ImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// Export reads the state stored in the datastore. The state is read within a transaction,
+// so the document is consistent. The trust domains imported from peers are not exported, along with their
+// relationships and bundles, as they are imported again from the peers.
+func Export(ctx context.Context, ds datastore.Datastore, opts ExportOptions) (*Document, error) {
+	doc := &Document{
+		Version:       Version,
+		ExportedAt:    time.Now().UTC(),
+		TrustDomains:  []TrustDomain{},
+		Relationships: []Relationship{},
+		Bundles:       []Bundle{},
+	}
+
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		trustDomains, err := tx.ListTrustDomains(ctx)
+		if err != nil {
+			return err
+		}
+
+		names := make(map[uuid.UUID]string, len(trustDomains))
+		imported := make(map[uuid.UUID]bool)
+		for _, td := range trustDomains {
+			if td.PeerID.Valid {
+				imported[td.ID.UUID] = true
+				continue
+			}
+			names[td.ID.UUID] = td.Name.String()
+
+			t := TrustDomain{
+				Name:             td.Name.String(),
+				Description:      td.Description,
+				OnboardingBundle: td.OnboardingBundle,
+				PublishBundle:    td.PublishBundle,
+				PublishOIDC:      td.PublishOIDC,
+				Labels:           td.Labels,
+
+				BundleEndpointURL:     td.BundleEndpointURL,
+				BundleEndpointProfile: td.BundleEndpointProfile,
+			}
+			if !td.HarvesterSpiffeID.IsZero() {
+				t.HarvesterSpiffeID = td.HarvesterSpiffeID.String()
+			}
+			if !td.BundleEndpointSPIFFEID.IsZero() {
+				t.BundleEndpointSPIFFEID = td.BundleEndpointSPIFFEID.String()
+			}
+			doc.TrustDomains = append(doc.TrustDomains, t)
+		}
+
+		relationships, err := tx.ListRelationships(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range relationships {
+			if imported[r.TrustDomainAID] || imported[r.TrustDomainBID] {
+				continue
+			}
+			doc.Relationships = append(doc.Relationships, Relationship{
+				TrustDomainA:        names[r.TrustDomainAID],
+				TrustDomainB:        names[r.TrustDomainBID],
+				TrustDomainAConsent: r.TrustDomainAConsent,
+				TrustDomainBConsent: r.TrustDomainBConsent,
+				Direction:           r.Direction.OrDefault(),
+				NotBefore:           timePtr(r.NotBefore),
+				NotAfter:            timePtr(r.NotAfter),
+			})
+		}
+
+		bundles, err := tx.ListBundles(ctx)
+		if err != nil {
+			return err
+		}
+		for _, b := range bundles {
+			if imported[b.TrustDomainID] {
+				continue
+			}
+			doc.Bundles = append(doc.Bundles, Bundle{
+				TrustDomain:        names[b.TrustDomainID],
+				Data:               b.Data,
+				Digest:             b.Digest,
+				Signature:          b.Signature,
+				DigestAlgorithm:    b.DigestAlgorithm,
+				SignatureAlgorithm: b.SignatureAlgorithm,
+				SigningCert:        b.SigningCert,
+			})
+		}
+
+		if opts.ExcludeJoinTokens {
+			return nil
+		}
+
+		joinTokens, err := tx.ListJoinTokens(ctx)
+		if err != nil {
+			return err
+		}
+		for _, jt := range joinTokens {
+			if imported[jt.TrustDomainID] {
+				continue
+			}
+			doc.JoinTokens = append(doc.JoinTokens, JoinToken{
+				TrustDomain: names[jt.TrustDomainID],
+				Token:       jt.Token,
+				Used:        jt.Used,
+				ExpiresAt:   jt.ExpiresAt.UTC(),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export state: %w", err)
+	}
+
+	return doc, nil
+}
+
+// Import restores the document into the datastore, within a single transaction.
+// Entities are matched by their natural keys: trust domains by name, relationships by the pair of trust domains,
+// bundles by trust domain, and join tokens by token. Existing entities are updated to match the document, and
+// entities that are not in the document are left untouched, so importing the same document again is a no-op.
+func Import(ctx context.Context, ds datastore.Datastore, doc *Document) (*ImportResult, error) {
+	if doc.Version != Version {
+		return nil, fmt.Errorf("unsupported backup version %d: expected version %d", doc.Version, Version)
+	}
+
+	var result *ImportResult
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		im := &importer{
+			tx:           tx,
+			result:       &ImportResult{},
+			trustDomains: make(map[string]*entity.TrustDomain),
+		}
+
+		if err := im.importTrustDomains(ctx, doc.TrustDomains); err != nil {
+			return err
+		}
+		if err := im.importRelationships(ctx, doc.Relationships); err != nil {
+			return err
+		}
+		if err := im.importBundles(ctx, doc.Bundles); err != nil {
+			return err
+		}
+		if err := im.importJoinTokens(ctx, doc.JoinTokens); err != nil {
+			return err
+		}
+
+		result = im.result
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import backup: %w", err)
+	}
+
+	return result, nil
+}
+
+type importer struct {
+	tx     datastore.Datastore
+	result *ImportResult
+
+	// trustDomains caches the trust domains by name
+	trustDomains map[string]*entity.TrustDomain
+}
+
+func (im *importer) importTrustDomains(ctx context.Context, trustDomains []TrustDomain) error {
+	for _, t := range trustDomains {
+		want, err := t.toEntity()
+		if err != nil {
+			return err
+		}
+		if err := bundles.ValidateEndpoint(want); err != nil {
+			return fmt.Errorf("invalid bundle endpoint for trust domain %q: %w", t.Name, err)
+		}
+
+		td, err := im.tx.FindTrustDomainByName(ctx, want.Name)
+		if err != nil {
+			return err
+		}
+
+		if td == nil {
+			td, err = im.tx.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: want.Name})
+			if err != nil {
+				return err
+			}
+			im.result.Created++
+		} else if !trustDomainMatches(td, want) {
+			im.result.Updated++
+		}
+
+		// the attributes can only be set through an update
+		if !trustDomainMatches(td, want) {
+			td.Description = want.Description
+			td.HarvesterSpiffeID = want.HarvesterSpiffeID
+			td.OnboardingBundle = want.OnboardingBundle
+			td.PublishBundle = want.PublishBundle
+			td.PublishOIDC = want.PublishOIDC
+			td.Labels = want.Labels
+			td.BundleEndpointURL = want.BundleEndpointURL
+			td.BundleEndpointProfile = want.BundleEndpointProfile
+			td.BundleEndpointSPIFFEID = want.BundleEndpointSPIFFEID
+
+			td, err = im.tx.CreateOrUpdateTrustDomain(ctx, td)
+			if err != nil {
+				return err
+			}
+		}
+
+		im.trustDomains[t.Name] = td
+	}
+
+	return nil
+}
+
+func (im *importer) importRelationships(ctx context.Context, relationships []Relationship) error {
+	for _, r := range relationships {
+		if err := r.Direction.Validate(); err != nil {
+			return fmt.Errorf("invalid relationship between %q and %q: %w", r.TrustDomainA, r.TrustDomainB, err)
+		}
+
+		tdA, err := im.findTrustDomain(ctx, r.TrustDomainA)
+		if err != nil {
+			return err
+		}
+		tdB, err := im.findTrustDomain(ctx, r.TrustDomainB)
+		if err != nil {
+			return err
+		}
+
+		existing, err := im.tx.FindRelationshipsByTrustDomainID(ctx, tdA.ID.UUID)
+		if err != nil {
+			return err
+		}
+
+		// the relationship might be stored with the trust domains in the opposite order
+		consentA, consentB := r.TrustDomainAConsent, r.TrustDomainBConsent
+		direction := r.Direction.OrDefault()
+		notBefore, notAfter := timeValue(r.NotBefore), timeValue(r.NotAfter)
+		var rel *entity.Relationship
+		for _, e := range existing {
+			if e.TrustDomainAID == tdA.ID.UUID && e.TrustDomainBID == tdB.ID.UUID {
+				rel = e
+				break
+			}
+			if e.TrustDomainAID == tdB.ID.UUID && e.TrustDomainBID == tdA.ID.UUID {
+				rel = e
+				consentA, consentB = consentB, consentA
+				direction = direction.Reverse()
+				break
+			}
+		}
+
+		if rel == nil {
+			rel, err = im.tx.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+				TrustDomainAID: tdA.ID.UUID,
+				TrustDomainBID: tdB.ID.UUID,
+				Direction:      direction,
+				NotBefore:      notBefore,
+				NotAfter:       notAfter,
+			})
+			if err != nil {
+				return err
+			}
+			im.result.Created++
+		} else if relationshipDiffers(rel, consentA, consentB, direction, notBefore, notAfter) {
+			im.result.Updated++
+		}
+
+		// consent can only be set through an update
+		if relationshipDiffers(rel, consentA, consentB, direction, notBefore, notAfter) {
+			if !rel.NotAfter.Equal(notAfter) {
+				// the relationship expires again at the end of its imported validity
+				rel.ExpiredAt = time.Time{}
+			}
+			rel.TrustDomainAConsent = consentA
+			rel.TrustDomainBConsent = consentB
+			rel.Direction = direction
+			rel.NotBefore = notBefore
+			rel.NotAfter = notAfter
+
+			if _, err := im.tx.CreateOrUpdateRelationship(ctx, rel); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func relationshipDiffers(rel *entity.Relationship, consentA, consentB bool, direction entity.RelationshipDirection, notBefore, notAfter time.Time) bool {
+	return rel.TrustDomainAConsent != consentA || rel.TrustDomainBConsent != consentB || rel.Direction.OrDefault() != direction ||
+		!rel.NotBefore.Equal(notBefore) || !rel.NotAfter.Equal(notAfter)
+}
+
+func (im *importer) importBundles(ctx context.Context, bundles []Bundle) error {
+	for _, b := range bundles {
+		td, err := im.findTrustDomain(ctx, b.TrustDomain)
+		if err != nil {
+			return err
+		}
+
+		bundle := &entity.Bundle{
+			Data:               b.Data,
+			Digest:             b.Digest,
+			Signature:          b.Signature,
+			DigestAlgorithm:    b.DigestAlgorithm,
+			SignatureAlgorithm: b.SignatureAlgorithm,
+			SigningCert:        b.SigningCert,
+			TrustDomainID:      td.ID.UUID,
+		}
+
+		current, err := im.tx.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case current == nil:
+			im.result.Created++
+		case equalBundles(current, bundle):
+			continue
+		default:
+			bundle.ID = current.ID
+			im.result.Updated++
+		}
+
+		if _, err := im.tx.CreateOrUpdateBundle(ctx, bundle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *importer) importJoinTokens(ctx context.Context, joinTokens []JoinToken) error {
+	for _, t := range joinTokens {
+		td, err := im.findTrustDomain(ctx, t.TrustDomain)
+		if err != nil {
+			return err
+		}
+
+		jt, err := im.tx.FindJoinToken(ctx, t.Token)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case jt == nil:
+			jt, err = im.tx.CreateJoinToken(ctx, &entity.JoinToken{
+				Token:         t.Token,
+				ExpiresAt:     t.ExpiresAt,
+				TrustDomainID: td.ID.UUID,
+			})
+			if err != nil {
+				return err
+			}
+			im.result.Created++
+		case jt.TrustDomainID != td.ID.UUID:
+			return fmt.Errorf("join token for trust domain %q is already bound to a different trust domain", t.TrustDomain)
+		case jt.Used != t.Used:
+			im.result.Updated++
+		}
+
+		if jt.Used != t.Used {
+			if _, err := im.tx.UpdateJoinToken(ctx, jt.ID.UUID, t.Used); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findTrustDomain returns a trust domain imported from the document, or that already exists in the datastore.
+func (im *importer) findTrustDomain(ctx context.Context, name string) (*entity.TrustDomain, error) {
+	if td, ok := im.trustDomains[name]; ok {
+		return td, nil
+	}
+
+	trustDomain, err := spiffeid.TrustDomainFromString(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust domain name %q: %w", name, err)
+	}
+
+	td, err := im.tx.FindTrustDomainByName(ctx, trustDomain)
+	if err != nil {
+		return nil, err
+	}
+	if td == nil {
+		return nil, fmt.Errorf("trust domain %q not found", name)
+	}
+
+	im.trustDomains[name] = td
+	return td, nil
+}
+
+// toEntity parses the trust domain attributes from the document.
+func (t *TrustDomain) toEntity() (*entity.TrustDomain, error) {
+	name, err := spiffeid.TrustDomainFromString(t.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust domain name %q: %w", t.Name, err)
+	}
+
+	td := &entity.TrustDomain{
+		Name:                  name,
+		Description:           t.Description,
+		OnboardingBundle:      t.OnboardingBundle,
+		PublishBundle:         t.PublishBundle,
+		PublishOIDC:           t.PublishOIDC,
+		Labels:                t.Labels,
+		BundleEndpointURL:     t.BundleEndpointURL,
+		BundleEndpointProfile: t.BundleEndpointProfile,
+	}
+
+	if err := entity.ValidateLabels(t.Labels); err != nil {
+		return nil, fmt.Errorf("invalid labels for trust domain %q: %w", t.Name, err)
+	}
+
+	if t.HarvesterSpiffeID != "" {
+		td.HarvesterSpiffeID, err = spiffeid.FromString(t.HarvesterSpiffeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid harvester SPIFFE ID for trust domain %q: %w", t.Name, err)
+		}
+	}
+
+	if t.BundleEndpointSPIFFEID != "" {
+		td.BundleEndpointSPIFFEID, err = spiffeid.FromString(t.BundleEndpointSPIFFEID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle endpoint SPIFFE ID for trust domain %q: %w", t.Name, err)
+		}
+	}
+
+	return td, nil
+}
+
+func trustDomainMatches(td, want *entity.TrustDomain) bool {
+	return td.Description == want.Description &&
+		td.HarvesterSpiffeID == want.HarvesterSpiffeID &&
+		bytes.Equal(td.OnboardingBundle, want.OnboardingBundle) &&
+		td.PublishBundle == want.PublishBundle &&
+		td.PublishOIDC == want.PublishOIDC &&
+		entity.FormatLabels(td.Labels) == entity.FormatLabels(want.Labels) &&
+		td.BundleEndpointURL == want.BundleEndpointURL &&
+		td.BundleEndpointProfile == want.BundleEndpointProfile &&
+		td.BundleEndpointSPIFFEID == want.BundleEndpointSPIFFEID
+}
+
+func equalBundles(a, b *entity.Bundle) bool {
+	return bytes.Equal(a.Data, b.Data) &&
+		bytes.Equal(a.Digest, b.Digest) &&
+		bytes.Equal(a.Signature, b.Signature) &&
+		a.DigestAlgorithm == b.DigestAlgorithm &&
+		a.SignatureAlgorithm == b.SignatureAlgorithm &&
+		bytes.Equal(a.SigningCert, b.SigningCert)
+}
+
+// timePtr returns nil for the zero time, so that it is omitted from the document.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
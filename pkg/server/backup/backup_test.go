@@ -0,0 +1,161 @@
+package backup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/backup"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	td1 = spiffeid.RequireTrustDomainFromString("foo.test")
+	td2 = spiffeid.RequireTrustDomainFromString("bar.test")
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := datastore.NewMemoryDatastore(logrus.New())
+	populate(ctx, t, source)
+
+	doc, err := backup.Export(ctx, source, backup.ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, backup.Version, doc.Version)
+	assert.Len(t, doc.TrustDomains, 2)
+	assert.Len(t, doc.Relationships, 1)
+	assert.Len(t, doc.Bundles, 1)
+	assert.Len(t, doc.JoinTokens, 1)
+
+	target := datastore.NewMemoryDatastore(logrus.New())
+
+	result, err := backup.Import(ctx, target, doc)
+	require.NoError(t, err)
+	assert.Equal(t, &backup.ImportResult{Created: 5}, result)
+
+	restored, err := backup.Export(ctx, target, backup.ExportOptions{})
+	require.NoError(t, err)
+	restored.ExportedAt = doc.ExportedAt
+	assert.Equal(t, doc, restored)
+
+	// importing the same document again is a no-op
+	result, err = backup.Import(ctx, target, doc)
+	require.NoError(t, err)
+	assert.Equal(t, &backup.ImportResult{}, result)
+
+	// existing entities are updated to match the document
+	doc.TrustDomains[0].Description = "updated"
+	doc.Relationships[0].TrustDomainBConsent = false
+	doc.Relationships[0].Direction = entity.RelationshipBidirectional
+	doc.Bundles[0].Data = []byte("updated")
+
+	result, err = backup.Import(ctx, target, doc)
+	require.NoError(t, err)
+	assert.Equal(t, &backup.ImportResult{Updated: 3}, result)
+
+	restored, err = backup.Export(ctx, target, backup.ExportOptions{})
+	require.NoError(t, err)
+	restored.ExportedAt = doc.ExportedAt
+	assert.Equal(t, doc, restored)
+}
+
+func TestExportExcludeJoinTokens(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	populate(ctx, t, ds)
+
+	doc, err := backup.Export(ctx, ds, backup.ExportOptions{ExcludeJoinTokens: true})
+	require.NoError(t, err)
+	assert.Empty(t, doc.JoinTokens)
+}
+
+func TestExportExcludesImportedTrustDomains(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	populate(ctx, t, ds)
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	imported, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("acme.test"), PeerID: peer.ID})
+	require.NoError(t, err)
+	local, err := ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: local.ID.UUID, TrustDomainBID: imported.ID.UUID})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte("acme"), Digest: []byte("digest"), TrustDomainID: imported.ID.UUID})
+	require.NoError(t, err)
+
+	// the imported trust domain, its relationship and its bundle are imported again from the peer
+	doc, err := backup.Export(ctx, ds, backup.ExportOptions{})
+	require.NoError(t, err)
+	assert.Len(t, doc.TrustDomains, 2)
+	assert.Len(t, doc.Relationships, 1)
+	assert.Len(t, doc.Bundles, 1)
+}
+
+func TestImportErrors(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	_, err := backup.Import(ctx, ds, &backup.Document{Version: backup.Version + 1})
+	require.EqualError(t, err, "unsupported backup version 2: expected version 1")
+
+	// nothing is imported if any entity fails
+	_, err = backup.Import(ctx, ds, &backup.Document{
+		Version:      backup.Version,
+		TrustDomains: []backup.TrustDomain{{Name: td1.String()}},
+		Bundles:      []backup.Bundle{{TrustDomain: td2.String(), Data: []byte("data")}},
+	})
+	require.EqualError(t, err, `failed to import backup: trust domain "bar.test" not found`)
+
+	trustDomains, err := ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, trustDomains)
+}
+
+func populate(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+
+	tdA.Description = "trust domain A"
+	tdA.HarvesterSpiffeID = spiffeid.RequireFromString("spiffe://foo.test/harvester")
+	tdA.PublishBundle = true
+	tdA.PublishOIDC = true
+	tdA.Labels = map[string]string{"env": "prod"}
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, tdA)
+	require.NoError(t, err)
+
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td2})
+	require.NoError(t, err)
+
+	tdB.BundleEndpointURL = "https://bar.test/bundle"
+	tdB.BundleEndpointProfile = "https_spiffe"
+	tdB.BundleEndpointSPIFFEID = spiffeid.RequireFromString("spiffe://bar.test/bundle-endpoint")
+	tdB, err = ds.CreateOrUpdateTrustDomain(ctx, tdB)
+	require.NoError(t, err)
+
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	rel.TrustDomainAConsent = true
+	rel.TrustDomainBConsent = true
+	rel.Direction = entity.RelationshipBTrustsA
+	rel.NotAfter = time.Now().Add(time.Hour).Truncate(time.Second)
+	_, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte("data"), Digest: []byte("digest"), TrustDomainID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	_, err = ds.CreateJoinToken(ctx, &entity.JoinToken{
+		Token:         "token",
+		ExpiresAt:     time.Now().Add(time.Hour).Truncate(time.Second),
+		TrustDomainID: tdA.ID.UUID,
+	})
+	require.NoError(t, err)
+}
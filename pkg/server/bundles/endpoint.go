@@ -0,0 +1,51 @@
+package bundles
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+)
+
+// SPIFFE bundle endpoint profiles.
+const (
+	// ProfileHTTPSWeb authenticates the bundle endpoint with a Web PKI certificate.
+	ProfileHTTPSWeb = "https_web"
+
+	// ProfileHTTPSSPIFFE authenticates the bundle endpoint with an X509-SVID for the endpoint SPIFFE ID.
+	ProfileHTTPSSPIFFE = "https_spiffe"
+)
+
+// ValidateEndpoint checks the bundle endpoint of a trust domain. A trust domain without a bundle endpoint URL is valid.
+func ValidateEndpoint(td *entity.TrustDomain) error {
+	if td.BundleEndpointURL == "" {
+		if td.BundleEndpointProfile != "" || !td.BundleEndpointSPIFFEID.IsZero() {
+			return errors.New("bundle endpoint profile and SPIFFE ID require a bundle endpoint URL")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(td.BundleEndpointURL)
+	if err != nil {
+		return fmt.Errorf("invalid bundle endpoint URL: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid bundle endpoint URL %q: an https URL is required", td.BundleEndpointURL)
+	}
+
+	switch td.BundleEndpointProfile {
+	case ProfileHTTPSWeb:
+		if !td.BundleEndpointSPIFFEID.IsZero() {
+			return fmt.Errorf("the %s profile does not take an endpoint SPIFFE ID", ProfileHTTPSWeb)
+		}
+	case ProfileHTTPSSPIFFE:
+		if td.BundleEndpointSPIFFEID.IsZero() {
+			return fmt.Errorf("the %s profile requires an endpoint SPIFFE ID", ProfileHTTPSSPIFFE)
+		}
+	default:
+		return fmt.Errorf("unknown bundle endpoint profile %q: expected %q or %q", td.BundleEndpointProfile, ProfileHTTPSWeb, ProfileHTTPSSPIFFE)
+	}
+
+	return nil
+}
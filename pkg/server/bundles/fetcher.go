@@ -0,0 +1,205 @@
+package bundles
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/federation"
+)
+
+const (
+	// pollInterval is how often the trust domains are checked for bundles due to be fetched.
+	pollInterval = 10 * time.Second
+
+	// defaultRefreshHint is used when a fetched bundle has no refresh hint.
+	defaultRefreshHint = 5 * time.Minute
+
+	// minRefreshHint bounds the refresh hint of the fetched bundles, so that an endpoint cannot make the server poll it continuously.
+	minRefreshHint = 30 * time.Second
+
+	// retryInterval is how long to wait before fetching a bundle again after a failure.
+	retryInterval = time.Minute
+
+	fetchTimeout = 30 * time.Second
+)
+
+// FetcherConfig conveys the configuration of a Fetcher.
+type FetcherConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	// WebPKIRoots authenticates the endpoints using the https_web profile. If not set, the system roots are used.
+	WebPKIRoots *x509.CertPool
+
+	// Policy validates the fetched bundles. If not set, the bundles are not validated.
+	Policy *Policy
+}
+
+// Fetcher fetches the bundles of the trust domains that have a SPIFFE bundle endpoint, on the refresh hint
+// of each bundle, and stores them the same way the bundles posted by harvesters are stored.
+type Fetcher struct {
+	ds          datastore.Datastore
+	logger      logrus.FieldLogger
+	webPKIRoots *x509.CertPool
+	policy      *Policy
+
+	// next is when the bundle of each trust domain is due to be fetched
+	next map[uuid.UUID]time.Time
+}
+
+func NewFetcher(c *FetcherConfig) *Fetcher {
+	return &Fetcher{
+		ds:          c.Datastore,
+		logger:      c.Logger,
+		webPKIRoots: c.WebPKIRoots,
+		policy:      c.Policy,
+		next:        make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Run fetches the bundles until the context is canceled.
+func (f *Fetcher) Run(ctx context.Context) error {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		f.fetchDue(ctx, time.Now())
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// fetchDue fetches the bundles that are due at the given time.
+func (f *Fetcher) fetchDue(ctx context.Context, now time.Time) {
+	trustDomains, err := f.ds.ListTrustDomains(ctx)
+	if err != nil {
+		f.logger.WithError(err).Error("Failed to list trust domains")
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, td := range trustDomains {
+		if td.BundleEndpointURL == "" {
+			continue
+		}
+		seen[td.ID.UUID] = true
+
+		if next, ok := f.next[td.ID.UUID]; ok && now.Before(next) {
+			continue
+		}
+
+		refreshHint, err := f.fetch(ctx, td)
+		if err != nil {
+			f.logger.WithError(err).Warnf("Failed to fetch bundle of trust domain %q from %s", td.Name, td.BundleEndpointURL)
+			f.next[td.ID.UUID] = now.Add(retryInterval)
+			continue
+		}
+		f.next[td.ID.UUID] = now.Add(refreshHint)
+	}
+
+	// forget the trust domains that were deleted or no longer have an endpoint
+	for id := range f.next {
+		if !seen[id] {
+			delete(f.next, id)
+		}
+	}
+}
+
+// fetch fetches and stores the bundle of a trust domain, returning when it should be fetched again.
+func (f *Fetcher) fetch(ctx context.Context, td *entity.TrustDomain) (time.Duration, error) {
+	if err := ValidateEndpoint(td); err != nil {
+		return 0, err
+	}
+
+	var opts []federation.FetchOption
+	switch td.BundleEndpointProfile {
+	case ProfileHTTPSWeb:
+		if f.webPKIRoots != nil {
+			opts = append(opts, federation.WithWebPKIRoots(f.webPKIRoots))
+		}
+	case ProfileHTTPSSPIFFE:
+		trustAnchor, err := f.trustAnchor(ctx, td)
+		if err != nil {
+			return 0, err
+		}
+		opts = append(opts, federation.WithSPIFFEAuth(trustAnchor, td.BundleEndpointSPIFFEID))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	bundle, err := federation.FetchBundle(ctx, td.Name, td.BundleEndpointURL, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := bundle.Marshal()
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	outcome, err := Ingest(ctx, f.ds, f.policy, td, data)
+	if err != nil {
+		return 0, err
+	}
+	switch outcome {
+	case Stored:
+		f.logger.Infof("Bundle of trust domain %q fetched from %s", td.Name, td.BundleEndpointURL)
+	case Quarantined:
+		f.logger.Warnf("Bundle of trust domain %q fetched from %s breaks the continuity with the stored bundle and was quarantined", td.Name, td.BundleEndpointURL)
+	}
+
+	refreshHint, ok := bundle.RefreshHint()
+	if !ok {
+		refreshHint = defaultRefreshHint
+	}
+	if refreshHint < minRefreshHint {
+		refreshHint = minRefreshHint
+	}
+
+	return refreshHint, nil
+}
+
+// trustAnchor returns the bundle that authenticates an endpoint using the https_spiffe profile: the stored
+// bundle of the trust domain of the endpoint SPIFFE ID. When the endpoint belongs to the trust domain whose
+// bundle it serves and no bundle is stored yet, the onboarding bundle of the trust domain is used instead.
+func (f *Fetcher) trustAnchor(ctx context.Context, td *entity.TrustDomain) (*spiffebundle.Bundle, error) {
+	endpointTD := td.BundleEndpointSPIFFEID.TrustDomain()
+
+	owner := td
+	if endpointTD != td.Name {
+		var err error
+		owner, err = f.ds.FindTrustDomainByName(ctx, endpointTD)
+		if err != nil {
+			return nil, err
+		}
+		if owner == nil {
+			return nil, fmt.Errorf("trust domain %q of the endpoint SPIFFE ID is not managed by the server", endpointTD)
+		}
+	}
+
+	stored, err := f.ds.FindBundleByTrustDomainID(ctx, owner.ID.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case stored != nil:
+		return spiffebundle.Parse(endpointTD, stored.Data)
+	case owner == td && len(td.OnboardingBundle) > 0:
+		return spiffebundle.Parse(endpointTD, td.OnboardingBundle)
+	default:
+		return nil, fmt.Errorf("no bundle to authenticate the endpoint with: trust domain %q has no bundle", endpointTD)
+	}
+}
@@ -0,0 +1,142 @@
+package bundles
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetcher(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	bundle := certtest.CreateBundle(t, td1)
+	bundle.SetRefreshHint(time.Hour)
+	data := certtest.MarshalBundle(t, bundle)
+
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+	td.BundleEndpointURL = server.URL
+	td.BundleEndpointProfile = ProfileHTTPSWeb
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, td)
+	require.NoError(t, err)
+
+	// trust domains without a bundle endpoint are left alone
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("bar.test")})
+	require.NoError(t, err)
+
+	f := NewFetcher(&FetcherConfig{Datastore: ds, Logger: logrus.New(), WebPKIRoots: roots})
+
+	now := time.Now()
+	f.fetchDue(ctx, now)
+	assert.Equal(t, 1, requests)
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, data, stored.Data)
+
+	// the bundle is fetched again on its refresh hint
+	f.fetchDue(ctx, now.Add(time.Minute))
+	assert.Equal(t, 1, requests)
+
+	f.fetchDue(ctx, now.Add(time.Hour))
+	assert.Equal(t, 2, requests)
+
+	// the trust domain is forgotten once its endpoint is removed
+	td.BundleEndpointURL = ""
+	td.BundleEndpointProfile = ""
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, td)
+	require.NoError(t, err)
+
+	f.fetchDue(ctx, now.Add(2*time.Hour))
+	assert.Equal(t, 2, requests)
+	assert.Empty(t, f.next)
+}
+
+func TestFetcherRetry(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+	td.BundleEndpointURL = server.URL
+	td.BundleEndpointProfile = ProfileHTTPSWeb
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, td)
+	require.NoError(t, err)
+
+	f := NewFetcher(&FetcherConfig{Datastore: ds, Logger: logrus.New(), WebPKIRoots: roots})
+
+	now := time.Now()
+	f.fetchDue(ctx, now)
+	assert.Equal(t, now.Add(retryInterval), f.next[td.ID.UUID])
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestFetcherTrustAnchor(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	f := NewFetcher(&FetcherConfig{Datastore: ds, Logger: logrus.New()})
+
+	onboarding := certtest.CreateBundle(t, td1)
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+	td.BundleEndpointURL = "https://foo.test/bundle"
+	td.BundleEndpointProfile = ProfileHTTPSSPIFFE
+	td.BundleEndpointSPIFFEID = spiffeid.RequireFromString("spiffe://foo.test/bundle-endpoint")
+
+	_, err = f.trustAnchor(ctx, td)
+	require.EqualError(t, err, `no bundle to authenticate the endpoint with: trust domain "foo.test" has no bundle`)
+
+	// the onboarding bundle is used until a bundle is stored
+	td.OnboardingBundle = certtest.MarshalBundle(t, onboarding)
+	anchor, err := f.trustAnchor(ctx, td)
+	require.NoError(t, err)
+	assert.True(t, anchor.Equal(onboarding))
+
+	current := certtest.CreateBundle(t, td1)
+	_, err = Ingest(ctx, ds, nil, td, certtest.MarshalBundle(t, current))
+	require.NoError(t, err)
+
+	anchor, err = f.trustAnchor(ctx, td)
+	require.NoError(t, err)
+	assert.True(t, anchor.Equal(current))
+
+	// an endpoint of another trust domain is authenticated with the bundle of that trust domain
+	td.BundleEndpointSPIFFEID = spiffeid.RequireFromString("spiffe://bar.test/bundle-endpoint")
+	_, err = f.trustAnchor(ctx, td)
+	require.EqualError(t, err, `trust domain "bar.test" of the endpoint SPIFFE ID is not managed by the server`)
+}
@@ -0,0 +1,199 @@
+// Package bundles stores the SPIFFE bundles of the trust domains, whether they are posted by a harvester
+// or fetched by the server from a SPIFFE bundle endpoint.
+package bundles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+)
+
+// Outcome is the result of the ingestion of a bundle.
+type Outcome int
+
+const (
+	// Unchanged means the bundle is the stored or the quarantined bundle of the trust domain.
+	Unchanged Outcome = iota
+
+	// Stored means the bundle was stored.
+	Stored
+
+	// Quarantined means the bundle breaks the continuity with the stored bundle and was quarantined.
+	Quarantined
+)
+
+// Ingest validates that data is a SPIFFE bundle of the trust domain and stores it, unless the stored bundle
+// has the same canonical digest.
+// A bundle that breaks the policy is not stored, the rejection is recorded and a *PolicyError is returned.
+// A bundle update that breaks the continuity with the stored bundle is quarantined instead of stored, replacing
+// any bundle already quarantined for the trust domain, and a *PolicyError is returned if an admin rejected it.
+// No policy is enforced if policy is nil.
+func Ingest(ctx context.Context, ds datastore.Datastore, policy *Policy, td *entity.TrustDomain, data []byte) (Outcome, error) {
+	bundle, err := spiffebundle.Parse(td.Name, data)
+	if err != nil {
+		return Unchanged, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	digest, err := util.GetBundleDigest(bundle, util.DigestAlgorithmCanonical)
+	if err != nil {
+		return Unchanged, err
+	}
+
+	now := time.Now()
+	if policy != nil {
+		if violations := policy.Check(bundle, now); len(violations) > 0 {
+			if err := recordRejection(ctx, ds, td, digest, violations); err != nil {
+				return Unchanged, err
+			}
+			return Unchanged, &PolicyError{Violations: violations}
+		}
+	}
+
+	outcome := Unchanged
+	err = ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		current, err := tx.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+		if err != nil {
+			return err
+		}
+
+		quarantined, err := tx.FindQuarantinedBundleByTrustDomainID(ctx, td.ID.UUID)
+		if err != nil {
+			return err
+		}
+
+		// bundles stored with another digest algorithm are written again to store their canonical digest
+		if current != nil && current.DigestAlgorithm == util.DigestAlgorithmCanonical && bytes.Equal(current.Digest, digest) {
+			// the trust domain went back to its stored bundle, so the quarantined update is obsolete
+			if quarantined != nil {
+				return tx.DeleteQuarantinedBundle(ctx, quarantined.ID.UUID)
+			}
+			return nil
+		}
+
+		if quarantined != nil && bytes.Equal(quarantined.Digest, digest) {
+			return nil
+		}
+
+		if policy != nil && current != nil {
+			reasons, err := checkRotation(policy, td, current, bundle)
+			if err != nil {
+				return err
+			}
+			if len(reasons) > 0 {
+				if err := quarantine(ctx, tx, td, quarantined, data, digest, reasons, now.Add(policy.QuarantineDelay())); err != nil {
+					return err
+				}
+				outcome = Quarantined
+				return nil
+			}
+		}
+
+		if err := store(ctx, tx, td.ID.UUID, current, data, digest); err != nil {
+			return err
+		}
+		if quarantined != nil {
+			if err := tx.DeleteQuarantinedBundle(ctx, quarantined.ID.UUID); err != nil {
+				return fmt.Errorf("failed to delete quarantined bundle: %w", err)
+			}
+		}
+
+		outcome = Stored
+		return nil
+	})
+	if err != nil {
+		return Unchanged, err
+	}
+
+	return outcome, nil
+}
+
+// checkRotation returns the reasons to quarantine the update of the stored bundle of a trust domain.
+func checkRotation(policy *Policy, td *entity.TrustDomain, current *entity.Bundle, next *spiffebundle.Bundle) ([]entity.BundleViolation, error) {
+	currentBundle, err := spiffebundle.Parse(td.Name, current.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored bundle: %w", err)
+	}
+
+	return policy.CheckRotation(currentBundle, next), nil
+}
+
+// quarantine holds back a bundle update until releaseAt, unless it is a bundle an admin already rejected.
+func quarantine(ctx context.Context, tx datastore.Datastore, td *entity.TrustDomain, quarantined *entity.QuarantinedBundle, data, digest []byte, reasons []entity.BundleViolation, releaseAt time.Time) error {
+	rejections, err := tx.FindBundleRejectionsByTrustDomainID(ctx, td.ID.UUID)
+	if err != nil {
+		return err
+	}
+	if len(rejections) > 0 && bytes.Equal(rejections[0].Digest, digest) {
+		return &PolicyError{Violations: rejections[0].Violations}
+	}
+
+	qb := &entity.QuarantinedBundle{
+		TrustDomainID:   td.ID.UUID,
+		Data:            data,
+		Digest:          digest,
+		DigestAlgorithm: util.DigestAlgorithmCanonical,
+		Reasons:         reasons,
+		ReleaseAt:       releaseAt,
+	}
+	if quarantined != nil {
+		qb.ID = quarantined.ID
+	}
+
+	if _, err := tx.CreateOrUpdateQuarantinedBundle(ctx, qb); err != nil {
+		return fmt.Errorf("failed to quarantine bundle: %w", err)
+	}
+
+	return nil
+}
+
+// store writes the bundle of a trust domain, replacing the current one if any.
+func store(ctx context.Context, tx datastore.Datastore, trustDomainID uuid.UUID, current *entity.Bundle, data, digest []byte) error {
+	b := &entity.Bundle{
+		Data:            data,
+		Digest:          digest,
+		DigestAlgorithm: util.DigestAlgorithmCanonical,
+		TrustDomainID:   trustDomainID,
+	}
+	if current != nil {
+		b.ID = current.ID
+	}
+
+	if _, err := tx.CreateOrUpdateBundle(ctx, b); err != nil {
+		return fmt.Errorf("failed to store bundle: %w", err)
+	}
+
+	return nil
+}
+
+// recordRejection records the rejection of a bundle, unless it is the same bundle the last rejection of the
+// trust domain was recorded for, as harvesters post a rejected bundle again until it changes.
+func recordRejection(ctx context.Context, ds datastore.Datastore, td *entity.TrustDomain, digest []byte, violations []entity.BundleViolation) error {
+	return ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		rejections, err := tx.FindBundleRejectionsByTrustDomainID(ctx, td.ID.UUID)
+		if err != nil {
+			return err
+		}
+
+		if len(rejections) > 0 && bytes.Equal(rejections[0].Digest, digest) {
+			return nil
+		}
+
+		_, err = tx.CreateBundleRejection(ctx, &entity.BundleRejection{
+			TrustDomainID: td.ID.UUID,
+			Digest:        digest,
+			Violations:    violations,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record bundle rejection: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,284 @@
+package bundles
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var td1 = spiffeid.RequireTrustDomainFromString("foo.test")
+
+func TestIngest(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+
+	data := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1))
+
+	outcome, err := Ingest(ctx, ds, nil, td, data)
+	require.NoError(t, err)
+	assert.Equal(t, Stored, outcome)
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, data, stored.Data)
+
+	// the same bundle is not written again
+	outcome, err = Ingest(ctx, ds, nil, td, data)
+	require.NoError(t, err)
+	assert.Equal(t, Unchanged, outcome)
+
+	rotated := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1))
+	outcome, err = Ingest(ctx, ds, nil, td, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, Stored, outcome)
+
+	updatedBundle, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, stored.ID, updatedBundle.ID)
+	assert.Equal(t, rotated, updatedBundle.Data)
+
+	// a bundle stored with the X.509 digest of older servers is written again with its canonical digest
+	updatedBundle.Digest = []byte("x509 digest")
+	updatedBundle.DigestAlgorithm = ""
+	_, err = ds.CreateOrUpdateBundle(ctx, updatedBundle)
+	require.NoError(t, err)
+
+	outcome, err = Ingest(ctx, ds, nil, td, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, Stored, outcome)
+
+	updatedBundle, err = ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, util.DigestAlgorithmCanonical, updatedBundle.DigestAlgorithm)
+
+	_, err = Ingest(ctx, ds, nil, td, []byte("not a bundle"))
+	assert.ErrorContains(t, err, "failed to parse bundle")
+}
+
+func TestIngestPolicy(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	policy := DefaultPolicy()
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+
+	notAfter := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1, certtest.WithValidity(notAfter.Add(-time.Hour), notAfter)))
+
+	_, err = Ingest(ctx, ds, policy, td, expired)
+	var policyErr *PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []entity.BundleViolation{{Code: ViolationExpired, Message: `X.509 authority "CN=CA" expired at 2020-01-01T00:00:00Z`}}, policyErr.Violations)
+	assert.EqualError(t, err, `bundle rejected by the validation policy: X.509 authority "CN=CA" expired at 2020-01-01T00:00:00Z`)
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	rejections, err := ds.FindBundleRejectionsByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	require.Len(t, rejections, 1)
+	assert.Equal(t, policyErr.Violations, rejections[0].Violations)
+
+	// the same bundle posted again is rejected without being recorded again
+	_, err = Ingest(ctx, ds, policy, td, expired)
+	require.ErrorAs(t, err, &policyErr)
+
+	rejections, err = ds.FindBundleRejectionsByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Len(t, rejections, 1)
+
+	outcome, err := Ingest(ctx, ds, policy, td, certtest.MarshalBundle(t, certtest.CreateBundle(t, td1)))
+	require.NoError(t, err)
+	assert.Equal(t, Stored, outcome)
+}
+
+func TestIngestQuarantine(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	policy := DefaultPolicy()
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+
+	ca1, ca2, ca3 := certtest.CreateCA(t), certtest.CreateCA(t), certtest.CreateCA(t)
+	bundle1 := certtest.MarshalBundle(t, spiffebundle.FromX509Authorities(td1, []*x509.Certificate{ca1}))
+	bundle2 := certtest.MarshalBundle(t, spiffebundle.FromX509Authorities(td1, []*x509.Certificate{ca1, ca2}))
+	bundle3 := certtest.MarshalBundle(t, spiffebundle.FromX509Authorities(td1, []*x509.Certificate{ca3}))
+
+	// the first bundle of a trust domain is never quarantined
+	outcome, err := Ingest(ctx, ds, policy, td, bundle1)
+	require.NoError(t, err)
+	assert.Equal(t, Stored, outcome)
+
+	// a rotation that keeps the current authority is stored
+	outcome, err = Ingest(ctx, ds, policy, td, bundle2)
+	require.NoError(t, err)
+	assert.Equal(t, Stored, outcome)
+
+	// a bundle that keeps none of the current authorities is quarantined
+	outcome, err = Ingest(ctx, ds, policy, td, bundle3)
+	require.NoError(t, err)
+	assert.Equal(t, Quarantined, outcome)
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, bundle2, stored.Data)
+
+	qb, err := ds.FindQuarantinedBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	require.NotNil(t, qb)
+	assert.Equal(t, bundle3, qb.Data)
+	assert.Equal(t, []entity.BundleViolation{{
+		Code:    ViolationNoSharedAuthority,
+		Message: "the bundle keeps none of the X.509 authorities of the stored bundle",
+	}}, qb.Reasons)
+	assert.WithinDuration(t, time.Now().Add(DefaultQuarantineDelay), qb.ReleaseAt, time.Minute)
+
+	// the quarantined bundle posted again is left as is
+	outcome, err = Ingest(ctx, ds, policy, td, bundle3)
+	require.NoError(t, err)
+	assert.Equal(t, Unchanged, outcome)
+
+	// a rejected bundle is rejected when it is posted again
+	require.NoError(t, Reject(ctx, ds, td.ID.UUID))
+
+	_, err = Ingest(ctx, ds, policy, td, bundle3)
+	var policyErr *PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, qb.Reasons, policyErr.Violations)
+
+	qb, err = ds.FindQuarantinedBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, qb)
+
+	// going back to the stored bundle discards the quarantined update
+	bundle4 := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1))
+	outcome, err = Ingest(ctx, ds, policy, td, bundle4)
+	require.NoError(t, err)
+	assert.Equal(t, Quarantined, outcome)
+
+	outcome, err = Ingest(ctx, ds, policy, td, bundle2)
+	require.NoError(t, err)
+	assert.Equal(t, Unchanged, outcome)
+
+	qb, err = ds.FindQuarantinedBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, qb)
+
+	// an approved bundle is stored
+	outcome, err = Ingest(ctx, ds, policy, td, bundle4)
+	require.NoError(t, err)
+	assert.Equal(t, Quarantined, outcome)
+
+	require.NoError(t, Approve(ctx, ds, td.ID.UUID))
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, bundle4, stored.Data)
+
+	assert.ErrorIs(t, Approve(ctx, ds, td.ID.UUID), ErrNotQuarantined)
+	assert.ErrorIs(t, Reject(ctx, ds, td.ID.UUID), ErrNotQuarantined)
+}
+
+func TestReleaser(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	policy := DefaultPolicy()
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+
+	_, err = Ingest(ctx, ds, policy, td, certtest.MarshalBundle(t, certtest.CreateBundle(t, td1)))
+	require.NoError(t, err)
+
+	next := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1))
+	outcome, err := Ingest(ctx, ds, policy, td, next)
+	require.NoError(t, err)
+	require.Equal(t, Quarantined, outcome)
+
+	r := NewReleaser(&ReleaserConfig{Datastore: ds, Logger: logrus.New()})
+
+	// the bundle is held back until its release
+	r.releaseDue(ctx, time.Now())
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.NotEqual(t, next, stored.Data)
+
+	r.releaseDue(ctx, time.Now().Add(DefaultQuarantineDelay+time.Minute))
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, next, stored.Data)
+
+	qb, err := ds.FindQuarantinedBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, qb)
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	endpointID := spiffeid.RequireFromString("spiffe://foo.test/bundle-endpoint")
+
+	tests := []struct {
+		name string
+		td   entity.TrustDomain
+		err  string
+	}{
+		{name: "no_endpoint"},
+		{name: "https_web", td: entity.TrustDomain{BundleEndpointURL: "https://foo.test/bundle", BundleEndpointProfile: ProfileHTTPSWeb}},
+		{name: "https_spiffe", td: entity.TrustDomain{BundleEndpointURL: "https://foo.test/bundle", BundleEndpointProfile: ProfileHTTPSSPIFFE, BundleEndpointSPIFFEID: endpointID}},
+		{
+			name: "missing_url",
+			td:   entity.TrustDomain{BundleEndpointProfile: ProfileHTTPSWeb},
+			err:  "bundle endpoint profile and SPIFFE ID require a bundle endpoint URL",
+		},
+		{
+			name: "http_url",
+			td:   entity.TrustDomain{BundleEndpointURL: "http://foo.test/bundle", BundleEndpointProfile: ProfileHTTPSWeb},
+			err:  `invalid bundle endpoint URL "http://foo.test/bundle": an https URL is required`,
+		},
+		{
+			name: "unknown_profile",
+			td:   entity.TrustDomain{BundleEndpointURL: "https://foo.test/bundle"},
+			err:  `unknown bundle endpoint profile "": expected "https_web" or "https_spiffe"`,
+		},
+		{
+			name: "https_web_with_spiffe_id",
+			td:   entity.TrustDomain{BundleEndpointURL: "https://foo.test/bundle", BundleEndpointProfile: ProfileHTTPSWeb, BundleEndpointSPIFFEID: endpointID},
+			err:  "the https_web profile does not take an endpoint SPIFFE ID",
+		},
+		{
+			name: "https_spiffe_without_spiffe_id",
+			td:   entity.TrustDomain{BundleEndpointURL: "https://foo.test/bundle", BundleEndpointProfile: ProfileHTTPSSPIFFE},
+			err:  "the https_spiffe profile requires an endpoint SPIFFE ID",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEndpoint(&tt.td)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
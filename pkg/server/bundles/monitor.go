@@ -0,0 +1,288 @@
+package bundles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/webhooks"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+)
+
+// Defaults of the warning thresholds of the bundle monitor.
+const (
+	DefaultExpiryWarning    = 7 * 24 * time.Hour
+	DefaultStalenessWarning = 48 * time.Hour
+)
+
+// monitorInterval is how often the bundles are checked by the monitor.
+const monitorInterval = time.Minute
+
+// MonitorConfig conveys the configuration of a Monitor.
+type MonitorConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	// ExpiryWarning is how long before the earliest expiry of its X.509 authorities a bundle is reported as
+	// expiring. If not set, DefaultExpiryWarning is used.
+	ExpiryWarning time.Duration
+
+	// StalenessWarning is how long after its last update a bundle is reported as stale, and how long after its
+	// last call a harvester is reported as stale. If not set, DefaultStalenessWarning is used.
+	StalenessWarning time.Duration
+}
+
+// Monitor reports the bundles that are about to expire or that were not updated for a while, which is
+// usually the sign of a harvester, or a bundle endpoint, that stopped working, and the harvesters that
+// stopped calling.
+type Monitor struct {
+	ds               datastore.Datastore
+	logger           logrus.FieldLogger
+	expiryWarning    time.Duration
+	stalenessWarning time.Duration
+
+	// expiring and stale are the trust domains whose bundle was last reported as expiring or stale,
+	// so that a warning is logged once until the bundle recovers
+	expiring map[uuid.UUID]bool
+	stale    map[uuid.UUID]bool
+
+	// harvesterStale are the trust domains whose harvester was last reported as stale
+	harvesterStale map[uuid.UUID]bool
+}
+
+func NewMonitor(c *MonitorConfig) *Monitor {
+	expiryWarning := c.ExpiryWarning
+	if expiryWarning == 0 {
+		expiryWarning = DefaultExpiryWarning
+	}
+	stalenessWarning := c.StalenessWarning
+	if stalenessWarning == 0 {
+		stalenessWarning = DefaultStalenessWarning
+	}
+
+	return &Monitor{
+		ds:               c.Datastore,
+		logger:           c.Logger,
+		expiryWarning:    expiryWarning,
+		stalenessWarning: stalenessWarning,
+		expiring:         make(map[uuid.UUID]bool),
+		stale:            make(map[uuid.UUID]bool),
+		harvesterStale:   make(map[uuid.UUID]bool),
+	}
+}
+
+// Run checks the bundles until the context is canceled.
+func (m *Monitor) Run(ctx context.Context) error {
+	t := time.NewTicker(monitorInterval)
+	defer t.Stop()
+
+	for {
+		now := time.Now()
+		m.check(ctx, now)
+		m.checkHarvesters(ctx, now)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// TrustDomains lists the trust domains along with the health of their bundles at the given time.
+func (m *Monitor) TrustDomains(ctx context.Context, now time.Time) ([]*entity.TrustDomain, error) {
+	trustDomains, err := m.ds.ListTrustDomains(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trust domains: %w", err)
+	}
+
+	bundles, err := m.ds.ListBundles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundles: %w", err)
+	}
+
+	byTrustDomain := make(map[uuid.UUID]*entity.Bundle, len(bundles))
+	for _, b := range bundles {
+		byTrustDomain[b.TrustDomainID] = b
+	}
+
+	for _, td := range trustDomains {
+		if b, ok := byTrustDomain[td.ID.UUID]; ok {
+			td.BundleHealth = m.health(td, b, now)
+		}
+	}
+
+	return trustDomains, nil
+}
+
+// health returns the health of the bundle of a trust domain at the given time.
+func (m *Monitor) health(td *entity.TrustDomain, b *entity.Bundle, now time.Time) *entity.BundleHealth {
+	h := &entity.BundleHealth{
+		UpdatedAt: b.UpdatedAt,
+		Digest:    b.Digest,
+		Stale:     now.Sub(b.UpdatedAt) > m.stalenessWarning,
+	}
+
+	bundle, err := spiffebundle.Parse(td.Name, b.Data)
+	if err != nil {
+		m.logger.WithError(err).Errorf("Failed to parse bundle of trust domain %q", td.Name)
+		return h
+	}
+
+	for _, cert := range bundle.X509Authorities() {
+		if h.EarliestExpiry.IsZero() || cert.NotAfter.Before(h.EarliestExpiry) {
+			h.EarliestExpiry = cert.NotAfter
+		}
+	}
+	h.Expiring = !h.EarliestExpiry.IsZero() && h.EarliestExpiry.Sub(now) < m.expiryWarning
+
+	return h
+}
+
+// check logs a warning for the bundles that became expiring or stale since the last check, and a message
+// for those that recovered.
+func (m *Monitor) check(ctx context.Context, now time.Time) {
+	trustDomains, err := m.TrustDomains(ctx, now)
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to check bundles")
+		return
+	}
+
+	expiring := make(map[uuid.UUID]bool)
+	stale := make(map[uuid.UUID]bool)
+	for _, td := range trustDomains {
+		h := td.BundleHealth
+		if h == nil {
+			continue
+		}
+
+		id := td.ID.UUID
+		switch {
+		case h.Expiring && !m.expiring[id]:
+			m.logger.Warnf("Bundle of trust domain %q expires soon: its first X.509 authority expires at %s", td.Name, h.EarliestExpiry.UTC().Format(time.RFC3339))
+		case !h.Expiring && m.expiring[id]:
+			m.logger.Infof("Bundle of trust domain %q no longer expires soon", td.Name)
+		}
+		switch {
+		case h.Stale && !m.stale[id]:
+			m.logger.Warnf("Bundle of trust domain %q is stale: it was last updated at %s", td.Name, h.UpdatedAt.UTC().Format(time.RFC3339))
+		case !h.Stale && m.stale[id]:
+			m.logger.Infof("Bundle of trust domain %q is no longer stale", td.Name)
+		}
+
+		expiring[id] = h.Expiring
+		stale[id] = h.Stale
+	}
+
+	m.expiring = expiring
+	m.stale = stale
+}
+
+// checkHarvesters logs a warning and notifies the webhooks for the harvesters that became stale since the last
+// check, and logs a message for those that recovered.
+func (m *Monitor) checkHarvesters(ctx context.Context, now time.Time) {
+	sessions, err := m.ds.ListHarvesterSessions(ctx)
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to check harvesters")
+		return
+	}
+
+	harvesterStale := make(map[uuid.UUID]bool)
+	for _, s := range sessions {
+		id := s.TrustDomainID
+		stale := now.Sub(s.UpdatedAt) > m.stalenessWarning
+		switch {
+		case stale && !m.harvesterStale[id]:
+			m.logger.Warnf("Harvester of trust domain %q is stale: it was last seen at %s", s.TrustDomainName, s.UpdatedAt.UTC().Format(time.RFC3339))
+			if err := webhooks.Enqueue(ctx, m.ds, webhooks.NewEvent(webhooks.HarvesterStale, s.TrustDomainName.String())); err != nil {
+				m.logger.WithError(err).Error("Failed to notify webhooks of stale harvester")
+			}
+		case !stale && m.harvesterStale[id]:
+			m.logger.Infof("Harvester of trust domain %q is no longer stale", s.TrustDomainName)
+		}
+
+		harvesterStale[id] = stale
+	}
+
+	m.harvesterStale = harvesterStale
+}
+
+// WriteMetrics writes the health of the bundles at the given time in the Prometheus text exposition format.
+func (m *Monitor) WriteMetrics(ctx context.Context, w io.Writer, now time.Time) error {
+	trustDomains, err := m.TrustDomains(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	metrics := []struct {
+		name  string
+		help  string
+		value func(h *entity.BundleHealth) (float64, bool)
+	}{
+		{
+			name: "galadriel_bundle_age_seconds",
+			help: "Time since the bundle of the trust domain was last updated.",
+			value: func(h *entity.BundleHealth) (float64, bool) {
+				return now.Sub(h.UpdatedAt).Seconds(), true
+			},
+		},
+		{
+			name: "galadriel_bundle_expiry_seconds",
+			help: "Time until the first X.509 authority of the bundle of the trust domain expires, negative once expired.",
+			value: func(h *entity.BundleHealth) (float64, bool) {
+				return h.EarliestExpiry.Sub(now).Seconds(), !h.EarliestExpiry.IsZero()
+			},
+		},
+		{
+			name: "galadriel_bundle_expiring",
+			help: "Whether the bundle of the trust domain expires within the expiry warning threshold.",
+			value: func(h *entity.BundleHealth) (float64, bool) {
+				return boolToFloat(h.Expiring), true
+			},
+		},
+		{
+			name: "galadriel_bundle_stale",
+			help: "Whether the bundle of the trust domain was not updated within the staleness warning threshold.",
+			value: func(h *entity.BundleHealth) (float64, bool) {
+				return boolToFloat(h.Stale), true
+			},
+		},
+	}
+
+	if _, err := fmt.Fprintf(w, "# HELP galadriel_trust_domains Number of trust domains.\n# TYPE galadriel_trust_domains gauge\ngaladriel_trust_domains %d\n", len(trustDomains)); err != nil {
+		return err
+	}
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name); err != nil {
+			return err
+		}
+		for _, td := range trustDomains {
+			if td.BundleHealth == nil {
+				continue
+			}
+			value, ok := metric.value(td.BundleHealth)
+			if !ok {
+				continue
+			}
+			// trust domain names have no characters to escape in label values
+			if _, err := fmt.Fprintf(w, "%s{trust_domain=\"%s\"} %g\n", metric.name, td.Name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package bundles
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/webhooks"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitor(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	logger, hook := test.NewNullLogger()
+
+	m := NewMonitor(&MonitorConfig{
+		Datastore:        ds,
+		Logger:           logger,
+		ExpiryWarning:    48 * time.Hour,
+		StalenessWarning: 24 * time.Hour,
+	})
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("bar.test")})
+	require.NoError(t, err)
+
+	now := time.Now()
+	earliestExpiry := now.Add(72 * time.Hour).Truncate(time.Second)
+	cas := []*x509.Certificate{
+		certtest.CreateCA(t, certtest.WithNotAfter(now.Add(30*24*time.Hour))),
+		certtest.CreateCA(t, certtest.WithNotAfter(earliestExpiry)),
+	}
+	_, err = Ingest(ctx, ds, nil, td, certtest.MarshalBundle(t, spiffebundle.FromX509Authorities(td1, cas)))
+	require.NoError(t, err)
+
+	trustDomains, err := m.TrustDomains(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, trustDomains, 2)
+
+	healths := make(map[string]*entity.BundleHealth)
+	for _, td := range trustDomains {
+		healths[td.Name.String()] = td.BundleHealth
+	}
+
+	// trust domains without a bundle have no bundle health
+	assert.Nil(t, healths["bar.test"])
+
+	h := healths["foo.test"]
+	require.NotNil(t, h)
+	assert.True(t, earliestExpiry.Equal(h.EarliestExpiry))
+	assert.NotEmpty(t, h.Digest)
+	assert.False(t, h.Expiring)
+	assert.False(t, h.Stale)
+
+	m.check(ctx, now)
+	assert.Empty(t, hook.AllEntries())
+
+	// a day and a half later, the bundle is stale and expires within the expiry warning threshold
+	later := now.Add(36 * time.Hour)
+	m.check(ctx, later)
+	require.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, logrus.WarnLevel, hook.AllEntries()[0].Level)
+	assert.Contains(t, hook.AllEntries()[0].Message, `Bundle of trust domain "foo.test" expires soon`)
+	assert.Contains(t, hook.AllEntries()[1].Message, `Bundle of trust domain "foo.test" is stale`)
+
+	// warnings are not logged again until the bundle recovers
+	hook.Reset()
+	m.check(ctx, later.Add(time.Minute))
+	assert.Empty(t, hook.AllEntries())
+
+	m.check(ctx, now)
+	require.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.WriteMetrics(ctx, &buf, later))
+	metrics := buf.String()
+	assert.Contains(t, metrics, "galadriel_trust_domains 2\n")
+	assert.Contains(t, metrics, "# TYPE galadriel_bundle_age_seconds gauge\n")
+	assert.Contains(t, metrics, `galadriel_bundle_expiring{trust_domain="foo.test"} 1`+"\n")
+	assert.Contains(t, metrics, `galadriel_bundle_stale{trust_domain="foo.test"} 1`+"\n")
+	assert.Contains(t, metrics, `galadriel_bundle_expiry_seconds{trust_domain="foo.test"} `)
+	assert.NotContains(t, metrics, "bar.test")
+}
+
+func TestMonitorHarvesters(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	logger, hook := test.NewNullLogger()
+
+	m := NewMonitor(&MonitorConfig{
+		Datastore:        ds,
+		Logger:           logger,
+		StalenessWarning: time.Hour,
+	})
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.NoError(t, err)
+	_, err = webhooks.Register(ctx, ds, "https://hooks.example.org", []string{string(webhooks.HarvesterStale)})
+	require.NoError(t, err)
+	session, err := ds.RecordHarvesterSession(ctx, &entity.HarvesterSession{TrustDomainID: td.ID.UUID, LastSyncAt: time.Now()})
+	require.NoError(t, err)
+
+	m.checkHarvesters(ctx, session.UpdatedAt.Add(time.Minute))
+	assert.Empty(t, hook.AllEntries())
+
+	// a harvester becoming stale is logged and notified once
+	m.checkHarvesters(ctx, session.UpdatedAt.Add(2*time.Hour))
+	m.checkHarvesters(ctx, session.UpdatedAt.Add(3*time.Hour))
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, `Harvester of trust domain "foo.test" is stale`)
+
+	deliveries, err := ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, string(webhooks.HarvesterStale), deliveries[0].EventType)
+
+	// and so is its recovery
+	m.checkHarvesters(ctx, session.UpdatedAt)
+	require.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+}
@@ -0,0 +1,288 @@
+package bundles
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+)
+
+// Defaults of the bundle validation policy.
+const (
+	DefaultMaxAuthorities      = 32
+	DefaultMinRSAKeySize       = 2048
+	DefaultMaxAddedAuthorities = 2
+	DefaultQuarantineDelay     = 24 * time.Hour
+)
+
+// DefaultAllowedCurves are the elliptic curves allowed by default for ECDSA keys.
+var DefaultAllowedCurves = []string{"P-256", "P-384", "P-521"}
+
+// Codes of the rules of the bundle validation policy.
+const (
+	ViolationNoAuthorities      = "no_authorities"
+	ViolationTooManyAuthorities = "too_many_authorities"
+	ViolationExpired            = "expired"
+	ViolationNotYetValid        = "not_yet_valid"
+	ViolationNotCA              = "not_ca"
+	ViolationWeakKey            = "weak_key"
+	ViolationDisallowedCurve    = "disallowed_curve"
+	ViolationUnsupportedKeyType = "unsupported_key_type"
+
+	ViolationNoSharedAuthority       = "no_shared_authority"
+	ViolationTooManyAddedAuthorities = "too_many_added_authorities"
+)
+
+const (
+	// validityClockSkew is the tolerance for clock skew when checking the validity of the X.509 authorities.
+	validityClockSkew = 5 * time.Minute
+
+	// lowestMinRSAKeySize bounds the minimum RSA key size a policy can be configured with.
+	lowestMinRSAKeySize = 1024
+)
+
+// knownCurves are the curves that can be allowed, keyed by name.
+var knownCurves = map[string]bool{"P-224": true, "P-256": true, "P-384": true, "P-521": true}
+
+// Policy is the validation policy the bundles are checked against before they are stored, so that a
+// misconfigured SPIRE server cannot push a bundle that would be redistributed to every federated trust domain.
+//
+// Bundle updates that break the continuity with the stored bundle are not rejected, but quarantined: they
+// are held back for the quarantine delay, or until an admin approves or rejects them.
+type Policy struct {
+	maxAuthorities      int
+	minRSAKeySize       int
+	allowedCurves       map[string]bool
+	maxAddedAuthorities int
+	quarantineDelay     time.Duration
+}
+
+// PolicyConfig conveys the settings of a Policy.
+type PolicyConfig struct {
+	// MaxAuthorities bounds the number of X.509 and JWT authorities of a bundle.
+	MaxAuthorities int
+
+	// MinRSAKeySize is the minimum size in bits of RSA keys.
+	MinRSAKeySize int
+
+	// AllowedCurves lists the names of the elliptic curves allowed for ECDSA keys, e.g., "P-256".
+	AllowedCurves []string
+
+	// MaxAddedAuthorities bounds the number of authorities a bundle update can add before it is quarantined.
+	MaxAddedAuthorities int
+
+	// QuarantineDelay is how long a bundle update is quarantined before it is released.
+	QuarantineDelay time.Duration
+}
+
+// DefaultPolicyConfig returns the default settings of the bundle validation policy.
+func DefaultPolicyConfig() PolicyConfig {
+	return PolicyConfig{
+		MaxAuthorities:      DefaultMaxAuthorities,
+		MinRSAKeySize:       DefaultMinRSAKeySize,
+		AllowedCurves:       DefaultAllowedCurves,
+		MaxAddedAuthorities: DefaultMaxAddedAuthorities,
+		QuarantineDelay:     DefaultQuarantineDelay,
+	}
+}
+
+// NewPolicy creates a Policy with the given settings.
+func NewPolicy(c PolicyConfig) (*Policy, error) {
+	if c.MaxAuthorities <= 0 {
+		return nil, errors.New("the maximum number of authorities must be positive")
+	}
+	if c.MinRSAKeySize < lowestMinRSAKeySize {
+		return nil, fmt.Errorf("the minimum RSA key size cannot be lower than %d bits", lowestMinRSAKeySize)
+	}
+	if c.MaxAddedAuthorities <= 0 {
+		return nil, errors.New("the maximum number of added authorities must be positive")
+	}
+	if c.QuarantineDelay <= 0 {
+		return nil, errors.New("the quarantine delay must be positive")
+	}
+
+	curves := make(map[string]bool, len(c.AllowedCurves))
+	for _, curve := range c.AllowedCurves {
+		if !knownCurves[curve] {
+			return nil, fmt.Errorf("unknown elliptic curve %q", curve)
+		}
+		curves[curve] = true
+	}
+
+	return &Policy{
+		maxAuthorities:      c.MaxAuthorities,
+		minRSAKeySize:       c.MinRSAKeySize,
+		allowedCurves:       curves,
+		maxAddedAuthorities: c.MaxAddedAuthorities,
+		quarantineDelay:     c.QuarantineDelay,
+	}, nil
+}
+
+// DefaultPolicy returns the Policy with the default settings.
+func DefaultPolicy() *Policy {
+	p, err := NewPolicy(DefaultPolicyConfig())
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// QuarantineDelay returns how long a bundle update is quarantined before it is released.
+func (p *Policy) QuarantineDelay() time.Duration {
+	return p.quarantineDelay
+}
+
+// PolicyError is returned when a bundle breaks the validation policy.
+type PolicyError struct {
+	Violations []entity.BundleViolation
+}
+
+func (e *PolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "bundle rejected by the validation policy: " + strings.Join(msgs, "; ")
+}
+
+// Check returns the rules of the policy broken by the bundle at the given time. The validity of the X.509
+// authorities is checked with some tolerance for clock skew.
+func (p *Policy) Check(bundle *spiffebundle.Bundle, now time.Time) []entity.BundleViolation {
+	var violations []entity.BundleViolation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, entity.BundleViolation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	x509Authorities := bundle.X509Authorities()
+	jwtAuthorities := bundle.JWTAuthorities()
+
+	switch n := len(x509Authorities) + len(jwtAuthorities); {
+	case n == 0:
+		add(ViolationNoAuthorities, "the bundle has no authorities")
+	case n > p.maxAuthorities:
+		add(ViolationTooManyAuthorities, "the bundle has %d authorities, the maximum is %d", n, p.maxAuthorities)
+	}
+
+	for _, cert := range x509Authorities {
+		name := fmt.Sprintf("X.509 authority %q", cert.Subject)
+
+		if now.Add(-validityClockSkew).After(cert.NotAfter) {
+			add(ViolationExpired, "%s expired at %s", name, cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+		if now.Add(validityClockSkew).Before(cert.NotBefore) {
+			add(ViolationNotYetValid, "%s is not valid before %s", name, cert.NotBefore.UTC().Format(time.RFC3339))
+		}
+		if !cert.BasicConstraintsValid || !cert.IsCA {
+			add(ViolationNotCA, "%s is not a CA certificate", name)
+		}
+		if v := p.checkKey(name, cert.PublicKey); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+
+	keyIDs := make([]string, 0, len(jwtAuthorities))
+	for keyID := range jwtAuthorities {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	for _, keyID := range keyIDs {
+		if v := p.checkKey(fmt.Sprintf("JWT authority %q", keyID), jwtAuthorities[keyID]); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+
+	return violations
+}
+
+// CheckRotation returns the reasons to quarantine the update of the current bundle to the next one: the next
+// bundle keeps none of the X.509 authorities, or none of the JWT authorities, of the current bundle, or it adds
+// more authorities than the policy allows. A trust domain rotating its keys keeps trusting the old authorities
+// for a while, so an update that replaces all of them at once is suspicious.
+func (p *Policy) CheckRotation(current, next *spiffebundle.Bundle) []entity.BundleViolation {
+	var violations []entity.BundleViolation
+
+	added := 0
+
+	currentX509 := x509bundle.FromX509Authorities(current.TrustDomain(), current.X509Authorities())
+	sharedX509 := false
+	for _, cert := range next.X509Authorities() {
+		if currentX509.HasX509Authority(cert) {
+			sharedX509 = true
+		} else {
+			added++
+		}
+	}
+	if len(current.X509Authorities()) > 0 && !sharedX509 {
+		violations = append(violations, entity.BundleViolation{
+			Code:    ViolationNoSharedAuthority,
+			Message: "the bundle keeps none of the X.509 authorities of the stored bundle",
+		})
+	}
+
+	sharedJWT := false
+	for keyID, key := range next.JWTAuthorities() {
+		if currentKey, ok := current.FindJWTAuthority(keyID); ok && equalKeys(currentKey, key) {
+			sharedJWT = true
+		} else {
+			added++
+		}
+	}
+	if len(current.JWTAuthorities()) > 0 && !sharedJWT {
+		violations = append(violations, entity.BundleViolation{
+			Code:    ViolationNoSharedAuthority,
+			Message: "the bundle keeps none of the JWT authorities of the stored bundle",
+		})
+	}
+
+	if added > p.maxAddedAuthorities {
+		violations = append(violations, entity.BundleViolation{
+			Code:    ViolationTooManyAddedAuthorities,
+			Message: fmt.Sprintf("the bundle adds %d authorities, the maximum is %d", added, p.maxAddedAuthorities),
+		})
+	}
+
+	return violations
+}
+
+// equalKeys reports whether two public keys are equal.
+func equalKeys(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// checkKey checks that a key of an authority can be used with one of the RSA or ECDSA signature algorithms
+// of X509-SVIDs and JWT-SVIDs, and that it is strong enough.
+func (p *Policy) checkKey(name string, key crypto.PublicKey) *entity.BundleViolation {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if size := k.N.BitLen(); size < p.minRSAKeySize {
+			return &entity.BundleViolation{
+				Code:    ViolationWeakKey,
+				Message: fmt.Sprintf("%s has a %d bit RSA key, the minimum is %d", name, size, p.minRSAKeySize),
+			}
+		}
+	case *ecdsa.PublicKey:
+		if curve := k.Curve.Params().Name; !p.allowedCurves[curve] {
+			return &entity.BundleViolation{
+				Code:    ViolationDisallowedCurve,
+				Message: fmt.Sprintf("%s uses the disallowed curve %s", name, curve),
+			}
+		}
+	default:
+		return &entity.BundleViolation{
+			Code:    ViolationUnsupportedKeyType,
+			Message: fmt.Sprintf("%s has an unsupported key type %T", name, key),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package bundles
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPolicy(t *testing.T) {
+	config := func(update func(c *PolicyConfig)) PolicyConfig {
+		c := DefaultPolicyConfig()
+		update(&c)
+		return c
+	}
+
+	_, err := NewPolicy(config(func(c *PolicyConfig) { c.MaxAuthorities = 0 }))
+	assert.EqualError(t, err, "the maximum number of authorities must be positive")
+
+	_, err = NewPolicy(config(func(c *PolicyConfig) { c.MinRSAKeySize = 512 }))
+	assert.EqualError(t, err, "the minimum RSA key size cannot be lower than 1024 bits")
+
+	_, err = NewPolicy(config(func(c *PolicyConfig) { c.AllowedCurves = []string{"P-256", "secp256k1"} }))
+	assert.EqualError(t, err, `unknown elliptic curve "secp256k1"`)
+
+	_, err = NewPolicy(config(func(c *PolicyConfig) { c.MaxAddedAuthorities = 0 }))
+	assert.EqualError(t, err, "the maximum number of added authorities must be positive")
+
+	_, err = NewPolicy(config(func(c *PolicyConfig) { c.QuarantineDelay = 0 }))
+	assert.EqualError(t, err, "the quarantine delay must be positive")
+
+	_, err = NewPolicy(config(func(c *PolicyConfig) { c.AllowedCurves = nil }))
+	assert.NoError(t, err)
+}
+
+func TestPolicyCheck(t *testing.T) {
+	now := time.Now()
+	c := DefaultPolicyConfig()
+	c.MaxAuthorities = 3
+	p, err := NewPolicy(c)
+	require.NoError(t, err)
+
+	ecKey := generateECKey(t, elliptic.P256())
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		x509  []*x509.Certificate
+		jwt   map[string]crypto.PublicKey
+		codes []string
+	}{
+		{
+			name: "valid",
+			x509: []*x509.Certificate{certtest.CreateCA(t)},
+			jwt:  map[string]crypto.PublicKey{"key-1": ecKey.Public()},
+		},
+		{
+			name:  "no_authorities",
+			codes: []string{ViolationNoAuthorities},
+		},
+		{
+			name:  "too_many_authorities",
+			x509:  []*x509.Certificate{certtest.CreateCA(t), certtest.CreateCA(t)},
+			jwt:   map[string]crypto.PublicKey{"key-1": ecKey.Public(), "key-2": ecKey.Public()},
+			codes: []string{ViolationTooManyAuthorities},
+		},
+		{
+			name:  "expired",
+			x509:  []*x509.Certificate{certtest.CreateCA(t, certtest.WithKey(ecKey), certtest.WithValidity(now.Add(-2*time.Hour), now.Add(-time.Hour)))},
+			codes: []string{ViolationExpired},
+		},
+		{
+			name: "recently_expired",
+			x509: []*x509.Certificate{certtest.CreateCA(t, certtest.WithKey(ecKey), certtest.WithValidity(now.Add(-2*time.Hour), now.Add(-time.Minute)))},
+		},
+		{
+			name:  "not_yet_valid",
+			x509:  []*x509.Certificate{certtest.CreateCA(t, certtest.WithKey(ecKey), certtest.WithValidity(now.Add(time.Hour), now.Add(2*time.Hour)))},
+			codes: []string{ViolationNotYetValid},
+		},
+		{
+			name: "not_ca",
+			x509: []*x509.Certificate{certtest.CreateCA(t, certtest.WithKey(ecKey), certtest.WithTemplate(func(c *x509.Certificate) {
+				c.IsCA = false
+				c.KeyUsage = x509.KeyUsageDigitalSignature
+			}))},
+			codes: []string{ViolationNotCA},
+		},
+		{
+			name:  "weak_rsa_key",
+			x509:  []*x509.Certificate{certtest.CreateCA(t, certtest.WithKey(rsaKey))},
+			jwt:   map[string]crypto.PublicKey{"key-1": rsaKey.Public()},
+			codes: []string{ViolationWeakKey, ViolationWeakKey},
+		},
+		{
+			name:  "disallowed_curve",
+			jwt:   map[string]crypto.PublicKey{"key-1": generateECKey(t, elliptic.P224()).Public()},
+			codes: []string{ViolationDisallowedCurve},
+		},
+		{
+			name:  "unsupported_jwt_key",
+			jwt:   map[string]crypto.PublicKey{"key-1": edKey.Public()},
+			codes: []string{ViolationUnsupportedKeyType},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := spiffebundle.FromX509Authorities(td1, tt.x509)
+			for keyID, key := range tt.jwt {
+				require.NoError(t, bundle.AddJWTAuthority(keyID, key))
+			}
+
+			var codes []string
+			for _, v := range p.Check(bundle, now) {
+				assert.NotEmpty(t, v.Message)
+				codes = append(codes, v.Code)
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+}
+
+func TestPolicyCheckRotation(t *testing.T) {
+	p := DefaultPolicy()
+
+	ca1, ca2, ca3, ca4 := certtest.CreateCA(t), certtest.CreateCA(t), certtest.CreateCA(t), certtest.CreateCA(t)
+	key1, key2 := generateECKey(t, elliptic.P256()).Public(), generateECKey(t, elliptic.P256()).Public()
+
+	newBundle := func(cas []*x509.Certificate, jwt map[string]crypto.PublicKey) *spiffebundle.Bundle {
+		bundle := spiffebundle.FromX509Authorities(td1, cas)
+		for keyID, key := range jwt {
+			require.NoError(t, bundle.AddJWTAuthority(keyID, key))
+		}
+		return bundle
+	}
+	current := newBundle([]*x509.Certificate{ca1}, map[string]crypto.PublicKey{"key-1": key1})
+
+	tests := []struct {
+		name  string
+		next  *spiffebundle.Bundle
+		codes []string
+	}{
+		{
+			name: "unchanged",
+			next: current,
+		},
+		{
+			name: "rotation",
+			next: newBundle([]*x509.Certificate{ca1, ca2}, map[string]crypto.PublicKey{"key-1": key1, "key-2": key2}),
+		},
+		{
+			name:  "x509_authorities_replaced",
+			next:  newBundle([]*x509.Certificate{ca2}, map[string]crypto.PublicKey{"key-1": key1}),
+			codes: []string{ViolationNoSharedAuthority},
+		},
+		{
+			name:  "jwt_key_replaced_under_same_key_id",
+			next:  newBundle([]*x509.Certificate{ca1}, map[string]crypto.PublicKey{"key-1": key2}),
+			codes: []string{ViolationNoSharedAuthority},
+		},
+		{
+			name:  "too_many_added_authorities",
+			next:  newBundle([]*x509.Certificate{ca1, ca2, ca3, ca4}, map[string]crypto.PublicKey{"key-1": key1}),
+			codes: []string{ViolationTooManyAddedAuthorities},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var codes []string
+			for _, v := range p.CheckRotation(current, tt.next) {
+				assert.NotEmpty(t, v.Message)
+				codes = append(codes, v.Code)
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+}
+
+func generateECKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	require.NoError(t, err)
+	return key
+}
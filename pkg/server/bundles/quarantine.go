@@ -0,0 +1,161 @@
+package bundles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotQuarantined is returned when a trust domain has no quarantined bundle to approve or reject.
+var ErrNotQuarantined = errors.New("the trust domain has no quarantined bundle")
+
+// Approve stores the quarantined bundle of a trust domain without waiting for its release.
+func Approve(ctx context.Context, ds datastore.Datastore, trustDomainID uuid.UUID) error {
+	return ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		qb, err := tx.FindQuarantinedBundleByTrustDomainID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+		if qb == nil {
+			return ErrNotQuarantined
+		}
+
+		return release(ctx, tx, qb)
+	})
+}
+
+// Reject discards the quarantined bundle of a trust domain and records its rejection, so that the bundle is
+// rejected if it is posted or fetched again.
+func Reject(ctx context.Context, ds datastore.Datastore, trustDomainID uuid.UUID) error {
+	return ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		qb, err := tx.FindQuarantinedBundleByTrustDomainID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+		if qb == nil {
+			return ErrNotQuarantined
+		}
+
+		_, err = tx.CreateBundleRejection(ctx, &entity.BundleRejection{
+			TrustDomainID: trustDomainID,
+			Digest:        qb.Digest,
+			Violations:    qb.Reasons,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record bundle rejection: %w", err)
+		}
+
+		if err := tx.DeleteQuarantinedBundle(ctx, qb.ID.UUID); err != nil {
+			return fmt.Errorf("failed to delete quarantined bundle: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// release stores a quarantined bundle and deletes it from the quarantine.
+func release(ctx context.Context, tx datastore.Datastore, qb *entity.QuarantinedBundle) error {
+	current, err := tx.FindBundleByTrustDomainID(ctx, qb.TrustDomainID)
+	if err != nil {
+		return err
+	}
+
+	if err := store(ctx, tx, qb.TrustDomainID, current, qb.Data, qb.Digest); err != nil {
+		return err
+	}
+
+	if err := tx.DeleteQuarantinedBundle(ctx, qb.ID.UUID); err != nil {
+		return fmt.Errorf("failed to delete quarantined bundle: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaserConfig conveys the configuration of a Releaser.
+type ReleaserConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+}
+
+// Releaser stores the quarantined bundles once their quarantine delay is over.
+type Releaser struct {
+	ds     datastore.Datastore
+	logger logrus.FieldLogger
+}
+
+func NewReleaser(c *ReleaserConfig) *Releaser {
+	return &Releaser{
+		ds:     c.Datastore,
+		logger: c.Logger,
+	}
+}
+
+// Run releases the quarantined bundles until the context is canceled.
+func (r *Releaser) Run(ctx context.Context) error {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		r.releaseDue(ctx, time.Now())
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// releaseDue releases the quarantined bundles that are due at the given time.
+func (r *Releaser) releaseDue(ctx context.Context, now time.Time) {
+	quarantined, err := r.ds.ListQuarantinedBundles(ctx)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to list quarantined bundles")
+		return
+	}
+
+	for _, qb := range quarantined {
+		if now.Before(qb.ReleaseAt) {
+			continue
+		}
+
+		released, err := r.releaseBundle(ctx, qb.TrustDomainID, now)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Failed to release quarantined bundle of trust domain %q", qb.TrustDomainName)
+			continue
+		}
+		if released {
+			r.logger.Infof("Quarantined bundle of trust domain %q released", qb.TrustDomainName)
+		}
+	}
+}
+
+// releaseBundle releases the quarantined bundle of a trust domain if it is due at the given time, as it may
+// have been replaced, approved or rejected since it was listed. It returns whether the bundle was released.
+func (r *Releaser) releaseBundle(ctx context.Context, trustDomainID uuid.UUID, now time.Time) (bool, error) {
+	released := false
+	err := r.ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		qb, err := tx.FindQuarantinedBundleByTrustDomainID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+		if qb == nil || now.Before(qb.ReleaseAt) {
+			return nil
+		}
+
+		if err := release(ctx, tx, qb); err != nil {
+			return err
+		}
+
+		released = true
+		return nil
+	})
+
+	return released, err
+}
@@ -0,0 +1,172 @@
+// Package changes notifies every replica of the server sharing the datastore of the changes committed by any of
+// them, so their caches and the watches streamed from them see the changes immediately.
+package changes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+)
+
+// Channel is the datastore channel the changes are notified on.
+const Channel = "galadriel_changes"
+
+// Kinds of the changed resources, sent as the payload of the notifications.
+const (
+	KindTrustDomain      = "trust_domain"
+	KindRelationship     = "relationship"
+	KindBundle           = "bundle"
+	KindJoinToken        = "join_token"
+	KindFederationGroup  = "federation_group"
+	KindFederationPolicy = "federation_policy"
+)
+
+// notifyingDatastore is a Datastore notifying the changes made through it. Each change is notified within its
+// transaction, so the notification is only sent once the change is committed.
+type notifyingDatastore struct {
+	datastore.Datastore
+}
+
+// NewDatastore returns a Datastore notifying the changes of the trust domains, relationships, bundles, join
+// tokens, federation groups and federation policies made through it, whichever component makes them.
+func NewDatastore(ds datastore.Datastore) datastore.Datastore {
+	return &notifyingDatastore{Datastore: ds}
+}
+
+func (d *notifyingDatastore) WithTx(ctx context.Context, fn func(tx datastore.Datastore) error) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		return fn(&notifyingDatastore{Datastore: tx})
+	})
+}
+
+func (d *notifyingDatastore) CreateOrUpdateTrustDomain(ctx context.Context, req *entity.TrustDomain) (*entity.TrustDomain, error) {
+	return change(ctx, d.Datastore, KindTrustDomain, func(tx datastore.Datastore) (*entity.TrustDomain, error) {
+		return tx.CreateOrUpdateTrustDomain(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) DeleteTrustDomain(ctx context.Context, trustDomainID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindTrustDomain, func(tx datastore.Datastore) error {
+		return tx.DeleteTrustDomain(ctx, trustDomainID)
+	})
+}
+
+func (d *notifyingDatastore) CreateOrUpdateBundle(ctx context.Context, req *entity.Bundle) (*entity.Bundle, error) {
+	return change(ctx, d.Datastore, KindBundle, func(tx datastore.Datastore) (*entity.Bundle, error) {
+		return tx.CreateOrUpdateBundle(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) DeleteBundle(ctx context.Context, bundleID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindBundle, func(tx datastore.Datastore) error {
+		return tx.DeleteBundle(ctx, bundleID)
+	})
+}
+
+func (d *notifyingDatastore) CreateJoinToken(ctx context.Context, req *entity.JoinToken) (*entity.JoinToken, error) {
+	return change(ctx, d.Datastore, KindJoinToken, func(tx datastore.Datastore) (*entity.JoinToken, error) {
+		return tx.CreateJoinToken(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) UpdateJoinToken(ctx context.Context, joinTokenID uuid.UUID, used bool) (*entity.JoinToken, error) {
+	return change(ctx, d.Datastore, KindJoinToken, func(tx datastore.Datastore) (*entity.JoinToken, error) {
+		return tx.UpdateJoinToken(ctx, joinTokenID, used)
+	})
+}
+
+func (d *notifyingDatastore) DeleteJoinToken(ctx context.Context, joinTokenID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindJoinToken, func(tx datastore.Datastore) error {
+		return tx.DeleteJoinToken(ctx, joinTokenID)
+	})
+}
+
+func (d *notifyingDatastore) CreateOrUpdateRelationship(ctx context.Context, req *entity.Relationship) (*entity.Relationship, error) {
+	return change(ctx, d.Datastore, KindRelationship, func(tx datastore.Datastore) (*entity.Relationship, error) {
+		return tx.CreateOrUpdateRelationship(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) DeleteRelationship(ctx context.Context, relationshipID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindRelationship, func(tx datastore.Datastore) error {
+		return tx.DeleteRelationship(ctx, relationshipID)
+	})
+}
+
+func (d *notifyingDatastore) CreateFederationGroup(ctx context.Context, req *entity.FederationGroup) (*entity.FederationGroup, error) {
+	return change(ctx, d.Datastore, KindFederationGroup, func(tx datastore.Datastore) (*entity.FederationGroup, error) {
+		return tx.CreateFederationGroup(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) DeleteFederationGroup(ctx context.Context, groupID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindFederationGroup, func(tx datastore.Datastore) error {
+		return tx.DeleteFederationGroup(ctx, groupID)
+	})
+}
+
+func (d *notifyingDatastore) AddFederationGroupMember(ctx context.Context, req *entity.FederationGroupMember) (*entity.FederationGroupMember, error) {
+	return change(ctx, d.Datastore, KindFederationGroup, func(tx datastore.Datastore) (*entity.FederationGroupMember, error) {
+		return tx.AddFederationGroupMember(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) RemoveFederationGroupMember(ctx context.Context, groupID, trustDomainID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindFederationGroup, func(tx datastore.Datastore) error {
+		return tx.RemoveFederationGroupMember(ctx, groupID, trustDomainID)
+	})
+}
+
+func (d *notifyingDatastore) CreateFederationPolicy(ctx context.Context, req *entity.FederationPolicy) (*entity.FederationPolicy, error) {
+	return change(ctx, d.Datastore, KindFederationPolicy, func(tx datastore.Datastore) (*entity.FederationPolicy, error) {
+		return tx.CreateFederationPolicy(ctx, req)
+	})
+}
+
+func (d *notifyingDatastore) DeleteFederationPolicy(ctx context.Context, policyID uuid.UUID) error {
+	return deletion(ctx, d.Datastore, KindFederationPolicy, func(tx datastore.Datastore) error {
+		return tx.DeleteFederationPolicy(ctx, policyID)
+	})
+}
+
+// change makes a change of a resource of the given kind and notifies it, within a transaction.
+func change[T any](ctx context.Context, ds datastore.Datastore, kind string, fn func(tx datastore.Datastore) (T, error)) (T, error) {
+	var result T
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		result, err = fn(tx)
+		if err != nil {
+			return err
+		}
+
+		return notify(ctx, tx, kind)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return result, nil
+}
+
+// deletion deletes a resource of the given kind and notifies it, within a transaction.
+func deletion(ctx context.Context, ds datastore.Datastore, kind string, fn func(tx datastore.Datastore) error) error {
+	return ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		return notify(ctx, tx, kind)
+	})
+}
+
+func notify(ctx context.Context, ds datastore.Datastore, kind string) error {
+	if err := ds.Notify(ctx, Channel, kind); err != nil {
+		return fmt.Errorf("failed notifying %s change: %w", kind, err)
+	}
+
+	return nil
+}
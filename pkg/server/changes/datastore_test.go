@@ -0,0 +1,90 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatastoreNotifiesChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := NewDatastore(memory)
+
+	payloads := listen(ctx, t, memory)
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test")})
+	require.NoError(t, err)
+	assert.Equal(t, KindTrustDomain, next(t, payloads))
+
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{TrustDomainID: td.ID.UUID, Data: []byte("1"), Digest: []byte("d1")})
+	require.NoError(t, err)
+	assert.Equal(t, KindBundle, next(t, payloads))
+
+	group, err := ds.CreateFederationGroup(ctx, &entity.FederationGroup{Name: "group", Mode: entity.FederationGroupMesh})
+	require.NoError(t, err)
+	assert.Equal(t, KindFederationGroup, next(t, payloads))
+
+	require.NoError(t, ds.DeleteFederationGroup(ctx, group.ID.UUID))
+	assert.Equal(t, KindFederationGroup, next(t, payloads))
+
+	// changes rolled back are not notified
+	err = ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		if _, err := tx.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("bar.test")}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	// nor are failed changes
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test")})
+	require.Error(t, err)
+
+	require.NoError(t, ds.DeleteTrustDomain(ctx, td.ID.UUID))
+	assert.Equal(t, KindTrustDomain, next(t, payloads))
+}
+
+// listen returns the payloads of the changes notified once the listener is listening.
+func listen(ctx context.Context, t *testing.T, ds datastore.Datastore) <-chan string {
+	payloads := make(chan string, 10)
+	go func() {
+		_ = ds.Listen(ctx, Channel, func(payload string) {
+			payloads <- payload
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		assert.NoError(t, ds.Notify(ctx, Channel, "ready"))
+		select {
+		case <-payloads:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	return payloads
+}
+
+func next(t *testing.T, payloads <-chan string) string {
+	for {
+		select {
+		case p := <-payloads:
+			if p != "ready" {
+				return p
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no change notified")
+		}
+	}
+}
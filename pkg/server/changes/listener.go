@@ -0,0 +1,88 @@
+package changes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+// retryInterval is how long the listener waits before listening again after losing its connection.
+const retryInterval = 5 * time.Second
+
+// ListenerConfig conveys the configuration of a Listener.
+type ListenerConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+}
+
+// Listener listens to the changes notified by every replica of the server sharing the datastore, and signals
+// them to its subscribers.
+type Listener struct {
+	ds     datastore.Datastore
+	logger logrus.FieldLogger
+
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func NewListener(c *ListenerConfig) *Listener {
+	return &Listener{
+		ds:          c.Datastore,
+		logger:      c.Logger,
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Run listens to the changes until the context is canceled. If the connection to the datastore is lost, the
+// subscribers are signaled, as changes might have been missed, and the listener listens again.
+func (l *Listener) Run(ctx context.Context) error {
+	for {
+		err := l.ds.Listen(ctx, Channel, func(string) {
+			l.signal()
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		l.logger.WithError(err).Warn("Stopped listening to changes")
+		l.signal()
+
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Subscribe returns a channel signaled after changes are committed, along with a function unsubscribing it.
+// Changes committed before the subscriber receives the signal are coalesced into a single signal.
+func (l *Listener) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers, ch)
+	}
+}
+
+func (l *Listener) signal() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// a signal is already pending
+		}
+	}
+}
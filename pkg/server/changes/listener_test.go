@@ -0,0 +1,51 @@
+package changes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListener(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	listener := NewListener(&ListenerConfig{Datastore: ds, Logger: logrus.New()})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- listener.Run(ctx)
+	}()
+
+	changed, unsubscribe := listener.Subscribe()
+	other, unsubscribeOther := listener.Subscribe()
+	unsubscribeOther()
+
+	// every subscriber is signaled once the listener listens, and the signals are coalesced
+	require.Eventually(t, func() bool {
+		assert.NoError(t, ds.Notify(ctx, Channel, KindBundle))
+		select {
+		case <-changed:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, ds.Notify(ctx, Channel, KindBundle))
+	require.NoError(t, ds.Notify(ctx, Channel, KindRelationship))
+	require.Eventually(t, func() bool {
+		return len(changed) == 1
+	}, time.Second, time.Millisecond)
+
+	// unsubscribed subscribers are not signaled
+	assert.Empty(t, other)
+
+	unsubscribe()
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -2,7 +2,12 @@ package server
 
 import (
 	"net"
+	"time"
 
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/jobs"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
 	"github.com/sirupsen/logrus"
 )
 
@@ -14,11 +19,54 @@ type Config struct {
 	// Address of Galadriel Server to be reached locally
 	LocalAddress net.Addr
 
+	// Address of the SPIFFE federation bundle endpoint. If not set, the endpoint is disabled.
+	FederationAddress *net.TCPAddr
+
+	// Web PKI certificate and private key presented by the SPIFFE federation bundle endpoint
+	FederationCertFile string
+	FederationKeyFile  string
+
+	// URL the SPIFFE federation bundle endpoint is reached at, used as the base of the OIDC issuers
+	FederationPublicURL string
+
+	// Address of the peering endpoint, which serves the bundles shared with the peer Galadriel Servers.
+	// If not set, the peering is disabled.
+	PeeringAddress *net.TCPAddr
+
+	// Certificate and private key presented to the peers, and CA certificates the peers are verified with
+	PeeringCertFile string
+	PeeringKeyFile  string
+	PeeringCAFile   string
+
+	// Validation policy of the bundles posted by harvesters and fetched from bundle endpoints
+	BundlePolicy *bundles.Policy
+
+	// Rules constraining which trust domains can be federated. If not set, any trust domains can be federated.
+	RelationshipRules *relationships.RuleSet
+
+	// Thresholds of the warnings about bundles about to expire and bundles not updated for a while.
+	// If not set, the defaults of the bundle monitor are used.
+	BundleExpiryWarning    time.Duration
+	BundleStalenessWarning time.Duration
+
+	// Settings of the maintenance jobs, by job name. The jobs not listed keep their default settings.
+	Jobs map[string]jobs.Settings
+
+	// Address of the Prometheus metrics endpoint. If not set, the endpoint is disabled.
+	MetricsAddress *net.TCPAddr
+
 	// Directory to store runtime data
 	DataDir string
 
 	// DB Connection string
 	DBConnString string
 
+	// DBMigrationMode defines whether the DB schema is migrated on startup, or just verified
+	DBMigrationMode datastore.MigrationMode
+
+	// DevMode runs the server with an in-memory datastore instead of Postgres.
+	// Nothing is persisted, so it is only meant for development and testing.
+	DevMode bool
+
 	Logger logrus.FieldLogger
 }
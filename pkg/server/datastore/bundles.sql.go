@@ -7,6 +7,7 @@ package datastore
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgtype"
 )
@@ -112,6 +113,87 @@ func (q *Queries) FindBundleByTrustDomainID(ctx context.Context, trustDomainID p
 	return i, err
 }
 
+const findFederatedBundlesByTrustDomainID = `-- name: FindFederatedBundlesByTrustDomainID :many
+WITH peers AS (SELECT CASE
+                          WHEN r.trust_domain_a_id = $1 THEN r.trust_domain_b_id
+                          ELSE r.trust_domain_a_id END AS trust_domain_id
+               FROM relationships r
+               WHERE ((r.trust_domain_a_id = $1 AND r.direction != 'b_trusts_a')
+                   OR (r.trust_domain_b_id = $1 AND r.direction != 'a_trusts_b'))
+                 AND (r.not_before IS NULL OR r.not_before <= now())
+                 AND (r.not_after IS NULL OR r.not_after > now())
+               UNION
+               SELECT peer.trust_domain_id
+               FROM federation_group_members self
+                        JOIN federation_groups g ON g.id = self.group_id
+                        JOIN federation_group_members peer
+                             ON peer.group_id = self.group_id AND peer.trust_domain_id != self.trust_domain_id
+               WHERE self.trust_domain_id = $1
+                 AND (g.mode = 'mesh' OR self.hub OR peer.hub)
+               UNION
+               SELECT peer.id
+               FROM federation_policies fp
+                        JOIN trust_domains self ON self.id = $1
+                        JOIN trust_domains peer
+                             ON (self.labels @> fp.selector AND peer.labels @> fp.peer_selector)
+                                 OR (self.labels @> fp.peer_selector AND peer.labels @> fp.selector)
+               WHERE peer.id != self.id)
+SELECT b.id, b.trust_domain_id, b.data, b.digest, b.signature, b.digest_algorithm, b.signature_algorithm, b.signing_cert, b.created_at, b.updated_at, td.name AS trust_domain_name
+FROM peers p
+         JOIN bundles b ON b.trust_domain_id = p.trust_domain_id
+         JOIN trust_domains td ON td.id = b.trust_domain_id
+ORDER BY td.name
+`
+
+type FindFederatedBundlesByTrustDomainIDRow struct {
+	ID                 pgtype.UUID
+	TrustDomainID      pgtype.UUID
+	Data               []byte
+	Digest             []byte
+	Signature          []byte
+	DigestAlgorithm    string
+	SignatureAlgorithm string
+	SigningCert        []byte
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	TrustDomainName    string
+}
+
+func (q *Queries) FindFederatedBundlesByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) ([]FindFederatedBundlesByTrustDomainIDRow, error) {
+	rows, err := q.query(ctx, q.findFederatedBundlesByTrustDomainIDStmt, findFederatedBundlesByTrustDomainID, trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindFederatedBundlesByTrustDomainIDRow
+	for rows.Next() {
+		var i FindFederatedBundlesByTrustDomainIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TrustDomainID,
+			&i.Data,
+			&i.Digest,
+			&i.Signature,
+			&i.DigestAlgorithm,
+			&i.SignatureAlgorithm,
+			&i.SigningCert,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TrustDomainName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listBundles = `-- name: ListBundles :many
 SELECT id, trust_domain_id, data, digest, signature, digest_algorithm, signature_algorithm, signing_cert, created_at, updated_at
 FROM bundles
@@ -3,10 +3,13 @@ package datastore
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
 	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pkg/errors"
@@ -23,6 +26,7 @@ type Datastore interface {
 	CreateOrUpdateBundle(ctx context.Context, req *entity.Bundle) (*entity.Bundle, error)
 	FindBundleByID(ctx context.Context, bundleID uuid.UUID) (*entity.Bundle, error)
 	FindBundleByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.Bundle, error)
+	FindFederatedBundlesByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.Bundle, error)
 	ListBundles(ctx context.Context) ([]*entity.Bundle, error)
 	DeleteBundle(ctx context.Context, bundleID uuid.UUID) error
 	CreateJoinToken(ctx context.Context, req *entity.JoinToken) (*entity.JoinToken, error)
@@ -36,7 +40,80 @@ type Datastore interface {
 	FindRelationshipByID(ctx context.Context, relationshipID uuid.UUID) (*entity.Relationship, error)
 	FindRelationshipsByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.Relationship, error)
 	ListRelationships(ctx context.Context) ([]*entity.Relationship, error)
+	ListExpiredRelationships(ctx context.Context, now time.Time) ([]*entity.Relationship, error)
+	ListRelationshipsWithTrustDomainNames(ctx context.Context) ([]*entity.Relationship, error)
 	DeleteRelationship(ctx context.Context, relationshipID uuid.UUID) error
+	CreateBundleRejection(ctx context.Context, req *entity.BundleRejection) (*entity.BundleRejection, error)
+	FindBundleRejectionsByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.BundleRejection, error)
+	ListBundleRejections(ctx context.Context) ([]*entity.BundleRejection, error)
+	DeleteBundleRejectionsBefore(ctx context.Context, before time.Time) error
+	CreateOrUpdateQuarantinedBundle(ctx context.Context, req *entity.QuarantinedBundle) (*entity.QuarantinedBundle, error)
+	FindQuarantinedBundleByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.QuarantinedBundle, error)
+	ListQuarantinedBundles(ctx context.Context) ([]*entity.QuarantinedBundle, error)
+	DeleteQuarantinedBundle(ctx context.Context, quarantinedBundleID uuid.UUID) error
+	RecordHarvesterSession(ctx context.Context, req *entity.HarvesterSession) (*entity.HarvesterSession, error)
+	FindHarvesterSessionByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.HarvesterSession, error)
+	ListHarvesterSessions(ctx context.Context) ([]*entity.HarvesterSession, error)
+	CreateFederationPolicy(ctx context.Context, req *entity.FederationPolicy) (*entity.FederationPolicy, error)
+	FindFederationPolicyByName(ctx context.Context, name string) (*entity.FederationPolicy, error)
+	ListFederationPolicies(ctx context.Context) ([]*entity.FederationPolicy, error)
+	DeleteFederationPolicy(ctx context.Context, policyID uuid.UUID) error
+	CreateFederationGroup(ctx context.Context, req *entity.FederationGroup) (*entity.FederationGroup, error)
+	FindFederationGroupByName(ctx context.Context, name string) (*entity.FederationGroup, error)
+	ListFederationGroups(ctx context.Context) ([]*entity.FederationGroup, error)
+	DeleteFederationGroup(ctx context.Context, groupID uuid.UUID) error
+	AddFederationGroupMember(ctx context.Context, req *entity.FederationGroupMember) (*entity.FederationGroupMember, error)
+	ListFederationGroupMembers(ctx context.Context, groupID uuid.UUID) ([]*entity.FederationGroupMember, error)
+	RemoveFederationGroupMember(ctx context.Context, groupID, trustDomainID uuid.UUID) error
+	CreatePeer(ctx context.Context, req *entity.Peer) (*entity.Peer, error)
+	FindPeerByName(ctx context.Context, name string) (*entity.Peer, error)
+	ListPeers(ctx context.Context) ([]*entity.Peer, error)
+	DeletePeer(ctx context.Context, peerID uuid.UUID) error
+	AddPeerSharedTrustDomain(ctx context.Context, peerID, trustDomainID uuid.UUID) error
+	ListPeerSharedTrustDomains(ctx context.Context, peerID uuid.UUID) ([]*entity.TrustDomain, error)
+	RemovePeerSharedTrustDomain(ctx context.Context, peerID, trustDomainID uuid.UUID) error
+	CreateWebhook(ctx context.Context, req *entity.Webhook) (*entity.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	CreateWebhookDelivery(ctx context.Context, req *entity.WebhookDelivery) (*entity.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, req *entity.WebhookDelivery) (*entity.WebhookDelivery, error)
+	FindWebhookDeliveryByID(ctx context.Context, deliveryID uuid.UUID) (*entity.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, dead bool) ([]*entity.WebhookDelivery, error)
+	DeleteWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error
+	CreateWatchEvent(ctx context.Context, req *entity.WatchEvent) (*entity.WatchEvent, error)
+	FindResourceVersion(ctx context.Context) (int64, error)
+	ListWatchEvents(ctx context.Context, afterVersion int64, limit int) ([]*entity.WatchEvent, error)
+	DeleteWatchEventsBefore(ctx context.Context, before time.Time) error
+	RecordJobStatus(ctx context.Context, req *entity.JobStatus) (*entity.JobStatus, error)
+	ListJobStatuses(ctx context.Context) ([]*entity.JobStatus, error)
+
+	// TryLock acquires the advisory lock identified by key, which is held until it is released or the connection
+	// holding it is lost, so a single replica of the server sharing the datastore holds it at a time.
+	// The returned Lock is nil if another replica holds it.
+	TryLock(ctx context.Context, key int64) (Lock, error)
+
+	// Notify sends the payload to the listeners of the channel on every replica of the server sharing the
+	// datastore. Within a transaction, the notification is sent when the transaction is committed.
+	Notify(ctx context.Context, channel, payload string) error
+
+	// Listen calls fn with the payload of each notification sent to the channel, until the context is canceled
+	// or the connection to the datastore is lost.
+	Listen(ctx context.Context, channel string, fn func(payload string)) error
+
+	// WithTx runs fn within a transaction. The Datastore passed to fn is bound to the transaction,
+	// which is committed if fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(tx Datastore) error) error
+
+	Close() error
+}
+
+// Lock is an advisory lock acquired with TryLock.
+type Lock interface {
+	// Check returns an error if the lock was lost along with the connection holding it.
+	Check(ctx context.Context) error
+
+	// Release releases the lock.
+	Release(ctx context.Context) error
 }
 
 // SQLDatastore is a SQL database accessor that provides convenient methods
@@ -44,13 +121,15 @@ type Datastore interface {
 type SQLDatastore struct {
 	logger  logrus.FieldLogger
 	db      *sql.DB
-	querier Querier
+	tx      *sql.Tx
+	querier *Queries
 }
 
 // NewSQLDatastore creates a new instance of a Datastore object that connects to a Postgres database
 // parsing the connString.
 // The connString can be a URL, e.g, "postgresql://host...", or a DSN, e.g., "host= user= password= dbname= port=".
-func NewSQLDatastore(logger logrus.FieldLogger, connString string) (*SQLDatastore, error) {
+// The migrationMode defines whether the schema is migrated to the version supported by the app, or just verified.
+func NewSQLDatastore(logger logrus.FieldLogger, connString string, migrationMode MigrationMode) (*SQLDatastore, error) {
 	c, err := pgx.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Postgres Connection URL: %w", err)
@@ -59,7 +138,8 @@ func NewSQLDatastore(logger logrus.FieldLogger, connString string) (*SQLDatastor
 	db := stdlib.OpenDB(*c)
 
 	// validates if the schema in the DB matches the schema supported by the app, and runs the migrations if needed
-	if err = validateAndMigrateSchema(db); err != nil {
+	if err = validateAndMigrateSchema(context.Background(), newMigrator(logger, db), migrationMode); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to validate or migrate schema: %w", err)
 	}
 
@@ -71,9 +151,54 @@ func NewSQLDatastore(logger logrus.FieldLogger, connString string) (*SQLDatastor
 }
 
 func (d *SQLDatastore) Close() error {
+	if d.tx != nil {
+		// the connection pool is owned by the datastore that started the transaction
+		return nil
+	}
 	return d.db.Close()
 }
 
+// WithTx runs fn within a database transaction. If the datastore is already bound to a transaction,
+// fn joins it and the outermost WithTx call decides whether it is committed.
+func (d *SQLDatastore) WithTx(ctx context.Context, fn func(tx Datastore) error) (err error) {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed starting transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			d.logger.WithError(rbErr).Error("Failed to rollback transaction")
+		}
+	}()
+
+	txDatastore := &SQLDatastore{
+		logger:  d.logger,
+		db:      d.db,
+		tx:      tx,
+		querier: d.querier.WithTx(tx),
+	}
+
+	if err = fn(txDatastore); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wrapError("failed committing transaction", err)
+	}
+	committed = true
+
+	return nil
+}
+
 // CreateOrUpdateTrustDomain creates or updates the given TrustDomain in the underlying datastore, based on
 // whether the given entity has an ID, in which case, it is updated.
 func (d *SQLDatastore) CreateOrUpdateTrustDomain(ctx context.Context, req *entity.TrustDomain) (*entity.TrustDomain, error) {
@@ -101,9 +226,17 @@ func (d *SQLDatastore) CreateOrUpdateTrustDomain(ctx context.Context, req *entit
 }
 
 func (d *SQLDatastore) createTrustDomain(ctx context.Context, req *entity.TrustDomain) (*TrustDomain, error) {
+	labels, err := labelsToJSON(req.Labels)
+	if err != nil {
+		return nil, err
+	}
 
 	params := CreateTrustDomainParams{
-		Name: req.Name.String(),
+		Name:          req.Name.String(),
+		PublishBundle: req.PublishBundle,
+		PublishOidc:   req.PublishOIDC,
+		Labels:        labels,
+		PeerID:        pgtype.UUID{Status: pgtype.Null},
 	}
 	if req.Description != "" {
 		params.Description = sql.NullString{
@@ -111,10 +244,16 @@ func (d *SQLDatastore) createTrustDomain(ctx context.Context, req *entity.TrustD
 			Valid:  true,
 		}
 	}
+	if req.PeerID.Valid {
+		params.PeerID, err = uuidToPgType(req.PeerID.UUID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	td, err := d.querier.CreateTrustDomain(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed creating new trust domain: %w", err)
+		return nil, wrapError("failed creating new trust domain", err)
 	}
 	return &td, nil
 }
@@ -125,9 +264,17 @@ func (d *SQLDatastore) updateTrustDomain(ctx context.Context, req *entity.TrustD
 		return nil, err
 	}
 
+	labels, err := labelsToJSON(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
 	params := UpdateTrustDomainParams{
 		ID:               pgID,
 		OnboardingBundle: req.OnboardingBundle,
+		PublishBundle:    req.PublishBundle,
+		PublishOidc:      req.PublishOIDC,
+		Labels:           labels,
 	}
 
 	if req.Description != "" {
@@ -144,6 +291,27 @@ func (d *SQLDatastore) updateTrustDomain(ctx context.Context, req *entity.TrustD
 		}
 	}
 
+	if req.BundleEndpointURL != "" {
+		params.BundleEndpointUrl = sql.NullString{
+			String: req.BundleEndpointURL,
+			Valid:  true,
+		}
+	}
+
+	if req.BundleEndpointProfile != "" {
+		params.BundleEndpointProfile = sql.NullString{
+			String: req.BundleEndpointProfile,
+			Valid:  true,
+		}
+	}
+
+	if !req.BundleEndpointSPIFFEID.IsZero() {
+		params.BundleEndpointSpiffeID = sql.NullString{
+			String: req.BundleEndpointSPIFFEID.String(),
+			Valid:  true,
+		}
+	}
+
 	td, err := d.querier.UpdateTrustDomain(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed updating trust domain: %w", err)
@@ -258,7 +426,7 @@ func (d *SQLDatastore) createBundle(ctx context.Context, req *entity.Bundle) (*B
 
 	bundle, err := d.querier.CreateBundle(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed creating new bundle: %w", err)
+		return nil, wrapError("failed creating new bundle", err)
 	}
 
 	return &bundle, nil
@@ -331,6 +499,31 @@ func (d *SQLDatastore) FindBundleByTrustDomainID(ctx context.Context, trustDomai
 	return td, nil
 }
 
+// FindFederatedBundlesByTrustDomainID returns the bundles of the trust domains trusted by the given trust domain
+// through its relationships, along with their trust domain names, ordered by trust domain name.
+func (d *SQLDatastore) FindFederatedBundlesByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.Bundle, error) {
+	pgID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles, err := d.querier.FindFederatedBundlesByTrustDomainID(ctx, pgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up federated bundles for TrustDomainID %q: %w", trustDomainID, err)
+	}
+
+	result := make([]*entity.Bundle, len(bundles))
+	for i, m := range bundles {
+		b, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting model bundle to entity: %w", err)
+		}
+		result[i] = b
+	}
+
+	return result, nil
+}
+
 func (d *SQLDatastore) ListBundles(ctx context.Context) ([]*entity.Bundle, error) {
 	bundles, err := d.querier.ListBundles(ctx)
 	if err != nil {
@@ -375,7 +568,7 @@ func (d *SQLDatastore) CreateJoinToken(ctx context.Context, req *entity.JoinToke
 	}
 	joinToken, err := d.querier.CreateJoinToken(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed creating join token: %w", err)
+		return nil, wrapError("failed creating join token", err)
 	}
 
 	return joinToken.ToEntity(), nil
@@ -515,11 +708,14 @@ func (d *SQLDatastore) createRelationship(ctx context.Context, req *entity.Relat
 	params := CreateRelationshipParams{
 		TrustDomainAID: pgTrustDomainAID,
 		TrustDomainBID: pgTrustDomainBID,
+		Direction:      string(req.Direction.OrDefault()),
+		NotBefore:      timeToNullTime(req.NotBefore),
+		NotAfter:       timeToNullTime(req.NotAfter),
 	}
 
 	relationship, err := d.querier.CreateRelationship(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed creating new relationship: %w", err)
+		return nil, wrapError("failed creating new relationship", err)
 	}
 
 	return &relationship, nil
@@ -535,6 +731,10 @@ func (d *SQLDatastore) updateRelationship(ctx context.Context, req *entity.Relat
 		ID:                  pgID,
 		TrustDomainAConsent: req.TrustDomainAConsent,
 		TrustDomainBConsent: req.TrustDomainBConsent,
+		Direction:           string(req.Direction.OrDefault()),
+		NotBefore:           timeToNullTime(req.NotBefore),
+		NotAfter:            timeToNullTime(req.NotAfter),
+		ExpiredAt:           timeToNullTime(req.ExpiredAt),
 	}
 
 	relationship, err := d.querier.UpdateRelationship(ctx, params)
@@ -611,6 +811,46 @@ func (d *SQLDatastore) ListRelationships(ctx context.Context) ([]*entity.Relatio
 	return result, nil
 }
 
+// ListExpiredRelationships returns the relationships whose validity ended at or before now and
+// whose expiry was not processed yet, ordered by expiry.
+func (d *SQLDatastore) ListExpiredRelationships(ctx context.Context, now time.Time) ([]*entity.Relationship, error) {
+	relationships, err := d.querier.ListExpiredRelationships(ctx, timeToNullTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up expired relationships: %w", err)
+	}
+
+	result := make([]*entity.Relationship, len(relationships))
+	for i, m := range relationships {
+		ent, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting relationship model to entity: %w", err)
+		}
+		result[i] = ent
+	}
+
+	return result, nil
+}
+
+// ListRelationshipsWithTrustDomainNames returns the relationships along with the names of the trust domains
+// participating in them.
+func (d *SQLDatastore) ListRelationshipsWithTrustDomainNames(ctx context.Context) ([]*entity.Relationship, error) {
+	relationships, err := d.querier.ListRelationshipsWithTrustDomainNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up relationships: %w", err)
+	}
+
+	result := make([]*entity.Relationship, len(relationships))
+	for i, m := range relationships {
+		ent, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting relationship model to entity: %w", err)
+		}
+		result[i] = ent
+	}
+
+	return result, nil
+}
+
 func (d *SQLDatastore) DeleteRelationship(ctx context.Context, relationshipID uuid.UUID) error {
 	pgID, err := uuidToPgType(relationshipID)
 	if err != nil {
@@ -623,3 +863,893 @@ func (d *SQLDatastore) DeleteRelationship(ctx context.Context, relationshipID uu
 
 	return nil
 }
+
+func (d *SQLDatastore) CreateBundleRejection(ctx context.Context, req *entity.BundleRejection) (*entity.BundleRejection, error) {
+	pgTrustDomainID, err := uuidToPgType(req.TrustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	violations, err := json.Marshal(req.Violations)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling bundle violations: %w", err)
+	}
+
+	params := CreateBundleRejectionParams{
+		TrustDomainID: pgTrustDomainID,
+		Digest:        req.Digest,
+		Violations:    violations,
+	}
+
+	rejection, err := d.querier.CreateBundleRejection(ctx, params)
+	if err != nil {
+		return nil, wrapError("failed creating new bundle rejection", err)
+	}
+
+	response, err := rejection.ToEntity()
+	if err != nil {
+		return nil, fmt.Errorf("failed converting bundle rejection model to entity: %w", err)
+	}
+
+	return response, nil
+}
+
+func (d *SQLDatastore) FindBundleRejectionsByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.BundleRejection, error) {
+	pgID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	rejections, err := d.querier.FindBundleRejectionsByTrustDomainID(ctx, pgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up bundle rejections for TrustDomainID %q: %w", trustDomainID, err)
+	}
+
+	result := make([]*entity.BundleRejection, len(rejections))
+	for i, m := range rejections {
+		r, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting bundle rejection model to entity: %w", err)
+		}
+		result[i] = r
+	}
+
+	return result, nil
+}
+
+// ListBundleRejections returns the bundle rejections along with their trust domain names, newest first.
+func (d *SQLDatastore) ListBundleRejections(ctx context.Context) ([]*entity.BundleRejection, error) {
+	rejections, err := d.querier.ListBundleRejections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting bundle rejection list: %w", err)
+	}
+
+	result := make([]*entity.BundleRejection, len(rejections))
+	for i, m := range rejections {
+		r, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting bundle rejection model to entity: %w", err)
+		}
+		result[i] = r
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteBundleRejectionsBefore(ctx context.Context, before time.Time) error {
+	if err := d.querier.DeleteBundleRejectionsBefore(ctx, before); err != nil {
+		return fmt.Errorf("failed deleting bundle rejections: %w", err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) CreateOrUpdateQuarantinedBundle(ctx context.Context, req *entity.QuarantinedBundle) (*entity.QuarantinedBundle, error) {
+	reasons, err := json.Marshal(req.Reasons)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling quarantine reasons: %w", err)
+	}
+
+	var qb QuarantinedBundle
+	if req.ID.Valid {
+		pgID, err := uuidToPgType(req.ID.UUID)
+		if err != nil {
+			return nil, err
+		}
+
+		qb, err = d.querier.UpdateQuarantinedBundle(ctx, UpdateQuarantinedBundleParams{
+			ID:              pgID,
+			Data:            req.Data,
+			Digest:          req.Digest,
+			DigestAlgorithm: req.DigestAlgorithm,
+			Reasons:         reasons,
+			ReleaseAt:       req.ReleaseAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed updating quarantined bundle: %w", err)
+		}
+	} else {
+		pgTrustDomainID, err := uuidToPgType(req.TrustDomainID)
+		if err != nil {
+			return nil, err
+		}
+
+		qb, err = d.querier.CreateQuarantinedBundle(ctx, CreateQuarantinedBundleParams{
+			TrustDomainID:   pgTrustDomainID,
+			Data:            req.Data,
+			Digest:          req.Digest,
+			DigestAlgorithm: req.DigestAlgorithm,
+			Reasons:         reasons,
+			ReleaseAt:       req.ReleaseAt,
+		})
+		if err != nil {
+			return nil, wrapError("failed creating new quarantined bundle", err)
+		}
+	}
+
+	response, err := qb.ToEntity()
+	if err != nil {
+		return nil, fmt.Errorf("failed converting quarantined bundle model to entity: %w", err)
+	}
+
+	return response, nil
+}
+
+func (d *SQLDatastore) FindQuarantinedBundleByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.QuarantinedBundle, error) {
+	pgID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	qb, err := d.querier.FindQuarantinedBundleByTrustDomainID(ctx, pgID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed looking up quarantined bundle for ID=%q: %w", trustDomainID, err)
+	}
+
+	response, err := qb.ToEntity()
+	if err != nil {
+		return nil, fmt.Errorf("failed converting quarantined bundle model to entity: %w", err)
+	}
+
+	return response, nil
+}
+
+// ListQuarantinedBundles returns the quarantined bundles along with their trust domain names, newest first.
+func (d *SQLDatastore) ListQuarantinedBundles(ctx context.Context) ([]*entity.QuarantinedBundle, error) {
+	bundles, err := d.querier.ListQuarantinedBundles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting quarantined bundle list: %w", err)
+	}
+
+	result := make([]*entity.QuarantinedBundle, len(bundles))
+	for i, m := range bundles {
+		qb, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting quarantined bundle model to entity: %w", err)
+		}
+		result[i] = qb
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteQuarantinedBundle(ctx context.Context, quarantinedBundleID uuid.UUID) error {
+	pgID, err := uuidToPgType(quarantinedBundleID)
+	if err != nil {
+		return err
+	}
+
+	if err = d.querier.DeleteQuarantinedBundle(ctx, pgID); err != nil {
+		return fmt.Errorf("failed deleting quarantined bundle with ID=%q: %w", quarantinedBundleID, err)
+	}
+
+	return nil
+}
+
+// RecordHarvesterSession creates or updates the harvester session of the trust domain of the request. The zero
+// call times of the request keep the times already recorded.
+func (d *SQLDatastore) RecordHarvesterSession(ctx context.Context, req *entity.HarvesterSession) (*entity.HarvesterSession, error) {
+	pgTrustDomainID, err := uuidToPgType(req.TrustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := d.querier.UpsertHarvesterSession(ctx, UpsertHarvesterSessionParams{
+		TrustDomainID:    pgTrustDomainID,
+		LastOnboardAt:    timeToNullTime(req.LastOnboardAt),
+		LastPostAt:       timeToNullTime(req.LastPostAt),
+		LastSyncAt:       timeToNullTime(req.LastSyncAt),
+		RemoteAddress:    req.RemoteAddress,
+		HarvesterVersion: req.HarvesterVersion,
+		SpireVersion:     req.SPIREVersion,
+	})
+	if err != nil {
+		return nil, wrapError("failed recording harvester session", err)
+	}
+
+	return hs.ToEntity(), nil
+}
+
+func (d *SQLDatastore) FindHarvesterSessionByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.HarvesterSession, error) {
+	pgID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := d.querier.FindHarvesterSessionByTrustDomainID(ctx, pgID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed looking up harvester session for ID=%q: %w", trustDomainID, err)
+	}
+
+	return hs.ToEntity(), nil
+}
+
+// ListHarvesterSessions returns the harvester sessions along with their trust domain names, newest first.
+func (d *SQLDatastore) ListHarvesterSessions(ctx context.Context) ([]*entity.HarvesterSession, error) {
+	sessions, err := d.querier.ListHarvesterSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting harvester session list: %w", err)
+	}
+
+	result := make([]*entity.HarvesterSession, len(sessions))
+	for i, m := range sessions {
+		hs, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting harvester session model to entity: %w", err)
+		}
+		result[i] = hs
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) CreateFederationPolicy(ctx context.Context, req *entity.FederationPolicy) (*entity.FederationPolicy, error) {
+	selector, err := json.Marshal(req.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling federation policy selector: %w", err)
+	}
+	peerSelector, err := json.Marshal(req.PeerSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling federation policy peer selector: %w", err)
+	}
+
+	p, err := d.querier.CreateFederationPolicy(ctx, CreateFederationPolicyParams{
+		Name:         req.Name,
+		Selector:     selector,
+		PeerSelector: peerSelector,
+	})
+	if err != nil {
+		return nil, wrapError("failed creating new federation policy", err)
+	}
+
+	response, err := p.ToEntity()
+	if err != nil {
+		return nil, fmt.Errorf("failed converting federation policy model to entity: %w", err)
+	}
+
+	return response, nil
+}
+
+func (d *SQLDatastore) FindFederationPolicyByName(ctx context.Context, name string) (*entity.FederationPolicy, error) {
+	p, err := d.querier.FindFederationPolicyByName(ctx, name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed looking up federation policy with name=%q: %w", name, err)
+	}
+
+	response, err := p.ToEntity()
+	if err != nil {
+		return nil, fmt.Errorf("failed converting federation policy model to entity: %w", err)
+	}
+
+	return response, nil
+}
+
+// ListFederationPolicies returns the federation policies ordered by name.
+func (d *SQLDatastore) ListFederationPolicies(ctx context.Context) ([]*entity.FederationPolicy, error) {
+	policies, err := d.querier.ListFederationPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting federation policy list: %w", err)
+	}
+
+	result := make([]*entity.FederationPolicy, len(policies))
+	for i, m := range policies {
+		p, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting federation policy model to entity: %w", err)
+		}
+		result[i] = p
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteFederationPolicy(ctx context.Context, policyID uuid.UUID) error {
+	pgID, err := uuidToPgType(policyID)
+	if err != nil {
+		return err
+	}
+
+	if err = d.querier.DeleteFederationPolicy(ctx, pgID); err != nil {
+		return fmt.Errorf("failed deleting federation policy with ID=%q: %w", policyID, err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) CreateFederationGroup(ctx context.Context, req *entity.FederationGroup) (*entity.FederationGroup, error) {
+	g, err := d.querier.CreateFederationGroup(ctx, CreateFederationGroupParams{
+		Name: req.Name,
+		Mode: string(req.Mode.OrDefault()),
+	})
+	if err != nil {
+		return nil, wrapError("failed creating new federation group", err)
+	}
+
+	return g.ToEntity(), nil
+}
+
+func (d *SQLDatastore) FindFederationGroupByName(ctx context.Context, name string) (*entity.FederationGroup, error) {
+	g, err := d.querier.FindFederationGroupByName(ctx, name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed looking up federation group with name=%q: %w", name, err)
+	}
+
+	return g.ToEntity(), nil
+}
+
+// ListFederationGroups returns the federation groups ordered by name, without their members.
+func (d *SQLDatastore) ListFederationGroups(ctx context.Context) ([]*entity.FederationGroup, error) {
+	groups, err := d.querier.ListFederationGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting federation group list: %w", err)
+	}
+
+	result := make([]*entity.FederationGroup, len(groups))
+	for i, g := range groups {
+		result[i] = g.ToEntity()
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteFederationGroup(ctx context.Context, groupID uuid.UUID) error {
+	pgID, err := uuidToPgType(groupID)
+	if err != nil {
+		return err
+	}
+
+	if err = d.querier.DeleteFederationGroup(ctx, pgID); err != nil {
+		return fmt.Errorf("failed deleting federation group with ID=%q: %w", groupID, err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) AddFederationGroupMember(ctx context.Context, req *entity.FederationGroupMember) (*entity.FederationGroupMember, error) {
+	pgGroupID, err := uuidToPgType(req.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	pgTrustDomainID, err := uuidToPgType(req.TrustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := d.querier.AddFederationGroupMember(ctx, AddFederationGroupMemberParams{
+		GroupID:       pgGroupID,
+		TrustDomainID: pgTrustDomainID,
+		Hub:           req.Hub,
+	})
+	if err != nil {
+		return nil, wrapError("failed adding federation group member", err)
+	}
+
+	return m.ToEntity(), nil
+}
+
+// ListFederationGroupMembers returns the members of the federation group along with their trust domain names,
+// ordered by name.
+func (d *SQLDatastore) ListFederationGroupMembers(ctx context.Context, groupID uuid.UUID) ([]*entity.FederationGroupMember, error) {
+	pgID, err := uuidToPgType(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := d.querier.ListFederationGroupMembers(ctx, pgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting members of federation group with ID=%q: %w", groupID, err)
+	}
+
+	result := make([]*entity.FederationGroupMember, len(members))
+	for i, m := range members {
+		member, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting federation group member model to entity: %w", err)
+		}
+		result[i] = member
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) RemoveFederationGroupMember(ctx context.Context, groupID, trustDomainID uuid.UUID) error {
+	pgGroupID, err := uuidToPgType(groupID)
+	if err != nil {
+		return err
+	}
+	pgTrustDomainID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return err
+	}
+
+	err = d.querier.RemoveFederationGroupMember(ctx, RemoveFederationGroupMemberParams{
+		GroupID:       pgGroupID,
+		TrustDomainID: pgTrustDomainID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed removing trust domain with ID=%q from federation group with ID=%q: %w", trustDomainID, groupID, err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) CreatePeer(ctx context.Context, req *entity.Peer) (*entity.Peer, error) {
+	p, err := d.querier.CreatePeer(ctx, CreatePeerParams{
+		Name: req.Name,
+		Url:  req.URL,
+	})
+	if err != nil {
+		return nil, wrapError("failed creating new peer", err)
+	}
+
+	return p.ToEntity(), nil
+}
+
+func (d *SQLDatastore) FindPeerByName(ctx context.Context, name string) (*entity.Peer, error) {
+	p, err := d.querier.FindPeerByName(ctx, name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed looking up peer with name=%q: %w", name, err)
+	}
+
+	return p.ToEntity(), nil
+}
+
+// ListPeers returns the peers ordered by name, without their trust domains.
+func (d *SQLDatastore) ListPeers(ctx context.Context) ([]*entity.Peer, error) {
+	peers, err := d.querier.ListPeers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting peer list: %w", err)
+	}
+
+	result := make([]*entity.Peer, len(peers))
+	for i, p := range peers {
+		result[i] = p.ToEntity()
+	}
+
+	return result, nil
+}
+
+// DeletePeer deletes the peer along with the trust domains imported from it.
+func (d *SQLDatastore) DeletePeer(ctx context.Context, peerID uuid.UUID) error {
+	pgID, err := uuidToPgType(peerID)
+	if err != nil {
+		return err
+	}
+
+	if err = d.querier.DeletePeer(ctx, pgID); err != nil {
+		return fmt.Errorf("failed deleting peer with ID=%q: %w", peerID, err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) AddPeerSharedTrustDomain(ctx context.Context, peerID, trustDomainID uuid.UUID) error {
+	pgPeerID, err := uuidToPgType(peerID)
+	if err != nil {
+		return err
+	}
+	pgTrustDomainID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return err
+	}
+
+	err = d.querier.AddPeerSharedTrustDomain(ctx, AddPeerSharedTrustDomainParams{
+		PeerID:        pgPeerID,
+		TrustDomainID: pgTrustDomainID,
+	})
+	if err != nil {
+		return wrapError("failed sharing trust domain with peer", err)
+	}
+
+	return nil
+}
+
+// ListPeerSharedTrustDomains returns the trust domains shared with the peer, ordered by name.
+func (d *SQLDatastore) ListPeerSharedTrustDomains(ctx context.Context, peerID uuid.UUID) ([]*entity.TrustDomain, error) {
+	pgID, err := uuidToPgType(peerID)
+	if err != nil {
+		return nil, err
+	}
+
+	trustDomains, err := d.querier.ListPeerSharedTrustDomains(ctx, pgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting trust domains shared with peer with ID=%q: %w", peerID, err)
+	}
+
+	result := make([]*entity.TrustDomain, len(trustDomains))
+	for i, td := range trustDomains {
+		r, err := td.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting trust domain model to entity: %w", err)
+		}
+		result[i] = r
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) RemovePeerSharedTrustDomain(ctx context.Context, peerID, trustDomainID uuid.UUID) error {
+	pgPeerID, err := uuidToPgType(peerID)
+	if err != nil {
+		return err
+	}
+	pgTrustDomainID, err := uuidToPgType(trustDomainID)
+	if err != nil {
+		return err
+	}
+
+	err = d.querier.RemovePeerSharedTrustDomain(ctx, RemovePeerSharedTrustDomainParams{
+		PeerID:        pgPeerID,
+		TrustDomainID: pgTrustDomainID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed removing trust domain with ID=%q from peer with ID=%q: %w", trustDomainID, peerID, err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) CreateWebhook(ctx context.Context, req *entity.Webhook) (*entity.Webhook, error) {
+	eventTypes, err := json.Marshal(req.EventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling webhook event types: %w", err)
+	}
+
+	w, err := d.querier.CreateWebhook(ctx, CreateWebhookParams{
+		Url:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		return nil, wrapError("failed creating new webhook", err)
+	}
+
+	response, err := w.ToEntity()
+	if err != nil {
+		return nil, fmt.Errorf("failed converting webhook model to entity: %w", err)
+	}
+
+	return response, nil
+}
+
+func (d *SQLDatastore) ListWebhooks(ctx context.Context) ([]*entity.Webhook, error) {
+	webhooks, err := d.querier.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting webhook list: %w", err)
+	}
+
+	result := make([]*entity.Webhook, len(webhooks))
+	for i, m := range webhooks {
+		w, err := m.ToEntity()
+		if err != nil {
+			return nil, fmt.Errorf("failed converting webhook model to entity: %w", err)
+		}
+		result[i] = w
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	pgID, err := uuidToPgType(webhookID)
+	if err != nil {
+		return err
+	}
+
+	if err = d.querier.DeleteWebhook(ctx, pgID); err != nil {
+		return fmt.Errorf("failed deleting webhook with ID=%q: %w", webhookID, err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) CreateWebhookDelivery(ctx context.Context, req *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	pgWebhookID, err := uuidToPgType(req.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := d.querier.CreateWebhookDelivery(ctx, CreateWebhookDeliveryParams{
+		WebhookID:     pgWebhookID,
+		EventType:     req.EventType,
+		Payload:       req.Payload,
+		NextAttemptAt: req.NextAttemptAt,
+	})
+	if err != nil {
+		return nil, wrapError("failed creating new webhook delivery", err)
+	}
+
+	return wd.ToEntity(), nil
+}
+
+// UpdateWebhookDelivery updates the attempts, the time of the next attempt, the last error and whether the
+// delivery is dead.
+func (d *SQLDatastore) UpdateWebhookDelivery(ctx context.Context, req *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	pgID, err := uuidToPgType(req.ID.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := d.querier.UpdateWebhookDelivery(ctx, UpdateWebhookDeliveryParams{
+		ID:            pgID,
+		Attempts:      int32(req.Attempts),
+		NextAttemptAt: req.NextAttemptAt,
+		LastError:     req.LastError,
+		Dead:          req.Dead,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed updating webhook delivery: %w", err)
+	}
+
+	return wd.ToEntity(), nil
+}
+
+func (d *SQLDatastore) FindWebhookDeliveryByID(ctx context.Context, deliveryID uuid.UUID) (*entity.WebhookDelivery, error) {
+	pgID, err := uuidToPgType(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := d.querier.FindWebhookDeliveryByID(ctx, pgID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed looking up webhook delivery for ID=%q: %w", deliveryID, err)
+	}
+
+	return wd.ToEntity(), nil
+}
+
+// ListWebhookDeliveries returns either the pending or the dead webhook deliveries along with the URLs of their
+// webhooks, newest first.
+func (d *SQLDatastore) ListWebhookDeliveries(ctx context.Context, dead bool) ([]*entity.WebhookDelivery, error) {
+	deliveries, err := d.querier.ListWebhookDeliveries(ctx, dead)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting webhook delivery list: %w", err)
+	}
+
+	result := make([]*entity.WebhookDelivery, len(deliveries))
+	for i, m := range deliveries {
+		result[i] = m.ToEntity()
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error {
+	pgID, err := uuidToPgType(deliveryID)
+	if err != nil {
+		return err
+	}
+
+	if err = d.querier.DeleteWebhookDelivery(ctx, pgID); err != nil {
+		return fmt.Errorf("failed deleting webhook delivery with ID=%q: %w", deliveryID, err)
+	}
+
+	return nil
+}
+
+// CreateWatchEvent records a change with the next resource version. The resource version stays locked until
+// the transaction ends, so the change and its event must be stored within the same transaction.
+func (d *SQLDatastore) CreateWatchEvent(ctx context.Context, req *entity.WatchEvent) (*entity.WatchEvent, error) {
+	var response *entity.WatchEvent
+	err := d.WithTx(ctx, func(tx Datastore) error {
+		querier := tx.(*SQLDatastore).querier
+
+		version, err := querier.IncrementResourceVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("failed incrementing resource version: %w", err)
+		}
+
+		we, err := querier.CreateWatchEvent(ctx, CreateWatchEventParams{
+			Version: version,
+			Kind:    req.Kind,
+			Type:    req.Type,
+			Object:  req.Object,
+		})
+		if err != nil {
+			return wrapError("failed creating new watch event", err)
+		}
+
+		response = we.ToEntity()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// FindResourceVersion returns the version of the last recorded change.
+func (d *SQLDatastore) FindResourceVersion(ctx context.Context) (int64, error) {
+	version, err := d.querier.FindResourceVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed getting resource version: %w", err)
+	}
+
+	return version, nil
+}
+
+// ListWatchEvents returns up to limit events recorded after the given version, oldest first.
+func (d *SQLDatastore) ListWatchEvents(ctx context.Context, afterVersion int64, limit int) ([]*entity.WatchEvent, error) {
+	events, err := d.querier.ListWatchEvents(ctx, ListWatchEventsParams{
+		Version: afterVersion,
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed getting watch event list: %w", err)
+	}
+
+	result := make([]*entity.WatchEvent, len(events))
+	for i, m := range events {
+		result[i] = m.ToEntity()
+	}
+
+	return result, nil
+}
+
+func (d *SQLDatastore) DeleteWatchEventsBefore(ctx context.Context, before time.Time) error {
+	if err := d.querier.DeleteWatchEventsBefore(ctx, before); err != nil {
+		return fmt.Errorf("failed deleting watch events: %w", err)
+	}
+
+	return nil
+}
+
+func (d *SQLDatastore) RecordJobStatus(ctx context.Context, req *entity.JobStatus) (*entity.JobStatus, error) {
+	js, err := d.querier.UpsertJobStatus(ctx, UpsertJobStatusParams{
+		Name:       req.Name,
+		Schedule:   req.Schedule,
+		StartedAt:  req.StartedAt,
+		FinishedAt: req.FinishedAt,
+		NextRunAt:  req.NextRunAt,
+		Result:     req.Result,
+		Error:      req.Error,
+	})
+	if err != nil {
+		return nil, wrapError("failed recording job status", err)
+	}
+
+	return js.ToEntity(), nil
+}
+
+// ListJobStatuses returns the statuses of the jobs which ran at least once, ordered by name.
+func (d *SQLDatastore) ListJobStatuses(ctx context.Context) ([]*entity.JobStatus, error) {
+	statuses, err := d.querier.ListJobStatuses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting job status list: %w", err)
+	}
+
+	result := make([]*entity.JobStatus, len(statuses))
+	for i, m := range statuses {
+		result[i] = m.ToEntity()
+	}
+
+	return result, nil
+}
+
+// TryLock acquires a session level advisory lock on a connection of its own, which is kept out of the pool until
+// the lock is released.
+func (d *SQLDatastore) TryLock(ctx context.Context, key int64) (Lock, error) {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed acquiring connection: %w", err)
+	}
+
+	querier := New(conn)
+	acquired, err := querier.TryAdvisoryLock(ctx, key)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed acquiring lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+
+	return &sqlLock{key: key, conn: conn, querier: querier}, nil
+}
+
+func (d *SQLDatastore) Notify(ctx context.Context, channel, payload string) error {
+	if err := d.querier.Notify(ctx, NotifyParams{Channel: channel, Payload: payload}); err != nil {
+		return fmt.Errorf("failed sending notification: %w", err)
+	}
+
+	return nil
+}
+
+// Listen listens to the channel on a connection of its own, which is closed rather than returned to the pool.
+func (d *SQLDatastore) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		defer pgConn.Close(context.Background())
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("failed listening to channel %q: %w", channel, err)
+		}
+
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed waiting for notification: %w", err)
+			}
+
+			fn(n.Payload)
+		}
+	})
+}
+
+// sqlLock is an advisory lock held by the session of its connection.
+type sqlLock struct {
+	key     int64
+	conn    *sql.Conn
+	querier *Queries
+}
+
+func (l *sqlLock) Check(ctx context.Context) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("lost connection holding the lock: %w", err)
+	}
+
+	return nil
+}
+
+func (l *sqlLock) Release(ctx context.Context) error {
+	defer l.conn.Close()
+
+	if _, err := l.querier.AdvisoryUnlock(ctx, l.key); err != nil {
+		// the connection is discarded rather than returned to the pool, so the lock is released along with it
+		_ = l.conn.Raw(func(driverConn any) error {
+			return driverConn.(*stdlib.Conn).Close()
+		})
+		return fmt.Errorf("failed releasing lock: %w", err)
+	}
+
+	return nil
+}
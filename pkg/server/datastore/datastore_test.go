@@ -10,6 +10,7 @@ import (
 
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
 	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore/datastoretest"
 	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -34,6 +35,15 @@ var (
 	spiffeTD3 = spiffeid.RequireTrustDomainFromString("baz.test")
 )
 
+func TestSQLDatastoreConformance(t *testing.T) {
+	t.Parallel()
+	datastoretest.Run(t, func(t *testing.T) datastore.Datastore {
+		ds, err := setupDatastore(t)
+		require.NoError(t, err, "Failed to setup the datastore")
+		return ds
+	})
+}
+
 func TestCreateTrustDomain(t *testing.T) {
 	t.Parallel()
 	ds, ctx := setupTest(t)
@@ -823,7 +833,7 @@ func setupDatastore(t *testing.T) (*datastore.SQLDatastore, error) {
 	log := logrus.New()
 
 	conn := startDB(t)
-	datastore, err := datastore.NewSQLDatastore(log, conn)
+	datastore, err := datastore.NewSQLDatastore(log, conn, datastore.MigrationModeAuto)
 	require.NoError(t, err)
 
 	t.Cleanup(func() {
@@ -0,0 +1,1817 @@
+// Package datastoretest provides a conformance test suite that every
+// implementation of the datastore.Datastore interface must pass.
+package datastoretest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewDatastoreFunc returns a new empty Datastore to be tested.
+type NewDatastoreFunc func(t *testing.T) datastore.Datastore
+
+var (
+	td1 = spiffeid.RequireTrustDomainFromString("foo.test")
+	td2 = spiffeid.RequireTrustDomainFromString("bar.test")
+	td3 = spiffeid.RequireTrustDomainFromString("baz.test")
+)
+
+// Run runs the conformance suite against the Datastore returned by newDatastore.
+// A new Datastore is requested for every test.
+func Run(t *testing.T, newDatastore NewDatastoreFunc) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context, t *testing.T, ds datastore.Datastore)
+	}{
+		{"TrustDomainCRUD", testTrustDomainCRUD},
+		{"TrustDomainUniqueName", testTrustDomainUniqueName},
+		{"TrustDomainNotFound", testTrustDomainNotFound},
+		{"TrustDomainLabels", testTrustDomainLabels},
+		{"RelationshipCRUD", testRelationshipCRUD},
+		{"RelationshipUniqueTrustDomains", testRelationshipUniqueTrustDomains},
+		{"RelationshipRequiresTrustDomains", testRelationshipRequiresTrustDomains},
+		{"RelationshipNotFound", testRelationshipNotFound},
+		{"ListRelationshipsWithTrustDomainNames", testListRelationshipsWithTrustDomainNames},
+		{"BundleCRUD", testBundleCRUD},
+		{"BundleUniqueTrustDomain", testBundleUniqueTrustDomain},
+		{"BundleNotFound", testBundleNotFound},
+		{"FederatedBundles", testFederatedBundles},
+		{"FederatedBundlesDirection", testFederatedBundlesDirection},
+		{"FederatedBundlesValidity", testFederatedBundlesValidity},
+		{"ExpiredRelationships", testExpiredRelationships},
+		{"FederationGroupCRUD", testFederationGroupCRUD},
+		{"FederationGroupMembers", testFederationGroupMembers},
+		{"FederatedBundlesMeshGroup", testFederatedBundlesMeshGroup},
+		{"FederatedBundlesHubAndSpokeGroup", testFederatedBundlesHubAndSpokeGroup},
+		{"FederationPolicyCRUD", testFederationPolicyCRUD},
+		{"FederatedBundlesPolicy", testFederatedBundlesPolicy},
+		{"PeerCRUD", testPeerCRUD},
+		{"PeerTrustDomains", testPeerTrustDomains},
+		{"JoinTokenCRUD", testJoinTokenCRUD},
+		{"JoinTokenUniqueToken", testJoinTokenUniqueToken},
+		{"JoinTokenNotFound", testJoinTokenNotFound},
+		{"BundleRejections", testBundleRejections},
+		{"QuarantinedBundleCRUD", testQuarantinedBundleCRUD},
+		{"QuarantinedBundleUniqueTrustDomain", testQuarantinedBundleUniqueTrustDomain},
+		{"HarvesterSessions", testHarvesterSessions},
+		{"WebhookCRUD", testWebhookCRUD},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"WatchEvents", testWatchEvents},
+		{"JobStatuses", testJobStatuses},
+		{"Locks", testLocks},
+		{"Notifications", testNotifications},
+		{"DeleteTrustDomainCascades", testDeleteTrustDomainCascades},
+		{"DeleteTrustDomainWithRelationships", testDeleteTrustDomainWithRelationships},
+		{"WithTxCommit", testWithTxCommit},
+		{"WithTxRollback", testWithTxRollback},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ds := newDatastore(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			tt.fn(ctx, t, ds)
+		})
+	}
+}
+
+func testTrustDomainCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	created := createTrustDomain(ctx, t, ds, td1)
+	assert.Equal(t, td1, created.Name)
+	assert.False(t, created.CreatedAt.IsZero())
+	assert.False(t, created.UpdatedAt.IsZero())
+	assert.False(t, created.PublishBundle)
+	assert.False(t, created.PublishOIDC)
+
+	stored, err := ds.FindTrustDomainByID(ctx, created.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	stored, err = ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	created.Description = "updated description"
+	created.HarvesterSpiffeID = spiffeid.RequireFromString("spiffe://foo.test/harvester")
+	created.OnboardingBundle = []byte{1, 2, 3}
+	created.PublishBundle = true
+	created.PublishOIDC = true
+	created.BundleEndpointURL = "https://foo.test/bundle"
+	created.BundleEndpointProfile = "https_spiffe"
+	created.BundleEndpointSPIFFEID = spiffeid.RequireFromString("spiffe://foo.test/bundle-endpoint")
+
+	updated, err := ds.CreateOrUpdateTrustDomain(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, created.Name, updated.Name)
+	assert.Equal(t, created.Description, updated.Description)
+	assert.Equal(t, created.HarvesterSpiffeID, updated.HarvesterSpiffeID)
+	assert.Equal(t, created.OnboardingBundle, updated.OnboardingBundle)
+	assert.True(t, updated.PublishBundle)
+	assert.True(t, updated.PublishOIDC)
+	assert.Equal(t, created.BundleEndpointURL, updated.BundleEndpointURL)
+	assert.Equal(t, created.BundleEndpointProfile, updated.BundleEndpointProfile)
+	assert.Equal(t, created.BundleEndpointSPIFFEID, updated.BundleEndpointSPIFFEID)
+
+	stored, err = ds.FindTrustDomainByID(ctx, created.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, stored)
+
+	other := createTrustDomain(ctx, t, ds, td2)
+
+	list, err := ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	// trust domains are listed by name
+	assert.Equal(t, other, list[0])
+	assert.Equal(t, updated, list[1])
+
+	require.NoError(t, ds.DeleteTrustDomain(ctx, created.ID.UUID))
+	stored, err = ds.FindTrustDomainByID(ctx, created.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	list, err = ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.TrustDomain{other}, list)
+}
+
+func testTrustDomainUniqueName(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	createTrustDomain(ctx, t, ds, td1)
+
+	_, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+	require.ErrorIs(t, err, datastore.ErrConflict)
+
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{})
+	require.Error(t, err)
+}
+
+func testTrustDomainNotFound(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindTrustDomainByID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	list, err := ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// updating a trust domain that does not exist fails
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{
+		ID:   uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Name: td1,
+	})
+	require.Error(t, err)
+
+	// deleting a trust domain that does not exist is a no-op
+	require.NoError(t, ds.DeleteTrustDomain(ctx, uuid.New()))
+}
+func testTrustDomainLabels(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	created, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{
+		Name:   td1,
+		Labels: map[string]string{"env": "prod", "org": "payments"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "org": "payments"}, created.Labels)
+
+	stored, err := ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	created.Labels = map[string]string{"env": "staging"}
+	updated, err := ds.CreateOrUpdateTrustDomain(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "staging"}, updated.Labels)
+
+	// removing every label leaves the trust domain without labels
+	updated.Labels = map[string]string{}
+	updated, err = ds.CreateOrUpdateTrustDomain(ctx, updated)
+	require.NoError(t, err)
+	assert.Nil(t, updated.Labels)
+
+	stored, err = ds.FindTrustDomainByID(ctx, created.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, stored)
+}
+
+func testRelationshipCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	rel1 := createRelationship(ctx, t, ds, tdA, tdB)
+	assert.Equal(t, tdA.ID.UUID, rel1.TrustDomainAID)
+	assert.Equal(t, tdB.ID.UUID, rel1.TrustDomainBID)
+	assert.False(t, rel1.TrustDomainAConsent)
+	assert.False(t, rel1.TrustDomainBConsent)
+	assert.Equal(t, entity.RelationshipBidirectional, rel1.Direction)
+
+	rel2, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdC.ID.UUID,
+		Direction:      entity.RelationshipATrustsB,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, entity.RelationshipATrustsB, rel2.Direction)
+
+	stored, err := ds.FindRelationshipByID(ctx, rel1.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, rel1, stored)
+
+	rel1.TrustDomainAConsent = true
+	rel1.TrustDomainBConsent = true
+	rel1.Direction = entity.RelationshipBTrustsA
+	updated, err := ds.CreateOrUpdateRelationship(ctx, rel1)
+	require.NoError(t, err)
+	assert.True(t, updated.TrustDomainAConsent)
+	assert.True(t, updated.TrustDomainBConsent)
+	assert.Equal(t, entity.RelationshipBTrustsA, updated.Direction)
+
+	stored, err = ds.FindRelationshipByID(ctx, rel1.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, stored)
+
+	rels, err := ds.FindRelationshipsByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Len(t, rels, 2)
+	assert.Contains(t, rels, updated)
+	assert.Contains(t, rels, rel2)
+
+	// relationships are found from either side
+	rels, err = ds.FindRelationshipsByTrustDomainID(ctx, tdC.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Relationship{rel2}, rels)
+
+	rels, err = ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rels, 2)
+	assert.Contains(t, rels, updated)
+	assert.Contains(t, rels, rel2)
+
+	require.NoError(t, ds.DeleteRelationship(ctx, rel1.ID.UUID))
+	stored, err = ds.FindRelationshipByID(ctx, rel1.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	rels, err = ds.FindRelationshipsByTrustDomainID(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, rels)
+}
+
+func testRelationshipUniqueTrustDomains(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	createRelationship(ctx, t, ds, tdA, tdB)
+
+	_, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdB.ID.UUID,
+	})
+	require.ErrorIs(t, err, datastore.ErrConflict)
+}
+
+func testRelationshipRequiresTrustDomains(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+
+	_, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: uuid.New(),
+	})
+	require.Error(t, err)
+
+	rels, err := ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rels)
+}
+
+func testRelationshipNotFound(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindRelationshipByID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	rels, err := ds.FindRelationshipsByTrustDomainID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, rels)
+
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		ID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	})
+	require.Error(t, err)
+
+	require.NoError(t, ds.DeleteRelationship(ctx, uuid.New()))
+}
+
+func testListRelationshipsWithTrustDomainNames(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	relAB := createRelationship(ctx, t, ds, tdA, tdB)
+	relAB.TrustDomainAName = td1
+	relAB.TrustDomainBName = td2
+
+	relCA := createRelationship(ctx, t, ds, tdC, tdA)
+	relCA.TrustDomainAName = td3
+	relCA.TrustDomainBName = td1
+
+	relationships, err := ds.ListRelationshipsWithTrustDomainNames(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Relationship{relCA, relAB}, relationships)
+}
+
+func testBundleCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	req := &entity.Bundle{
+		Data:               []byte{1, 2, 3},
+		Digest:             []byte{10, 20, 30},
+		Signature:          []byte{4, 2},
+		DigestAlgorithm:    "MD5",
+		SignatureAlgorithm: "RSA",
+		SigningCert:        []byte{50, 60},
+		TrustDomainID:      tdA.ID.UUID,
+	}
+	b1, err := ds.CreateOrUpdateBundle(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, req.Data, b1.Data)
+	assert.Equal(t, req.Digest, b1.Digest)
+	assert.Equal(t, req.Signature, b1.Signature)
+	assert.Equal(t, req.DigestAlgorithm, b1.DigestAlgorithm)
+	assert.Equal(t, req.SignatureAlgorithm, b1.SignatureAlgorithm)
+	assert.Equal(t, req.SigningCert, b1.SigningCert)
+	assert.Equal(t, req.TrustDomainID, b1.TrustDomainID)
+
+	stored, err := ds.FindBundleByID(ctx, b1.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, b1, stored)
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, b1, stored)
+
+	b2, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte{4, 5, 6},
+		Digest:        []byte{40, 50, 60},
+		TrustDomainID: tdB.ID.UUID,
+	})
+	require.NoError(t, err)
+
+	b1.Data = []byte("updated data")
+	b1.Digest = []byte("updated digest")
+	updated, err := ds.CreateOrUpdateBundle(ctx, b1)
+	require.NoError(t, err)
+	assert.Equal(t, b1.ID, updated.ID)
+	assert.Equal(t, b1.Data, updated.Data)
+	assert.Equal(t, b1.Digest, updated.Digest)
+	assert.Equal(t, tdA.ID.UUID, updated.TrustDomainID)
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, stored)
+
+	bundles, err := ds.ListBundles(ctx)
+	require.NoError(t, err)
+	assert.Len(t, bundles, 2)
+	assert.Contains(t, bundles, updated)
+	assert.Contains(t, bundles, b2)
+
+	require.NoError(t, ds.DeleteBundle(ctx, b1.ID.UUID))
+	stored, err = ds.FindBundleByID(ctx, b1.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func testBundleUniqueTrustDomain(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+
+	_, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte{1, 2, 3},
+		Digest:        []byte{10, 20, 30},
+		TrustDomainID: tdA.ID.UUID,
+	})
+	require.NoError(t, err)
+
+	b, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte{4, 5, 6},
+		Digest:        []byte{40, 50, 60},
+		TrustDomainID: tdA.ID.UUID,
+	})
+	require.ErrorIs(t, err, datastore.ErrConflict)
+	assert.Nil(t, b)
+
+	// a bundle cannot reference an unknown trust domain
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte{1, 2, 3},
+		Digest:        []byte{10, 20, 30},
+		TrustDomainID: uuid.New(),
+	})
+	require.Error(t, err)
+}
+
+func testBundleNotFound(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindBundleByID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		ID:     uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Data:   []byte{1, 2, 3},
+		Digest: []byte{10, 20, 30},
+	})
+	require.Error(t, err)
+
+	require.NoError(t, ds.DeleteBundle(ctx, uuid.New()))
+}
+
+func testFederatedBundles(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	bundleA := createBundle(ctx, t, ds, tdA)
+	bundleB := createBundle(ctx, t, ds, tdB)
+	bundleC := createBundle(ctx, t, ds, tdC)
+
+	bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+
+	createRelationship(ctx, t, ds, tdA, tdB)
+	createRelationship(ctx, t, ds, tdC, tdA)
+
+	bundleB.TrustDomainName = td2
+	bundleC.TrustDomainName = td3
+
+	// ordered by trust domain name
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleB, bundleC}, bundles)
+
+	bundleA.TrustDomainName = td1
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdC.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleA}, bundles)
+
+	// trust domains without a bundle are left out
+	require.NoError(t, ds.DeleteBundle(ctx, bundleA.ID.UUID))
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+}
+
+func testFederatedBundlesDirection(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	bundleA := createBundle(ctx, t, ds, tdA)
+	bundleA.TrustDomainName = td1
+	bundleB := createBundle(ctx, t, ds, tdB)
+	bundleB.TrustDomainName = td2
+
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdB.ID.UUID,
+		Direction:      entity.RelationshipATrustsB,
+	})
+	require.NoError(t, err)
+
+	// only the trusting trust domain gets the bundle of its peer
+	bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleB}, bundles)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+
+	rel.Direction = entity.RelationshipBTrustsA
+	_, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleA}, bundles)
+}
+
+func testFederatedBundlesValidity(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	createBundle(ctx, t, ds, tdA)
+	bundleB := createBundle(ctx, t, ds, tdB)
+	bundleB.TrustDomainName = td2
+
+	now := time.Now()
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdB.ID.UUID,
+		NotBefore:      now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(time.Hour), rel.NotBefore, time.Millisecond)
+	assert.True(t, rel.NotAfter.IsZero())
+
+	// a relationship is not in effect before its validity starts
+	bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+
+	rel.NotBefore = now.Add(-time.Hour)
+	rel.NotAfter = now.Add(time.Hour)
+	rel, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleB}, bundles)
+
+	// nor after its validity ends
+	rel.NotAfter = now.Add(-time.Minute)
+	_, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+}
+
+func testExpiredRelationships(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	now := time.Now()
+	relAB, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdB.ID.UUID,
+		NotAfter:       now.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	relAC, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdC.ID.UUID,
+		NotAfter:       now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdB.ID.UUID,
+		TrustDomainBID: tdC.ID.UUID,
+	})
+	require.NoError(t, err)
+
+	expired, err := ds.ListExpiredRelationships(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Relationship{relAC, relAB}, expired)
+
+	expired, err = ds.ListExpiredRelationships(ctx, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Relationship{relAC}, expired)
+
+	// relationships whose expiry was processed are not listed again
+	relAC.ExpiredAt = now
+	relAC, err = ds.CreateOrUpdateRelationship(ctx, relAC)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now, relAC.ExpiredAt, time.Millisecond)
+
+	expired, err = ds.ListExpiredRelationships(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, relAB.ID, expired[0].ID)
+}
+
+func testFederationGroupCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindFederationGroupByName(ctx, "partners")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	created, err := ds.CreateFederationGroup(ctx, &entity.FederationGroup{Name: "partners"})
+	require.NoError(t, err)
+	require.True(t, created.ID.Valid)
+	assert.Equal(t, "partners", created.Name)
+	assert.Equal(t, entity.FederationGroupMesh, created.Mode)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err = ds.FindFederationGroupByName(ctx, "partners")
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// group names are unique
+	_, err = ds.CreateFederationGroup(ctx, &entity.FederationGroup{Name: "partners"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, datastore.ErrConflict))
+
+	other, err := ds.CreateFederationGroup(ctx, &entity.FederationGroup{Name: "branches", Mode: entity.FederationGroupHubAndSpoke})
+	require.NoError(t, err)
+	assert.Equal(t, entity.FederationGroupHubAndSpoke, other.Mode)
+
+	// groups are listed by name
+	list, err := ds.ListFederationGroups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationGroup{other, created}, list)
+
+	require.NoError(t, ds.DeleteFederationGroup(ctx, created.ID.UUID))
+
+	list, err = ds.ListFederationGroups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationGroup{other}, list)
+}
+
+func testFederationGroupMembers(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	group := createFederationGroup(ctx, t, ds, "branches", entity.FederationGroupHubAndSpoke)
+
+	memberA, err := ds.AddFederationGroupMember(ctx, &entity.FederationGroupMember{
+		GroupID:       group.ID.UUID,
+		TrustDomainID: tdA.ID.UUID,
+		Hub:           true,
+	})
+	require.NoError(t, err)
+	require.True(t, memberA.ID.Valid)
+	assert.True(t, memberA.Hub)
+	memberB := addFederationGroupMember(ctx, t, ds, group, tdB)
+
+	// a trust domain is a member of a group at most once
+	_, err = ds.AddFederationGroupMember(ctx, &entity.FederationGroupMember{GroupID: group.ID.UUID, TrustDomainID: tdB.ID.UUID})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, datastore.ErrConflict))
+
+	// and a group has at most one hub
+	_, err = ds.AddFederationGroupMember(ctx, &entity.FederationGroupMember{GroupID: group.ID.UUID, TrustDomainID: tdC.ID.UUID, Hub: true})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, datastore.ErrConflict))
+
+	// members are listed by trust domain name
+	memberA.TrustDomainName = td1
+	memberB.TrustDomainName = td2
+	members, err := ds.ListFederationGroupMembers(ctx, group.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationGroupMember{memberB, memberA}, members)
+
+	require.NoError(t, ds.RemoveFederationGroupMember(ctx, group.ID.UUID, tdB.ID.UUID))
+
+	members, err = ds.ListFederationGroupMembers(ctx, group.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationGroupMember{memberA}, members)
+
+	// memberships are deleted along with their group
+	require.NoError(t, ds.DeleteFederationGroup(ctx, group.ID.UUID))
+
+	members, err = ds.ListFederationGroupMembers(ctx, group.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func testFederatedBundlesMeshGroup(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	bundleA := createBundle(ctx, t, ds, tdA)
+	bundleA.TrustDomainName = td1
+	bundleB := createBundle(ctx, t, ds, tdB)
+	bundleB.TrustDomainName = td2
+	bundleC := createBundle(ctx, t, ds, tdC)
+	bundleC.TrustDomainName = td3
+
+	group := createFederationGroup(ctx, t, ds, "partners", entity.FederationGroupMesh)
+	addFederationGroupMember(ctx, t, ds, group, tdA)
+	addFederationGroupMember(ctx, t, ds, group, tdB)
+	addFederationGroupMember(ctx, t, ds, group, tdC)
+
+	// a peer federated through both a relationship and a group is only returned once
+	createRelationship(ctx, t, ds, tdA, tdB)
+
+	// every member receives the bundles of every other member
+	for _, tt := range []struct {
+		td       *entity.TrustDomain
+		expected []*entity.Bundle
+	}{
+		{tdA, []*entity.Bundle{bundleB, bundleC}},
+		{tdB, []*entity.Bundle{bundleC, bundleA}},
+		{tdC, []*entity.Bundle{bundleB, bundleA}},
+	} {
+		bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tt.td.ID.UUID)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, bundles, "federated bundles of %q", tt.td.Name)
+	}
+
+	require.NoError(t, ds.RemoveFederationGroupMember(ctx, group.ID.UUID, tdC.ID.UUID))
+
+	bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tdC.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleB}, bundles)
+}
+
+func testFederatedBundlesHubAndSpokeGroup(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+	tdC := createTrustDomain(ctx, t, ds, td3)
+
+	bundleA := createBundle(ctx, t, ds, tdA)
+	bundleA.TrustDomainName = td1
+	bundleB := createBundle(ctx, t, ds, tdB)
+	bundleB.TrustDomainName = td2
+	bundleC := createBundle(ctx, t, ds, tdC)
+	bundleC.TrustDomainName = td3
+
+	group := createFederationGroup(ctx, t, ds, "branches", entity.FederationGroupHubAndSpoke)
+	_, err := ds.AddFederationGroupMember(ctx, &entity.FederationGroupMember{
+		GroupID:       group.ID.UUID,
+		TrustDomainID: tdA.ID.UUID,
+		Hub:           true,
+	})
+	require.NoError(t, err)
+	addFederationGroupMember(ctx, t, ds, group, tdB)
+	addFederationGroupMember(ctx, t, ds, group, tdC)
+
+	// the hub receives the bundles of every spoke, which only receive the bundle of the hub
+	for _, tt := range []struct {
+		td       *entity.TrustDomain
+		expected []*entity.Bundle
+	}{
+		{tdA, []*entity.Bundle{bundleB, bundleC}},
+		{tdB, []*entity.Bundle{bundleA}},
+		{tdC, []*entity.Bundle{bundleA}},
+	} {
+		bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tt.td.ID.UUID)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, bundles, "federated bundles of %q", tt.td.Name)
+	}
+}
+func testFederationPolicyCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindFederationPolicyByName(ctx, "prod-shared-services")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	created := createFederationPolicy(ctx, t, ds, "prod-shared-services", entity.LabelSelector{"env": "prod"}, entity.LabelSelector{"role": "shared-services"})
+	assert.Equal(t, "prod-shared-services", created.Name)
+	assert.Equal(t, entity.LabelSelector{"env": "prod"}, created.Selector)
+	assert.Equal(t, entity.LabelSelector{"role": "shared-services"}, created.PeerSelector)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err = ds.FindFederationPolicyByName(ctx, "prod-shared-services")
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// policy names are unique
+	_, err = ds.CreateFederationPolicy(ctx, &entity.FederationPolicy{
+		Name:         "prod-shared-services",
+		Selector:     entity.LabelSelector{"env": "staging"},
+		PeerSelector: entity.LabelSelector{"role": "shared-services"},
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, datastore.ErrConflict))
+
+	other := createFederationPolicy(ctx, t, ds, "payments", entity.LabelSelector{"org": "payments"}, entity.LabelSelector{"org": "payments"})
+
+	// policies are listed by name
+	list, err := ds.ListFederationPolicies(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationPolicy{other, created}, list)
+
+	require.NoError(t, ds.DeleteFederationPolicy(ctx, created.ID.UUID))
+
+	list, err = ds.ListFederationPolicies(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationPolicy{other}, list)
+}
+
+func testFederatedBundlesPolicy(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	newTrustDomain := func(name spiffeid.TrustDomain, labels map[string]string) (*entity.TrustDomain, *entity.Bundle) {
+		td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: name, Labels: labels})
+		require.NoError(t, err)
+		b := createBundle(ctx, t, ds, td)
+		b.TrustDomainName = name
+		return td, b
+	}
+
+	tdA, bundleA := newTrustDomain(td1, map[string]string{"env": "prod", "org": "payments"})
+	tdB, bundleB := newTrustDomain(td2, map[string]string{"env": "prod", "org": "payments"})
+	tdC, bundleC := newTrustDomain(td3, map[string]string{"role": "shared-services"})
+
+	createFederationPolicy(ctx, t, ds, "payments-shared-services", entity.LabelSelector{"env": "prod", "org": "payments"}, entity.LabelSelector{"role": "shared-services"})
+
+	// the selected trust domains and their selected peers receive the bundles of each other, but the trust domains
+	// selected by the same selector are not federated with each other
+	for _, tt := range []struct {
+		td       *entity.TrustDomain
+		expected []*entity.Bundle
+	}{
+		{tdA, []*entity.Bundle{bundleC}},
+		{tdB, []*entity.Bundle{bundleC}},
+		{tdC, []*entity.Bundle{bundleB, bundleA}},
+	} {
+		bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tt.td.ID.UUID)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, bundles, "federated bundles of %q", tt.td.Name)
+	}
+
+	// selectors are evaluated as labels change
+	tdB.Labels = map[string]string{"env": "staging", "org": "payments"}
+	_, err := ds.CreateOrUpdateTrustDomain(ctx, tdB)
+	require.NoError(t, err)
+
+	bundles, err := ds.FindFederatedBundlesByTrustDomainID(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, bundles)
+
+	bundles, err = ds.FindFederatedBundlesByTrustDomainID(ctx, tdC.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Bundle{bundleA}, bundles)
+}
+
+func testPeerCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindPeerByName(ctx, "acme")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	created, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	require.True(t, created.ID.Valid)
+	assert.Equal(t, "acme", created.Name)
+	assert.Equal(t, "https://galadriel.acme.test:8444", created.URL)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err = ds.FindPeerByName(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// peer names are unique
+	_, err = ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://other.acme.test:8444"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, datastore.ErrConflict))
+
+	other, err := ds.CreatePeer(ctx, &entity.Peer{Name: "partner", URL: "https://galadriel.partner.test:8444"})
+	require.NoError(t, err)
+
+	// peers are listed by name
+	list, err := ds.ListPeers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Peer{created, other}, list)
+
+	require.NoError(t, ds.DeletePeer(ctx, created.ID.UUID))
+
+	list, err = ds.ListPeers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Peer{other}, list)
+}
+
+func testPeerTrustDomains(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	// trust domains imported from the peer record it
+	imported, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td3, PeerID: peer.ID})
+	require.NoError(t, err)
+	assert.Equal(t, peer.ID, imported.PeerID)
+	assert.False(t, tdA.PeerID.Valid)
+
+	stored, err := ds.FindTrustDomainByName(ctx, td3)
+	require.NoError(t, err)
+	assert.Equal(t, imported, stored)
+
+	require.NoError(t, ds.AddPeerSharedTrustDomain(ctx, peer.ID.UUID, tdB.ID.UUID))
+	require.NoError(t, ds.AddPeerSharedTrustDomain(ctx, peer.ID.UUID, tdA.ID.UUID))
+
+	// a trust domain is shared with a peer at most once
+	err = ds.AddPeerSharedTrustDomain(ctx, peer.ID.UUID, tdA.ID.UUID)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, datastore.ErrConflict))
+
+	// shared trust domains are listed by name
+	shared, err := ds.ListPeerSharedTrustDomains(ctx, peer.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.TrustDomain{tdB, tdA}, shared)
+
+	require.NoError(t, ds.RemovePeerSharedTrustDomain(ctx, peer.ID.UUID, tdB.ID.UUID))
+
+	shared, err = ds.ListPeerSharedTrustDomains(ctx, peer.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.TrustDomain{tdA}, shared)
+
+	// a peer cannot be deleted while its trust domains are referenced by relationships
+	rel := createRelationship(ctx, t, ds, tdA, imported)
+	require.Error(t, ds.DeletePeer(ctx, peer.ID.UUID))
+	require.NoError(t, ds.DeleteRelationship(ctx, rel.ID.UUID))
+
+	// imported trust domains and shares are deleted along with their peer, shared trust domains are kept
+	require.NoError(t, ds.DeletePeer(ctx, peer.ID.UUID))
+
+	stored, err = ds.FindTrustDomainByName(ctx, td3)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	assert.Equal(t, tdA, stored)
+
+	shared, err = ds.ListPeerSharedTrustDomains(ctx, peer.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, shared)
+}
+
+func testJoinTokenCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	expiry := time.Now().Add(time.Hour)
+	token1 := createJoinToken(ctx, t, ds, tdA, expiry)
+	assert.False(t, token1.Used)
+	assert.Equal(t, tdA.ID.UUID, token1.TrustDomainID)
+	assert.WithinDuration(t, expiry, token1.ExpiresAt, time.Second)
+
+	token2 := createJoinToken(ctx, t, ds, tdB, expiry)
+	token3 := createJoinToken(ctx, t, ds, tdB, expiry)
+
+	stored, err := ds.FindJoinTokensByID(ctx, token1.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, token1, stored)
+
+	stored, err = ds.FindJoinToken(ctx, token2.Token)
+	require.NoError(t, err)
+	assert.Equal(t, token2, stored)
+
+	tokens, err := ds.FindJoinTokensByTrustDomainID(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Contains(t, tokens, token2)
+	assert.Contains(t, tokens, token3)
+
+	tokens, err = ds.ListJoinTokens(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 3)
+
+	updated, err := ds.UpdateJoinToken(ctx, token1.ID.UUID, true)
+	require.NoError(t, err)
+	assert.True(t, updated.Used)
+
+	stored, err = ds.FindJoinTokensByID(ctx, token1.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, stored)
+
+	require.NoError(t, ds.DeleteJoinToken(ctx, token1.ID.UUID))
+	stored, err = ds.FindJoinTokensByID(ctx, token1.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func testJoinTokenUniqueToken(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	token := createJoinToken(ctx, t, ds, tdA, time.Now().Add(time.Hour))
+
+	_, err := ds.CreateJoinToken(ctx, &entity.JoinToken{
+		Token:         token.Token,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		TrustDomainID: tdA.ID.UUID,
+	})
+	require.ErrorIs(t, err, datastore.ErrConflict)
+
+	_, err = ds.CreateJoinToken(ctx, &entity.JoinToken{
+		Token:         uuid.NewString(),
+		ExpiresAt:     time.Now().Add(time.Hour),
+		TrustDomainID: uuid.New(),
+	})
+	require.Error(t, err)
+}
+
+func testJoinTokenNotFound(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	stored, err := ds.FindJoinTokensByID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = ds.FindJoinToken(ctx, uuid.NewString())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	tokens, err := ds.FindJoinTokensByTrustDomainID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	_, err = ds.UpdateJoinToken(ctx, uuid.New(), true)
+	require.Error(t, err)
+
+	require.NoError(t, ds.DeleteJoinToken(ctx, uuid.New()))
+}
+
+func testBundleRejections(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	req := &entity.BundleRejection{
+		TrustDomainID: tdA.ID.UUID,
+		Digest:        []byte{10, 20, 30},
+		Violations: []entity.BundleViolation{
+			{Code: "expired", Message: "X.509 authority expired"},
+			{Code: "weak_key", Message: "RSA key too short"},
+		},
+	}
+	r1, err := ds.CreateBundleRejection(ctx, req)
+	require.NoError(t, err)
+	require.True(t, r1.ID.Valid)
+	assert.Equal(t, req.TrustDomainID, r1.TrustDomainID)
+	assert.Equal(t, req.Digest, r1.Digest)
+	assert.Equal(t, req.Violations, r1.Violations)
+	assert.False(t, r1.CreatedAt.IsZero())
+
+	r2 := createBundleRejection(ctx, t, ds, tdA)
+	r3 := createBundleRejection(ctx, t, ds, tdB)
+
+	// rejections are listed newest first
+	rejections, err := ds.FindBundleRejectionsByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.BundleRejection{r2, r1}, rejections)
+
+	rejections, err = ds.ListBundleRejections(ctx)
+	require.NoError(t, err)
+	require.Len(t, rejections, 3)
+	assert.Equal(t, []uuid.NullUUID{r3.ID, r2.ID, r1.ID}, []uuid.NullUUID{rejections[0].ID, rejections[1].ID, rejections[2].ID})
+	assert.Equal(t, tdB.Name, rejections[0].TrustDomainName)
+	assert.Equal(t, tdA.Name, rejections[2].TrustDomainName)
+
+	// a rejection requires its trust domain
+	_, err = ds.CreateBundleRejection(ctx, &entity.BundleRejection{TrustDomainID: uuid.New(), Digest: []byte{1}})
+	require.Error(t, err)
+
+	// pruned rejections are gone
+	require.NoError(t, ds.DeleteBundleRejectionsBefore(ctx, r2.CreatedAt))
+	rejections, err = ds.ListBundleRejections(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rejections, 2)
+
+	require.NoError(t, ds.DeleteBundleRejectionsBefore(ctx, r3.CreatedAt.Add(time.Second)))
+	rejections, err = ds.ListBundleRejections(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rejections)
+}
+
+func testQuarantinedBundleCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	stored, err := ds.FindQuarantinedBundleByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	releaseAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	req := &entity.QuarantinedBundle{
+		TrustDomainID:   tdA.ID.UUID,
+		Data:            []byte{1, 2, 3},
+		Digest:          []byte{10, 20, 30},
+		DigestAlgorithm: "sha3-256-canonical",
+		Reasons:         []entity.BundleViolation{{Code: "no_shared_authority", Message: "no X.509 authority is kept"}},
+		ReleaseAt:       releaseAt,
+	}
+	created, err := ds.CreateOrUpdateQuarantinedBundle(ctx, req)
+	require.NoError(t, err)
+	require.True(t, created.ID.Valid)
+	assert.Equal(t, req.TrustDomainID, created.TrustDomainID)
+	assert.Equal(t, req.Data, created.Data)
+	assert.Equal(t, req.Digest, created.Digest)
+	assert.Equal(t, req.DigestAlgorithm, created.DigestAlgorithm)
+	assert.Equal(t, req.Reasons, created.Reasons)
+	assert.True(t, releaseAt.Equal(created.ReleaseAt))
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err = ds.FindQuarantinedBundleByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// update
+	created.Data = []byte{4, 5, 6}
+	created.Digest = []byte{40, 50, 60}
+	created.ReleaseAt = releaseAt.Add(time.Hour)
+	updated, err := ds.CreateOrUpdateQuarantinedBundle(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, created.Data, updated.Data)
+	assert.Equal(t, created.Digest, updated.Digest)
+	assert.True(t, created.ReleaseAt.Equal(updated.ReleaseAt))
+
+	qbB, err := ds.CreateOrUpdateQuarantinedBundle(ctx, &entity.QuarantinedBundle{
+		TrustDomainID:   tdB.ID.UUID,
+		Data:            []byte{7, 8, 9},
+		Digest:          []byte{70, 80, 90},
+		DigestAlgorithm: "sha3-256-canonical",
+		Reasons:         []entity.BundleViolation{{Code: "too_many_added_authorities", Message: "3 authorities are added"}},
+		ReleaseAt:       releaseAt,
+	})
+	require.NoError(t, err)
+
+	// quarantined bundles are listed newest first along with the names of their trust domains
+	list, err := ds.ListQuarantinedBundles(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, []uuid.NullUUID{qbB.ID, updated.ID}, []uuid.NullUUID{list[0].ID, list[1].ID})
+	assert.Equal(t, tdB.Name, list[0].TrustDomainName)
+	assert.Equal(t, tdA.Name, list[1].TrustDomainName)
+
+	// delete
+	require.NoError(t, ds.DeleteQuarantinedBundle(ctx, updated.ID.UUID))
+
+	stored, err = ds.FindQuarantinedBundleByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// a quarantined bundle requires its trust domain
+	_, err = ds.CreateOrUpdateQuarantinedBundle(ctx, &entity.QuarantinedBundle{TrustDomainID: uuid.New(), Data: []byte{1}, Digest: []byte{1}})
+	require.Error(t, err)
+}
+
+func testQuarantinedBundleUniqueTrustDomain(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	td := createTrustDomain(ctx, t, ds, td1)
+
+	req := &entity.QuarantinedBundle{
+		TrustDomainID: td.ID.UUID,
+		Data:          []byte{1, 2, 3},
+		Digest:        []byte{10, 20, 30},
+		ReleaseAt:     time.Now(),
+	}
+	_, err := ds.CreateOrUpdateQuarantinedBundle(ctx, req)
+	require.NoError(t, err)
+
+	// a trust domain has at most one quarantined bundle
+	_, err = ds.CreateOrUpdateQuarantinedBundle(ctx, req)
+	require.ErrorIs(t, err, datastore.ErrConflict)
+}
+
+func testHarvesterSessions(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	stored, err := ds.FindHarvesterSessionByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	onboardAt := time.Now().UTC().Truncate(time.Second)
+	created, err := ds.RecordHarvesterSession(ctx, &entity.HarvesterSession{
+		TrustDomainID:    tdA.ID.UUID,
+		LastOnboardAt:    onboardAt,
+		RemoteAddress:    "10.0.0.1",
+		HarvesterVersion: "0.1.0",
+		SPIREVersion:     "1.5.0",
+	})
+	require.NoError(t, err)
+	require.True(t, created.ID.Valid)
+	assert.Equal(t, tdA.ID.UUID, created.TrustDomainID)
+	assert.True(t, onboardAt.Equal(created.LastOnboardAt))
+	assert.True(t, created.LastPostAt.IsZero())
+	assert.True(t, created.LastSyncAt.IsZero())
+	assert.Equal(t, "10.0.0.1", created.RemoteAddress)
+	assert.Equal(t, "0.1.0", created.HarvesterVersion)
+	assert.Equal(t, "1.5.0", created.SPIREVersion)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err = ds.FindHarvesterSessionByTrustDomainID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// recording another call keeps the times of the calls not made, and replaces the address and versions
+	syncAt := onboardAt.Add(time.Minute)
+	updated, err := ds.RecordHarvesterSession(ctx, &entity.HarvesterSession{
+		TrustDomainID:    tdA.ID.UUID,
+		LastSyncAt:       syncAt,
+		RemoteAddress:    "10.0.0.2",
+		HarvesterVersion: "0.2.0",
+		SPIREVersion:     "1.6.0",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.True(t, onboardAt.Equal(updated.LastOnboardAt))
+	assert.True(t, updated.LastPostAt.IsZero())
+	assert.True(t, syncAt.Equal(updated.LastSyncAt))
+	assert.Equal(t, "10.0.0.2", updated.RemoteAddress)
+	assert.Equal(t, "0.2.0", updated.HarvesterVersion)
+	assert.Equal(t, "1.6.0", updated.SPIREVersion)
+	assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+	sessionB := createHarvesterSession(ctx, t, ds, tdB)
+
+	// harvester sessions are listed newest first along with the names of their trust domains
+	list, err := ds.ListHarvesterSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, []uuid.NullUUID{sessionB.ID, updated.ID}, []uuid.NullUUID{list[0].ID, list[1].ID})
+	assert.Equal(t, tdB.Name, list[0].TrustDomainName)
+	assert.Equal(t, tdA.Name, list[1].TrustDomainName)
+
+	// a harvester session requires its trust domain
+	_, err = ds.RecordHarvesterSession(ctx, &entity.HarvesterSession{TrustDomainID: uuid.New(), LastPostAt: time.Now()})
+	require.Error(t, err)
+}
+
+func testWebhookCRUD(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	req := &entity.Webhook{
+		URL:        "https://hooks.example.org/a",
+		Secret:     "secret-a",
+		EventTypes: []string{"trust_domain.created", "bundle.rotated"},
+	}
+	created, err := ds.CreateWebhook(ctx, req)
+	require.NoError(t, err)
+	require.True(t, created.ID.Valid)
+	assert.Equal(t, req.URL, created.URL)
+	assert.Equal(t, req.Secret, created.Secret)
+	assert.Equal(t, req.EventTypes, created.EventTypes)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	other := createWebhook(ctx, t, ds)
+
+	// webhooks are listed newest first
+	list, err := ds.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Webhook{other, created}, list)
+
+	require.NoError(t, ds.DeleteWebhook(ctx, created.ID.UUID))
+
+	list, err = ds.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.Webhook{other}, list)
+}
+
+func testWebhookDeliveries(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	webhookA := createWebhook(ctx, t, ds)
+	webhookB := createWebhook(ctx, t, ds)
+
+	stored, err := ds.FindWebhookDeliveryByID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	nextAttemptAt := time.Now().UTC().Truncate(time.Second)
+	created, err := ds.CreateWebhookDelivery(ctx, &entity.WebhookDelivery{
+		WebhookID:     webhookA.ID.UUID,
+		EventType:     "bundle.rotated",
+		Payload:       []byte(`{"type": "bundle.rotated"}`),
+		NextAttemptAt: nextAttemptAt,
+	})
+	require.NoError(t, err)
+	require.True(t, created.ID.Valid)
+	assert.Equal(t, webhookA.ID.UUID, created.WebhookID)
+	assert.Equal(t, "bundle.rotated", created.EventType)
+	assert.JSONEq(t, `{"type": "bundle.rotated"}`, string(created.Payload))
+	assert.Zero(t, created.Attempts)
+	assert.True(t, nextAttemptAt.Equal(created.NextAttemptAt))
+	assert.False(t, created.Dead)
+
+	stored, err = ds.FindWebhookDeliveryByID(ctx, created.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// update
+	created.Attempts = 3
+	created.NextAttemptAt = nextAttemptAt.Add(time.Minute)
+	created.LastError = "connection refused"
+	created.Dead = true
+	updated, err := ds.UpdateWebhookDelivery(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, 3, updated.Attempts)
+	assert.True(t, created.NextAttemptAt.Equal(updated.NextAttemptAt))
+	assert.Equal(t, "connection refused", updated.LastError)
+	assert.True(t, updated.Dead)
+
+	pending, err := ds.CreateWebhookDelivery(ctx, &entity.WebhookDelivery{
+		WebhookID:     webhookB.ID.UUID,
+		EventType:     "trust_domain.created",
+		Payload:       []byte(`{}`),
+		NextAttemptAt: nextAttemptAt,
+	})
+	require.NoError(t, err)
+
+	// pending and dead deliveries are listed apart, along with the URLs of their webhooks
+	list, err := ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, pending.ID, list[0].ID)
+	assert.Equal(t, webhookB.URL, list[0].WebhookURL)
+
+	list, err = ds.ListWebhookDeliveries(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, updated.ID, list[0].ID)
+	assert.Equal(t, webhookA.URL, list[0].WebhookURL)
+
+	// delete
+	require.NoError(t, ds.DeleteWebhookDelivery(ctx, pending.ID.UUID))
+
+	stored, err = ds.FindWebhookDeliveryByID(ctx, pending.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// deliveries are deleted along with their webhook
+	require.NoError(t, ds.DeleteWebhook(ctx, webhookA.ID.UUID))
+
+	stored, err = ds.FindWebhookDeliveryByID(ctx, updated.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// a delivery requires its webhook
+	_, err = ds.CreateWebhookDelivery(ctx, &entity.WebhookDelivery{WebhookID: uuid.New(), Payload: []byte(`{}`)})
+	require.Error(t, err)
+}
+
+func testWatchEvents(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	version, err := ds.FindResourceVersion(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, version)
+
+	first := createWatchEvent(ctx, t, ds, "created")
+	assert.Equal(t, int64(1), first.Version)
+	assert.Equal(t, "trust_domain", first.Kind)
+	assert.Equal(t, "created", first.Type)
+	assert.JSONEq(t, `{"name": "foo.test"}`, string(first.Object))
+	assert.False(t, first.CreatedAt.IsZero())
+
+	// versions of rolled back events are reused, so there are no gaps
+	err = ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		createWatchEvent(ctx, t, tx, "updated")
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	second := createWatchEvent(ctx, t, ds, "updated")
+	assert.Equal(t, int64(2), second.Version)
+	third := createWatchEvent(ctx, t, ds, "deleted")
+	assert.Equal(t, int64(3), third.Version)
+
+	version, err = ds.FindResourceVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+
+	// events are listed oldest first
+	list, err := ds.ListWatchEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.WatchEvent{first, second, third}, list)
+
+	list, err = ds.ListWatchEvents(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.WatchEvent{second}, list)
+
+	list, err = ds.ListWatchEvents(ctx, 3, 10)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// pruned events are gone, but the resource version is kept
+	require.NoError(t, ds.DeleteWatchEventsBefore(ctx, third.CreatedAt))
+	list, err = ds.ListWatchEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.WatchEvent{third}, list)
+
+	require.NoError(t, ds.DeleteWatchEventsBefore(ctx, third.CreatedAt.Add(time.Second)))
+	list, err = ds.ListWatchEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	version, err = ds.FindResourceVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+}
+
+func testJobStatuses(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	list, err := ds.ListJobStatuses(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	startedAt := time.Now().UTC().Truncate(time.Second)
+	req := &entity.JobStatus{
+		Name:       "prune_watch_events",
+		Schedule:   "5m0s",
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(time.Second),
+		NextRunAt:  startedAt.Add(5 * time.Minute),
+		Result:     "pruned the watch events created before the last hour",
+	}
+	recorded, err := ds.RecordJobStatus(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, req.Name, recorded.Name)
+	assert.Equal(t, req.Schedule, recorded.Schedule)
+	assert.True(t, req.StartedAt.Equal(recorded.StartedAt))
+	assert.True(t, req.FinishedAt.Equal(recorded.FinishedAt))
+	assert.True(t, req.NextRunAt.Equal(recorded.NextRunAt))
+	assert.Equal(t, req.Result, recorded.Result)
+	assert.Empty(t, recorded.Error)
+
+	other, err := ds.RecordJobStatus(ctx, &entity.JobStatus{
+		Name:       "expire_relationships",
+		Schedule:   "30s",
+		StartedAt:  startedAt,
+		FinishedAt: startedAt,
+		NextRunAt:  startedAt.Add(30 * time.Second),
+	})
+	require.NoError(t, err)
+
+	// the status of the last run replaces the previous one
+	req.StartedAt = startedAt.Add(5 * time.Minute)
+	req.FinishedAt = req.StartedAt.Add(time.Second)
+	req.NextRunAt = req.StartedAt.Add(5 * time.Minute)
+	req.Result = ""
+	req.Error = "failed deleting watch events"
+	updated, err := ds.RecordJobStatus(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, req.StartedAt.Equal(updated.StartedAt))
+	assert.Empty(t, updated.Result)
+	assert.Equal(t, req.Error, updated.Error)
+
+	// statuses are listed by name
+	list, err = ds.ListJobStatuses(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.JobStatus{other, updated}, list)
+}
+
+func testLocks(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	lock, err := ds.TryLock(ctx, 42)
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+	require.NoError(t, lock.Check(ctx))
+
+	// the lock is held until it is released
+	held, err := ds.TryLock(ctx, 42)
+	require.NoError(t, err)
+	assert.Nil(t, held)
+
+	other, err := ds.TryLock(ctx, 43)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, lock.Release(ctx))
+
+	lock, err = ds.TryLock(ctx, 42)
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+	require.NoError(t, lock.Release(ctx))
+}
+
+func testNotifications(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	payloads := make(chan string, 100)
+	listenCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- ds.Listen(listenCtx, "changes", func(payload string) {
+			payloads <- payload
+		})
+	}()
+
+	// notifications sent before the listener listens are not received
+	require.Eventually(t, func() bool {
+		assert.NoError(t, ds.Notify(ctx, "changes", "ready"))
+		select {
+		case p := <-payloads:
+			return p == "ready"
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	next := func() string {
+		for {
+			select {
+			case p := <-payloads:
+				if p != "ready" {
+					return p
+				}
+			case <-time.After(5 * time.Second):
+				return ""
+			}
+		}
+	}
+
+	// notifications sent within a transaction are sent once it is committed
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		require.NoError(t, tx.Notify(ctx, "changes", "rolled back"))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	err = ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		return tx.Notify(ctx, "changes", "committed")
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ds.Notify(ctx, "other", "ignored"))
+	require.NoError(t, ds.Notify(ctx, "changes", "sent"))
+
+	assert.Equal(t, "committed", next())
+	assert.Equal(t, "sent", next())
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func testDeleteTrustDomainCascades(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	bundleA, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte{1, 2, 3},
+		Digest:        []byte{10, 20, 30},
+		TrustDomainID: tdA.ID.UUID,
+	})
+	require.NoError(t, err)
+	bundleB, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte{4, 5, 6},
+		Digest:        []byte{40, 50, 60},
+		TrustDomainID: tdB.ID.UUID,
+	})
+	require.NoError(t, err)
+
+	tokenA := createJoinToken(ctx, t, ds, tdA, time.Now().Add(time.Hour))
+	tokenB := createJoinToken(ctx, t, ds, tdB, time.Now().Add(time.Hour))
+
+	createBundleRejection(ctx, t, ds, tdA)
+	rejectionB := createBundleRejection(ctx, t, ds, tdB)
+
+	createQuarantinedBundle(ctx, t, ds, tdA)
+	quarantinedB := createQuarantinedBundle(ctx, t, ds, tdB)
+
+	createHarvesterSession(ctx, t, ds, tdA)
+	sessionB := createHarvesterSession(ctx, t, ds, tdB)
+
+	group := createFederationGroup(ctx, t, ds, "partners", entity.FederationGroupMesh)
+	addFederationGroupMember(ctx, t, ds, group, tdA)
+	memberB := addFederationGroupMember(ctx, t, ds, group, tdB)
+	memberB.TrustDomainName = td2
+
+	require.NoError(t, ds.DeleteTrustDomain(ctx, tdA.ID.UUID))
+
+	// bundle, join tokens, bundle rejections, quarantined bundle, harvester session and group memberships of the
+	// deleted trust domain are deleted along with it
+	stored, err := ds.FindBundleByID(ctx, bundleA.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	token, err := ds.FindJoinTokensByID(ctx, tokenA.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	// everything else is left untouched
+	stored, err = ds.FindBundleByID(ctx, bundleB.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, bundleB, stored)
+
+	token, err = ds.FindJoinTokensByID(ctx, tokenB.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, tokenB, token)
+
+	rejections, err := ds.ListBundleRejections(ctx)
+	require.NoError(t, err)
+	require.Len(t, rejections, 1)
+	assert.Equal(t, rejectionB.ID, rejections[0].ID)
+
+	quarantined, err := ds.ListQuarantinedBundles(ctx)
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, quarantinedB.ID, quarantined[0].ID)
+
+	sessions, err := ds.ListHarvesterSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, sessionB.ID, sessions[0].ID)
+
+	members, err := ds.ListFederationGroupMembers(ctx, group.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.FederationGroupMember{memberB}, members)
+}
+
+func testDeleteTrustDomainWithRelationships(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+	tdB := createTrustDomain(ctx, t, ds, td2)
+
+	rel := createRelationship(ctx, t, ds, tdA, tdB)
+
+	// a trust domain cannot be deleted while it participates in a relationship
+	require.Error(t, ds.DeleteTrustDomain(ctx, tdA.ID.UUID))
+	require.Error(t, ds.DeleteTrustDomain(ctx, tdB.ID.UUID))
+
+	stored, err := ds.FindTrustDomainByID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, tdA, stored)
+
+	require.NoError(t, ds.DeleteRelationship(ctx, rel.ID.UUID))
+	require.NoError(t, ds.DeleteTrustDomain(ctx, tdA.ID.UUID))
+}
+
+func testWithTxCommit(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	var tdA, tdB *entity.TrustDomain
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		tdA = createTrustDomain(ctx, t, tx, td1)
+		tdB = createTrustDomain(ctx, t, tx, td2)
+
+		// writes are visible within the transaction
+		stored, err := tx.FindTrustDomainByName(ctx, td1)
+		require.NoError(t, err)
+		assert.Equal(t, tdA, stored)
+
+		// nested transactions join the outer one
+		return tx.WithTx(ctx, func(tx datastore.Datastore) error {
+			createRelationship(ctx, t, tx, tdA, tdB)
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	list, err := ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.TrustDomain{tdB, tdA}, list)
+
+	relationships, err := ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Len(t, relationships, 1)
+}
+
+func testWithTxRollback(ctx context.Context, t *testing.T, ds datastore.Datastore) {
+	tdA := createTrustDomain(ctx, t, ds, td1)
+
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		createTrustDomain(ctx, t, tx, td2)
+
+		tdA.Description = "updated description"
+		_, err := tx.CreateOrUpdateTrustDomain(ctx, tdA)
+		require.NoError(t, err)
+
+		// fails and aborts the transaction
+		_, err = tx.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td1})
+		return err
+	})
+	require.ErrorIs(t, err, datastore.ErrConflict)
+
+	stored, err := ds.FindTrustDomainByName(ctx, td2)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	stored, err = ds.FindTrustDomainByID(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Description)
+}
+
+func createTrustDomain(ctx context.Context, t *testing.T, ds datastore.Datastore, name spiffeid.TrustDomain) *entity.TrustDomain {
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: name})
+	require.NoError(t, err)
+	require.True(t, td.ID.Valid)
+
+	return td
+}
+
+func createWatchEvent(ctx context.Context, t *testing.T, ds datastore.Datastore, eventType string) *entity.WatchEvent {
+	we, err := ds.CreateWatchEvent(ctx, &entity.WatchEvent{
+		Kind:   "trust_domain",
+		Type:   eventType,
+		Object: []byte(`{"name": "foo.test"}`),
+	})
+	require.NoError(t, err)
+
+	return we
+}
+
+func createRelationship(ctx context.Context, t *testing.T, ds datastore.Datastore, a, b *entity.TrustDomain) *entity.Relationship {
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: a.ID.UUID,
+		TrustDomainBID: b.ID.UUID,
+	})
+	require.NoError(t, err)
+	require.True(t, rel.ID.Valid)
+
+	return rel
+}
+
+func createFederationGroup(ctx context.Context, t *testing.T, ds datastore.Datastore, name string, mode entity.FederationGroupMode) *entity.FederationGroup {
+	g, err := ds.CreateFederationGroup(ctx, &entity.FederationGroup{Name: name, Mode: mode})
+	require.NoError(t, err)
+	require.True(t, g.ID.Valid)
+
+	return g
+}
+
+func addFederationGroupMember(ctx context.Context, t *testing.T, ds datastore.Datastore, g *entity.FederationGroup, td *entity.TrustDomain) *entity.FederationGroupMember {
+	m, err := ds.AddFederationGroupMember(ctx, &entity.FederationGroupMember{
+		GroupID:       g.ID.UUID,
+		TrustDomainID: td.ID.UUID,
+	})
+	require.NoError(t, err)
+	require.True(t, m.ID.Valid)
+
+	return m
+}
+
+func createFederationPolicy(ctx context.Context, t *testing.T, ds datastore.Datastore, name string, selector, peerSelector entity.LabelSelector) *entity.FederationPolicy {
+	p, err := ds.CreateFederationPolicy(ctx, &entity.FederationPolicy{
+		Name:         name,
+		Selector:     selector,
+		PeerSelector: peerSelector,
+	})
+	require.NoError(t, err)
+	require.True(t, p.ID.Valid)
+
+	return p
+}
+
+func createBundle(ctx context.Context, t *testing.T, ds datastore.Datastore, td *entity.TrustDomain) *entity.Bundle {
+	b, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{
+		Data:          []byte(td.Name.String()),
+		Digest:        []byte(td.ID.UUID.String()),
+		TrustDomainID: td.ID.UUID,
+	})
+	require.NoError(t, err)
+	require.True(t, b.ID.Valid)
+
+	return b
+}
+
+func createJoinToken(ctx context.Context, t *testing.T, ds datastore.Datastore, td *entity.TrustDomain, expiry time.Time) *entity.JoinToken {
+	jt, err := ds.CreateJoinToken(ctx, &entity.JoinToken{
+		Token:         uuid.NewString(),
+		ExpiresAt:     expiry,
+		TrustDomainID: td.ID.UUID,
+	})
+	require.NoError(t, err)
+	require.True(t, jt.ID.Valid)
+
+	return jt
+}
+
+func createBundleRejection(ctx context.Context, t *testing.T, ds datastore.Datastore, td *entity.TrustDomain) *entity.BundleRejection {
+	br, err := ds.CreateBundleRejection(ctx, &entity.BundleRejection{
+		TrustDomainID: td.ID.UUID,
+		Digest:        []byte(td.ID.UUID.String()),
+		Violations:    []entity.BundleViolation{{Code: "no_authorities", Message: "the bundle has no authorities"}},
+	})
+	require.NoError(t, err)
+	require.True(t, br.ID.Valid)
+
+	return br
+}
+
+func createQuarantinedBundle(ctx context.Context, t *testing.T, ds datastore.Datastore, td *entity.TrustDomain) *entity.QuarantinedBundle {
+	qb, err := ds.CreateOrUpdateQuarantinedBundle(ctx, &entity.QuarantinedBundle{
+		TrustDomainID:   td.ID.UUID,
+		Data:            []byte(td.Name.String()),
+		Digest:          []byte(td.ID.UUID.String()),
+		DigestAlgorithm: "sha3-256-canonical",
+		Reasons:         []entity.BundleViolation{{Code: "no_shared_authority", Message: "no X.509 authority is kept"}},
+		ReleaseAt:       time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.True(t, qb.ID.Valid)
+
+	return qb
+}
+
+func createHarvesterSession(ctx context.Context, t *testing.T, ds datastore.Datastore, td *entity.TrustDomain) *entity.HarvesterSession {
+	hs, err := ds.RecordHarvesterSession(ctx, &entity.HarvesterSession{
+		TrustDomainID: td.ID.UUID,
+		LastOnboardAt: time.Now(),
+		RemoteAddress: "127.0.0.1",
+	})
+	require.NoError(t, err)
+	require.True(t, hs.ID.Valid)
+
+	return hs
+}
+
+func createWebhook(ctx context.Context, t *testing.T, ds datastore.Datastore) *entity.Webhook {
+	w, err := ds.CreateWebhook(ctx, &entity.Webhook{
+		URL:        "https://hooks.example.org/" + uuid.NewString(),
+		Secret:     uuid.NewString(),
+		EventTypes: []string{"bundle.rotated"},
+	})
+	require.NoError(t, err)
+	require.True(t, w.ID.Valid)
+
+	return w
+}
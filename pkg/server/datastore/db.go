@@ -24,36 +24,108 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addFederationGroupMemberStmt, err = db.PrepareContext(ctx, addFederationGroupMember); err != nil {
+		return nil, fmt.Errorf("error preparing query AddFederationGroupMember: %w", err)
+	}
+	if q.addPeerSharedTrustDomainStmt, err = db.PrepareContext(ctx, addPeerSharedTrustDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query AddPeerSharedTrustDomain: %w", err)
+	}
+	if q.advisoryUnlockStmt, err = db.PrepareContext(ctx, advisoryUnlock); err != nil {
+		return nil, fmt.Errorf("error preparing query AdvisoryUnlock: %w", err)
+	}
 	if q.createBundleStmt, err = db.PrepareContext(ctx, createBundle); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateBundle: %w", err)
 	}
+	if q.createBundleRejectionStmt, err = db.PrepareContext(ctx, createBundleRejection); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBundleRejection: %w", err)
+	}
+	if q.createFederationGroupStmt, err = db.PrepareContext(ctx, createFederationGroup); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFederationGroup: %w", err)
+	}
+	if q.createFederationPolicyStmt, err = db.PrepareContext(ctx, createFederationPolicy); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFederationPolicy: %w", err)
+	}
 	if q.createJoinTokenStmt, err = db.PrepareContext(ctx, createJoinToken); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateJoinToken: %w", err)
 	}
+	if q.createPeerStmt, err = db.PrepareContext(ctx, createPeer); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePeer: %w", err)
+	}
+	if q.createQuarantinedBundleStmt, err = db.PrepareContext(ctx, createQuarantinedBundle); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateQuarantinedBundle: %w", err)
+	}
 	if q.createRelationshipStmt, err = db.PrepareContext(ctx, createRelationship); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateRelationship: %w", err)
 	}
 	if q.createTrustDomainStmt, err = db.PrepareContext(ctx, createTrustDomain); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateTrustDomain: %w", err)
 	}
+	if q.createWatchEventStmt, err = db.PrepareContext(ctx, createWatchEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWatchEvent: %w", err)
+	}
+	if q.createWebhookStmt, err = db.PrepareContext(ctx, createWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhook: %w", err)
+	}
+	if q.createWebhookDeliveryStmt, err = db.PrepareContext(ctx, createWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhookDelivery: %w", err)
+	}
 	if q.deleteBundleStmt, err = db.PrepareContext(ctx, deleteBundle); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteBundle: %w", err)
 	}
+	if q.deleteBundleRejectionsBeforeStmt, err = db.PrepareContext(ctx, deleteBundleRejectionsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteBundleRejectionsBefore: %w", err)
+	}
+	if q.deleteFederationGroupStmt, err = db.PrepareContext(ctx, deleteFederationGroup); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFederationGroup: %w", err)
+	}
+	if q.deleteFederationPolicyStmt, err = db.PrepareContext(ctx, deleteFederationPolicy); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFederationPolicy: %w", err)
+	}
 	if q.deleteJoinTokenStmt, err = db.PrepareContext(ctx, deleteJoinToken); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteJoinToken: %w", err)
 	}
+	if q.deletePeerStmt, err = db.PrepareContext(ctx, deletePeer); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePeer: %w", err)
+	}
+	if q.deleteQuarantinedBundleStmt, err = db.PrepareContext(ctx, deleteQuarantinedBundle); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteQuarantinedBundle: %w", err)
+	}
 	if q.deleteRelationshipStmt, err = db.PrepareContext(ctx, deleteRelationship); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteRelationship: %w", err)
 	}
 	if q.deleteTrustDomainStmt, err = db.PrepareContext(ctx, deleteTrustDomain); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteTrustDomain: %w", err)
 	}
+	if q.deleteWatchEventsBeforeStmt, err = db.PrepareContext(ctx, deleteWatchEventsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWatchEventsBefore: %w", err)
+	}
+	if q.deleteWebhookStmt, err = db.PrepareContext(ctx, deleteWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhook: %w", err)
+	}
+	if q.deleteWebhookDeliveryStmt, err = db.PrepareContext(ctx, deleteWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookDelivery: %w", err)
+	}
 	if q.findBundleByIDStmt, err = db.PrepareContext(ctx, findBundleByID); err != nil {
 		return nil, fmt.Errorf("error preparing query FindBundleByID: %w", err)
 	}
 	if q.findBundleByTrustDomainIDStmt, err = db.PrepareContext(ctx, findBundleByTrustDomainID); err != nil {
 		return nil, fmt.Errorf("error preparing query FindBundleByTrustDomainID: %w", err)
 	}
+	if q.findBundleRejectionsByTrustDomainIDStmt, err = db.PrepareContext(ctx, findBundleRejectionsByTrustDomainID); err != nil {
+		return nil, fmt.Errorf("error preparing query FindBundleRejectionsByTrustDomainID: %w", err)
+	}
+	if q.findFederatedBundlesByTrustDomainIDStmt, err = db.PrepareContext(ctx, findFederatedBundlesByTrustDomainID); err != nil {
+		return nil, fmt.Errorf("error preparing query FindFederatedBundlesByTrustDomainID: %w", err)
+	}
+	if q.findFederationGroupByNameStmt, err = db.PrepareContext(ctx, findFederationGroupByName); err != nil {
+		return nil, fmt.Errorf("error preparing query FindFederationGroupByName: %w", err)
+	}
+	if q.findFederationPolicyByNameStmt, err = db.PrepareContext(ctx, findFederationPolicyByName); err != nil {
+		return nil, fmt.Errorf("error preparing query FindFederationPolicyByName: %w", err)
+	}
+	if q.findHarvesterSessionByTrustDomainIDStmt, err = db.PrepareContext(ctx, findHarvesterSessionByTrustDomainID); err != nil {
+		return nil, fmt.Errorf("error preparing query FindHarvesterSessionByTrustDomainID: %w", err)
+	}
 	if q.findJoinTokenStmt, err = db.PrepareContext(ctx, findJoinToken); err != nil {
 		return nil, fmt.Errorf("error preparing query FindJoinToken: %w", err)
 	}
@@ -63,57 +135,178 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.findJoinTokensByTrustDomainIDStmt, err = db.PrepareContext(ctx, findJoinTokensByTrustDomainID); err != nil {
 		return nil, fmt.Errorf("error preparing query FindJoinTokensByTrustDomainID: %w", err)
 	}
+	if q.findPeerByNameStmt, err = db.PrepareContext(ctx, findPeerByName); err != nil {
+		return nil, fmt.Errorf("error preparing query FindPeerByName: %w", err)
+	}
+	if q.findQuarantinedBundleByTrustDomainIDStmt, err = db.PrepareContext(ctx, findQuarantinedBundleByTrustDomainID); err != nil {
+		return nil, fmt.Errorf("error preparing query FindQuarantinedBundleByTrustDomainID: %w", err)
+	}
 	if q.findRelationshipByIDStmt, err = db.PrepareContext(ctx, findRelationshipByID); err != nil {
 		return nil, fmt.Errorf("error preparing query FindRelationshipByID: %w", err)
 	}
 	if q.findRelationshipsByTrustDomainIDStmt, err = db.PrepareContext(ctx, findRelationshipsByTrustDomainID); err != nil {
 		return nil, fmt.Errorf("error preparing query FindRelationshipsByTrustDomainID: %w", err)
 	}
+	if q.findResourceVersionStmt, err = db.PrepareContext(ctx, findResourceVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query FindResourceVersion: %w", err)
+	}
 	if q.findTrustDomainByIDStmt, err = db.PrepareContext(ctx, findTrustDomainByID); err != nil {
 		return nil, fmt.Errorf("error preparing query FindTrustDomainByID: %w", err)
 	}
 	if q.findTrustDomainByNameStmt, err = db.PrepareContext(ctx, findTrustDomainByName); err != nil {
 		return nil, fmt.Errorf("error preparing query FindTrustDomainByName: %w", err)
 	}
+	if q.findWebhookDeliveryByIDStmt, err = db.PrepareContext(ctx, findWebhookDeliveryByID); err != nil {
+		return nil, fmt.Errorf("error preparing query FindWebhookDeliveryByID: %w", err)
+	}
+	if q.incrementResourceVersionStmt, err = db.PrepareContext(ctx, incrementResourceVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query IncrementResourceVersion: %w", err)
+	}
+	if q.listBundleRejectionsStmt, err = db.PrepareContext(ctx, listBundleRejections); err != nil {
+		return nil, fmt.Errorf("error preparing query ListBundleRejections: %w", err)
+	}
 	if q.listBundlesStmt, err = db.PrepareContext(ctx, listBundles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListBundles: %w", err)
 	}
+	if q.listExpiredRelationshipsStmt, err = db.PrepareContext(ctx, listExpiredRelationships); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpiredRelationships: %w", err)
+	}
+	if q.listFederationGroupMembersStmt, err = db.PrepareContext(ctx, listFederationGroupMembers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFederationGroupMembers: %w", err)
+	}
+	if q.listFederationGroupsStmt, err = db.PrepareContext(ctx, listFederationGroups); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFederationGroups: %w", err)
+	}
+	if q.listFederationPoliciesStmt, err = db.PrepareContext(ctx, listFederationPolicies); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFederationPolicies: %w", err)
+	}
+	if q.listHarvesterSessionsStmt, err = db.PrepareContext(ctx, listHarvesterSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListHarvesterSessions: %w", err)
+	}
+	if q.listJobStatusesStmt, err = db.PrepareContext(ctx, listJobStatuses); err != nil {
+		return nil, fmt.Errorf("error preparing query ListJobStatuses: %w", err)
+	}
 	if q.listJoinTokensStmt, err = db.PrepareContext(ctx, listJoinTokens); err != nil {
 		return nil, fmt.Errorf("error preparing query ListJoinTokens: %w", err)
 	}
+	if q.listPeerSharedTrustDomainsStmt, err = db.PrepareContext(ctx, listPeerSharedTrustDomains); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPeerSharedTrustDomains: %w", err)
+	}
+	if q.listPeersStmt, err = db.PrepareContext(ctx, listPeers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPeers: %w", err)
+	}
+	if q.listQuarantinedBundlesStmt, err = db.PrepareContext(ctx, listQuarantinedBundles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListQuarantinedBundles: %w", err)
+	}
 	if q.listRelationshipsStmt, err = db.PrepareContext(ctx, listRelationships); err != nil {
 		return nil, fmt.Errorf("error preparing query ListRelationships: %w", err)
 	}
+	if q.listRelationshipsWithTrustDomainNamesStmt, err = db.PrepareContext(ctx, listRelationshipsWithTrustDomainNames); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRelationshipsWithTrustDomainNames: %w", err)
+	}
 	if q.listTrustDomainsStmt, err = db.PrepareContext(ctx, listTrustDomains); err != nil {
 		return nil, fmt.Errorf("error preparing query ListTrustDomains: %w", err)
 	}
+	if q.listWatchEventsStmt, err = db.PrepareContext(ctx, listWatchEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWatchEvents: %w", err)
+	}
+	if q.listWebhookDeliveriesStmt, err = db.PrepareContext(ctx, listWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWebhookDeliveries: %w", err)
+	}
+	if q.listWebhooksStmt, err = db.PrepareContext(ctx, listWebhooks); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWebhooks: %w", err)
+	}
+	if q.notifyStmt, err = db.PrepareContext(ctx, notify); err != nil {
+		return nil, fmt.Errorf("error preparing query Notify: %w", err)
+	}
+	if q.removeFederationGroupMemberStmt, err = db.PrepareContext(ctx, removeFederationGroupMember); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveFederationGroupMember: %w", err)
+	}
+	if q.removePeerSharedTrustDomainStmt, err = db.PrepareContext(ctx, removePeerSharedTrustDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query RemovePeerSharedTrustDomain: %w", err)
+	}
+	if q.tryAdvisoryLockStmt, err = db.PrepareContext(ctx, tryAdvisoryLock); err != nil {
+		return nil, fmt.Errorf("error preparing query TryAdvisoryLock: %w", err)
+	}
 	if q.updateBundleStmt, err = db.PrepareContext(ctx, updateBundle); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateBundle: %w", err)
 	}
 	if q.updateJoinTokenStmt, err = db.PrepareContext(ctx, updateJoinToken); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateJoinToken: %w", err)
 	}
+	if q.updateQuarantinedBundleStmt, err = db.PrepareContext(ctx, updateQuarantinedBundle); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateQuarantinedBundle: %w", err)
+	}
 	if q.updateRelationshipStmt, err = db.PrepareContext(ctx, updateRelationship); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateRelationship: %w", err)
 	}
 	if q.updateTrustDomainStmt, err = db.PrepareContext(ctx, updateTrustDomain); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateTrustDomain: %w", err)
 	}
+	if q.updateWebhookDeliveryStmt, err = db.PrepareContext(ctx, updateWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateWebhookDelivery: %w", err)
+	}
+	if q.upsertHarvesterSessionStmt, err = db.PrepareContext(ctx, upsertHarvesterSession); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertHarvesterSession: %w", err)
+	}
+	if q.upsertJobStatusStmt, err = db.PrepareContext(ctx, upsertJobStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertJobStatus: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addFederationGroupMemberStmt != nil {
+		if cerr := q.addFederationGroupMemberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addFederationGroupMemberStmt: %w", cerr)
+		}
+	}
+	if q.addPeerSharedTrustDomainStmt != nil {
+		if cerr := q.addPeerSharedTrustDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addPeerSharedTrustDomainStmt: %w", cerr)
+		}
+	}
+	if q.advisoryUnlockStmt != nil {
+		if cerr := q.advisoryUnlockStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing advisoryUnlockStmt: %w", cerr)
+		}
+	}
 	if q.createBundleStmt != nil {
 		if cerr := q.createBundleStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createBundleStmt: %w", cerr)
 		}
 	}
+	if q.createBundleRejectionStmt != nil {
+		if cerr := q.createBundleRejectionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBundleRejectionStmt: %w", cerr)
+		}
+	}
+	if q.createFederationGroupStmt != nil {
+		if cerr := q.createFederationGroupStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFederationGroupStmt: %w", cerr)
+		}
+	}
+	if q.createFederationPolicyStmt != nil {
+		if cerr := q.createFederationPolicyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFederationPolicyStmt: %w", cerr)
+		}
+	}
 	if q.createJoinTokenStmt != nil {
 		if cerr := q.createJoinTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createJoinTokenStmt: %w", cerr)
 		}
 	}
+	if q.createPeerStmt != nil {
+		if cerr := q.createPeerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPeerStmt: %w", cerr)
+		}
+	}
+	if q.createQuarantinedBundleStmt != nil {
+		if cerr := q.createQuarantinedBundleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createQuarantinedBundleStmt: %w", cerr)
+		}
+	}
 	if q.createRelationshipStmt != nil {
 		if cerr := q.createRelationshipStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createRelationshipStmt: %w", cerr)
@@ -124,16 +317,56 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createTrustDomainStmt: %w", cerr)
 		}
 	}
+	if q.createWatchEventStmt != nil {
+		if cerr := q.createWatchEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWatchEventStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookStmt != nil {
+		if cerr := q.createWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookDeliveryStmt != nil {
+		if cerr := q.createWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookDeliveryStmt: %w", cerr)
+		}
+	}
 	if q.deleteBundleStmt != nil {
 		if cerr := q.deleteBundleStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteBundleStmt: %w", cerr)
 		}
 	}
+	if q.deleteBundleRejectionsBeforeStmt != nil {
+		if cerr := q.deleteBundleRejectionsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteBundleRejectionsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.deleteFederationGroupStmt != nil {
+		if cerr := q.deleteFederationGroupStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFederationGroupStmt: %w", cerr)
+		}
+	}
+	if q.deleteFederationPolicyStmt != nil {
+		if cerr := q.deleteFederationPolicyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFederationPolicyStmt: %w", cerr)
+		}
+	}
 	if q.deleteJoinTokenStmt != nil {
 		if cerr := q.deleteJoinTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteJoinTokenStmt: %w", cerr)
 		}
 	}
+	if q.deletePeerStmt != nil {
+		if cerr := q.deletePeerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePeerStmt: %w", cerr)
+		}
+	}
+	if q.deleteQuarantinedBundleStmt != nil {
+		if cerr := q.deleteQuarantinedBundleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteQuarantinedBundleStmt: %w", cerr)
+		}
+	}
 	if q.deleteRelationshipStmt != nil {
 		if cerr := q.deleteRelationshipStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteRelationshipStmt: %w", cerr)
@@ -144,6 +377,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteTrustDomainStmt: %w", cerr)
 		}
 	}
+	if q.deleteWatchEventsBeforeStmt != nil {
+		if cerr := q.deleteWatchEventsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWatchEventsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookStmt != nil {
+		if cerr := q.deleteWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookDeliveryStmt != nil {
+		if cerr := q.deleteWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookDeliveryStmt: %w", cerr)
+		}
+	}
 	if q.findBundleByIDStmt != nil {
 		if cerr := q.findBundleByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing findBundleByIDStmt: %w", cerr)
@@ -154,6 +402,31 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing findBundleByTrustDomainIDStmt: %w", cerr)
 		}
 	}
+	if q.findBundleRejectionsByTrustDomainIDStmt != nil {
+		if cerr := q.findBundleRejectionsByTrustDomainIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findBundleRejectionsByTrustDomainIDStmt: %w", cerr)
+		}
+	}
+	if q.findFederatedBundlesByTrustDomainIDStmt != nil {
+		if cerr := q.findFederatedBundlesByTrustDomainIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findFederatedBundlesByTrustDomainIDStmt: %w", cerr)
+		}
+	}
+	if q.findFederationGroupByNameStmt != nil {
+		if cerr := q.findFederationGroupByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findFederationGroupByNameStmt: %w", cerr)
+		}
+	}
+	if q.findFederationPolicyByNameStmt != nil {
+		if cerr := q.findFederationPolicyByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findFederationPolicyByNameStmt: %w", cerr)
+		}
+	}
+	if q.findHarvesterSessionByTrustDomainIDStmt != nil {
+		if cerr := q.findHarvesterSessionByTrustDomainIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findHarvesterSessionByTrustDomainIDStmt: %w", cerr)
+		}
+	}
 	if q.findJoinTokenStmt != nil {
 		if cerr := q.findJoinTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing findJoinTokenStmt: %w", cerr)
@@ -169,6 +442,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing findJoinTokensByTrustDomainIDStmt: %w", cerr)
 		}
 	}
+	if q.findPeerByNameStmt != nil {
+		if cerr := q.findPeerByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findPeerByNameStmt: %w", cerr)
+		}
+	}
+	if q.findQuarantinedBundleByTrustDomainIDStmt != nil {
+		if cerr := q.findQuarantinedBundleByTrustDomainIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findQuarantinedBundleByTrustDomainIDStmt: %w", cerr)
+		}
+	}
 	if q.findRelationshipByIDStmt != nil {
 		if cerr := q.findRelationshipByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing findRelationshipByIDStmt: %w", cerr)
@@ -179,6 +462,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing findRelationshipsByTrustDomainIDStmt: %w", cerr)
 		}
 	}
+	if q.findResourceVersionStmt != nil {
+		if cerr := q.findResourceVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findResourceVersionStmt: %w", cerr)
+		}
+	}
 	if q.findTrustDomainByIDStmt != nil {
 		if cerr := q.findTrustDomainByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing findTrustDomainByIDStmt: %w", cerr)
@@ -189,26 +477,126 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing findTrustDomainByNameStmt: %w", cerr)
 		}
 	}
+	if q.findWebhookDeliveryByIDStmt != nil {
+		if cerr := q.findWebhookDeliveryByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findWebhookDeliveryByIDStmt: %w", cerr)
+		}
+	}
+	if q.incrementResourceVersionStmt != nil {
+		if cerr := q.incrementResourceVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing incrementResourceVersionStmt: %w", cerr)
+		}
+	}
+	if q.listBundleRejectionsStmt != nil {
+		if cerr := q.listBundleRejectionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listBundleRejectionsStmt: %w", cerr)
+		}
+	}
 	if q.listBundlesStmt != nil {
 		if cerr := q.listBundlesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listBundlesStmt: %w", cerr)
 		}
 	}
+	if q.listExpiredRelationshipsStmt != nil {
+		if cerr := q.listExpiredRelationshipsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpiredRelationshipsStmt: %w", cerr)
+		}
+	}
+	if q.listFederationGroupMembersStmt != nil {
+		if cerr := q.listFederationGroupMembersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFederationGroupMembersStmt: %w", cerr)
+		}
+	}
+	if q.listFederationGroupsStmt != nil {
+		if cerr := q.listFederationGroupsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFederationGroupsStmt: %w", cerr)
+		}
+	}
+	if q.listFederationPoliciesStmt != nil {
+		if cerr := q.listFederationPoliciesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFederationPoliciesStmt: %w", cerr)
+		}
+	}
+	if q.listHarvesterSessionsStmt != nil {
+		if cerr := q.listHarvesterSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listHarvesterSessionsStmt: %w", cerr)
+		}
+	}
+	if q.listJobStatusesStmt != nil {
+		if cerr := q.listJobStatusesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listJobStatusesStmt: %w", cerr)
+		}
+	}
 	if q.listJoinTokensStmt != nil {
 		if cerr := q.listJoinTokensStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listJoinTokensStmt: %w", cerr)
 		}
 	}
+	if q.listPeerSharedTrustDomainsStmt != nil {
+		if cerr := q.listPeerSharedTrustDomainsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPeerSharedTrustDomainsStmt: %w", cerr)
+		}
+	}
+	if q.listPeersStmt != nil {
+		if cerr := q.listPeersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPeersStmt: %w", cerr)
+		}
+	}
+	if q.listQuarantinedBundlesStmt != nil {
+		if cerr := q.listQuarantinedBundlesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listQuarantinedBundlesStmt: %w", cerr)
+		}
+	}
 	if q.listRelationshipsStmt != nil {
 		if cerr := q.listRelationshipsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listRelationshipsStmt: %w", cerr)
 		}
 	}
+	if q.listRelationshipsWithTrustDomainNamesStmt != nil {
+		if cerr := q.listRelationshipsWithTrustDomainNamesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRelationshipsWithTrustDomainNamesStmt: %w", cerr)
+		}
+	}
 	if q.listTrustDomainsStmt != nil {
 		if cerr := q.listTrustDomainsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listTrustDomainsStmt: %w", cerr)
 		}
 	}
+	if q.listWatchEventsStmt != nil {
+		if cerr := q.listWatchEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWatchEventsStmt: %w", cerr)
+		}
+	}
+	if q.listWebhookDeliveriesStmt != nil {
+		if cerr := q.listWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.listWebhooksStmt != nil {
+		if cerr := q.listWebhooksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWebhooksStmt: %w", cerr)
+		}
+	}
+	if q.notifyStmt != nil {
+		if cerr := q.notifyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing notifyStmt: %w", cerr)
+		}
+	}
+	if q.removeFederationGroupMemberStmt != nil {
+		if cerr := q.removeFederationGroupMemberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeFederationGroupMemberStmt: %w", cerr)
+		}
+	}
+	if q.removePeerSharedTrustDomainStmt != nil {
+		if cerr := q.removePeerSharedTrustDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removePeerSharedTrustDomainStmt: %w", cerr)
+		}
+	}
+	if q.tryAdvisoryLockStmt != nil {
+		if cerr := q.tryAdvisoryLockStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing tryAdvisoryLockStmt: %w", cerr)
+		}
+	}
 	if q.updateBundleStmt != nil {
 		if cerr := q.updateBundleStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateBundleStmt: %w", cerr)
@@ -219,6 +607,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateJoinTokenStmt: %w", cerr)
 		}
 	}
+	if q.updateQuarantinedBundleStmt != nil {
+		if cerr := q.updateQuarantinedBundleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateQuarantinedBundleStmt: %w", cerr)
+		}
+	}
 	if q.updateRelationshipStmt != nil {
 		if cerr := q.updateRelationshipStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateRelationshipStmt: %w", cerr)
@@ -229,6 +622,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateTrustDomainStmt: %w", cerr)
 		}
 	}
+	if q.updateWebhookDeliveryStmt != nil {
+		if cerr := q.updateWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateWebhookDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.upsertHarvesterSessionStmt != nil {
+		if cerr := q.upsertHarvesterSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertHarvesterSessionStmt: %w", cerr)
+		}
+	}
+	if q.upsertJobStatusStmt != nil {
+		if cerr := q.upsertJobStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertJobStatusStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -266,63 +674,165 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                                   DBTX
-	tx                                   *sql.Tx
-	createBundleStmt                     *sql.Stmt
-	createJoinTokenStmt                  *sql.Stmt
-	createRelationshipStmt               *sql.Stmt
-	createTrustDomainStmt                *sql.Stmt
-	deleteBundleStmt                     *sql.Stmt
-	deleteJoinTokenStmt                  *sql.Stmt
-	deleteRelationshipStmt               *sql.Stmt
-	deleteTrustDomainStmt                *sql.Stmt
-	findBundleByIDStmt                   *sql.Stmt
-	findBundleByTrustDomainIDStmt        *sql.Stmt
-	findJoinTokenStmt                    *sql.Stmt
-	findJoinTokenByIDStmt                *sql.Stmt
-	findJoinTokensByTrustDomainIDStmt    *sql.Stmt
-	findRelationshipByIDStmt             *sql.Stmt
-	findRelationshipsByTrustDomainIDStmt *sql.Stmt
-	findTrustDomainByIDStmt              *sql.Stmt
-	findTrustDomainByNameStmt            *sql.Stmt
-	listBundlesStmt                      *sql.Stmt
-	listJoinTokensStmt                   *sql.Stmt
-	listRelationshipsStmt                *sql.Stmt
-	listTrustDomainsStmt                 *sql.Stmt
-	updateBundleStmt                     *sql.Stmt
-	updateJoinTokenStmt                  *sql.Stmt
-	updateRelationshipStmt               *sql.Stmt
-	updateTrustDomainStmt                *sql.Stmt
+	db                                        DBTX
+	tx                                        *sql.Tx
+	addFederationGroupMemberStmt              *sql.Stmt
+	addPeerSharedTrustDomainStmt              *sql.Stmt
+	advisoryUnlockStmt                        *sql.Stmt
+	createBundleStmt                          *sql.Stmt
+	createBundleRejectionStmt                 *sql.Stmt
+	createFederationGroupStmt                 *sql.Stmt
+	createFederationPolicyStmt                *sql.Stmt
+	createJoinTokenStmt                       *sql.Stmt
+	createPeerStmt                            *sql.Stmt
+	createQuarantinedBundleStmt               *sql.Stmt
+	createRelationshipStmt                    *sql.Stmt
+	createTrustDomainStmt                     *sql.Stmt
+	createWatchEventStmt                      *sql.Stmt
+	createWebhookStmt                         *sql.Stmt
+	createWebhookDeliveryStmt                 *sql.Stmt
+	deleteBundleStmt                          *sql.Stmt
+	deleteBundleRejectionsBeforeStmt          *sql.Stmt
+	deleteFederationGroupStmt                 *sql.Stmt
+	deleteFederationPolicyStmt                *sql.Stmt
+	deleteJoinTokenStmt                       *sql.Stmt
+	deletePeerStmt                            *sql.Stmt
+	deleteQuarantinedBundleStmt               *sql.Stmt
+	deleteRelationshipStmt                    *sql.Stmt
+	deleteTrustDomainStmt                     *sql.Stmt
+	deleteWatchEventsBeforeStmt               *sql.Stmt
+	deleteWebhookStmt                         *sql.Stmt
+	deleteWebhookDeliveryStmt                 *sql.Stmt
+	findBundleByIDStmt                        *sql.Stmt
+	findBundleByTrustDomainIDStmt             *sql.Stmt
+	findBundleRejectionsByTrustDomainIDStmt   *sql.Stmt
+	findFederatedBundlesByTrustDomainIDStmt   *sql.Stmt
+	findFederationGroupByNameStmt             *sql.Stmt
+	findFederationPolicyByNameStmt            *sql.Stmt
+	findHarvesterSessionByTrustDomainIDStmt   *sql.Stmt
+	findJoinTokenStmt                         *sql.Stmt
+	findJoinTokenByIDStmt                     *sql.Stmt
+	findJoinTokensByTrustDomainIDStmt         *sql.Stmt
+	findPeerByNameStmt                        *sql.Stmt
+	findQuarantinedBundleByTrustDomainIDStmt  *sql.Stmt
+	findRelationshipByIDStmt                  *sql.Stmt
+	findRelationshipsByTrustDomainIDStmt      *sql.Stmt
+	findResourceVersionStmt                   *sql.Stmt
+	findTrustDomainByIDStmt                   *sql.Stmt
+	findTrustDomainByNameStmt                 *sql.Stmt
+	findWebhookDeliveryByIDStmt               *sql.Stmt
+	incrementResourceVersionStmt              *sql.Stmt
+	listBundleRejectionsStmt                  *sql.Stmt
+	listBundlesStmt                           *sql.Stmt
+	listExpiredRelationshipsStmt              *sql.Stmt
+	listFederationGroupMembersStmt            *sql.Stmt
+	listFederationGroupsStmt                  *sql.Stmt
+	listFederationPoliciesStmt                *sql.Stmt
+	listHarvesterSessionsStmt                 *sql.Stmt
+	listJobStatusesStmt                       *sql.Stmt
+	listJoinTokensStmt                        *sql.Stmt
+	listPeerSharedTrustDomainsStmt            *sql.Stmt
+	listPeersStmt                             *sql.Stmt
+	listQuarantinedBundlesStmt                *sql.Stmt
+	listRelationshipsStmt                     *sql.Stmt
+	listRelationshipsWithTrustDomainNamesStmt *sql.Stmt
+	listTrustDomainsStmt                      *sql.Stmt
+	listWatchEventsStmt                       *sql.Stmt
+	listWebhookDeliveriesStmt                 *sql.Stmt
+	listWebhooksStmt                          *sql.Stmt
+	notifyStmt                                *sql.Stmt
+	removeFederationGroupMemberStmt           *sql.Stmt
+	removePeerSharedTrustDomainStmt           *sql.Stmt
+	tryAdvisoryLockStmt                       *sql.Stmt
+	updateBundleStmt                          *sql.Stmt
+	updateJoinTokenStmt                       *sql.Stmt
+	updateQuarantinedBundleStmt               *sql.Stmt
+	updateRelationshipStmt                    *sql.Stmt
+	updateTrustDomainStmt                     *sql.Stmt
+	updateWebhookDeliveryStmt                 *sql.Stmt
+	upsertHarvesterSessionStmt                *sql.Stmt
+	upsertJobStatusStmt                       *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                                   tx,
-		tx:                                   tx,
-		createBundleStmt:                     q.createBundleStmt,
-		createJoinTokenStmt:                  q.createJoinTokenStmt,
-		createRelationshipStmt:               q.createRelationshipStmt,
-		createTrustDomainStmt:                q.createTrustDomainStmt,
-		deleteBundleStmt:                     q.deleteBundleStmt,
-		deleteJoinTokenStmt:                  q.deleteJoinTokenStmt,
-		deleteRelationshipStmt:               q.deleteRelationshipStmt,
-		deleteTrustDomainStmt:                q.deleteTrustDomainStmt,
-		findBundleByIDStmt:                   q.findBundleByIDStmt,
-		findBundleByTrustDomainIDStmt:        q.findBundleByTrustDomainIDStmt,
-		findJoinTokenStmt:                    q.findJoinTokenStmt,
-		findJoinTokenByIDStmt:                q.findJoinTokenByIDStmt,
-		findJoinTokensByTrustDomainIDStmt:    q.findJoinTokensByTrustDomainIDStmt,
-		findRelationshipByIDStmt:             q.findRelationshipByIDStmt,
-		findRelationshipsByTrustDomainIDStmt: q.findRelationshipsByTrustDomainIDStmt,
-		findTrustDomainByIDStmt:              q.findTrustDomainByIDStmt,
-		findTrustDomainByNameStmt:            q.findTrustDomainByNameStmt,
-		listBundlesStmt:                      q.listBundlesStmt,
-		listJoinTokensStmt:                   q.listJoinTokensStmt,
-		listRelationshipsStmt:                q.listRelationshipsStmt,
-		listTrustDomainsStmt:                 q.listTrustDomainsStmt,
-		updateBundleStmt:                     q.updateBundleStmt,
-		updateJoinTokenStmt:                  q.updateJoinTokenStmt,
-		updateRelationshipStmt:               q.updateRelationshipStmt,
-		updateTrustDomainStmt:                q.updateTrustDomainStmt,
+		db:                                        tx,
+		tx:                                        tx,
+		addFederationGroupMemberStmt:              q.addFederationGroupMemberStmt,
+		addPeerSharedTrustDomainStmt:              q.addPeerSharedTrustDomainStmt,
+		advisoryUnlockStmt:                        q.advisoryUnlockStmt,
+		createBundleStmt:                          q.createBundleStmt,
+		createBundleRejectionStmt:                 q.createBundleRejectionStmt,
+		createFederationGroupStmt:                 q.createFederationGroupStmt,
+		createFederationPolicyStmt:                q.createFederationPolicyStmt,
+		createJoinTokenStmt:                       q.createJoinTokenStmt,
+		createPeerStmt:                            q.createPeerStmt,
+		createQuarantinedBundleStmt:               q.createQuarantinedBundleStmt,
+		createRelationshipStmt:                    q.createRelationshipStmt,
+		createTrustDomainStmt:                     q.createTrustDomainStmt,
+		createWatchEventStmt:                      q.createWatchEventStmt,
+		createWebhookStmt:                         q.createWebhookStmt,
+		createWebhookDeliveryStmt:                 q.createWebhookDeliveryStmt,
+		deleteBundleStmt:                          q.deleteBundleStmt,
+		deleteBundleRejectionsBeforeStmt:          q.deleteBundleRejectionsBeforeStmt,
+		deleteFederationGroupStmt:                 q.deleteFederationGroupStmt,
+		deleteFederationPolicyStmt:                q.deleteFederationPolicyStmt,
+		deleteJoinTokenStmt:                       q.deleteJoinTokenStmt,
+		deletePeerStmt:                            q.deletePeerStmt,
+		deleteQuarantinedBundleStmt:               q.deleteQuarantinedBundleStmt,
+		deleteRelationshipStmt:                    q.deleteRelationshipStmt,
+		deleteTrustDomainStmt:                     q.deleteTrustDomainStmt,
+		deleteWatchEventsBeforeStmt:               q.deleteWatchEventsBeforeStmt,
+		deleteWebhookStmt:                         q.deleteWebhookStmt,
+		deleteWebhookDeliveryStmt:                 q.deleteWebhookDeliveryStmt,
+		findBundleByIDStmt:                        q.findBundleByIDStmt,
+		findBundleByTrustDomainIDStmt:             q.findBundleByTrustDomainIDStmt,
+		findBundleRejectionsByTrustDomainIDStmt:   q.findBundleRejectionsByTrustDomainIDStmt,
+		findFederatedBundlesByTrustDomainIDStmt:   q.findFederatedBundlesByTrustDomainIDStmt,
+		findFederationGroupByNameStmt:             q.findFederationGroupByNameStmt,
+		findFederationPolicyByNameStmt:            q.findFederationPolicyByNameStmt,
+		findHarvesterSessionByTrustDomainIDStmt:   q.findHarvesterSessionByTrustDomainIDStmt,
+		findJoinTokenStmt:                         q.findJoinTokenStmt,
+		findJoinTokenByIDStmt:                     q.findJoinTokenByIDStmt,
+		findJoinTokensByTrustDomainIDStmt:         q.findJoinTokensByTrustDomainIDStmt,
+		findPeerByNameStmt:                        q.findPeerByNameStmt,
+		findQuarantinedBundleByTrustDomainIDStmt:  q.findQuarantinedBundleByTrustDomainIDStmt,
+		findRelationshipByIDStmt:                  q.findRelationshipByIDStmt,
+		findRelationshipsByTrustDomainIDStmt:      q.findRelationshipsByTrustDomainIDStmt,
+		findResourceVersionStmt:                   q.findResourceVersionStmt,
+		findTrustDomainByIDStmt:                   q.findTrustDomainByIDStmt,
+		findTrustDomainByNameStmt:                 q.findTrustDomainByNameStmt,
+		findWebhookDeliveryByIDStmt:               q.findWebhookDeliveryByIDStmt,
+		incrementResourceVersionStmt:              q.incrementResourceVersionStmt,
+		listBundleRejectionsStmt:                  q.listBundleRejectionsStmt,
+		listBundlesStmt:                           q.listBundlesStmt,
+		listExpiredRelationshipsStmt:              q.listExpiredRelationshipsStmt,
+		listFederationGroupMembersStmt:            q.listFederationGroupMembersStmt,
+		listFederationGroupsStmt:                  q.listFederationGroupsStmt,
+		listFederationPoliciesStmt:                q.listFederationPoliciesStmt,
+		listHarvesterSessionsStmt:                 q.listHarvesterSessionsStmt,
+		listJobStatusesStmt:                       q.listJobStatusesStmt,
+		listJoinTokensStmt:                        q.listJoinTokensStmt,
+		listPeerSharedTrustDomainsStmt:            q.listPeerSharedTrustDomainsStmt,
+		listPeersStmt:                             q.listPeersStmt,
+		listQuarantinedBundlesStmt:                q.listQuarantinedBundlesStmt,
+		listRelationshipsStmt:                     q.listRelationshipsStmt,
+		listRelationshipsWithTrustDomainNamesStmt: q.listRelationshipsWithTrustDomainNamesStmt,
+		listTrustDomainsStmt:                      q.listTrustDomainsStmt,
+		listWatchEventsStmt:                       q.listWatchEventsStmt,
+		listWebhookDeliveriesStmt:                 q.listWebhookDeliveriesStmt,
+		listWebhooksStmt:                          q.listWebhooksStmt,
+		notifyStmt:                                q.notifyStmt,
+		removeFederationGroupMemberStmt:           q.removeFederationGroupMemberStmt,
+		removePeerSharedTrustDomainStmt:           q.removePeerSharedTrustDomainStmt,
+		tryAdvisoryLockStmt:                       q.tryAdvisoryLockStmt,
+		updateBundleStmt:                          q.updateBundleStmt,
+		updateJoinTokenStmt:                       q.updateJoinTokenStmt,
+		updateQuarantinedBundleStmt:               q.updateQuarantinedBundleStmt,
+		updateRelationshipStmt:                    q.updateRelationshipStmt,
+		updateTrustDomainStmt:                     q.updateTrustDomainStmt,
+		updateWebhookDeliveryStmt:                 q.updateWebhookDeliveryStmt,
+		upsertHarvesterSessionStmt:                q.upsertHarvesterSessionStmt,
+		upsertJobStatusStmt:                       q.upsertJobStatusStmt,
 	}
 }
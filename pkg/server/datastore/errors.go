@@ -0,0 +1,46 @@
+package datastore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrConflict is matched, using errors.Is, by the errors returned when an operation
+// violates a uniqueness constraint, e.g., creating a trust domain whose name is already taken.
+var ErrConflict = errors.New("conflict")
+
+// ConflictError is returned when an operation violates a uniqueness constraint.
+type ConflictError struct {
+	msg string
+	err error
+}
+
+func (e *ConflictError) Error() string {
+	if e.err == nil {
+		return e.msg
+	}
+	return fmt.Sprintf("%s: %v", e.msg, e.err)
+}
+
+// Unwrap returns the underlying error, e.g., the *pgconn.PgError reported by Postgres.
+func (e *ConflictError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// wrapError annotates err with msg. Unique constraint violations are surfaced as a ConflictError.
+func wrapError(msg string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return &ConflictError{msg: msg, err: err}
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
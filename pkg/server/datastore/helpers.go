@@ -1,7 +1,10 @@
 package datastore
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
 	"github.com/google/uuid"
@@ -25,6 +28,8 @@ func (td TrustDomain) ToEntity() (*entity.TrustDomain, error) {
 		ID:               id,
 		Name:             trustDomain,
 		OnboardingBundle: td.OnboardingBundle,
+		PublishBundle:    td.PublishBundle,
+		PublishOIDC:      td.PublishOidc,
 		CreatedAt:        td.CreatedAt,
 		UpdatedAt:        td.UpdatedAt,
 	}
@@ -41,6 +46,33 @@ func (td TrustDomain) ToEntity() (*entity.TrustDomain, error) {
 		result.HarvesterSpiffeID = id
 	}
 
+	if td.BundleEndpointUrl.Valid {
+		result.BundleEndpointURL = td.BundleEndpointUrl.String
+	}
+
+	if td.BundleEndpointProfile.Valid {
+		result.BundleEndpointProfile = td.BundleEndpointProfile.String
+	}
+
+	if td.BundleEndpointSpiffeID.Valid {
+		id, err := spiffeid.FromString(td.BundleEndpointSpiffeID.String)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+		}
+		result.BundleEndpointSPIFFEID = id
+	}
+
+	if err := json.Unmarshal(td.Labels, &result.Labels); err != nil {
+		return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+	}
+	if len(result.Labels) == 0 {
+		result.Labels = nil
+	}
+
+	if td.PeerID.Status == pgtype.Present {
+		result.PeerID = uuid.NullUUID{UUID: td.PeerID.Bytes, Valid: true}
+	}
+
 	return result, nil
 }
 
@@ -56,8 +88,12 @@ func (r Relationship) ToEntity() (*entity.Relationship, error) {
 		TrustDomainBID:      r.TrustDomainBID.Bytes,
 		TrustDomainAConsent: r.TrustDomainAConsent,
 		TrustDomainBConsent: r.TrustDomainBConsent,
+		Direction:           entity.RelationshipDirection(r.Direction),
 		CreatedAt:           r.CreatedAt,
 		UpdatedAt:           r.UpdatedAt,
+		NotBefore:           r.NotBefore.Time,
+		NotAfter:            r.NotAfter.Time,
+		ExpiredAt:           r.ExpiredAt.Time,
 	}, nil
 }
 
@@ -81,6 +117,67 @@ func (b Bundle) ToEntity() (*entity.Bundle, error) {
 	}, nil
 }
 
+func (r ListRelationshipsWithTrustDomainNamesRow) ToEntity() (*entity.Relationship, error) {
+	tda, err := spiffeid.TrustDomainFromString(r.TrustDomainAName)
+	if err != nil {
+		return nil, err
+	}
+
+	tdb, err := spiffeid.TrustDomainFromString(r.TrustDomainBName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Relationship{
+		ID:                  r.ID,
+		TrustDomainAID:      r.TrustDomainAID,
+		TrustDomainBID:      r.TrustDomainBID,
+		TrustDomainAConsent: r.TrustDomainAConsent,
+		TrustDomainBConsent: r.TrustDomainBConsent,
+		CreatedAt:           r.CreatedAt,
+		UpdatedAt:           r.UpdatedAt,
+		Direction:           r.Direction,
+		NotBefore:           r.NotBefore,
+		NotAfter:            r.NotAfter,
+		ExpiredAt:           r.ExpiredAt,
+	}.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	result.TrustDomainAName = tda
+	result.TrustDomainBName = tdb
+
+	return result, nil
+}
+
+func (b FindFederatedBundlesByTrustDomainIDRow) ToEntity() (*entity.Bundle, error) {
+	td, err := spiffeid.TrustDomainFromString(b.TrustDomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Bundle{
+		ID:                 b.ID,
+		TrustDomainID:      b.TrustDomainID,
+		Data:               b.Data,
+		Digest:             b.Digest,
+		Signature:          b.Signature,
+		DigestAlgorithm:    b.DigestAlgorithm,
+		SignatureAlgorithm: b.SignatureAlgorithm,
+		SigningCert:        b.SigningCert,
+		CreatedAt:          b.CreatedAt,
+		UpdatedAt:          b.UpdatedAt,
+	}.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	result.TrustDomainName = td
+
+	return result, nil
+}
+
 func (jt JoinToken) ToEntity() *entity.JoinToken {
 	id := uuid.NullUUID{
 		UUID:  jt.ID.Bytes,
@@ -103,6 +200,286 @@ func (jt JoinToken) ToEntity() *entity.JoinToken {
 	}
 }
 
+func (br BundleRejection) ToEntity() (*entity.BundleRejection, error) {
+	var violations []entity.BundleViolation
+	if err := json.Unmarshal(br.Violations, &violations); err != nil {
+		return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+	}
+
+	return &entity.BundleRejection{
+		ID:            uuid.NullUUID{UUID: br.ID.Bytes, Valid: true},
+		TrustDomainID: br.TrustDomainID.Bytes,
+		Digest:        br.Digest,
+		Violations:    violations,
+		CreatedAt:     br.CreatedAt,
+	}, nil
+}
+
+func (br ListBundleRejectionsRow) ToEntity() (*entity.BundleRejection, error) {
+	td, err := spiffeid.TrustDomainFromString(br.TrustDomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := BundleRejection{
+		ID:            br.ID,
+		TrustDomainID: br.TrustDomainID,
+		Digest:        br.Digest,
+		Violations:    br.Violations,
+		CreatedAt:     br.CreatedAt,
+	}.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	result.TrustDomainName = td
+
+	return result, nil
+}
+
+func (qb QuarantinedBundle) ToEntity() (*entity.QuarantinedBundle, error) {
+	var reasons []entity.BundleViolation
+	if err := json.Unmarshal(qb.Reasons, &reasons); err != nil {
+		return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+	}
+
+	return &entity.QuarantinedBundle{
+		ID:              uuid.NullUUID{UUID: qb.ID.Bytes, Valid: true},
+		TrustDomainID:   qb.TrustDomainID.Bytes,
+		Data:            qb.Data,
+		Digest:          qb.Digest,
+		DigestAlgorithm: qb.DigestAlgorithm,
+		Reasons:         reasons,
+		ReleaseAt:       qb.ReleaseAt,
+		CreatedAt:       qb.CreatedAt,
+		UpdatedAt:       qb.UpdatedAt,
+	}, nil
+}
+
+func (qb ListQuarantinedBundlesRow) ToEntity() (*entity.QuarantinedBundle, error) {
+	td, err := spiffeid.TrustDomainFromString(qb.TrustDomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := QuarantinedBundle{
+		ID:              qb.ID,
+		TrustDomainID:   qb.TrustDomainID,
+		Data:            qb.Data,
+		Digest:          qb.Digest,
+		DigestAlgorithm: qb.DigestAlgorithm,
+		Reasons:         qb.Reasons,
+		ReleaseAt:       qb.ReleaseAt,
+		CreatedAt:       qb.CreatedAt,
+		UpdatedAt:       qb.UpdatedAt,
+	}.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	result.TrustDomainName = td
+
+	return result, nil
+}
+
+func (hs HarvesterSession) ToEntity() *entity.HarvesterSession {
+	return &entity.HarvesterSession{
+		ID:               uuid.NullUUID{UUID: hs.ID.Bytes, Valid: true},
+		TrustDomainID:    hs.TrustDomainID.Bytes,
+		LastOnboardAt:    hs.LastOnboardAt.Time,
+		LastPostAt:       hs.LastPostAt.Time,
+		LastSyncAt:       hs.LastSyncAt.Time,
+		RemoteAddress:    hs.RemoteAddress,
+		HarvesterVersion: hs.HarvesterVersion,
+		SPIREVersion:     hs.SpireVersion,
+		CreatedAt:        hs.CreatedAt,
+		UpdatedAt:        hs.UpdatedAt,
+	}
+}
+
+func (hs ListHarvesterSessionsRow) ToEntity() (*entity.HarvesterSession, error) {
+	td, err := spiffeid.TrustDomainFromString(hs.TrustDomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := HarvesterSession{
+		ID:               hs.ID,
+		TrustDomainID:    hs.TrustDomainID,
+		LastOnboardAt:    hs.LastOnboardAt,
+		LastPostAt:       hs.LastPostAt,
+		LastSyncAt:       hs.LastSyncAt,
+		RemoteAddress:    hs.RemoteAddress,
+		HarvesterVersion: hs.HarvesterVersion,
+		SpireVersion:     hs.SpireVersion,
+		CreatedAt:        hs.CreatedAt,
+		UpdatedAt:        hs.UpdatedAt,
+	}.ToEntity()
+
+	result.TrustDomainName = td
+
+	return result, nil
+}
+
+func (js JobStatus) ToEntity() *entity.JobStatus {
+	return &entity.JobStatus{
+		Name:       js.Name,
+		Schedule:   js.Schedule,
+		StartedAt:  js.StartedAt,
+		FinishedAt: js.FinishedAt,
+		NextRunAt:  js.NextRunAt,
+		Result:     js.Result,
+		Error:      js.Error,
+	}
+}
+
+func (p FederationPolicy) ToEntity() (*entity.FederationPolicy, error) {
+	var selector, peerSelector entity.LabelSelector
+	if err := json.Unmarshal(p.Selector, &selector); err != nil {
+		return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+	}
+	if err := json.Unmarshal(p.PeerSelector, &peerSelector); err != nil {
+		return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+	}
+
+	return &entity.FederationPolicy{
+		ID:           uuid.NullUUID{UUID: p.ID.Bytes, Valid: true},
+		Name:         p.Name,
+		Selector:     selector,
+		PeerSelector: peerSelector,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+	}, nil
+}
+
+func (g FederationGroup) ToEntity() *entity.FederationGroup {
+	return &entity.FederationGroup{
+		ID:        uuid.NullUUID{UUID: g.ID.Bytes, Valid: true},
+		Name:      g.Name,
+		Mode:      entity.FederationGroupMode(g.Mode),
+		CreatedAt: g.CreatedAt,
+		UpdatedAt: g.UpdatedAt,
+	}
+}
+
+func (m FederationGroupMember) ToEntity() *entity.FederationGroupMember {
+	return &entity.FederationGroupMember{
+		ID:            uuid.NullUUID{UUID: m.ID.Bytes, Valid: true},
+		GroupID:       m.GroupID.Bytes,
+		TrustDomainID: m.TrustDomainID.Bytes,
+		Hub:           m.Hub,
+		CreatedAt:     m.CreatedAt,
+	}
+}
+
+func (m ListFederationGroupMembersRow) ToEntity() (*entity.FederationGroupMember, error) {
+	td, err := spiffeid.TrustDomainFromString(m.TrustDomainName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := FederationGroupMember{
+		ID:            m.ID,
+		GroupID:       m.GroupID,
+		TrustDomainID: m.TrustDomainID,
+		Hub:           m.Hub,
+		CreatedAt:     m.CreatedAt,
+	}.ToEntity()
+
+	result.TrustDomainName = td
+
+	return result, nil
+}
+
+func (p Peer) ToEntity() *entity.Peer {
+	return &entity.Peer{
+		ID:        uuid.NullUUID{UUID: p.ID.Bytes, Valid: true},
+		Name:      p.Name,
+		URL:       p.Url,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+func (w Webhook) ToEntity() (*entity.Webhook, error) {
+	var eventTypes []string
+	if err := json.Unmarshal(w.EventTypes, &eventTypes); err != nil {
+		return nil, fmt.Errorf("cannot convert model to entity: %v", err)
+	}
+
+	return &entity.Webhook{
+		ID:         uuid.NullUUID{UUID: w.ID.Bytes, Valid: true},
+		URL:        w.Url,
+		Secret:     w.Secret,
+		EventTypes: eventTypes,
+		CreatedAt:  w.CreatedAt,
+		UpdatedAt:  w.UpdatedAt,
+	}, nil
+}
+
+func (wd WebhookDelivery) ToEntity() *entity.WebhookDelivery {
+	return &entity.WebhookDelivery{
+		ID:            uuid.NullUUID{UUID: wd.ID.Bytes, Valid: true},
+		WebhookID:     wd.WebhookID.Bytes,
+		EventType:     wd.EventType,
+		Payload:       wd.Payload,
+		Attempts:      int(wd.Attempts),
+		NextAttemptAt: wd.NextAttemptAt,
+		LastError:     wd.LastError,
+		Dead:          wd.Dead,
+		CreatedAt:     wd.CreatedAt,
+		UpdatedAt:     wd.UpdatedAt,
+	}
+}
+
+func (wd ListWebhookDeliveriesRow) ToEntity() *entity.WebhookDelivery {
+	result := WebhookDelivery{
+		ID:            wd.ID,
+		WebhookID:     wd.WebhookID,
+		EventType:     wd.EventType,
+		Payload:       wd.Payload,
+		Attempts:      wd.Attempts,
+		NextAttemptAt: wd.NextAttemptAt,
+		LastError:     wd.LastError,
+		Dead:          wd.Dead,
+		CreatedAt:     wd.CreatedAt,
+		UpdatedAt:     wd.UpdatedAt,
+	}.ToEntity()
+
+	result.WebhookURL = wd.WebhookUrl
+
+	return result
+}
+
+func (we WatchEvent) ToEntity() *entity.WatchEvent {
+	return &entity.WatchEvent{
+		Version:   we.Version,
+		Kind:      we.Kind,
+		Type:      we.Type,
+		Object:    we.Object,
+		CreatedAt: we.CreatedAt,
+	}
+}
+
+// labelsToJSON marshals the labels of a trust domain, which are stored as an empty object if there are none.
+func labelsToJSON(labels map[string]string) (json.RawMessage, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling trust domain labels: %w", err)
+	}
+
+	return b, nil
+}
+
+// timeToNullTime maps the zero time to NULL.
+func timeToNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 func uuidToPgType(id uuid.UUID) (pgtype.UUID, error) {
 	pgID := pgtype.UUID{}
 	err := pgID.Set(id)
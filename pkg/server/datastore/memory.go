@@ -0,0 +1,1754 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// MemoryDatastore is a concurrency-safe, in-memory implementation of the Datastore.
+// It enforces the same constraints as the SQL schema, but nothing is persisted,
+// so it is intended to be used for development and testing.
+type MemoryDatastore struct {
+	logger logrus.FieldLogger
+
+	mu    sync.RWMutex
+	state *memoryState
+
+	// coordinator is shared with the transactions, which hold the notifications sent within them as pending
+	// until they are committed
+	coordinator *memoryCoordinator
+	tx          bool
+	pending     []memoryNotification
+}
+
+// memoryCoordinator holds the locks and the listeners of a MemoryDatastore.
+type memoryCoordinator struct {
+	mu        sync.Mutex
+	locks     map[int64]bool
+	listeners map[string]map[chan string]struct{}
+}
+
+// memoryNotification is a notification sent to a channel.
+type memoryNotification struct {
+	channel string
+	payload string
+}
+
+// memoryListenerBuffer bounds the notifications queued for a listener, beyond which they are dropped.
+const memoryListenerBuffer = 64
+
+// memoryState holds the entities stored by a MemoryDatastore.
+type memoryState struct {
+	seq           uint64
+	trustDomains  map[uuid.UUID]*memoryRecord[entity.TrustDomain]
+	bundles       map[uuid.UUID]*memoryRecord[entity.Bundle]
+	joinTokens    map[uuid.UUID]*memoryRecord[entity.JoinToken]
+	relationships map[uuid.UUID]*memoryRecord[entity.Relationship]
+	rejections    map[uuid.UUID]*memoryRecord[entity.BundleRejection]
+	quarantined   map[uuid.UUID]*memoryRecord[entity.QuarantinedBundle]
+	sessions      map[uuid.UUID]*memoryRecord[entity.HarvesterSession]
+	webhooks      map[uuid.UUID]*memoryRecord[entity.Webhook]
+	deliveries    map[uuid.UUID]*memoryRecord[entity.WebhookDelivery]
+	groups        map[uuid.UUID]*memoryRecord[entity.FederationGroup]
+	groupMembers  map[uuid.UUID]*memoryRecord[entity.FederationGroupMember]
+	policies      map[uuid.UUID]*memoryRecord[entity.FederationPolicy]
+	peers         map[uuid.UUID]*memoryRecord[entity.Peer]
+	peerShares    map[uuid.UUID]*memoryRecord[memoryPeerShare]
+	jobStatuses   map[string]entity.JobStatus
+
+	resourceVersion int64
+	watchEvents     []*entity.WatchEvent
+}
+
+// memoryPeerShare is a trust domain shared with a peer.
+type memoryPeerShare struct {
+	peerID        uuid.UUID
+	trustDomainID uuid.UUID
+}
+
+// memoryRecord keeps a stored entity along with its insertion sequence, which is used
+// to provide a stable ordering for entities created within the same instant.
+type memoryRecord[T any] struct {
+	seq    uint64
+	entity T
+}
+
+// NewMemoryDatastore creates a new empty MemoryDatastore.
+func NewMemoryDatastore(logger logrus.FieldLogger) *MemoryDatastore {
+	return &MemoryDatastore{
+		logger: logger,
+		state: &memoryState{
+			trustDomains:  make(map[uuid.UUID]*memoryRecord[entity.TrustDomain]),
+			bundles:       make(map[uuid.UUID]*memoryRecord[entity.Bundle]),
+			joinTokens:    make(map[uuid.UUID]*memoryRecord[entity.JoinToken]),
+			relationships: make(map[uuid.UUID]*memoryRecord[entity.Relationship]),
+			rejections:    make(map[uuid.UUID]*memoryRecord[entity.BundleRejection]),
+			quarantined:   make(map[uuid.UUID]*memoryRecord[entity.QuarantinedBundle]),
+			sessions:      make(map[uuid.UUID]*memoryRecord[entity.HarvesterSession]),
+			webhooks:      make(map[uuid.UUID]*memoryRecord[entity.Webhook]),
+			deliveries:    make(map[uuid.UUID]*memoryRecord[entity.WebhookDelivery]),
+			groups:        make(map[uuid.UUID]*memoryRecord[entity.FederationGroup]),
+			groupMembers:  make(map[uuid.UUID]*memoryRecord[entity.FederationGroupMember]),
+			policies:      make(map[uuid.UUID]*memoryRecord[entity.FederationPolicy]),
+			peers:         make(map[uuid.UUID]*memoryRecord[entity.Peer]),
+			peerShares:    make(map[uuid.UUID]*memoryRecord[memoryPeerShare]),
+			jobStatuses:   make(map[string]entity.JobStatus),
+		},
+		coordinator: &memoryCoordinator{
+			locks:     make(map[int64]bool),
+			listeners: make(map[string]map[chan string]struct{}),
+		},
+	}
+}
+
+func (d *MemoryDatastore) Close() error {
+	return nil
+}
+
+// WithTx runs fn against a copy of the stored state, which replaces the current state only if fn succeeds.
+// The datastore is locked for the whole transaction, so transactions are serialized with any other operation.
+func (d *MemoryDatastore) WithTx(ctx context.Context, fn func(tx Datastore) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tx := &MemoryDatastore{
+		logger:      d.logger,
+		state:       d.state.clone(),
+		coordinator: d.coordinator,
+		tx:          true,
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	d.state = tx.state
+
+	for _, n := range tx.pending {
+		d.notify(n)
+	}
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateOrUpdateTrustDomain(ctx context.Context, req *entity.TrustDomain) (*entity.TrustDomain, error) {
+	if req.Name.String() == "" {
+		return nil, errors.New("trustDomain trust domain is missing")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := memoryNow()
+	if req.ID.Valid {
+		r, ok := d.state.trustDomains[req.ID.UUID]
+		if !ok {
+			return nil, fmt.Errorf("failed updating trust domain: %w", errMemoryNotFound)
+		}
+
+		r.entity.Description = req.Description
+		r.entity.HarvesterSpiffeID = req.HarvesterSpiffeID
+		r.entity.OnboardingBundle = cloneBytes(req.OnboardingBundle)
+		r.entity.PublishBundle = req.PublishBundle
+		r.entity.PublishOIDC = req.PublishOIDC
+		r.entity.BundleEndpointURL = req.BundleEndpointURL
+		r.entity.BundleEndpointProfile = req.BundleEndpointProfile
+		r.entity.BundleEndpointSPIFFEID = req.BundleEndpointSPIFFEID
+		r.entity.Labels = cloneLabels(req.Labels)
+		r.entity.UpdatedAt = now
+
+		return cloneTrustDomain(&r.entity), nil
+	}
+
+	for _, r := range d.state.trustDomains {
+		if r.entity.Name == req.Name {
+			return nil, &ConflictError{msg: fmt.Sprintf("failed creating new trust domain: trust domain %q already exists", req.Name)}
+		}
+	}
+
+	td := entity.TrustDomain{
+		ID:            uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Name:          req.Name,
+		Description:   req.Description,
+		PublishBundle: req.PublishBundle,
+		PublishOIDC:   req.PublishOIDC,
+		Labels:        cloneLabels(req.Labels),
+		PeerID:        req.PeerID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	d.state.trustDomains[td.ID.UUID] = newRecordLocked(d, td)
+
+	return cloneTrustDomain(&td), nil
+}
+
+func (d *MemoryDatastore) DeleteTrustDomain(ctx context.Context, trustDomainID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkTrustDomainUnreferencedLocked(trustDomainID); err != nil {
+		return err
+	}
+	d.deleteTrustDomainLocked(trustDomainID)
+
+	return nil
+}
+
+// checkTrustDomainUnreferencedLocked returns an error if the trust domain is referenced by a relationship, which
+// prevents its deletion.
+func (d *MemoryDatastore) checkTrustDomainUnreferencedLocked(trustDomainID uuid.UUID) error {
+	for _, r := range d.state.relationships {
+		if r.entity.TrustDomainAID == trustDomainID || r.entity.TrustDomainBID == trustDomainID {
+			return fmt.Errorf("failed deleting trust domain with ID=%q: it is referenced by relationship %q", trustDomainID, r.entity.ID.UUID)
+		}
+	}
+
+	return nil
+}
+
+func (d *MemoryDatastore) deleteTrustDomainLocked(trustDomainID uuid.UUID) {
+	// bundles, join tokens, bundle rejections, quarantined bundles, harvester sessions, federation group
+	// memberships and peer shares are owned by the trust domain
+	for id, r := range d.state.bundles {
+		if r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.bundles, id)
+		}
+	}
+	for id, r := range d.state.joinTokens {
+		if r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.joinTokens, id)
+		}
+	}
+	for id, r := range d.state.rejections {
+		if r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.rejections, id)
+		}
+	}
+	for id, r := range d.state.quarantined {
+		if r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.quarantined, id)
+		}
+	}
+	for id, r := range d.state.sessions {
+		if r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.sessions, id)
+		}
+	}
+	for id, r := range d.state.groupMembers {
+		if r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.groupMembers, id)
+		}
+	}
+	for id, r := range d.state.peerShares {
+		if r.entity.trustDomainID == trustDomainID {
+			delete(d.state.peerShares, id)
+		}
+	}
+
+	delete(d.state.trustDomains, trustDomainID)
+}
+
+func (d *MemoryDatastore) ListTrustDomains(ctx context.Context) ([]*entity.TrustDomain, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*entity.TrustDomain, 0, len(d.state.trustDomains))
+	for _, r := range d.state.trustDomains {
+		result = append(result, cloneTrustDomain(&r.entity))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name.String() < result[j].Name.String()
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) FindTrustDomainByID(ctx context.Context, trustDomainID uuid.UUID) (*entity.TrustDomain, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	r, ok := d.state.trustDomains[trustDomainID]
+	if !ok {
+		return nil, nil
+	}
+
+	return cloneTrustDomain(&r.entity), nil
+}
+
+func (d *MemoryDatastore) FindTrustDomainByName(ctx context.Context, trustDomain spiffeid.TrustDomain) (*entity.TrustDomain, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.trustDomains {
+		if r.entity.Name == trustDomain {
+			return cloneTrustDomain(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) CreateOrUpdateBundle(ctx context.Context, req *entity.Bundle) (*entity.Bundle, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := memoryNow()
+	if req.ID.Valid {
+		r, ok := d.state.bundles[req.ID.UUID]
+		if !ok {
+			return nil, fmt.Errorf("failed updating bundle: %w", errMemoryNotFound)
+		}
+
+		r.entity.Data = cloneBytes(req.Data)
+		r.entity.Digest = cloneBytes(req.Digest)
+		r.entity.Signature = cloneBytes(req.Signature)
+		r.entity.DigestAlgorithm = req.DigestAlgorithm
+		r.entity.SignatureAlgorithm = req.SignatureAlgorithm
+		r.entity.SigningCert = cloneBytes(req.SigningCert)
+		r.entity.UpdatedAt = now
+
+		return cloneBundle(&r.entity), nil
+	}
+
+	if _, ok := d.state.trustDomains[req.TrustDomainID]; !ok {
+		return nil, fmt.Errorf("failed creating new bundle: trust domain %q does not exist", req.TrustDomainID)
+	}
+	for _, r := range d.state.bundles {
+		if r.entity.TrustDomainID == req.TrustDomainID {
+			return nil, &ConflictError{msg: fmt.Sprintf("failed creating new bundle: a bundle already exists for trust domain %q", req.TrustDomainID)}
+		}
+	}
+
+	b := entity.Bundle{
+		ID:                 uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Data:               cloneBytes(req.Data),
+		Digest:             cloneBytes(req.Digest),
+		Signature:          cloneBytes(req.Signature),
+		DigestAlgorithm:    req.DigestAlgorithm,
+		SignatureAlgorithm: req.SignatureAlgorithm,
+		SigningCert:        cloneBytes(req.SigningCert),
+		TrustDomainID:      req.TrustDomainID,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	d.state.bundles[b.ID.UUID] = newRecordLocked(d, b)
+
+	return cloneBundle(&b), nil
+}
+
+func (d *MemoryDatastore) FindBundleByID(ctx context.Context, bundleID uuid.UUID) (*entity.Bundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	r, ok := d.state.bundles[bundleID]
+	if !ok {
+		return nil, nil
+	}
+
+	return cloneBundle(&r.entity), nil
+}
+
+func (d *MemoryDatastore) FindBundleByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.Bundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.bundles {
+		if r.entity.TrustDomainID == trustDomainID {
+			return cloneBundle(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) FindFederatedBundlesByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.Bundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	bundles := make(map[uuid.UUID]*entity.Bundle, len(d.state.bundles))
+	for _, r := range d.state.bundles {
+		bundles[r.entity.TrustDomainID] = &r.entity
+	}
+
+	// peers are collected in a set, as a trust domain can be a peer through relationships, groups and policies
+	peers := make(map[uuid.UUID]bool)
+	now := time.Now()
+	for _, r := range d.state.relationships {
+		if !r.entity.Trusts(trustDomainID) || !r.entity.InEffect(now) {
+			continue
+		}
+
+		peerID := r.entity.TrustDomainAID
+		if peerID == trustDomainID {
+			peerID = r.entity.TrustDomainBID
+		}
+		peers[peerID] = true
+	}
+	for _, self := range d.state.groupMembers {
+		if self.entity.TrustDomainID != trustDomainID {
+			continue
+		}
+
+		mode := d.state.groups[self.entity.GroupID].entity.Mode
+		for _, peer := range d.state.groupMembers {
+			if peer.entity.GroupID != self.entity.GroupID || peer.entity.TrustDomainID == trustDomainID {
+				continue
+			}
+			if mode.Federates(self.entity.Hub, peer.entity.Hub) {
+				peers[peer.entity.TrustDomainID] = true
+			}
+		}
+	}
+
+	if self, ok := d.state.trustDomains[trustDomainID]; ok {
+		for _, p := range d.state.policies {
+			for peerID, peer := range d.state.trustDomains {
+				if peerID != trustDomainID && p.entity.Federates(self.entity.Labels, peer.entity.Labels) {
+					peers[peerID] = true
+				}
+			}
+		}
+	}
+
+	var result []*entity.Bundle
+	for peerID := range peers {
+		b, ok := bundles[peerID]
+		if !ok {
+			continue
+		}
+
+		federated := cloneBundle(b)
+		federated.TrustDomainName = d.state.trustDomains[peerID].entity.Name
+		result = append(result, federated)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].TrustDomainName.String() < result[j].TrustDomainName.String()
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) ListBundles(ctx context.Context) ([]*entity.Bundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.bundles, func(b *entity.Bundle) time.Time { return b.CreatedAt })
+
+	result := make([]*entity.Bundle, len(records))
+	for i, r := range records {
+		result[i] = cloneBundle(&r.entity)
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteBundle(ctx context.Context, bundleID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.state.bundles, bundleID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateJoinToken(ctx context.Context, req *entity.JoinToken) (*entity.JoinToken, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.state.trustDomains[req.TrustDomainID]; !ok {
+		return nil, fmt.Errorf("failed creating join token: trust domain %q does not exist", req.TrustDomainID)
+	}
+	for _, r := range d.state.joinTokens {
+		if r.entity.Token == req.Token {
+			return nil, &ConflictError{msg: "failed creating join token: token already exists"}
+		}
+	}
+
+	now := memoryNow()
+	jt := entity.JoinToken{
+		ID:            uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Token:         req.Token,
+		TrustDomainID: req.TrustDomainID,
+		ExpiresAt:     req.ExpiresAt.Truncate(time.Microsecond),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	d.state.joinTokens[jt.ID.UUID] = newRecordLocked(d, jt)
+
+	return cloneJoinToken(&jt), nil
+}
+
+func (d *MemoryDatastore) FindJoinTokensByID(ctx context.Context, joinTokenID uuid.UUID) (*entity.JoinToken, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	r, ok := d.state.joinTokens[joinTokenID]
+	if !ok {
+		return nil, nil
+	}
+
+	return cloneJoinToken(&r.entity), nil
+}
+
+func (d *MemoryDatastore) FindJoinTokensByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.JoinToken, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []*entity.JoinToken
+	for _, r := range sortedByCreation(d.state.joinTokens, func(jt *entity.JoinToken) time.Time { return jt.CreatedAt }) {
+		if r.entity.TrustDomainID == trustDomainID {
+			result = append(result, cloneJoinToken(&r.entity))
+		}
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) ListJoinTokens(ctx context.Context) ([]*entity.JoinToken, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.joinTokens, func(jt *entity.JoinToken) time.Time { return jt.CreatedAt })
+
+	result := make([]*entity.JoinToken, len(records))
+	for i, r := range records {
+		result[i] = cloneJoinToken(&r.entity)
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) UpdateJoinToken(ctx context.Context, joinTokenID uuid.UUID, used bool) (*entity.JoinToken, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r, ok := d.state.joinTokens[joinTokenID]
+	if !ok {
+		return nil, fmt.Errorf("failed updating join token with ID=%q, %w", joinTokenID, errMemoryNotFound)
+	}
+
+	r.entity.Used = used
+	r.entity.UpdatedAt = memoryNow()
+
+	return cloneJoinToken(&r.entity), nil
+}
+
+func (d *MemoryDatastore) DeleteJoinToken(ctx context.Context, joinTokenID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.state.joinTokens, joinTokenID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) FindJoinToken(ctx context.Context, token string) (*entity.JoinToken, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.joinTokens {
+		if r.entity.Token == token {
+			return cloneJoinToken(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) CreateOrUpdateRelationship(ctx context.Context, req *entity.Relationship) (*entity.Relationship, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := memoryNow()
+	if req.ID.Valid {
+		r, ok := d.state.relationships[req.ID.UUID]
+		if !ok {
+			return nil, fmt.Errorf("failed updating relationship: %w", errMemoryNotFound)
+		}
+
+		r.entity.TrustDomainAConsent = req.TrustDomainAConsent
+		r.entity.TrustDomainBConsent = req.TrustDomainBConsent
+		r.entity.Direction = req.Direction.OrDefault()
+		r.entity.NotBefore = req.NotBefore.Truncate(time.Microsecond)
+		r.entity.NotAfter = req.NotAfter.Truncate(time.Microsecond)
+		r.entity.ExpiredAt = req.ExpiredAt.Truncate(time.Microsecond)
+		r.entity.UpdatedAt = now
+
+		return cloneRelationship(&r.entity), nil
+	}
+
+	for _, id := range []uuid.UUID{req.TrustDomainAID, req.TrustDomainBID} {
+		if _, ok := d.state.trustDomains[id]; !ok {
+			return nil, fmt.Errorf("failed creating new relationship: trust domain %q does not exist", id)
+		}
+	}
+	for _, r := range d.state.relationships {
+		if r.entity.TrustDomainAID == req.TrustDomainAID && r.entity.TrustDomainBID == req.TrustDomainBID {
+			return nil, &ConflictError{msg: "failed creating new relationship: relationship already exists"}
+		}
+	}
+
+	rel := entity.Relationship{
+		ID:             uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		TrustDomainAID: req.TrustDomainAID,
+		TrustDomainBID: req.TrustDomainBID,
+		Direction:      req.Direction.OrDefault(),
+		NotBefore:      req.NotBefore.Truncate(time.Microsecond),
+		NotAfter:       req.NotAfter.Truncate(time.Microsecond),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	d.state.relationships[rel.ID.UUID] = newRecordLocked(d, rel)
+
+	return cloneRelationship(&rel), nil
+}
+
+func (d *MemoryDatastore) FindRelationshipByID(ctx context.Context, relationshipID uuid.UUID) (*entity.Relationship, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	r, ok := d.state.relationships[relationshipID]
+	if !ok {
+		return nil, nil
+	}
+
+	return cloneRelationship(&r.entity), nil
+}
+
+func (d *MemoryDatastore) FindRelationshipsByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.Relationship, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []*entity.Relationship
+	for _, r := range sortedByCreation(d.state.relationships, func(r *entity.Relationship) time.Time { return r.CreatedAt }) {
+		if r.entity.TrustDomainAID == trustDomainID || r.entity.TrustDomainBID == trustDomainID {
+			result = append(result, cloneRelationship(&r.entity))
+		}
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) ListRelationships(ctx context.Context) ([]*entity.Relationship, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.relationships, func(r *entity.Relationship) time.Time { return r.CreatedAt })
+
+	result := make([]*entity.Relationship, len(records))
+	for i, r := range records {
+		result[i] = cloneRelationship(&r.entity)
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) ListExpiredRelationships(ctx context.Context, now time.Time) ([]*entity.Relationship, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []*entity.Relationship
+	for _, r := range d.state.relationships {
+		if r.entity.NotAfter.IsZero() || r.entity.NotAfter.After(now) || !r.entity.ExpiredAt.IsZero() {
+			continue
+		}
+		result = append(result, cloneRelationship(&r.entity))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NotAfter.Before(result[j].NotAfter) })
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) ListRelationshipsWithTrustDomainNames(ctx context.Context) ([]*entity.Relationship, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.relationships, func(r *entity.Relationship) time.Time { return r.CreatedAt })
+
+	result := make([]*entity.Relationship, len(records))
+	for i, r := range records {
+		rel := cloneRelationship(&r.entity)
+		rel.TrustDomainAName = d.state.trustDomains[rel.TrustDomainAID].entity.Name
+		rel.TrustDomainBName = d.state.trustDomains[rel.TrustDomainBID].entity.Name
+		result[i] = rel
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteRelationship(ctx context.Context, relationshipID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.state.relationships, relationshipID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateBundleRejection(ctx context.Context, req *entity.BundleRejection) (*entity.BundleRejection, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.state.trustDomains[req.TrustDomainID]; !ok {
+		return nil, fmt.Errorf("failed creating new bundle rejection: trust domain %q does not exist", req.TrustDomainID)
+	}
+
+	br := entity.BundleRejection{
+		ID:            uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		TrustDomainID: req.TrustDomainID,
+		Digest:        cloneBytes(req.Digest),
+		Violations:    cloneViolations(req.Violations),
+		CreatedAt:     memoryNow(),
+	}
+	d.state.rejections[br.ID.UUID] = newRecordLocked(d, br)
+
+	return cloneBundleRejection(&br), nil
+}
+
+func (d *MemoryDatastore) FindBundleRejectionsByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) ([]*entity.BundleRejection, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []*entity.BundleRejection
+	for _, r := range sortedByCreation(d.state.rejections, func(br *entity.BundleRejection) time.Time { return br.CreatedAt }) {
+		if r.entity.TrustDomainID == trustDomainID {
+			result = append(result, cloneBundleRejection(&r.entity))
+		}
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) ListBundleRejections(ctx context.Context) ([]*entity.BundleRejection, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.rejections, func(br *entity.BundleRejection) time.Time { return br.CreatedAt })
+
+	result := make([]*entity.BundleRejection, len(records))
+	for i, r := range records {
+		br := cloneBundleRejection(&r.entity)
+		br.TrustDomainName = d.state.trustDomains[br.TrustDomainID].entity.Name
+		result[i] = br
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteBundleRejectionsBefore(ctx context.Context, before time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, r := range d.state.rejections {
+		if r.entity.CreatedAt.Before(before) {
+			delete(d.state.rejections, id)
+		}
+	}
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateOrUpdateQuarantinedBundle(ctx context.Context, req *entity.QuarantinedBundle) (*entity.QuarantinedBundle, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := memoryNow()
+	if req.ID.Valid {
+		r, ok := d.state.quarantined[req.ID.UUID]
+		if !ok {
+			return nil, fmt.Errorf("failed updating quarantined bundle: %w", errMemoryNotFound)
+		}
+
+		r.entity.Data = cloneBytes(req.Data)
+		r.entity.Digest = cloneBytes(req.Digest)
+		r.entity.DigestAlgorithm = req.DigestAlgorithm
+		r.entity.Reasons = cloneViolations(req.Reasons)
+		r.entity.ReleaseAt = req.ReleaseAt.Truncate(time.Microsecond)
+		r.entity.UpdatedAt = now
+
+		return cloneQuarantinedBundle(&r.entity), nil
+	}
+
+	if _, ok := d.state.trustDomains[req.TrustDomainID]; !ok {
+		return nil, fmt.Errorf("failed creating new quarantined bundle: trust domain %q does not exist", req.TrustDomainID)
+	}
+	for _, r := range d.state.quarantined {
+		if r.entity.TrustDomainID == req.TrustDomainID {
+			return nil, &ConflictError{msg: "failed creating new quarantined bundle: the trust domain already has a quarantined bundle"}
+		}
+	}
+
+	qb := entity.QuarantinedBundle{
+		ID:              uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		TrustDomainID:   req.TrustDomainID,
+		Data:            cloneBytes(req.Data),
+		Digest:          cloneBytes(req.Digest),
+		DigestAlgorithm: req.DigestAlgorithm,
+		Reasons:         cloneViolations(req.Reasons),
+		ReleaseAt:       req.ReleaseAt.Truncate(time.Microsecond),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	d.state.quarantined[qb.ID.UUID] = newRecordLocked(d, qb)
+
+	return cloneQuarantinedBundle(&qb), nil
+}
+
+func (d *MemoryDatastore) FindQuarantinedBundleByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.QuarantinedBundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.quarantined {
+		if r.entity.TrustDomainID == trustDomainID {
+			return cloneQuarantinedBundle(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) ListQuarantinedBundles(ctx context.Context) ([]*entity.QuarantinedBundle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.quarantined, func(qb *entity.QuarantinedBundle) time.Time { return qb.CreatedAt })
+
+	result := make([]*entity.QuarantinedBundle, len(records))
+	for i, r := range records {
+		qb := cloneQuarantinedBundle(&r.entity)
+		qb.TrustDomainName = d.state.trustDomains[qb.TrustDomainID].entity.Name
+		result[i] = qb
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteQuarantinedBundle(ctx context.Context, quarantinedBundleID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.state.quarantined, quarantinedBundleID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) RecordHarvesterSession(ctx context.Context, req *entity.HarvesterSession) (*entity.HarvesterSession, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.state.trustDomains[req.TrustDomainID]; !ok {
+		return nil, fmt.Errorf("failed recording harvester session: trust domain %q does not exist", req.TrustDomainID)
+	}
+
+	now := memoryNow()
+	for _, r := range d.state.sessions {
+		if r.entity.TrustDomainID != req.TrustDomainID {
+			continue
+		}
+
+		if !req.LastOnboardAt.IsZero() {
+			r.entity.LastOnboardAt = req.LastOnboardAt.Truncate(time.Microsecond)
+		}
+		if !req.LastPostAt.IsZero() {
+			r.entity.LastPostAt = req.LastPostAt.Truncate(time.Microsecond)
+		}
+		if !req.LastSyncAt.IsZero() {
+			r.entity.LastSyncAt = req.LastSyncAt.Truncate(time.Microsecond)
+		}
+		r.entity.RemoteAddress = req.RemoteAddress
+		r.entity.HarvesterVersion = req.HarvesterVersion
+		r.entity.SPIREVersion = req.SPIREVersion
+		r.entity.UpdatedAt = now
+
+		return cloneHarvesterSession(&r.entity), nil
+	}
+
+	hs := entity.HarvesterSession{
+		ID:               uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		TrustDomainID:    req.TrustDomainID,
+		LastOnboardAt:    req.LastOnboardAt.Truncate(time.Microsecond),
+		LastPostAt:       req.LastPostAt.Truncate(time.Microsecond),
+		LastSyncAt:       req.LastSyncAt.Truncate(time.Microsecond),
+		RemoteAddress:    req.RemoteAddress,
+		HarvesterVersion: req.HarvesterVersion,
+		SPIREVersion:     req.SPIREVersion,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	d.state.sessions[hs.ID.UUID] = newRecordLocked(d, hs)
+
+	return cloneHarvesterSession(&hs), nil
+}
+
+func (d *MemoryDatastore) FindHarvesterSessionByTrustDomainID(ctx context.Context, trustDomainID uuid.UUID) (*entity.HarvesterSession, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.sessions {
+		if r.entity.TrustDomainID == trustDomainID {
+			return cloneHarvesterSession(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) ListHarvesterSessions(ctx context.Context) ([]*entity.HarvesterSession, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.sessions, func(hs *entity.HarvesterSession) time.Time { return hs.CreatedAt })
+
+	result := make([]*entity.HarvesterSession, len(records))
+	for i, r := range records {
+		hs := cloneHarvesterSession(&r.entity)
+		hs.TrustDomainName = d.state.trustDomains[hs.TrustDomainID].entity.Name
+		result[i] = hs
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) CreateFederationPolicy(ctx context.Context, req *entity.FederationPolicy) (*entity.FederationPolicy, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, r := range d.state.policies {
+		if r.entity.Name == req.Name {
+			return nil, &ConflictError{msg: fmt.Sprintf("failed creating new federation policy: federation policy %q already exists", req.Name)}
+		}
+	}
+
+	now := memoryNow()
+	p := entity.FederationPolicy{
+		ID:           uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Name:         req.Name,
+		Selector:     entity.LabelSelector(cloneLabels(req.Selector)),
+		PeerSelector: entity.LabelSelector(cloneLabels(req.PeerSelector)),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	d.state.policies[p.ID.UUID] = newRecordLocked(d, p)
+
+	return cloneFederationPolicy(&p), nil
+}
+
+func (d *MemoryDatastore) FindFederationPolicyByName(ctx context.Context, name string) (*entity.FederationPolicy, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.policies {
+		if r.entity.Name == name {
+			return cloneFederationPolicy(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) ListFederationPolicies(ctx context.Context) ([]*entity.FederationPolicy, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*entity.FederationPolicy, 0, len(d.state.policies))
+	for _, r := range d.state.policies {
+		result = append(result, cloneFederationPolicy(&r.entity))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteFederationPolicy(ctx context.Context, policyID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.state.policies, policyID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateFederationGroup(ctx context.Context, req *entity.FederationGroup) (*entity.FederationGroup, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := req.Mode.Validate(); err != nil {
+		return nil, fmt.Errorf("failed creating new federation group: %w", err)
+	}
+	for _, r := range d.state.groups {
+		if r.entity.Name == req.Name {
+			return nil, &ConflictError{msg: fmt.Sprintf("failed creating new federation group: federation group %q already exists", req.Name)}
+		}
+	}
+
+	now := memoryNow()
+	g := entity.FederationGroup{
+		ID:        uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Name:      req.Name,
+		Mode:      req.Mode.OrDefault(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	d.state.groups[g.ID.UUID] = newRecordLocked(d, g)
+
+	return cloneFederationGroup(&g), nil
+}
+
+func (d *MemoryDatastore) FindFederationGroupByName(ctx context.Context, name string) (*entity.FederationGroup, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.groups {
+		if r.entity.Name == name {
+			return cloneFederationGroup(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) ListFederationGroups(ctx context.Context) ([]*entity.FederationGroup, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*entity.FederationGroup, 0, len(d.state.groups))
+	for _, r := range d.state.groups {
+		result = append(result, cloneFederationGroup(&r.entity))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteFederationGroup(ctx context.Context, groupID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// memberships are owned by the group
+	for id, r := range d.state.groupMembers {
+		if r.entity.GroupID == groupID {
+			delete(d.state.groupMembers, id)
+		}
+	}
+
+	delete(d.state.groups, groupID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) AddFederationGroupMember(ctx context.Context, req *entity.FederationGroupMember) (*entity.FederationGroupMember, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.state.groups[req.GroupID]; !ok {
+		return nil, fmt.Errorf("failed adding federation group member: federation group %q does not exist", req.GroupID)
+	}
+	if _, ok := d.state.trustDomains[req.TrustDomainID]; !ok {
+		return nil, fmt.Errorf("failed adding federation group member: trust domain %q does not exist", req.TrustDomainID)
+	}
+	for _, r := range d.state.groupMembers {
+		if r.entity.GroupID != req.GroupID {
+			continue
+		}
+		if r.entity.TrustDomainID == req.TrustDomainID {
+			return nil, &ConflictError{msg: "failed adding federation group member: trust domain is already a member of the group"}
+		}
+		if r.entity.Hub && req.Hub {
+			return nil, &ConflictError{msg: "failed adding federation group member: the group already has a hub"}
+		}
+	}
+
+	m := entity.FederationGroupMember{
+		ID:            uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		GroupID:       req.GroupID,
+		TrustDomainID: req.TrustDomainID,
+		Hub:           req.Hub,
+		CreatedAt:     memoryNow(),
+	}
+	d.state.groupMembers[m.ID.UUID] = newRecordLocked(d, m)
+
+	return cloneFederationGroupMember(&m), nil
+}
+
+func (d *MemoryDatastore) ListFederationGroupMembers(ctx context.Context, groupID uuid.UUID) ([]*entity.FederationGroupMember, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []*entity.FederationGroupMember
+	for _, r := range d.state.groupMembers {
+		if r.entity.GroupID != groupID {
+			continue
+		}
+
+		m := cloneFederationGroupMember(&r.entity)
+		m.TrustDomainName = d.state.trustDomains[m.TrustDomainID].entity.Name
+		result = append(result, m)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TrustDomainName.String() < result[j].TrustDomainName.String()
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) RemoveFederationGroupMember(ctx context.Context, groupID, trustDomainID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, r := range d.state.groupMembers {
+		if r.entity.GroupID == groupID && r.entity.TrustDomainID == trustDomainID {
+			delete(d.state.groupMembers, id)
+		}
+	}
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreatePeer(ctx context.Context, req *entity.Peer) (*entity.Peer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, r := range d.state.peers {
+		if r.entity.Name == req.Name {
+			return nil, &ConflictError{msg: fmt.Sprintf("failed creating new peer: peer %q already exists", req.Name)}
+		}
+	}
+
+	now := memoryNow()
+	p := entity.Peer{
+		ID:        uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Name:      req.Name,
+		URL:       req.URL,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	d.state.peers[p.ID.UUID] = newRecordLocked(d, p)
+
+	return clonePeer(&p), nil
+}
+
+func (d *MemoryDatastore) FindPeerByName(ctx context.Context, name string) (*entity.Peer, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, r := range d.state.peers {
+		if r.entity.Name == name {
+			return clonePeer(&r.entity), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *MemoryDatastore) ListPeers(ctx context.Context) ([]*entity.Peer, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*entity.Peer, 0, len(d.state.peers))
+	for _, r := range d.state.peers {
+		result = append(result, clonePeer(&r.entity))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeletePeer(ctx context.Context, peerID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the trust domains imported from the peer are owned by the peer, and cannot be deleted while they are
+	// referenced by relationships
+	var imported []uuid.UUID
+	for id, r := range d.state.trustDomains {
+		if r.entity.PeerID.Valid && r.entity.PeerID.UUID == peerID {
+			if err := d.checkTrustDomainUnreferencedLocked(id); err != nil {
+				return fmt.Errorf("failed deleting peer with ID=%q: %w", peerID, err)
+			}
+			imported = append(imported, id)
+		}
+	}
+	for _, id := range imported {
+		d.deleteTrustDomainLocked(id)
+	}
+
+	for id, r := range d.state.peerShares {
+		if r.entity.peerID == peerID {
+			delete(d.state.peerShares, id)
+		}
+	}
+
+	delete(d.state.peers, peerID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) AddPeerSharedTrustDomain(ctx context.Context, peerID, trustDomainID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.state.peers[peerID]; !ok {
+		return fmt.Errorf("failed sharing trust domain with peer: peer %q does not exist", peerID)
+	}
+	if _, ok := d.state.trustDomains[trustDomainID]; !ok {
+		return fmt.Errorf("failed sharing trust domain with peer: trust domain %q does not exist", trustDomainID)
+	}
+	for _, r := range d.state.peerShares {
+		if r.entity.peerID == peerID && r.entity.trustDomainID == trustDomainID {
+			return &ConflictError{msg: "failed sharing trust domain with peer: trust domain is already shared with the peer"}
+		}
+	}
+
+	d.state.peerShares[uuid.New()] = newRecordLocked(d, memoryPeerShare{peerID: peerID, trustDomainID: trustDomainID})
+
+	return nil
+}
+
+func (d *MemoryDatastore) ListPeerSharedTrustDomains(ctx context.Context, peerID uuid.UUID) ([]*entity.TrustDomain, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []*entity.TrustDomain
+	for _, r := range d.state.peerShares {
+		if r.entity.peerID == peerID {
+			result = append(result, cloneTrustDomain(&d.state.trustDomains[r.entity.trustDomainID].entity))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name.String() < result[j].Name.String()
+	})
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) RemovePeerSharedTrustDomain(ctx context.Context, peerID, trustDomainID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, r := range d.state.peerShares {
+		if r.entity.peerID == peerID && r.entity.trustDomainID == trustDomainID {
+			delete(d.state.peerShares, id)
+		}
+	}
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateWebhook(ctx context.Context, req *entity.Webhook) (*entity.Webhook, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := memoryNow()
+	w := entity.Webhook{
+		ID:         uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: cloneStrings(req.EventTypes),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	d.state.webhooks[w.ID.UUID] = newRecordLocked(d, w)
+
+	return cloneWebhook(&w), nil
+}
+
+func (d *MemoryDatastore) ListWebhooks(ctx context.Context) ([]*entity.Webhook, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.webhooks, func(w *entity.Webhook) time.Time { return w.CreatedAt })
+
+	result := make([]*entity.Webhook, len(records))
+	for i, r := range records {
+		result[i] = cloneWebhook(&r.entity)
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// deliveries are owned by the webhook
+	for id, r := range d.state.deliveries {
+		if r.entity.WebhookID == webhookID {
+			delete(d.state.deliveries, id)
+		}
+	}
+
+	delete(d.state.webhooks, webhookID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateWebhookDelivery(ctx context.Context, req *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.state.webhooks[req.WebhookID]; !ok {
+		return nil, fmt.Errorf("failed creating new webhook delivery: webhook %q does not exist", req.WebhookID)
+	}
+
+	now := memoryNow()
+	wd := entity.WebhookDelivery{
+		ID:            uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		WebhookID:     req.WebhookID,
+		EventType:     req.EventType,
+		Payload:       cloneBytes(req.Payload),
+		NextAttemptAt: req.NextAttemptAt.Truncate(time.Microsecond),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	d.state.deliveries[wd.ID.UUID] = newRecordLocked(d, wd)
+
+	return cloneWebhookDelivery(&wd), nil
+}
+
+func (d *MemoryDatastore) UpdateWebhookDelivery(ctx context.Context, req *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r, ok := d.state.deliveries[req.ID.UUID]
+	if !ok {
+		return nil, fmt.Errorf("failed updating webhook delivery: %w", errMemoryNotFound)
+	}
+
+	r.entity.Attempts = req.Attempts
+	r.entity.NextAttemptAt = req.NextAttemptAt.Truncate(time.Microsecond)
+	r.entity.LastError = req.LastError
+	r.entity.Dead = req.Dead
+	r.entity.UpdatedAt = memoryNow()
+
+	return cloneWebhookDelivery(&r.entity), nil
+}
+
+func (d *MemoryDatastore) FindWebhookDeliveryByID(ctx context.Context, deliveryID uuid.UUID) (*entity.WebhookDelivery, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	r, ok := d.state.deliveries[deliveryID]
+	if !ok {
+		return nil, nil
+	}
+
+	return cloneWebhookDelivery(&r.entity), nil
+}
+
+func (d *MemoryDatastore) ListWebhookDeliveries(ctx context.Context, dead bool) ([]*entity.WebhookDelivery, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	records := sortedByCreation(d.state.deliveries, func(wd *entity.WebhookDelivery) time.Time { return wd.CreatedAt })
+
+	result := make([]*entity.WebhookDelivery, 0, len(records))
+	for _, r := range records {
+		if r.entity.Dead != dead {
+			continue
+		}
+		wd := cloneWebhookDelivery(&r.entity)
+		wd.WebhookURL = d.state.webhooks[wd.WebhookID].entity.URL
+		result = append(result, wd)
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.state.deliveries, deliveryID)
+
+	return nil
+}
+
+func (d *MemoryDatastore) CreateWatchEvent(ctx context.Context, req *entity.WatchEvent) (*entity.WatchEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.resourceVersion++
+	we := &entity.WatchEvent{
+		Version:   d.state.resourceVersion,
+		Kind:      req.Kind,
+		Type:      req.Type,
+		Object:    cloneBytes(req.Object),
+		CreatedAt: memoryNow(),
+	}
+	d.state.watchEvents = append(d.state.watchEvents, we)
+
+	return cloneWatchEvent(we), nil
+}
+
+func (d *MemoryDatastore) FindResourceVersion(ctx context.Context) (int64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.state.resourceVersion, nil
+}
+
+func (d *MemoryDatastore) ListWatchEvents(ctx context.Context, afterVersion int64, limit int) ([]*entity.WatchEvent, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	// events are appended in version order
+	i := sort.Search(len(d.state.watchEvents), func(i int) bool { return d.state.watchEvents[i].Version > afterVersion })
+
+	var result []*entity.WatchEvent
+	for _, we := range d.state.watchEvents[i:] {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, cloneWatchEvent(we))
+	}
+
+	return result, nil
+}
+
+func (d *MemoryDatastore) DeleteWatchEventsBefore(ctx context.Context, before time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var kept []*entity.WatchEvent
+	for _, we := range d.state.watchEvents {
+		if !we.CreatedAt.Before(before) {
+			kept = append(kept, we)
+		}
+	}
+	d.state.watchEvents = kept
+
+	return nil
+}
+
+func (d *MemoryDatastore) RecordJobStatus(ctx context.Context, req *entity.JobStatus) (*entity.JobStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	js := *req
+	js.StartedAt = js.StartedAt.Truncate(time.Microsecond)
+	js.FinishedAt = js.FinishedAt.Truncate(time.Microsecond)
+	js.NextRunAt = js.NextRunAt.Truncate(time.Microsecond)
+	d.state.jobStatuses[js.Name] = js
+
+	return &js, nil
+}
+
+func (d *MemoryDatastore) ListJobStatuses(ctx context.Context) ([]*entity.JobStatus, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*entity.JobStatus, 0, len(d.state.jobStatuses))
+	for _, js := range d.state.jobStatuses {
+		js := js
+		result = append(result, &js)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// TryLock acquires the lock unless it is already held, as every user of the datastore runs in this process.
+func (d *MemoryDatastore) TryLock(ctx context.Context, key int64) (Lock, error) {
+	d.coordinator.mu.Lock()
+	defer d.coordinator.mu.Unlock()
+
+	if d.coordinator.locks[key] {
+		return nil, nil
+	}
+	d.coordinator.locks[key] = true
+
+	return &memoryLock{coordinator: d.coordinator, key: key}, nil
+}
+
+func (d *MemoryDatastore) Notify(ctx context.Context, channel, payload string) error {
+	d.notify(memoryNotification{channel: channel, payload: payload})
+	return nil
+}
+
+func (d *MemoryDatastore) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	ch := make(chan string, memoryListenerBuffer)
+
+	d.coordinator.mu.Lock()
+	if d.coordinator.listeners[channel] == nil {
+		d.coordinator.listeners[channel] = make(map[chan string]struct{})
+	}
+	d.coordinator.listeners[channel][ch] = struct{}{}
+	d.coordinator.mu.Unlock()
+
+	defer func() {
+		d.coordinator.mu.Lock()
+		delete(d.coordinator.listeners[channel], ch)
+		d.coordinator.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case payload := <-ch:
+			fn(payload)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// notify sends the notification to the listeners of its channel, or holds it until the transaction is committed.
+func (d *MemoryDatastore) notify(n memoryNotification) {
+	if d.tx {
+		d.pending = append(d.pending, n)
+		return
+	}
+
+	d.coordinator.mu.Lock()
+	defer d.coordinator.mu.Unlock()
+
+	for ch := range d.coordinator.listeners[n.channel] {
+		select {
+		case ch <- n.payload:
+		default:
+			d.logger.Warnf("Notification dropped, a listener of channel %q is falling behind", n.channel)
+		}
+	}
+}
+
+// memoryLock is a lock held until it is released, as it cannot be lost.
+type memoryLock struct {
+	coordinator *memoryCoordinator
+	key         int64
+}
+
+func (l *memoryLock) Check(ctx context.Context) error {
+	return nil
+}
+
+func (l *memoryLock) Release(ctx context.Context) error {
+	l.coordinator.mu.Lock()
+	defer l.coordinator.mu.Unlock()
+
+	delete(l.coordinator.locks, l.key)
+	return nil
+}
+
+// errMemoryNotFound mirrors the error returned by the SQL datastore when
+// an update does not match any row.
+var errMemoryNotFound = errors.New("no rows in result set")
+
+// newRecordLocked wraps the given entity into a record with the next insertion sequence.
+// It must be called while holding the write lock.
+func newRecordLocked[T any](d *MemoryDatastore, e T) *memoryRecord[T] {
+	d.state.seq++
+	return &memoryRecord[T]{seq: d.state.seq, entity: e}
+}
+
+func (s *memoryState) clone() *memoryState {
+	return &memoryState{
+		seq:           s.seq,
+		trustDomains:  cloneRecords(s.trustDomains),
+		bundles:       cloneRecords(s.bundles),
+		joinTokens:    cloneRecords(s.joinTokens),
+		relationships: cloneRecords(s.relationships),
+		rejections:    cloneRecords(s.rejections),
+		quarantined:   cloneRecords(s.quarantined),
+		sessions:      cloneRecords(s.sessions),
+		webhooks:      cloneRecords(s.webhooks),
+		deliveries:    cloneRecords(s.deliveries),
+		groups:        cloneRecords(s.groups),
+		groupMembers:  cloneRecords(s.groupMembers),
+		policies:      cloneRecords(s.policies),
+		peers:         cloneRecords(s.peers),
+		peerShares:    cloneRecords(s.peerShares),
+		jobStatuses:   cloneJobStatuses(s.jobStatuses),
+
+		resourceVersion: s.resourceVersion,
+		// watch events are never modified, so they are shared
+		watchEvents: append([]*entity.WatchEvent{}, s.watchEvents...),
+	}
+}
+
+// cloneJobStatuses copies the job statuses, which only hold values.
+func cloneJobStatuses(statuses map[string]entity.JobStatus) map[string]entity.JobStatus {
+	result := make(map[string]entity.JobStatus, len(statuses))
+	for name, js := range statuses {
+		result[name] = js
+	}
+	return result
+}
+
+// cloneRecords copies the records so they can be updated in place without affecting the source.
+// Slices held by the entities are shared, as they are always replaced and never modified.
+func cloneRecords[T any](records map[uuid.UUID]*memoryRecord[T]) map[uuid.UUID]*memoryRecord[T] {
+	result := make(map[uuid.UUID]*memoryRecord[T], len(records))
+	for id, r := range records {
+		c := *r
+		result[id] = &c
+	}
+	return result
+}
+
+// sortedByCreation returns the records ordered by creation time, newest first,
+// which is the ordering used by the SQL list queries.
+func sortedByCreation[T any](records map[uuid.UUID]*memoryRecord[T], createdAt func(*T) time.Time) []*memoryRecord[T] {
+	result := make([]*memoryRecord[T], 0, len(records))
+	for _, r := range records {
+		result = append(result, r)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ti, tj := createdAt(&result[i].entity), createdAt(&result[j].entity)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return result[i].seq > result[j].seq
+	})
+
+	return result
+}
+
+// memoryNow returns the current time with the same precision Postgres stores timestamps with.
+func memoryNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func cloneTrustDomain(td *entity.TrustDomain) *entity.TrustDomain {
+	c := *td
+	c.OnboardingBundle = cloneBytes(td.OnboardingBundle)
+	c.Labels = cloneLabels(td.Labels)
+	return &c
+}
+
+func cloneBundle(b *entity.Bundle) *entity.Bundle {
+	c := *b
+	c.Data = cloneBytes(b.Data)
+	c.Digest = cloneBytes(b.Digest)
+	c.Signature = cloneBytes(b.Signature)
+	c.SigningCert = cloneBytes(b.SigningCert)
+	return &c
+}
+
+func cloneJoinToken(jt *entity.JoinToken) *entity.JoinToken {
+	c := *jt
+	return &c
+}
+
+func cloneRelationship(r *entity.Relationship) *entity.Relationship {
+	c := *r
+	return &c
+}
+
+func cloneBundleRejection(br *entity.BundleRejection) *entity.BundleRejection {
+	c := *br
+	c.Digest = cloneBytes(br.Digest)
+	c.Violations = cloneViolations(br.Violations)
+	return &c
+}
+
+func cloneQuarantinedBundle(qb *entity.QuarantinedBundle) *entity.QuarantinedBundle {
+	c := *qb
+	c.Data = cloneBytes(qb.Data)
+	c.Digest = cloneBytes(qb.Digest)
+	c.Reasons = cloneViolations(qb.Reasons)
+	return &c
+}
+
+func cloneHarvesterSession(hs *entity.HarvesterSession) *entity.HarvesterSession {
+	c := *hs
+	return &c
+}
+
+func cloneFederationPolicy(p *entity.FederationPolicy) *entity.FederationPolicy {
+	c := *p
+	c.Selector = entity.LabelSelector(cloneLabels(p.Selector))
+	c.PeerSelector = entity.LabelSelector(cloneLabels(p.PeerSelector))
+	return &c
+}
+
+func cloneFederationGroup(g *entity.FederationGroup) *entity.FederationGroup {
+	c := *g
+	c.Members = nil
+	return &c
+}
+
+func cloneFederationGroupMember(m *entity.FederationGroupMember) *entity.FederationGroupMember {
+	c := *m
+	return &c
+}
+
+func clonePeer(p *entity.Peer) *entity.Peer {
+	c := *p
+	c.SharedTrustDomains = nil
+	c.TrustDomains = nil
+	return &c
+}
+
+func cloneWebhook(w *entity.Webhook) *entity.Webhook {
+	c := *w
+	c.EventTypes = cloneStrings(w.EventTypes)
+	return &c
+}
+
+func cloneWebhookDelivery(wd *entity.WebhookDelivery) *entity.WebhookDelivery {
+	c := *wd
+	c.Payload = cloneBytes(wd.Payload)
+	return &c
+}
+
+func cloneWatchEvent(we *entity.WatchEvent) *entity.WatchEvent {
+	c := *we
+	c.Object = cloneBytes(we.Object)
+	return &c
+}
+
+// cloneLabels copies the labels, mapping empty labels to nil as the SQL datastore does.
+func cloneLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneViolations(v []entity.BundleViolation) []entity.BundleViolation {
+	if v == nil {
+		return nil
+	}
+	return append([]entity.BundleViolation{}, v...)
+}
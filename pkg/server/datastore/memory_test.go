@@ -0,0 +1,55 @@
+package datastore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore/datastoretest"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDatastoreConformance(t *testing.T) {
+	t.Parallel()
+	datastoretest.Run(t, func(t *testing.T) datastore.Datastore {
+		return datastore.NewMemoryDatastore(logrus.New())
+	})
+}
+
+func TestMemoryDatastoreConcurrentCreate(t *testing.T) {
+	t.Parallel()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	ctx := context.Background()
+	td := spiffeid.RequireTrustDomainFromString("foo.test")
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+
+	list, err := ds.ListTrustDomains(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
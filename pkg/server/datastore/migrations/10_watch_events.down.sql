@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS watch_events;
+DROP TABLE IF EXISTS resource_versions;
@@ -0,0 +1,23 @@
+-- resource_versions holds the version of the last change recorded in watch_events. Recording a change locks the
+-- row until the transaction ends, so versions are committed in order and without gaps.
+CREATE TABLE IF NOT EXISTS resource_versions
+(
+    id      INTEGER PRIMARY KEY CHECK (id = 1),
+    version BIGINT NOT NULL
+);
+
+INSERT INTO resource_versions(id, version)
+VALUES (1, 0)
+ON CONFLICT DO NOTHING;
+
+-- watch_events holds the changes of the watched resources, streamed by the watch API
+CREATE TABLE IF NOT EXISTS watch_events
+(
+    version    BIGINT PRIMARY KEY,
+    kind       TEXT                     NOT NULL,
+    type       TEXT                     NOT NULL,
+    object     JSONB                    NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS watch_events_created_at_idx ON watch_events (created_at);
@@ -0,0 +1,4 @@
+-- direction defines which trust domains of a relationship trust their peer, existing relationships are bidirectional
+ALTER TABLE "relationships"
+    ADD COLUMN IF NOT EXISTS "direction" TEXT NOT NULL DEFAULT 'bidirectional'
+        CHECK ("direction" IN ('bidirectional', 'a_trusts_b', 'b_trusts_a'));
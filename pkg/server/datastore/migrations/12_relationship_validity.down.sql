@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "relationships_not_after_idx";
+
+ALTER TABLE "relationships"
+    DROP COLUMN IF EXISTS "expired_at",
+    DROP COLUMN IF EXISTS "not_after",
+    DROP COLUMN IF EXISTS "not_before";
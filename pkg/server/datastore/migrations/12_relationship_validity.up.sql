@@ -0,0 +1,7 @@
+-- not_before and not_after bound when a relationship is in effect, expired_at records when its expiry was processed
+ALTER TABLE "relationships"
+    ADD COLUMN IF NOT EXISTS "not_before" TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS "not_after"  TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS "expired_at" TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS "relationships_not_after_idx" ON "relationships" ("not_after") WHERE "expired_at" IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS federation_group_members;
+DROP TABLE IF EXISTS federation_groups;
@@ -0,0 +1,25 @@
+-- federation_groups federate their member trust domains with each other according to their mode
+CREATE TABLE IF NOT EXISTS federation_groups
+(
+    id         UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    name       TEXT                     NOT NULL UNIQUE,
+    mode       TEXT                     NOT NULL DEFAULT 'mesh' CHECK (mode IN ('mesh', 'hub_and_spoke')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- federation_group_members holds the trust domains of each group, hub marks the hub of a hub-and-spoke group
+CREATE TABLE IF NOT EXISTS federation_group_members
+(
+    id              UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    group_id        UUID                     NOT NULL REFERENCES federation_groups (id) ON DELETE CASCADE,
+    trust_domain_id UUID                     NOT NULL REFERENCES trust_domains (id) ON DELETE CASCADE,
+    hub             BOOLEAN                  NOT NULL DEFAULT false,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (group_id, trust_domain_id)
+);
+
+CREATE INDEX IF NOT EXISTS federation_group_members_trust_domain_id_idx ON federation_group_members (trust_domain_id);
+
+-- a group has at most one hub
+CREATE UNIQUE INDEX IF NOT EXISTS federation_group_members_hub_idx ON federation_group_members (group_id) WHERE hub;
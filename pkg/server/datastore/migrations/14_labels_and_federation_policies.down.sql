@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS federation_policies;
+
+DROP INDEX IF EXISTS trust_domains_labels_idx;
+
+ALTER TABLE trust_domains
+    DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,17 @@
+-- labels are the key/value pairs of a trust domain selected by the federation policies
+ALTER TABLE trust_domains
+    ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS trust_domains_labels_idx ON trust_domains USING GIN (labels);
+
+-- federation_policies federate the trust domains whose labels contain the selector with the trust domains whose
+-- labels contain the peer selector
+CREATE TABLE IF NOT EXISTS federation_policies
+(
+    id            UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    name          TEXT                     NOT NULL UNIQUE,
+    selector      JSONB                    NOT NULL,
+    peer_selector JSONB                    NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
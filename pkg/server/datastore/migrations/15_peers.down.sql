@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS trust_domains_peer_id_idx;
+
+ALTER TABLE trust_domains
+    DROP COLUMN IF EXISTS peer_id;
+
+DROP TABLE IF EXISTS peer_shared_trust_domains;
+DROP TABLE IF EXISTS peers;
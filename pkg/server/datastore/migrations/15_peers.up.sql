@@ -0,0 +1,28 @@
+-- peers are the Galadriel Servers this server exchanges bundles with, url is their peering endpoint, whose host
+-- identifies them in the certificates they present
+CREATE TABLE IF NOT EXISTS peers
+(
+    id         UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    name       TEXT                     NOT NULL UNIQUE,
+    url        TEXT                     NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- peer_shared_trust_domains holds the local trust domains whose bundles are shared with each peer
+CREATE TABLE IF NOT EXISTS peer_shared_trust_domains
+(
+    id              UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    peer_id         UUID                     NOT NULL REFERENCES peers (id) ON DELETE CASCADE,
+    trust_domain_id UUID                     NOT NULL REFERENCES trust_domains (id) ON DELETE CASCADE,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (peer_id, trust_domain_id)
+);
+
+CREATE INDEX IF NOT EXISTS peer_shared_trust_domains_trust_domain_id_idx ON peer_shared_trust_domains (trust_domain_id);
+
+-- peer_id is the peer a trust domain is imported from, whose bundle is synced from the peer
+ALTER TABLE trust_domains
+    ADD COLUMN IF NOT EXISTS peer_id UUID REFERENCES peers (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS trust_domains_peer_id_idx ON trust_domains (peer_id);
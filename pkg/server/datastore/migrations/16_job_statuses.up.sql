@@ -0,0 +1,11 @@
+-- job_statuses records the last run of each maintenance job, whichever replica of the server ran it
+CREATE TABLE IF NOT EXISTS job_statuses
+(
+    name        TEXT PRIMARY KEY,
+    schedule    TEXT                     NOT NULL,
+    started_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    result      TEXT                     NOT NULL DEFAULT '',
+    error       TEXT                     NOT NULL DEFAULT ''
+);
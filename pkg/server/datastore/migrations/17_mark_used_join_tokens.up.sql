@@ -0,0 +1,9 @@
+-- join tokens are now marked as used once a harvester authenticates with them, and the expired ones which were
+-- never used are pruned, as well as the used ones not used within the retention. The tokens created before the
+-- last call of the harvester of their trust domain might be in use, so they are marked as used now.
+UPDATE join_tokens jt
+SET used       = true,
+    updated_at = now()
+FROM harvester_sessions hs
+WHERE hs.trust_domain_id = jt.trust_domain_id
+  AND GREATEST(hs.last_onboard_at, hs.last_post_at, hs.last_sync_at) >= jt.created_at;
@@ -0,0 +1,9 @@
+ALTER TABLE "bundles"
+    DROP CONSTRAINT IF EXISTS "bundles_trust_domain_id_fkey";
+ALTER TABLE "bundles"
+    ADD CONSTRAINT "bundles_trust_domain_id_fkey" FOREIGN KEY ("trust_domain_id") REFERENCES "trust_domains" ("id");
+
+ALTER TABLE "join_tokens"
+    DROP CONSTRAINT IF EXISTS "join_tokens_trust_domain_id_fkey";
+ALTER TABLE "join_tokens"
+    ADD CONSTRAINT "join_tokens_trust_domain_id_fkey" FOREIGN KEY ("trust_domain_id") REFERENCES "trust_domains" ("id");
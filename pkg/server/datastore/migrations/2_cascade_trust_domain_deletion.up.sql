@@ -0,0 +1,11 @@
+-- bundles and join tokens are owned by their trust domain and are removed along with it,
+-- while relationships must be explicitly deleted before any of its trust domains
+ALTER TABLE "bundles"
+    DROP CONSTRAINT IF EXISTS "bundles_trust_domain_id_fkey";
+ALTER TABLE "bundles"
+    ADD CONSTRAINT "bundles_trust_domain_id_fkey" FOREIGN KEY ("trust_domain_id") REFERENCES "trust_domains" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "join_tokens"
+    DROP CONSTRAINT IF EXISTS "join_tokens_trust_domain_id_fkey";
+ALTER TABLE "join_tokens"
+    ADD CONSTRAINT "join_tokens_trust_domain_id_fkey" FOREIGN KEY ("trust_domain_id") REFERENCES "trust_domains" ("id") ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+-- publish_bundle allows the bundle of a trust domain to be served by the SPIFFE federation bundle endpoint
+ALTER TABLE "trust_domains"
+    ADD COLUMN IF NOT EXISTS "publish_bundle" BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE "trust_domains"
+    DROP COLUMN IF EXISTS "bundle_endpoint_url",
+    DROP COLUMN IF EXISTS "bundle_endpoint_profile",
+    DROP COLUMN IF EXISTS "bundle_endpoint_spiffe_id";
@@ -0,0 +1,5 @@
+-- the bundle of a trust domain without a harvester is fetched by the server from its SPIFFE bundle endpoint
+ALTER TABLE "trust_domains"
+    ADD COLUMN IF NOT EXISTS "bundle_endpoint_url" TEXT,
+    ADD COLUMN IF NOT EXISTS "bundle_endpoint_profile" TEXT,
+    ADD COLUMN IF NOT EXISTS "bundle_endpoint_spiffe_id" TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE "trust_domains"
+    DROP COLUMN IF EXISTS "publish_oidc";
@@ -0,0 +1,3 @@
+-- publish_oidc allows the JWT authorities of a trust domain to be served as an OIDC discovery document
+ALTER TABLE "trust_domains"
+    ADD COLUMN IF NOT EXISTS "publish_oidc" BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,9 @@
+-- bundle_rejections records the bundles rejected by the bundle validation policy, along with the rules they broke
+CREATE TABLE IF NOT EXISTS bundle_rejections
+(
+    id              UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    trust_domain_id UUID                     NOT NULL REFERENCES trust_domains (id) ON DELETE CASCADE,
+    digest          BYTEA                    NOT NULL,
+    violations      JSONB                    NOT NULL,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,14 @@
+-- quarantined_bundles holds the bundle updates that break the continuity with the stored bundle of their trust domain,
+-- until they are released after a delay or approved or rejected by an admin
+CREATE TABLE IF NOT EXISTS quarantined_bundles
+(
+    id               UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    trust_domain_id  UUID                     NOT NULL UNIQUE REFERENCES trust_domains (id) ON DELETE CASCADE,
+    data             BYTEA                    NOT NULL,
+    digest           BYTEA                    NOT NULL,
+    digest_algorithm TEXT                     NOT NULL,
+    reasons          JSONB                    NOT NULL,
+    release_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
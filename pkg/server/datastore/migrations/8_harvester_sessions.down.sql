@@ -0,0 +1 @@
+DROP TABLE IF EXISTS harvester_sessions;
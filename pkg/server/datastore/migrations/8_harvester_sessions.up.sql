@@ -0,0 +1,14 @@
+-- harvester_sessions records the activity of the harvester of each trust domain, updated on each authenticated call
+CREATE TABLE IF NOT EXISTS harvester_sessions
+(
+    id                UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    trust_domain_id   UUID                     NOT NULL UNIQUE REFERENCES trust_domains (id) ON DELETE CASCADE,
+    last_onboard_at   TIMESTAMP WITH TIME ZONE,
+    last_post_at      TIMESTAMP WITH TIME ZONE,
+    last_sync_at      TIMESTAMP WITH TIME ZONE,
+    remote_address    TEXT                     NOT NULL,
+    harvester_version TEXT                     NOT NULL DEFAULT '',
+    spire_version     TEXT                     NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,26 @@
+-- webhooks are the HTTP endpoints notified of the federation events they are subscribed to
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id          UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    url         TEXT                     NOT NULL,
+    secret      TEXT                     NOT NULL,
+    event_types JSONB                    NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- webhook_deliveries holds the events waiting to be delivered to a webhook, and the dead deliveries that
+-- failed after the last attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    webhook_id      UUID                     NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type      TEXT                     NOT NULL,
+    payload         JSONB                    NOT NULL,
+    attempts        INTEGER                  NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_error      TEXT                     NOT NULL DEFAULT '',
+    dead            BOOLEAN                  NOT NULL DEFAULT false,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
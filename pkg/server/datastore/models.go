@@ -6,6 +6,7 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/jackc/pgtype"
@@ -24,6 +25,62 @@ type Bundle struct {
 	UpdatedAt          time.Time
 }
 
+type BundleRejection struct {
+	ID            pgtype.UUID
+	TrustDomainID pgtype.UUID
+	Digest        []byte
+	Violations    json.RawMessage
+	CreatedAt     time.Time
+}
+
+type FederationGroup struct {
+	ID        pgtype.UUID
+	Name      string
+	Mode      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type FederationGroupMember struct {
+	ID            pgtype.UUID
+	GroupID       pgtype.UUID
+	TrustDomainID pgtype.UUID
+	Hub           bool
+	CreatedAt     time.Time
+}
+
+type FederationPolicy struct {
+	ID           pgtype.UUID
+	Name         string
+	Selector     json.RawMessage
+	PeerSelector json.RawMessage
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type HarvesterSession struct {
+	ID               pgtype.UUID
+	TrustDomainID    pgtype.UUID
+	LastOnboardAt    sql.NullTime
+	LastPostAt       sql.NullTime
+	LastSyncAt       sql.NullTime
+	RemoteAddress    string
+	HarvesterVersion string
+	SpireVersion     string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type JobStatus struct {
+	Name       string
+	Schedule   string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	NextRunAt  time.Time
+	Result     string
+	Error      string
+}
+
 type JoinToken struct {
 	ID            pgtype.UUID
 	TrustDomainID pgtype.UUID
@@ -34,6 +91,33 @@ type JoinToken struct {
 	UpdatedAt     time.Time
 }
 
+type Peer struct {
+	ID        pgtype.UUID
+	Name      string
+	Url       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PeerSharedTrustDomain struct {
+	ID            pgtype.UUID
+	PeerID        pgtype.UUID
+	TrustDomainID pgtype.UUID
+	CreatedAt     time.Time
+}
+
+type QuarantinedBundle struct {
+	ID              pgtype.UUID
+	TrustDomainID   pgtype.UUID
+	Data            []byte
+	Digest          []byte
+	DigestAlgorithm string
+	Reasons         json.RawMessage
+	ReleaseAt       time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
 type Relationship struct {
 	ID                  pgtype.UUID
 	TrustDomainAID      pgtype.UUID
@@ -42,14 +126,60 @@ type Relationship struct {
 	TrustDomainBConsent bool
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
+	Direction           string
+	NotBefore           sql.NullTime
+	NotAfter            sql.NullTime
+	ExpiredAt           sql.NullTime
+}
+
+type ResourceVersion struct {
+	ID      int32
+	Version int64
 }
 
 type TrustDomain struct {
-	ID                pgtype.UUID
-	Name              string
-	Description       sql.NullString
-	HarvesterSpiffeID sql.NullString
-	OnboardingBundle  []byte
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	ID                     pgtype.UUID
+	Name                   string
+	Description            sql.NullString
+	HarvesterSpiffeID      sql.NullString
+	OnboardingBundle       []byte
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	PublishBundle          bool
+	BundleEndpointUrl      sql.NullString
+	BundleEndpointProfile  sql.NullString
+	BundleEndpointSpiffeID sql.NullString
+	PublishOidc            bool
+	Labels                 json.RawMessage
+	PeerID                 pgtype.UUID
+}
+
+type Webhook struct {
+	ID         pgtype.UUID
+	Url        string
+	Secret     string
+	EventTypes json.RawMessage
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WebhookDelivery struct {
+	ID            pgtype.UUID
+	WebhookID     pgtype.UUID
+	EventType     string
+	Payload       json.RawMessage
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	Dead          bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type WatchEvent struct {
+	Version   int64
+	Kind      string
+	Type      string
+	Object    json.RawMessage
+	CreatedAt time.Time
 }
@@ -6,36 +6,89 @@ package datastore
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/jackc/pgtype"
 )
 
 type Querier interface {
+	AddFederationGroupMember(ctx context.Context, arg AddFederationGroupMemberParams) (FederationGroupMember, error)
+	AddPeerSharedTrustDomain(ctx context.Context, arg AddPeerSharedTrustDomainParams) error
+	AdvisoryUnlock(ctx context.Context, key int64) (bool, error)
 	CreateBundle(ctx context.Context, arg CreateBundleParams) (Bundle, error)
+	CreateBundleRejection(ctx context.Context, arg CreateBundleRejectionParams) (BundleRejection, error)
+	CreateFederationGroup(ctx context.Context, arg CreateFederationGroupParams) (FederationGroup, error)
+	CreateFederationPolicy(ctx context.Context, arg CreateFederationPolicyParams) (FederationPolicy, error)
 	CreateJoinToken(ctx context.Context, arg CreateJoinTokenParams) (JoinToken, error)
+	CreatePeer(ctx context.Context, arg CreatePeerParams) (Peer, error)
+	CreateQuarantinedBundle(ctx context.Context, arg CreateQuarantinedBundleParams) (QuarantinedBundle, error)
 	CreateRelationship(ctx context.Context, arg CreateRelationshipParams) (Relationship, error)
 	CreateTrustDomain(ctx context.Context, arg CreateTrustDomainParams) (TrustDomain, error)
+	CreateWatchEvent(ctx context.Context, arg CreateWatchEventParams) (WatchEvent, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
 	DeleteBundle(ctx context.Context, id pgtype.UUID) error
+	DeleteBundleRejectionsBefore(ctx context.Context, createdAt time.Time) error
+	DeleteFederationGroup(ctx context.Context, id pgtype.UUID) error
+	DeleteFederationPolicy(ctx context.Context, id pgtype.UUID) error
 	DeleteJoinToken(ctx context.Context, id pgtype.UUID) error
+	DeletePeer(ctx context.Context, id pgtype.UUID) error
+	DeleteQuarantinedBundle(ctx context.Context, id pgtype.UUID) error
 	DeleteRelationship(ctx context.Context, id pgtype.UUID) error
 	DeleteTrustDomain(ctx context.Context, id pgtype.UUID) error
+	DeleteWatchEventsBefore(ctx context.Context, createdAt time.Time) error
+	DeleteWebhook(ctx context.Context, id pgtype.UUID) error
+	DeleteWebhookDelivery(ctx context.Context, id pgtype.UUID) error
 	FindBundleByID(ctx context.Context, id pgtype.UUID) (Bundle, error)
 	FindBundleByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) (Bundle, error)
+	FindBundleRejectionsByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) ([]BundleRejection, error)
+	FindFederatedBundlesByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) ([]FindFederatedBundlesByTrustDomainIDRow, error)
+	FindFederationGroupByName(ctx context.Context, name string) (FederationGroup, error)
+	FindFederationPolicyByName(ctx context.Context, name string) (FederationPolicy, error)
+	FindHarvesterSessionByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) (HarvesterSession, error)
 	FindJoinToken(ctx context.Context, token string) (JoinToken, error)
 	FindJoinTokenByID(ctx context.Context, id pgtype.UUID) (JoinToken, error)
 	FindJoinTokensByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) ([]JoinToken, error)
+	FindPeerByName(ctx context.Context, name string) (Peer, error)
+	FindQuarantinedBundleByTrustDomainID(ctx context.Context, trustDomainID pgtype.UUID) (QuarantinedBundle, error)
 	FindRelationshipByID(ctx context.Context, id pgtype.UUID) (Relationship, error)
 	FindRelationshipsByTrustDomainID(ctx context.Context, trustDomainAID pgtype.UUID) ([]Relationship, error)
+	FindResourceVersion(ctx context.Context) (int64, error)
 	FindTrustDomainByID(ctx context.Context, id pgtype.UUID) (TrustDomain, error)
 	FindTrustDomainByName(ctx context.Context, name string) (TrustDomain, error)
+	FindWebhookDeliveryByID(ctx context.Context, id pgtype.UUID) (WebhookDelivery, error)
+	IncrementResourceVersion(ctx context.Context) (int64, error)
+	ListBundleRejections(ctx context.Context) ([]ListBundleRejectionsRow, error)
 	ListBundles(ctx context.Context) ([]Bundle, error)
+	ListExpiredRelationships(ctx context.Context, notAfter sql.NullTime) ([]Relationship, error)
+	ListFederationGroupMembers(ctx context.Context, groupID pgtype.UUID) ([]ListFederationGroupMembersRow, error)
+	ListFederationGroups(ctx context.Context) ([]FederationGroup, error)
+	ListFederationPolicies(ctx context.Context) ([]FederationPolicy, error)
+	ListHarvesterSessions(ctx context.Context) ([]ListHarvesterSessionsRow, error)
+	ListJobStatuses(ctx context.Context) ([]JobStatus, error)
 	ListJoinTokens(ctx context.Context) ([]JoinToken, error)
+	ListPeerSharedTrustDomains(ctx context.Context, peerID pgtype.UUID) ([]TrustDomain, error)
+	ListPeers(ctx context.Context) ([]Peer, error)
+	ListQuarantinedBundles(ctx context.Context) ([]ListQuarantinedBundlesRow, error)
 	ListRelationships(ctx context.Context) ([]Relationship, error)
+	ListRelationshipsWithTrustDomainNames(ctx context.Context) ([]ListRelationshipsWithTrustDomainNamesRow, error)
 	ListTrustDomains(ctx context.Context) ([]TrustDomain, error)
+	ListWatchEvents(ctx context.Context, arg ListWatchEventsParams) ([]WatchEvent, error)
+	ListWebhookDeliveries(ctx context.Context, dead bool) ([]ListWebhookDeliveriesRow, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	Notify(ctx context.Context, arg NotifyParams) error
+	RemoveFederationGroupMember(ctx context.Context, arg RemoveFederationGroupMemberParams) error
+	RemovePeerSharedTrustDomain(ctx context.Context, arg RemovePeerSharedTrustDomainParams) error
+	TryAdvisoryLock(ctx context.Context, key int64) (bool, error)
 	UpdateBundle(ctx context.Context, arg UpdateBundleParams) (Bundle, error)
 	UpdateJoinToken(ctx context.Context, arg UpdateJoinTokenParams) (JoinToken, error)
+	UpdateQuarantinedBundle(ctx context.Context, arg UpdateQuarantinedBundleParams) (QuarantinedBundle, error)
 	UpdateRelationship(ctx context.Context, arg UpdateRelationshipParams) (Relationship, error)
 	UpdateTrustDomain(ctx context.Context, arg UpdateTrustDomainParams) (TrustDomain, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	UpsertHarvesterSession(ctx context.Context, arg UpsertHarvesterSessionParams) (HarvesterSession, error)
+	UpsertJobStatus(ctx context.Context, arg UpsertJobStatusParams) (JobStatus, error)
 }
 
 var _ Querier = (*Queries)(nil)
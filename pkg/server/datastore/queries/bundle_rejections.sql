@@ -0,0 +1,21 @@
+-- name: CreateBundleRejection :one
+INSERT INTO bundle_rejections(trust_domain_id, digest, violations)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: FindBundleRejectionsByTrustDomainID :many
+SELECT *
+FROM bundle_rejections
+WHERE trust_domain_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListBundleRejections :many
+SELECT br.*, td.name AS trust_domain_name
+FROM bundle_rejections br
+         JOIN trust_domains td ON td.id = br.trust_domain_id
+ORDER BY br.created_at DESC;
+
+-- name: DeleteBundleRejectionsBefore :exec
+DELETE
+FROM bundle_rejections
+WHERE created_at < $1;
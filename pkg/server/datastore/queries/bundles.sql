@@ -34,3 +34,34 @@ WHERE trust_domain_id = $1;
 SELECT *
 FROM bundles
 ORDER BY created_at DESC;
+
+-- name: FindFederatedBundlesByTrustDomainID :many
+WITH peers AS (SELECT CASE
+                          WHEN r.trust_domain_a_id = @trust_domain_id THEN r.trust_domain_b_id
+                          ELSE r.trust_domain_a_id END AS trust_domain_id
+               FROM relationships r
+               WHERE ((r.trust_domain_a_id = @trust_domain_id AND r.direction != 'b_trusts_a')
+                   OR (r.trust_domain_b_id = @trust_domain_id AND r.direction != 'a_trusts_b'))
+                 AND (r.not_before IS NULL OR r.not_before <= now())
+                 AND (r.not_after IS NULL OR r.not_after > now())
+               UNION
+               SELECT peer.trust_domain_id
+               FROM federation_group_members self
+                        JOIN federation_groups g ON g.id = self.group_id
+                        JOIN federation_group_members peer
+                             ON peer.group_id = self.group_id AND peer.trust_domain_id != self.trust_domain_id
+               WHERE self.trust_domain_id = @trust_domain_id
+                 AND (g.mode = 'mesh' OR self.hub OR peer.hub)
+               UNION
+               SELECT peer.id
+               FROM federation_policies fp
+                        JOIN trust_domains self ON self.id = @trust_domain_id
+                        JOIN trust_domains peer
+                             ON (self.labels @> fp.selector AND peer.labels @> fp.peer_selector)
+                                 OR (self.labels @> fp.peer_selector AND peer.labels @> fp.selector)
+               WHERE peer.id != self.id)
+SELECT b.*, td.name AS trust_domain_name
+FROM peers p
+         JOIN bundles b ON b.trust_domain_id = p.trust_domain_id
+         JOIN trust_domains td ON td.id = b.trust_domain_id
+ORDER BY td.name;
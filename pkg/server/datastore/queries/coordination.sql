@@ -0,0 +1,8 @@
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock(@key::bigint);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock(@key::bigint);
+
+-- name: Notify :exec
+SELECT pg_notify(@channel::text, @payload::text);
@@ -0,0 +1,37 @@
+-- name: CreateFederationGroup :one
+INSERT INTO federation_groups(name, mode)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeleteFederationGroup :exec
+DELETE
+FROM federation_groups
+WHERE id = $1;
+
+-- name: FindFederationGroupByName :one
+SELECT *
+FROM federation_groups
+WHERE name = $1;
+
+-- name: ListFederationGroups :many
+SELECT *
+FROM federation_groups
+ORDER BY name;
+
+-- name: AddFederationGroupMember :one
+INSERT INTO federation_group_members(group_id, trust_domain_id, hub)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: RemoveFederationGroupMember :exec
+DELETE
+FROM federation_group_members
+WHERE group_id = $1
+  AND trust_domain_id = $2;
+
+-- name: ListFederationGroupMembers :many
+SELECT m.*, td.name AS trust_domain_name
+FROM federation_group_members m
+         JOIN trust_domains td ON td.id = m.trust_domain_id
+WHERE m.group_id = $1
+ORDER BY td.name;
@@ -0,0 +1,19 @@
+-- name: CreateFederationPolicy :one
+INSERT INTO federation_policies(name, selector, peer_selector)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: DeleteFederationPolicy :exec
+DELETE
+FROM federation_policies
+WHERE id = $1;
+
+-- name: FindFederationPolicyByName :one
+SELECT *
+FROM federation_policies
+WHERE name = $1;
+
+-- name: ListFederationPolicies :many
+SELECT *
+FROM federation_policies
+ORDER BY name;
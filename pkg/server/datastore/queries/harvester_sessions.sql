@@ -0,0 +1,24 @@
+-- name: UpsertHarvesterSession :one
+INSERT INTO harvester_sessions(trust_domain_id, last_onboard_at, last_post_at, last_sync_at, remote_address,
+                               harvester_version, spire_version)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (trust_domain_id) DO UPDATE
+    SET last_onboard_at   = COALESCE(EXCLUDED.last_onboard_at, harvester_sessions.last_onboard_at),
+        last_post_at      = COALESCE(EXCLUDED.last_post_at, harvester_sessions.last_post_at),
+        last_sync_at      = COALESCE(EXCLUDED.last_sync_at, harvester_sessions.last_sync_at),
+        remote_address    = EXCLUDED.remote_address,
+        harvester_version = EXCLUDED.harvester_version,
+        spire_version     = EXCLUDED.spire_version,
+        updated_at        = now()
+RETURNING *;
+
+-- name: FindHarvesterSessionByTrustDomainID :one
+SELECT *
+FROM harvester_sessions
+WHERE trust_domain_id = $1;
+
+-- name: ListHarvesterSessions :many
+SELECT hs.*, td.name AS trust_domain_name
+FROM harvester_sessions hs
+         JOIN trust_domains td ON td.id = hs.trust_domain_id
+ORDER BY hs.created_at DESC;
@@ -0,0 +1,16 @@
+-- name: UpsertJobStatus :one
+INSERT INTO job_statuses(name, schedule, started_at, finished_at, next_run_at, result, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (name) DO UPDATE
+    SET schedule    = EXCLUDED.schedule,
+        started_at  = EXCLUDED.started_at,
+        finished_at = EXCLUDED.finished_at,
+        next_run_at = EXCLUDED.next_run_at,
+        result      = EXCLUDED.result,
+        error       = EXCLUDED.error
+RETURNING *;
+
+-- name: ListJobStatuses :many
+SELECT *
+FROM job_statuses
+ORDER BY name;
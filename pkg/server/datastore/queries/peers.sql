@@ -0,0 +1,36 @@
+-- name: CreatePeer :one
+INSERT INTO peers(name, url)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeletePeer :exec
+DELETE
+FROM peers
+WHERE id = $1;
+
+-- name: FindPeerByName :one
+SELECT *
+FROM peers
+WHERE name = $1;
+
+-- name: ListPeers :many
+SELECT *
+FROM peers
+ORDER BY name;
+
+-- name: AddPeerSharedTrustDomain :exec
+INSERT INTO peer_shared_trust_domains(peer_id, trust_domain_id)
+VALUES ($1, $2);
+
+-- name: RemovePeerSharedTrustDomain :exec
+DELETE
+FROM peer_shared_trust_domains
+WHERE peer_id = $1
+  AND trust_domain_id = $2;
+
+-- name: ListPeerSharedTrustDomains :many
+SELECT td.*
+FROM peer_shared_trust_domains s
+         JOIN trust_domains td ON td.id = s.trust_domain_id
+WHERE s.peer_id = $1
+ORDER BY td.name;
@@ -0,0 +1,31 @@
+-- name: CreateQuarantinedBundle :one
+INSERT INTO quarantined_bundles(trust_domain_id, data, digest, digest_algorithm, reasons, release_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: UpdateQuarantinedBundle :one
+UPDATE quarantined_bundles
+SET data             = $2,
+    digest           = $3,
+    digest_algorithm = $4,
+    reasons          = $5,
+    release_at       = $6,
+    updated_at       = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteQuarantinedBundle :exec
+DELETE
+FROM quarantined_bundles
+WHERE id = $1;
+
+-- name: FindQuarantinedBundleByTrustDomainID :one
+SELECT *
+FROM quarantined_bundles
+WHERE trust_domain_id = $1;
+
+-- name: ListQuarantinedBundles :many
+SELECT qb.*, td.name AS trust_domain_name
+FROM quarantined_bundles qb
+         JOIN trust_domains td ON td.id = qb.trust_domain_id
+ORDER BY qb.created_at DESC;
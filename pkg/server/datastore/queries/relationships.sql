@@ -1,12 +1,16 @@
 -- name: CreateRelationship :one
-INSERT INTO relationships(trust_domain_a_id, trust_domain_b_id)
-VALUES ($1, $2)
+INSERT INTO relationships(trust_domain_a_id, trust_domain_b_id, direction, not_before, not_after)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: UpdateRelationship :one
 UPDATE relationships
 SET trust_domain_a_consent = $2,
     trust_domain_b_consent = $3,
+    direction = $4,
+    not_before = $5,
+    not_after = $6,
+    expired_at = $7,
     updated_at = now()
 WHERE id = $1
 RETURNING *;
@@ -26,7 +30,21 @@ SELECT *
 FROM relationships
 WHERE trust_domain_a_id = $1 OR trust_domain_b_id = $1;
 
+-- name: ListExpiredRelationships :many
+SELECT *
+FROM relationships
+WHERE not_after <= $1
+  AND expired_at IS NULL
+ORDER BY not_after;
+
 -- name: ListRelationships :many
 SELECT *
 FROM relationships
 ORDER BY created_at DESC;
+
+-- name: ListRelationshipsWithTrustDomainNames :many
+SELECT r.*, tda.name AS trust_domain_a_name, tdb.name AS trust_domain_b_name
+FROM relationships r
+         JOIN trust_domains tda ON tda.id = r.trust_domain_a_id
+         JOIN trust_domains tdb ON tdb.id = r.trust_domain_b_id
+ORDER BY r.created_at DESC;
@@ -1,14 +1,20 @@
 -- name: CreateTrustDomain :one
-INSERT INTO trust_domains(name, description)
-VALUES ($1, $2)
+INSERT INTO trust_domains(name, description, publish_bundle, publish_oidc, labels, peer_id)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: UpdateTrustDomain :one
 UPDATE trust_domains
-SET description         = $2,
-    harvester_spiffe_id = $3,
-    onboarding_bundle   = $4,
-    updated_at          = now()
+SET description               = $2,
+    harvester_spiffe_id       = $3,
+    onboarding_bundle         = $4,
+    publish_bundle            = $5,
+    bundle_endpoint_url       = $6,
+    bundle_endpoint_profile   = $7,
+    bundle_endpoint_spiffe_id = $8,
+    publish_oidc              = $9,
+    labels                    = $10,
+    updated_at                = now()
 WHERE id = $1
 RETURNING *;
 
@@ -0,0 +1,27 @@
+-- name: IncrementResourceVersion :one
+UPDATE resource_versions
+SET version = version + 1
+WHERE id = 1
+RETURNING version;
+
+-- name: FindResourceVersion :one
+SELECT version
+FROM resource_versions
+WHERE id = 1;
+
+-- name: CreateWatchEvent :one
+INSERT INTO watch_events(version, kind, type, object)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListWatchEvents :many
+SELECT *
+FROM watch_events
+WHERE version > $1
+ORDER BY version
+LIMIT $2;
+
+-- name: DeleteWatchEventsBefore :exec
+DELETE
+FROM watch_events
+WHERE created_at < $1;
@@ -0,0 +1,46 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks(url, secret, event_types)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE
+FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooks :many
+SELECT *
+FROM webhooks
+ORDER BY created_at DESC;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries(webhook_id, event_type, payload, next_attempt_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET attempts        = $2,
+    next_attempt_at = $3,
+    last_error      = $4,
+    dead            = $5,
+    updated_at      = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookDelivery :exec
+DELETE
+FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: FindWebhookDeliveryByID :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT wd.*, w.url AS webhook_url
+FROM webhook_deliveries wd
+         JOIN webhooks w ON w.id = wd.webhook_id
+WHERE wd.dead = $1
+ORDER BY wd.created_at DESC;
@@ -7,23 +7,34 @@ package datastore
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/jackc/pgtype"
 )
 
 const createRelationship = `-- name: CreateRelationship :one
-INSERT INTO relationships(trust_domain_a_id, trust_domain_b_id)
-VALUES ($1, $2)
-RETURNING id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at
+INSERT INTO relationships(trust_domain_a_id, trust_domain_b_id, direction, not_before, not_after)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at, direction, not_before, not_after, expired_at
 `
 
 type CreateRelationshipParams struct {
 	TrustDomainAID pgtype.UUID
 	TrustDomainBID pgtype.UUID
+	Direction      string
+	NotBefore      sql.NullTime
+	NotAfter       sql.NullTime
 }
 
 func (q *Queries) CreateRelationship(ctx context.Context, arg CreateRelationshipParams) (Relationship, error) {
-	row := q.queryRow(ctx, q.createRelationshipStmt, createRelationship, arg.TrustDomainAID, arg.TrustDomainBID)
+	row := q.queryRow(ctx, q.createRelationshipStmt, createRelationship,
+		arg.TrustDomainAID,
+		arg.TrustDomainBID,
+		arg.Direction,
+		arg.NotBefore,
+		arg.NotAfter,
+	)
 	var i Relationship
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +44,10 @@ func (q *Queries) CreateRelationship(ctx context.Context, arg CreateRelationship
 		&i.TrustDomainBConsent,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Direction,
+		&i.NotBefore,
+		&i.NotAfter,
+		&i.ExpiredAt,
 	)
 	return i, err
 }
@@ -49,7 +64,7 @@ func (q *Queries) DeleteRelationship(ctx context.Context, id pgtype.UUID) error
 }
 
 const findRelationshipByID = `-- name: FindRelationshipByID :one
-SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at
+SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at, direction, not_before, not_after, expired_at
 FROM relationships
 WHERE id = $1
 `
@@ -65,12 +80,16 @@ func (q *Queries) FindRelationshipByID(ctx context.Context, id pgtype.UUID) (Rel
 		&i.TrustDomainBConsent,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Direction,
+		&i.NotBefore,
+		&i.NotAfter,
+		&i.ExpiredAt,
 	)
 	return i, err
 }
 
 const findRelationshipsByTrustDomainID = `-- name: FindRelationshipsByTrustDomainID :many
-SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at
+SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at, direction, not_before, not_after, expired_at
 FROM relationships
 WHERE trust_domain_a_id = $1 OR trust_domain_b_id = $1
 `
@@ -92,6 +111,53 @@ func (q *Queries) FindRelationshipsByTrustDomainID(ctx context.Context, trustDom
 			&i.TrustDomainBConsent,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Direction,
+			&i.NotBefore,
+			&i.NotAfter,
+			&i.ExpiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredRelationships = `-- name: ListExpiredRelationships :many
+SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at, direction, not_before, not_after, expired_at
+FROM relationships
+WHERE not_after <= $1
+  AND expired_at IS NULL
+ORDER BY not_after
+`
+
+func (q *Queries) ListExpiredRelationships(ctx context.Context, notAfter sql.NullTime) ([]Relationship, error) {
+	rows, err := q.query(ctx, q.listExpiredRelationshipsStmt, listExpiredRelationships, notAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Relationship
+	for rows.Next() {
+		var i Relationship
+		if err := rows.Scan(
+			&i.ID,
+			&i.TrustDomainAID,
+			&i.TrustDomainBID,
+			&i.TrustDomainAConsent,
+			&i.TrustDomainBConsent,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Direction,
+			&i.NotBefore,
+			&i.NotAfter,
+			&i.ExpiredAt,
 		); err != nil {
 			return nil, err
 		}
@@ -107,7 +173,7 @@ func (q *Queries) FindRelationshipsByTrustDomainID(ctx context.Context, trustDom
 }
 
 const listRelationships = `-- name: ListRelationships :many
-SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at
+SELECT id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at, direction, not_before, not_after, expired_at
 FROM relationships
 ORDER BY created_at DESC
 `
@@ -129,6 +195,71 @@ func (q *Queries) ListRelationships(ctx context.Context) ([]Relationship, error)
 			&i.TrustDomainBConsent,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Direction,
+			&i.NotBefore,
+			&i.NotAfter,
+			&i.ExpiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRelationshipsWithTrustDomainNames = `-- name: ListRelationshipsWithTrustDomainNames :many
+SELECT r.id, r.trust_domain_a_id, r.trust_domain_b_id, r.trust_domain_a_consent, r.trust_domain_b_consent, r.created_at, r.updated_at, r.direction, r.not_before, r.not_after, r.expired_at, tda.name AS trust_domain_a_name, tdb.name AS trust_domain_b_name
+FROM relationships r
+         JOIN trust_domains tda ON tda.id = r.trust_domain_a_id
+         JOIN trust_domains tdb ON tdb.id = r.trust_domain_b_id
+ORDER BY r.created_at DESC
+`
+
+type ListRelationshipsWithTrustDomainNamesRow struct {
+	ID                  pgtype.UUID
+	TrustDomainAID      pgtype.UUID
+	TrustDomainBID      pgtype.UUID
+	TrustDomainAConsent bool
+	TrustDomainBConsent bool
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Direction           string
+	NotBefore           sql.NullTime
+	NotAfter            sql.NullTime
+	ExpiredAt           sql.NullTime
+	TrustDomainAName    string
+	TrustDomainBName    string
+}
+
+func (q *Queries) ListRelationshipsWithTrustDomainNames(ctx context.Context) ([]ListRelationshipsWithTrustDomainNamesRow, error) {
+	rows, err := q.query(ctx, q.listRelationshipsWithTrustDomainNamesStmt, listRelationshipsWithTrustDomainNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRelationshipsWithTrustDomainNamesRow
+	for rows.Next() {
+		var i ListRelationshipsWithTrustDomainNamesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TrustDomainAID,
+			&i.TrustDomainBID,
+			&i.TrustDomainAConsent,
+			&i.TrustDomainBConsent,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Direction,
+			&i.NotBefore,
+			&i.NotAfter,
+			&i.ExpiredAt,
+			&i.TrustDomainAName,
+			&i.TrustDomainBName,
 		); err != nil {
 			return nil, err
 		}
@@ -147,19 +278,35 @@ const updateRelationship = `-- name: UpdateRelationship :one
 UPDATE relationships
 SET trust_domain_a_consent = $2,
     trust_domain_b_consent = $3,
+    direction = $4,
+    not_before = $5,
+    not_after = $6,
+    expired_at = $7,
     updated_at = now()
 WHERE id = $1
-RETURNING id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at
+RETURNING id, trust_domain_a_id, trust_domain_b_id, trust_domain_a_consent, trust_domain_b_consent, created_at, updated_at, direction, not_before, not_after, expired_at
 `
 
 type UpdateRelationshipParams struct {
 	ID                  pgtype.UUID
 	TrustDomainAConsent bool
 	TrustDomainBConsent bool
+	Direction           string
+	NotBefore           sql.NullTime
+	NotAfter            sql.NullTime
+	ExpiredAt           sql.NullTime
 }
 
 func (q *Queries) UpdateRelationship(ctx context.Context, arg UpdateRelationshipParams) (Relationship, error) {
-	row := q.queryRow(ctx, q.updateRelationshipStmt, updateRelationship, arg.ID, arg.TrustDomainAConsent, arg.TrustDomainBConsent)
+	row := q.queryRow(ctx, q.updateRelationshipStmt, updateRelationship,
+		arg.ID,
+		arg.TrustDomainAConsent,
+		arg.TrustDomainBConsent,
+		arg.Direction,
+		arg.NotBefore,
+		arg.NotAfter,
+		arg.ExpiredAt,
+	)
 	var i Relationship
 	err := row.Scan(
 		&i.ID,
@@ -169,6 +316,10 @@ func (q *Queries) UpdateRelationship(ctx context.Context, arg UpdateRelationship
 		&i.TrustDomainBConsent,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Direction,
+		&i.NotBefore,
+		&i.NotAfter,
+		&i.ExpiredAt,
 	)
 	return i, err
 }
@@ -1,12 +1,18 @@
 package datastore
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
+	"fmt"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
 )
 
 //go:embed migrations/*.sql
@@ -16,31 +22,228 @@ var fs embed.FS
 // This is used to ensure that the app is compatible with the database schema.
 // When a new migration is created, this version should be updated in order to force
 // the migrations to run when starting up the app.
-const currentDBVersion = 1
+const currentDBVersion = 17
 
 const scheme = "postgresql"
 
-func validateAndMigrateSchema(db *sql.DB) error {
+// migrationsLockID is the key of the Postgres advisory lock held while the schema is inspected or migrated,
+// so servers starting at the same time, or a server and the migrate command, do not step on each other.
+const migrationsLockID int64 = 0x67616c6164726965
 
-	sourceInstance, err := iofs.New(fs, "migrations")
+// MigrationMode defines what is done when the schema is not at the version supported by the app.
+type MigrationMode string
+
+const (
+	// MigrationModeAuto migrates the schema to the version supported by the app.
+	MigrationModeAuto MigrationMode = "auto"
+
+	// MigrationModeCheck fails if the schema is not at the version supported by the app,
+	// leaving the migrations to be applied explicitly, e.g., using 'galadriel-server migrate up'.
+	MigrationModeCheck MigrationMode = "check"
+)
+
+// ParseMigrationMode parses a MigrationMode. An empty string defaults to MigrationModeAuto.
+func ParseMigrationMode(s string) (MigrationMode, error) {
+	switch MigrationMode(s) {
+	case "", MigrationModeAuto:
+		return MigrationModeAuto, nil
+	case MigrationModeCheck:
+		return MigrationModeCheck, nil
+	default:
+		return "", fmt.Errorf("unknown migration mode %q: expected %q or %q", s, MigrationModeAuto, MigrationModeCheck)
+	}
+}
+
+// SchemaStatus describes the version of the database schema.
+type SchemaStatus struct {
+	// Version is the version of the schema, 0 if no migration has been applied.
+	Version uint `json:"version"`
+	// Dirty is true if the last migration failed, in which case the schema must be fixed manually.
+	Dirty bool `json:"dirty"`
+	// SupportedVersion is the version of the schema supported by the app.
+	SupportedVersion uint `json:"supported_version"`
+}
+
+// UpToDate returns true if the schema is at the version supported by the app.
+func (s *SchemaStatus) UpToDate() bool {
+	return !s.Dirty && s.Version == s.SupportedVersion
+}
+
+// Migrator inspects and migrates the database schema.
+type Migrator struct {
+	logger logrus.FieldLogger
+	db     *sql.DB
+	ownsDB bool
+}
+
+// NewMigrator creates a Migrator that connects to the Postgres database parsing the connString.
+func NewMigrator(logger logrus.FieldLogger, connString string) (*Migrator, error) {
+	c, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Postgres Connection URL: %w", err)
+	}
+
+	return &Migrator{
+		logger: logger,
+		db:     stdlib.OpenDB(*c),
+		ownsDB: true,
+	}, nil
+}
+
+func newMigrator(logger logrus.FieldLogger, db *sql.DB) *Migrator {
+	return &Migrator{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Close closes the database connection, unless it is shared with a datastore.
+func (m *Migrator) Close() error {
+	if !m.ownsDB {
+		return nil
+	}
+	return m.db.Close()
+}
+
+// Status returns the version of the schema.
+func (m *Migrator) Status(ctx context.Context) (*SchemaStatus, error) {
+	var status *SchemaStatus
+	err := m.withLock(ctx, func(mg *migrate.Migrate) error {
+		var err error
+		status, err = schemaStatus(mg)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Up migrates the schema to the version supported by the app.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(mg *migrate.Migrate) error {
+		return m.up(mg)
+	})
+}
+
+// Down reverts the given number of migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps %d: must be greater than 0", steps)
+	}
+
+	return m.withLock(ctx, func(mg *migrate.Migrate) error {
+		status, err := schemaStatus(mg)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return fmt.Errorf("schema version %d is dirty and must be fixed manually", status.Version)
+		}
+		if uint(steps) > status.Version {
+			return fmt.Errorf("cannot revert %d migrations, the schema is at version %d", steps, status.Version)
+		}
+
+		m.logger.Infof("Reverting schema from version %d to %d", status.Version, status.Version-uint(steps))
+		return mg.Steps(-steps)
+	})
+}
+
+// check returns an error if the schema is not at the version supported by the app.
+func (m *Migrator) check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !status.UpToDate() {
+		return fmt.Errorf("schema version %d (dirty: %t) does not match the version %d supported by the server, run 'galadriel-server migrate up' to migrate it",
+			status.Version, status.Dirty, status.SupportedVersion)
+	}
+
+	return nil
+}
+
+func (m *Migrator) up(mg *migrate.Migrate) error {
+	status, err := schemaStatus(mg)
 	if err != nil {
 		return err
 	}
 
-	driverInstance, err := postgres.WithInstance(db, new(postgres.Config))
+	switch {
+	case status.Dirty:
+		return fmt.Errorf("schema version %d is dirty and must be fixed manually", status.Version)
+	case status.Version > status.SupportedVersion:
+		// never downgrade the schema, e.g., when an older server is started after a newer one
+		return fmt.Errorf("schema version %d is newer than the version %d supported by the server", status.Version, status.SupportedVersion)
+	case status.Version == status.SupportedVersion:
+		return nil
+	}
+
+	m.logger.Infof("Migrating schema from version %d to %d", status.Version, status.SupportedVersion)
+	return mg.Migrate(currentDBVersion)
+}
+
+// withLock runs fn while holding the migrations advisory lock. The migrations run on
+// the same connection that holds the lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(mg *migrate.Migrate) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+	defer func() {
+		// the lock is bound to the session, so it must be released before the connection is returned to the pool
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID); err != nil {
+			m.logger.WithError(err).Error("Failed to release migrations lock")
+		}
+	}()
+
+	sourceInstance, err := iofs.New(fs, "migrations")
 	if err != nil {
 		return err
 	}
+	defer sourceInstance.Close()
 
-	m, err := migrate.NewWithInstance("iofs", sourceInstance, scheme, driverInstance)
+	// the driver is not closed, as that would close the connection holding the lock
+	driverInstance, err := postgres.WithConnection(ctx, conn, new(postgres.Config))
 	if err != nil {
 		return err
 	}
 
-	err = m.Migrate(currentDBVersion)
-	if err != nil && err != migrate.ErrNoChange {
+	mg, err := migrate.NewWithInstance("iofs", sourceInstance, scheme, driverInstance)
+	if err != nil {
 		return err
 	}
 
-	return sourceInstance.Close()
+	return fn(mg)
+}
+
+func schemaStatus(mg *migrate.Migrate) (*SchemaStatus, error) {
+	version, dirty, err := mg.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	return &SchemaStatus{
+		Version:          version,
+		Dirty:            dirty,
+		SupportedVersion: currentDBVersion,
+	}, nil
+}
+
+func validateAndMigrateSchema(ctx context.Context, m *Migrator, mode MigrationMode) error {
+	switch mode {
+	case MigrationModeAuto:
+		return m.Up(ctx)
+	case MigrationModeCheck:
+		return m.check(ctx)
+	default:
+		return fmt.Errorf("unknown migration mode %q", mode)
+	}
 }
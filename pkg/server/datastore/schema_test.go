@@ -0,0 +1,78 @@
+package datastore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	log := logrus.New()
+	conn := startDB(t)
+
+	// the schema is not migrated in check mode
+	_, err := datastore.NewSQLDatastore(log, conn, datastore.MigrationModeCheck)
+	require.ErrorContains(t, err, "does not match the version")
+
+	m, err := datastore.NewMigrator(log, conn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, m.Close())
+	})
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), status.Version)
+	assert.False(t, status.UpToDate())
+
+	require.NoError(t, m.Up(ctx))
+
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, status.SupportedVersion, status.Version)
+	assert.True(t, status.UpToDate())
+
+	// migrating an up to date schema is a no-op
+	require.NoError(t, m.Up(ctx))
+
+	ds, err := datastore.NewSQLDatastore(log, conn, datastore.MigrationModeCheck)
+	require.NoError(t, err)
+	require.NoError(t, ds.Close())
+
+	require.NoError(t, m.Down(ctx, 1))
+
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, status.SupportedVersion-1, status.Version)
+
+	require.Error(t, m.Down(ctx, int(status.Version)+1))
+	require.Error(t, m.Down(ctx, 0))
+
+	require.NoError(t, m.Up(ctx))
+}
+
+func TestConcurrentMigrations(t *testing.T) {
+	t.Parallel()
+	conn := startDB(t)
+
+	errs := make(chan error)
+	for i := 0; i < 5; i++ {
+		go func() {
+			ds, err := datastore.NewSQLDatastore(logrus.New(), conn, datastore.MigrationModeAuto)
+			if err == nil {
+				err = ds.Close()
+			}
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, <-errs)
+	}
+}
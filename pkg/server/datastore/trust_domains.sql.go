@@ -8,23 +8,35 @@ package datastore
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
 	"github.com/jackc/pgtype"
 )
 
 const createTrustDomain = `-- name: CreateTrustDomain :one
-INSERT INTO trust_domains(name, description)
-VALUES ($1, $2)
-RETURNING id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at
+INSERT INTO trust_domains(name, description, publish_bundle, publish_oidc, labels, peer_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at, publish_bundle, bundle_endpoint_url, bundle_endpoint_profile, bundle_endpoint_spiffe_id, publish_oidc, labels, peer_id
 `
 
 type CreateTrustDomainParams struct {
-	Name        string
-	Description sql.NullString
+	Name          string
+	Description   sql.NullString
+	PublishBundle bool
+	PublishOidc   bool
+	Labels        json.RawMessage
+	PeerID        pgtype.UUID
 }
 
 func (q *Queries) CreateTrustDomain(ctx context.Context, arg CreateTrustDomainParams) (TrustDomain, error) {
-	row := q.queryRow(ctx, q.createTrustDomainStmt, createTrustDomain, arg.Name, arg.Description)
+	row := q.queryRow(ctx, q.createTrustDomainStmt, createTrustDomain,
+		arg.Name,
+		arg.Description,
+		arg.PublishBundle,
+		arg.PublishOidc,
+		arg.Labels,
+		arg.PeerID,
+	)
 	var i TrustDomain
 	err := row.Scan(
 		&i.ID,
@@ -34,6 +46,13 @@ func (q *Queries) CreateTrustDomain(ctx context.Context, arg CreateTrustDomainPa
 		&i.OnboardingBundle,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PublishBundle,
+		&i.BundleEndpointUrl,
+		&i.BundleEndpointProfile,
+		&i.BundleEndpointSpiffeID,
+		&i.PublishOidc,
+		&i.Labels,
+		&i.PeerID,
 	)
 	return i, err
 }
@@ -50,7 +69,7 @@ func (q *Queries) DeleteTrustDomain(ctx context.Context, id pgtype.UUID) error {
 }
 
 const findTrustDomainByID = `-- name: FindTrustDomainByID :one
-SELECT id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at
+SELECT id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at, publish_bundle, bundle_endpoint_url, bundle_endpoint_profile, bundle_endpoint_spiffe_id, publish_oidc, labels, peer_id
 FROM trust_domains
 WHERE id = $1
 `
@@ -66,12 +85,19 @@ func (q *Queries) FindTrustDomainByID(ctx context.Context, id pgtype.UUID) (Trus
 		&i.OnboardingBundle,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PublishBundle,
+		&i.BundleEndpointUrl,
+		&i.BundleEndpointProfile,
+		&i.BundleEndpointSpiffeID,
+		&i.PublishOidc,
+		&i.Labels,
+		&i.PeerID,
 	)
 	return i, err
 }
 
 const findTrustDomainByName = `-- name: FindTrustDomainByName :one
-SELECT id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at
+SELECT id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at, publish_bundle, bundle_endpoint_url, bundle_endpoint_profile, bundle_endpoint_spiffe_id, publish_oidc, labels, peer_id
 FROM trust_domains
 WHERE name = $1
 `
@@ -87,12 +113,19 @@ func (q *Queries) FindTrustDomainByName(ctx context.Context, name string) (Trust
 		&i.OnboardingBundle,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PublishBundle,
+		&i.BundleEndpointUrl,
+		&i.BundleEndpointProfile,
+		&i.BundleEndpointSpiffeID,
+		&i.PublishOidc,
+		&i.Labels,
+		&i.PeerID,
 	)
 	return i, err
 }
 
 const listTrustDomains = `-- name: ListTrustDomains :many
-SELECT id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at
+SELECT id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at, publish_bundle, bundle_endpoint_url, bundle_endpoint_profile, bundle_endpoint_spiffe_id, publish_oidc, labels, peer_id
 FROM trust_domains
 ORDER BY name
 `
@@ -114,6 +147,13 @@ func (q *Queries) ListTrustDomains(ctx context.Context) ([]TrustDomain, error) {
 			&i.OnboardingBundle,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.PublishBundle,
+			&i.BundleEndpointUrl,
+			&i.BundleEndpointProfile,
+			&i.BundleEndpointSpiffeID,
+			&i.PublishOidc,
+			&i.Labels,
+			&i.PeerID,
 		); err != nil {
 			return nil, err
 		}
@@ -130,19 +170,31 @@ func (q *Queries) ListTrustDomains(ctx context.Context) ([]TrustDomain, error) {
 
 const updateTrustDomain = `-- name: UpdateTrustDomain :one
 UPDATE trust_domains
-SET description         = $2,
-    harvester_spiffe_id = $3,
-    onboarding_bundle   = $4,
-    updated_at          = now()
+SET description               = $2,
+    harvester_spiffe_id       = $3,
+    onboarding_bundle         = $4,
+    publish_bundle            = $5,
+    bundle_endpoint_url       = $6,
+    bundle_endpoint_profile   = $7,
+    bundle_endpoint_spiffe_id = $8,
+    publish_oidc              = $9,
+    labels                    = $10,
+    updated_at                = now()
 WHERE id = $1
-RETURNING id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at
+RETURNING id, name, description, harvester_spiffe_id, onboarding_bundle, created_at, updated_at, publish_bundle, bundle_endpoint_url, bundle_endpoint_profile, bundle_endpoint_spiffe_id, publish_oidc, labels, peer_id
 `
 
 type UpdateTrustDomainParams struct {
-	ID                pgtype.UUID
-	Description       sql.NullString
-	HarvesterSpiffeID sql.NullString
-	OnboardingBundle  []byte
+	ID                     pgtype.UUID
+	Description            sql.NullString
+	HarvesterSpiffeID      sql.NullString
+	OnboardingBundle       []byte
+	PublishBundle          bool
+	BundleEndpointUrl      sql.NullString
+	BundleEndpointProfile  sql.NullString
+	BundleEndpointSpiffeID sql.NullString
+	PublishOidc            bool
+	Labels                 json.RawMessage
 }
 
 func (q *Queries) UpdateTrustDomain(ctx context.Context, arg UpdateTrustDomainParams) (TrustDomain, error) {
@@ -151,6 +203,12 @@ func (q *Queries) UpdateTrustDomain(ctx context.Context, arg UpdateTrustDomainPa
 		arg.Description,
 		arg.HarvesterSpiffeID,
 		arg.OnboardingBundle,
+		arg.PublishBundle,
+		arg.BundleEndpointUrl,
+		arg.BundleEndpointProfile,
+		arg.BundleEndpointSpiffeID,
+		arg.PublishOidc,
+		arg.Labels,
 	)
 	var i TrustDomain
 	err := row.Scan(
@@ -161,6 +219,13 @@ func (q *Queries) UpdateTrustDomain(ctx context.Context, arg UpdateTrustDomainPa
 		&i.OnboardingBundle,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PublishBundle,
+		&i.BundleEndpointUrl,
+		&i.BundleEndpointProfile,
+		&i.BundleEndpointSpiffeID,
+		&i.PublishOidc,
+		&i.Labels,
+		&i.PeerID,
 	)
 	return i, err
 }
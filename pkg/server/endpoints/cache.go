@@ -0,0 +1,190 @@
+package endpoints
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+)
+
+// federationCacheTTL bounds how long a cached federation is served. The cache is invalidated
+// whenever changes are committed by any replica of the server, the TTL covers the notifications
+// missed while a replica was not listening, and changes made by other means.
+const federationCacheTTL = 30 * time.Second
+
+// federation holds the bundles of the trust domains federated with a given trust domain, along with
+// their canonical and X.509 digests.
+type federation struct {
+	bundles     []*entity.Bundle
+	digests     common.BundlesDigests
+	x509Digests common.BundlesDigests
+}
+
+type federationCacheEntry struct {
+	federation *federation
+	expiresAt  time.Time
+}
+
+// federationCache caches the federation of each trust domain, so the bundle sync requests
+// polled by every harvester do not hit the datastore.
+// Cached federations are shared and must not be modified.
+type federationCache struct {
+	ds     datastore.Datastore
+	rules  *relationships.RuleSet
+	logger logrus.FieldLogger
+	ttl    time.Duration
+
+	mu         sync.Mutex
+	generation uint64
+	entries    map[uuid.UUID]*federationCacheEntry
+}
+
+func newFederationCache(ds datastore.Datastore, rules *relationships.RuleSet, logger logrus.FieldLogger, ttl time.Duration) *federationCache {
+	return &federationCache{
+		ds:      ds,
+		rules:   rules,
+		logger:  logger,
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]*federationCacheEntry),
+	}
+}
+
+// get returns the federation of the trust domain, loading it from the datastore
+// if it is not cached or has expired.
+func (c *federationCache) get(ctx context.Context, trustDomainID uuid.UUID) (*federation, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[trustDomainID]
+	generation := c.generation
+	c.mu.Unlock()
+
+	now := time.Now()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.federation, nil
+	}
+
+	bundles, err := c.ds.FindFederatedBundlesByTrustDomainID(ctx, trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles, err = c.allowedBundles(ctx, trustDomainID, bundles)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &federation{
+		bundles:     bundles,
+		digests:     make(common.BundlesDigests, len(bundles)),
+		x509Digests: make(common.BundlesDigests, len(bundles)),
+	}
+	for _, b := range bundles {
+		f.digests[b.TrustDomainName], f.x509Digests[b.TrustDomainName] = bundleDigests(b)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the federation is not cached if the cache was invalidated while it was being
+	// loaded, as it might not reflect the change
+	if c.generation == generation {
+		c.entries[trustDomainID] = &federationCacheEntry{
+			federation: f,
+			expiresAt:  now.Add(c.ttl),
+		}
+	}
+
+	return f, nil
+}
+
+// allowedBundles drops the bundles of the peers the relationship rules do not allow the trust domain to be
+// federated with, whether they are peers through a relationship, a federation group or a federation policy. The
+// trust domains are listed at once, so the rules are evaluated without looking up each peer.
+func (c *federationCache) allowedBundles(ctx context.Context, trustDomainID uuid.UUID, bundles []*entity.Bundle) ([]*entity.Bundle, error) {
+	if len(c.rules.Rules()) == 0 || len(bundles) == 0 {
+		return bundles, nil
+	}
+
+	trustDomains, err := c.ds.ListTrustDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*entity.TrustDomain, len(trustDomains))
+	for _, td := range trustDomains {
+		byID[td.ID.UUID] = td
+	}
+
+	self, ok := byID[trustDomainID]
+	if !ok {
+		return nil, nil
+	}
+
+	allowed := make([]*entity.Bundle, 0, len(bundles))
+	for _, b := range bundles {
+		peer, ok := byID[b.TrustDomainID]
+		if !ok {
+			continue
+		}
+
+		if decision := c.rules.Evaluate(self, peer); !decision.Allowed {
+			c.logger.Warnf("Bundle of trust domain %q not synced to trust domain %q: %s (version %s)", peer.Name, self.Name, decision.Reason, decision.Version)
+			continue
+		}
+		allowed = append(allowed, b)
+	}
+
+	return allowed, nil
+}
+
+// invalidate drops every cached federation.
+func (c *federationCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[uuid.UUID]*federationCacheEntry)
+}
+
+// invalidateOnChanges invalidates the federation cache whenever changes are committed, by this replica of the
+// server or any other, until the context is canceled.
+func (e *Endpoints) invalidateOnChanges(ctx context.Context) error {
+	changed, unsubscribe := e.Changes.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-changed:
+			e.federationCache.invalidate()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// bundleDigests computes the canonical and X.509 digests of a stored bundle. The digests are computed from
+// the bundle data, as bundles stored by older servers have an X.509 digest. The stored digest is used for
+// both if the data cannot be parsed.
+func bundleDigests(b *entity.Bundle) (canonical, x509 []byte) {
+	bundle, err := spiffebundle.Parse(b.TrustDomainName, b.Data)
+	if err != nil {
+		return b.Digest, b.Digest
+	}
+
+	canonical, err = util.GetBundleDigest(bundle, util.DigestAlgorithmCanonical)
+	if err != nil {
+		canonical = b.Digest
+	}
+	x509, err = util.GetBundleDigest(bundle, util.DigestAlgorithmX509)
+	if err != nil {
+		x509 = b.Digest
+	}
+
+	return canonical, x509
+}
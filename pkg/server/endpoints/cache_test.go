@@ -0,0 +1,217 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/changes"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederationCache(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	cache := newFederationCache(ds, nil, logrus.New(), time.Hour)
+
+	f, err := cache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, f.bundles)
+
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte("b"), Digest: []byte("digest"), TrustDomainID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	// the cached federation is served until the cache is invalidated
+	f, err = cache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, f.bundles)
+
+	cache.invalidate()
+
+	f, err = cache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	require.Len(t, f.bundles, 1)
+	assert.Equal(t, []byte("digest"), f.digests[tdB.Name])
+}
+
+func TestFederationCacheInvalidatedOnChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds := changes.NewDatastore(datastore.NewMemoryDatastore(logrus.New()))
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	listener := changes.NewListener(&changes.ListenerConfig{Datastore: ds, Logger: logrus.New()})
+	e := &Endpoints{
+		Datastore:       ds,
+		Logger:          logrus.New(),
+		Changes:         listener,
+		federationCache: newFederationCache(ds, nil, logrus.New(), time.Hour),
+	}
+	go func() {
+		_ = listener.Run(ctx)
+	}()
+	go func() {
+		_ = e.invalidateOnChanges(ctx)
+	}()
+
+	f, err := e.federationCache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, f.bundles)
+
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte("b"), Digest: []byte("digest"), TrustDomainID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	// the change is notified as it would be by another replica, as it might have been committed before the
+	// listener listened
+	require.Eventually(t, func() bool {
+		assert.NoError(t, ds.Notify(ctx, changes.Channel, changes.KindBundle))
+		f, err := e.federationCache.get(ctx, tdA.ID.UUID)
+		return assert.NoError(t, err) && len(f.bundles) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFederationCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+
+	cache := newFederationCache(ds, nil, logrus.New(), 0)
+
+	f, err := cache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, f.bundles)
+
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte("b"), Digest: []byte("digest"), TrustDomainID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	f, err = cache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	assert.Len(t, f.bundles, 1)
+}
+
+func TestFederationCacheRelationshipRules(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test"), Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test"), Labels: map[string]string{"env": "dev"}})
+	require.NoError(t, err)
+	tdC, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("c.test"), Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+
+	// b.test is a peer of a.test through a group, as the rules apply to all the peers
+	group, err := ds.CreateFederationGroup(ctx, &entity.FederationGroup{Name: "all", Mode: entity.FederationGroupMesh})
+	require.NoError(t, err)
+	for _, td := range []*entity.TrustDomain{tdA, tdB, tdC} {
+		_, err = ds.AddFederationGroupMember(ctx, &entity.FederationGroupMember{GroupID: group.ID.UUID, TrustDomainID: td.ID.UUID})
+		require.NoError(t, err)
+		_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte(td.Name.String()), Digest: []byte("digest"), TrustDomainID: td.ID.UUID})
+		require.NoError(t, err)
+	}
+
+	rules, err := relationships.NewRuleSet("v1", []relationships.RuleConfig{
+		{Name: "prod-dev", Condition: `a.labels.env == 'prod' && b.labels.env == 'dev'`},
+	})
+	require.NoError(t, err)
+	logger, hook := test.NewNullLogger()
+	counting := &countingDatastore{Datastore: ds}
+	cache := newFederationCache(counting, rules, logger, time.Hour)
+
+	f, err := cache.get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+	require.Len(t, f.bundles, 1)
+	assert.Equal(t, tdC.Name, f.bundles[0].TrustDomainName)
+	assert.Equal(t, `Bundle of trust domain "b.test" not synced to trust domain "a.test": denied by relationship rule "prod-dev" (version v1)`, hook.LastEntry().Message)
+
+	// the trust domains are listed once, rather than looked up for each peer
+	assert.Equal(t, 1, counting.listTrustDomains)
+	assert.Zero(t, counting.findTrustDomainByID)
+
+	f, err = cache.get(ctx, tdB.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, f.bundles)
+}
+
+// countingDatastore counts the lookups of trust domains.
+type countingDatastore struct {
+	datastore.Datastore
+	listTrustDomains    int
+	findTrustDomainByID int
+}
+
+func (d *countingDatastore) ListTrustDomains(ctx context.Context) ([]*entity.TrustDomain, error) {
+	d.listTrustDomains++
+	return d.Datastore.ListTrustDomains(ctx)
+}
+
+func (d *countingDatastore) FindTrustDomainByID(ctx context.Context, trustDomainID uuid.UUID) (*entity.TrustDomain, error) {
+	d.findTrustDomainByID++
+	return d.Datastore.FindTrustDomainByID(ctx, trustDomainID)
+}
+
+func TestFederationCacheDigests(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	bundle := spiffebundle.New(tdB.Name)
+	require.NoError(t, bundle.AddJWTAuthority("key-1", key.Public()))
+	data, err := bundle.Marshal()
+	require.NoError(t, err)
+
+	// the digests are computed from the bundle data, whatever the stored digest is
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: data, Digest: []byte("x509 digest"), TrustDomainID: tdB.ID.UUID})
+	require.NoError(t, err)
+
+	f, err := newFederationCache(ds, nil, logrus.New(), time.Hour).get(ctx, tdA.ID.UUID)
+	require.NoError(t, err)
+
+	canonical, err := util.GetBundleDigest(bundle, util.DigestAlgorithmCanonical)
+	require.NoError(t, err)
+	x509Digest, err := util.GetBundleDigest(bundle, util.DigestAlgorithmX509)
+	require.NoError(t, err)
+	assert.Equal(t, canonical, f.digests[tdB.Name])
+	assert.Equal(t, x509Digest, f.x509Digests[tdB.Name])
+}
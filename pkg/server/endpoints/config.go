@@ -1,8 +1,13 @@
 package endpoints
 
 import (
+	"crypto/tls"
 	"net"
 
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/changes"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
 	"github.com/sirupsen/logrus"
 )
 
@@ -14,8 +19,48 @@ type Config struct {
 	// LocalAddress is the local address to bind the listener to.
 	LocalAddress net.Addr
 
-	// Postgres connection string
-	DatastoreConnString string
+	// FederationAddress is the address to bind the SPIFFE federation bundle endpoint to.
+	// If not set, the endpoint is disabled.
+	FederationAddress *net.TCPAddr
+
+	// FederationCertFile and FederationKeyFile are the Web PKI certificate and private key
+	// presented by the SPIFFE federation bundle endpoint.
+	FederationCertFile string
+	FederationKeyFile  string
+
+	// FederationPublicURL is the URL the federation endpoint is reached at, used as the base of the
+	// OIDC issuers. If not set, the host of each request is used.
+	FederationPublicURL string
+
+	// PeeringAddress is the address to bind the peering endpoint to, which serves the bundles shared with
+	// the peer Galadriel Servers. If not set, the endpoint is disabled.
+	PeeringAddress *net.TCPAddr
+
+	// PeeringTLSConfig holds the certificate presented to the peers and the CA certificates the peers are
+	// authenticated with. It is required by the peering endpoint.
+	PeeringTLSConfig *tls.Config
+
+	// BundlePolicy validates the bundles posted by harvesters. If not set, the default policy is used.
+	BundlePolicy *bundles.Policy
+
+	// BundleMonitor reports the health of the bundles of the listed trust domains and of the metrics.
+	// If not set, a monitor with the default thresholds is used.
+	BundleMonitor *bundles.Monitor
+
+	// RelationshipRules constrain which trust domains can be federated, on relationship creation and on every
+	// sync. If not set, any trust domains can be federated.
+	RelationshipRules *relationships.RuleSet
+
+	// Changes signals the changes committed by every replica of the server, which invalidate the federation
+	// cache and wake the watches. If not set, the cache expires and the watches poll for changes.
+	Changes *changes.Listener
+
+	// MetricsAddress is the address to bind the Prometheus metrics endpoint to.
+	// If not set, the endpoint is disabled.
+	MetricsAddress *net.TCPAddr
+
+	// Datastore used by the endpoints handlers
+	Datastore datastore.Datastore
 
 	Logger logrus.FieldLogger
 }
@@ -0,0 +1,75 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// runFederationServer serves the SPIFFE federation bundle endpoint, using the https_web profile:
+// the endpoint is authenticated with a Web PKI certificate and clients are not authenticated.
+// Only the bundles of the trust domains that are allowed to publish them are served. The server
+// also serves the OIDC discovery documents of the trust domains that are allowed to publish them.
+func (e *Endpoints) runFederationServer(ctx context.Context) error {
+	server := echo.New()
+	server.HideBanner = true
+	server.HidePort = true
+
+	server.GET("/federation/:trustDomain/bundle", e.federationBundleHandler)
+	server.GET("/oidc/:trustDomain/.well-known/openid-configuration", e.oidcDiscoveryHandler)
+	server.GET("/oidc/:trustDomain/keys", e.oidcKeysHandler)
+
+	e.Logger.Infof("Starting Federation Server on %s", e.FederationAddress.String())
+	errChan := make(chan error)
+	go func() {
+		errChan <- server.StartTLS(e.FederationAddress.String(), e.FederationCertFile, e.FederationKeyFile)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+		e.Logger.WithError(err).Error("Federation Server stopped prematurely")
+		return err
+	case <-ctx.Done():
+		e.Logger.Info("Stopping Federation Server")
+		server.Close()
+		<-errChan
+		e.Logger.Info("Federation Server stopped")
+		return nil
+	}
+}
+
+// federationBundleHandler writes the SPIFFE bundle of a trust domain. Unknown trust domains, trust domains
+// that are not allowed to publish their bundle and trust domains without a bundle are all reported as not found,
+// so that the endpoint does not disclose which trust domains are managed by the server.
+func (e *Endpoints) federationBundleHandler(ctx echo.Context) error {
+	notFound := echo.NewHTTPError(http.StatusNotFound, "bundle not found")
+
+	trustDomain, err := spiffeid.TrustDomainFromString(ctx.Param("trustDomain"))
+	if err != nil {
+		return notFound
+	}
+
+	td, err := e.Datastore.FindTrustDomainByName(ctx.Request().Context(), trustDomain)
+	if err != nil {
+		e.Logger.Errorf("Failed looking up trust domain %q: %v", trustDomain, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if td == nil || !td.PublishBundle {
+		return notFound
+	}
+
+	bundle, err := e.Datastore.FindBundleByTrustDomainID(ctx.Request().Context(), td.ID.UUID)
+	if err != nil {
+		e.Logger.Errorf("Failed looking up bundle for trust domain %q: %v", trustDomain, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if bundle == nil || len(bundle.Data) == 0 {
+		return notFound
+	}
+
+	// bundles are stored in the SPIFFE bundle format, so they are served as they are
+	return ctx.Blob(http.StatusOK, echo.MIMEApplicationJSON, bundle.Data)
+}
@@ -0,0 +1,63 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederationBundleHandler(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	published, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test"), PublishBundle: true})
+	require.NoError(t, err)
+	private, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("c.test"), PublishBundle: true})
+	require.NoError(t, err)
+
+	for _, td := range []*entity.TrustDomain{published, private} {
+		_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: []byte(`{"keys":[]}`), Digest: []byte("digest"), TrustDomainID: td.ID.UUID})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name        string
+		trustDomain string
+		status      int
+	}{
+		{name: "published", trustDomain: "a.test", status: http.StatusOK},
+		{name: "not_published", trustDomain: "b.test", status: http.StatusNotFound},
+		{name: "without_bundle", trustDomain: "c.test", status: http.StatusNotFound},
+		{name: "unknown", trustDomain: "d.test", status: http.StatusNotFound},
+		{name: "invalid", trustDomain: "Invalid", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := echo.New()
+			server.GET("/federation/:trustDomain/bundle", e.federationBundleHandler)
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/federation/"+tt.trustDomain+"/bundle", nil))
+
+			require.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+				assert.Equal(t, `{"keys":[]}`, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+func (e *Endpoints) createFederationGroupHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.FederationGroup
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, "federation group name is required")
+		return
+	}
+	if err := req.Mode.Validate(); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	group, err := e.Datastore.CreateFederationGroup(ctx, &req)
+	if err != nil {
+		e.handleDatastoreError(w, fmt.Errorf("failed creating federation group: %w", err))
+		return
+	}
+
+	e.Logger.Infof("Federation group %q created in %s mode", group.Name, group.Mode)
+
+	e.writeResponse(w, group)
+}
+
+// listFederationGroupsHandler returns the federation groups along with their members.
+func (e *Endpoints) listFederationGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	groups, err := e.Datastore.ListFederationGroups(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing federation groups: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	for _, group := range groups {
+		group.Members, err = e.Datastore.ListFederationGroupMembers(ctx, group.ID.UUID)
+		if err != nil {
+			errMsg := fmt.Sprintf("failed listing members of federation group %q: %v", group.Name, err)
+			e.handleError(w, errMsg)
+			return
+		}
+	}
+
+	e.writeResponse(w, groups)
+}
+
+func (e *Endpoints) deleteFederationGroupHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.FederationGroup
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	group, err := e.Datastore.FindFederationGroupByName(ctx, req.Name)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed looking up federation group: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+	if group == nil {
+		errMsg := fmt.Sprintf("federation group %q does not exist", req.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	if err := e.Datastore.DeleteFederationGroup(ctx, group.ID.UUID); err != nil {
+		errMsg := fmt.Sprintf("failed deleting federation group: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	e.Logger.Infof("Federation group %q deleted", group.Name)
+}
+
+// addFederationGroupMemberHandler adds the trust domains listed as members in the request to the named group.
+func (e *Endpoints) addFederationGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.FederationGroup
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	var group *entity.FederationGroup
+	err := e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		group, err = tx.FindFederationGroupByName(ctx, req.Name)
+		if err != nil || group == nil {
+			return err
+		}
+
+		for _, m := range req.Members {
+			if m.Hub && group.Mode != entity.FederationGroupHubAndSpoke {
+				return &badRequestError{err: fmt.Errorf("federation group %q is not a %s group, it has no hub", group.Name, entity.FederationGroupHubAndSpoke)}
+			}
+
+			td, err := findGroupMemberTrustDomain(ctx, tx, m.TrustDomainName)
+			if err != nil {
+				return err
+			}
+
+			member, err := tx.AddFederationGroupMember(ctx, &entity.FederationGroupMember{
+				GroupID:       group.ID.UUID,
+				TrustDomainID: td.ID.UUID,
+				Hub:           m.Hub,
+			})
+			if err != nil {
+				return fmt.Errorf("failed adding trust domain %q to federation group %q: %w", td.Name, group.Name, err)
+			}
+			member.TrustDomainName = td.Name
+			group.Members = append(group.Members, member)
+		}
+
+		return nil
+	})
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		e.handleErrorWithStatus(w, http.StatusBadRequest, badRequest.Error())
+		return
+	case err != nil:
+		e.handleDatastoreError(w, err)
+		return
+	case group == nil:
+		errMsg := fmt.Sprintf("federation group %q does not exist", req.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	for _, m := range group.Members {
+		e.Logger.Infof("Trust domain %q added to federation group %q", m.TrustDomainName, group.Name)
+	}
+
+	e.writeResponse(w, group)
+}
+
+// removeFederationGroupMemberHandler removes the trust domains listed as members in the request from the named group.
+func (e *Endpoints) removeFederationGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.FederationGroup
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	var group *entity.FederationGroup
+	err := e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		group, err = tx.FindFederationGroupByName(ctx, req.Name)
+		if err != nil || group == nil {
+			return err
+		}
+
+		for _, m := range req.Members {
+			td, err := findGroupMemberTrustDomain(ctx, tx, m.TrustDomainName)
+			if err != nil {
+				return err
+			}
+
+			if err := tx.RemoveFederationGroupMember(ctx, group.ID.UUID, td.ID.UUID); err != nil {
+				return fmt.Errorf("failed removing trust domain %q from federation group %q: %w", td.Name, group.Name, err)
+			}
+		}
+
+		return nil
+	})
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		e.handleErrorWithStatus(w, http.StatusBadRequest, badRequest.Error())
+		return
+	case err != nil:
+		e.handleDatastoreError(w, err)
+		return
+	case group == nil:
+		errMsg := fmt.Sprintf("federation group %q does not exist", req.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	for _, m := range req.Members {
+		e.Logger.Infof("Trust domain %q removed from federation group %q", m.TrustDomainName, group.Name)
+	}
+}
+
+// findGroupMemberTrustDomain looks up the trust domain of a member named in a request, which is a bad request if
+// the trust domain does not exist.
+func findGroupMemberTrustDomain(ctx context.Context, ds datastore.Datastore, name spiffeid.TrustDomain) (*entity.TrustDomain, error) {
+	td, err := ds.FindTrustDomainByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up trust domain: %w", err)
+	}
+	if td == nil {
+		return nil, &badRequestError{err: fmt.Errorf("trust domain %q does not exist", name)}
+	}
+
+	return td, nil
+}
@@ -2,23 +2,119 @@ package endpoints
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common"
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
 	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
-	"github.com/spiffe/go-spiffe/v2/spiffeid"
 )
 
 const tokenKey = "token"
 
+// harvesterCall is an authenticated call of a harvester, recorded in the harvester session of its trust domain.
+type harvesterCall int
+
+const (
+	onboardCall harvesterCall = iota
+	postCall
+	syncCall
+)
+
+// harvesterSessionCache holds the harvester sessions last recorded by this replica of the server, so the calls
+// which would only move the time of the last call of their kind by less than entity.HarvesterSessionInterval are
+// not written to the datastore.
+type harvesterSessionCache struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*entity.HarvesterSession
+}
+
+// changed returns whether recording the session would change the recorded one by more than moving the time of the
+// last call of its kind by less than entity.HarvesterSessionInterval.
+func (c *harvesterSessionCache) changed(session *entity.HarvesterSession, call harvesterCall) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	recorded, ok := c.sessions[session.TrustDomainID]
+	if !ok || recorded.RemoteAddress != session.RemoteAddress ||
+		recorded.HarvesterVersion != session.HarvesterVersion || recorded.SPIREVersion != session.SPIREVersion {
+		return true
+	}
+
+	var last, now time.Time
+	switch call {
+	case onboardCall:
+		last, now = recorded.LastOnboardAt, session.LastOnboardAt
+	case postCall:
+		last, now = recorded.LastPostAt, session.LastPostAt
+	case syncCall:
+		last, now = recorded.LastSyncAt, session.LastSyncAt
+	}
+	return now.Sub(last) >= entity.HarvesterSessionInterval
+}
+
+func (c *harvesterSessionCache) recorded(session *entity.HarvesterSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessions == nil {
+		c.sessions = make(map[uuid.UUID]*entity.HarvesterSession)
+	}
+	c.sessions[session.TrustDomainID] = session
+}
+
+// recordHarvesterCall records the call in the harvester session of the trust domain of the token, along with
+// the address the call comes from and the versions reported by the harvester. The calls which do not change the
+// session but the time of the last call of their kind are only recorded every entity.HarvesterSessionInterval.
+// Failing to record it does not fail the call.
+func (e *Endpoints) recordHarvesterCall(ctx echo.Context, jt *entity.JoinToken, call harvesterCall) {
+	r := ctx.Request()
+
+	remoteAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteAddress = r.RemoteAddr
+	}
+
+	session := &entity.HarvesterSession{
+		TrustDomainID:    jt.TrustDomainID,
+		RemoteAddress:    remoteAddress,
+		HarvesterVersion: r.Header.Get(common.HarvesterVersionHeader),
+		SPIREVersion:     r.Header.Get(common.SPIREVersionHeader),
+	}
+
+	now := time.Now()
+	switch call {
+	case onboardCall:
+		session.LastOnboardAt = now
+	case postCall:
+		session.LastPostAt = now
+	case syncCall:
+		session.LastSyncAt = now
+	}
+
+	if !e.harvesterSessions.changed(session, call) {
+		return
+	}
+
+	recorded, err := e.Datastore.RecordHarvesterSession(r.Context(), session)
+	if err != nil {
+		e.Logger.WithError(err).Errorf("Failed to record harvester session of trust domain %q", jt.TrustDomainID)
+		return
+	}
+	e.harvesterSessions.recorded(recorded)
+}
+
 func (e *Endpoints) postBundleHandler(ctx echo.Context) error {
 	e.Logger.Debug("Receiving post bundle request")
 
@@ -29,6 +125,8 @@ func (e *Endpoints) postBundleHandler(ctx echo.Context) error {
 		return err
 	}
 
+	e.recordHarvesterCall(ctx, jt, postCall)
+
 	token, err := e.Datastore.FindJoinToken(ctx.Request().Context(), jt.Token)
 	if err != nil {
 		err := errors.New("error looking up token")
@@ -43,6 +141,14 @@ func (e *Endpoints) postBundleHandler(ctx echo.Context) error {
 		return err
 	}
 
+	// the bundle of a trust domain imported from a peer is synced from the peer
+	if authenticatedTD.PeerID.Valid {
+		err := fmt.Errorf("trust domain %q is imported from a peer, its bundle is synced from the peer", authenticatedTD.Name)
+		ctx.Response().WriteHeader(http.StatusForbidden)
+		e.handleTCPError(ctx, err.Error())
+		return err
+	}
+
 	body, err := io.ReadAll(ctx.Request().Body)
 	if err != nil {
 		e.handleTCPError(ctx, fmt.Sprintf("failed to read body: %v", err))
@@ -62,54 +168,52 @@ func (e *Endpoints) postBundleHandler(ctx echo.Context) error {
 		return err
 	}
 
+	if harvesterReq.Bundle == nil {
+		err := errors.New("bundle is missing")
+		e.handleTCPError(ctx, err.Error())
+		return err
+	}
+
 	bundle, err := spiffebundle.Parse(authenticatedTD.Name, harvesterReq.Bundle.Data)
 	if err != nil {
 		e.handleTCPError(ctx, fmt.Sprintf("failed to parse bundle: %v", err))
 		return err
 	}
 
-	x509b, err := bundle.X509Bundle().Marshal()
+	// the digest is verified with the algorithm of the harvester, older harvesters compute it over the
+	// X.509 authorities only
+	digest, err := util.GetBundleDigest(bundle, harvesterReq.DigestAlgorithm)
 	if err != nil {
-		e.handleTCPError(ctx, fmt.Sprintf("failed to marshal bundle: %v", err))
+		e.handleTCPError(ctx, err.Error())
 		return err
 	}
 
-	digest := util.GetDigest(x509b)
-
 	if !bytes.Equal(harvesterReq.Digest, digest) {
 		err := errors.New("calculated digest does not match received digest")
 		e.handleTCPError(ctx, err.Error())
 		return err
 	}
 
-	currentStoredBundle, err := e.Datastore.FindBundleByTrustDomainID(ctx.Request().Context(), authenticatedTD.ID.UUID)
-	if err != nil {
-		e.handleTCPError(ctx, err.Error())
+	outcome, err := bundles.Ingest(ctx.Request().Context(), e.Datastore, e.BundlePolicy, authenticatedTD, harvesterReq.Bundle.Data)
+	var policyErr *bundles.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		e.Logger.Warnf("Rejected bundle of trust domain %s: %v", authenticatedTD.Name, err)
+		return ctx.JSON(http.StatusUnprocessableEntity, &common.PostBundleError{
+			Error:      err.Error(),
+			Violations: policyErr.Violations,
+		})
+	case err != nil:
+		e.handleTCPDatastoreError(ctx, err)
 		return err
 	}
 
-	if harvesterReq.Bundle != nil && currentStoredBundle != nil && !bytes.Equal(harvesterReq.Bundle.Digest, currentStoredBundle.Digest) {
-		_, err := e.Datastore.CreateOrUpdateBundle(ctx.Request().Context(), &entity.Bundle{
-			Data: harvesterReq.Bundle.Data,
-		})
-		if err != nil {
-			e.handleTCPError(ctx, fmt.Sprintf("failed to update trustDomain: %v", err))
-			return err
-		}
-
+	switch outcome {
+	case bundles.Stored:
 		e.Logger.Infof("Trust domain %s has been successfully updated", authenticatedTD.Name)
-	} else if currentStoredBundle == nil {
-		_, err := e.Datastore.CreateOrUpdateBundle(ctx.Request().Context(), &entity.Bundle{
-			Data:          harvesterReq.Bundle.Data,
-			Digest:        harvesterReq.Bundle.Digest,
-			TrustDomainID: authenticatedTD.ID.UUID,
-		})
-		if err != nil {
-			e.handleTCPError(ctx, fmt.Sprintf("failed to update trustDomain: %v", err))
-			return err
-		}
-
-		e.Logger.Debugf("Trust domain %s has been successfully updated", harvesterReq.TrustDomainName)
+		e.federationCache.invalidate()
+	case bundles.Quarantined:
+		e.Logger.Warnf("Bundle of trust domain %s breaks the continuity with the stored bundle and was quarantined", authenticatedTD.Name)
 	}
 
 	return nil
@@ -125,16 +229,11 @@ func (e *Endpoints) syncFederatedBundleHandler(ctx echo.Context) error {
 		return err
 	}
 
-	token, err := e.Datastore.FindJoinToken(ctx.Request().Context(), jt.Token)
-	if err != nil {
-		err := errors.New("error looking up token")
-		e.handleTCPError(ctx, err.Error())
-		return err
-	}
+	e.recordHarvesterCall(ctx, jt, syncCall)
 
-	harvesterTrustDomain, err := e.Datastore.FindTrustDomainByID(ctx.Request().Context(), token.TrustDomainID)
+	harvesterTrustDomain, err := e.Datastore.FindTrustDomainByID(ctx.Request().Context(), jt.TrustDomainID)
 	if err != nil {
-		e.handleTCPError(ctx, fmt.Sprintf("failed to read body: %v", err))
+		e.handleTCPError(ctx, fmt.Sprintf("failed to look up trust domain: %v", err))
 		return err
 	}
 
@@ -151,47 +250,38 @@ func (e *Endpoints) syncFederatedBundleHandler(ctx echo.Context) error {
 		return err
 	}
 
-	harvesterBundleDigests := receivedHarvesterState.State
-
 	_, foundSelf := receivedHarvesterState.State[harvesterTrustDomain.Name]
 	if foundSelf {
 		e.handleTCPError(ctx, "bad request: harvester cannot federate with itself")
 		return err
 	}
 
-	relationships, err := e.Datastore.FindRelationshipsByTrustDomainID(ctx.Request().Context(), harvesterTrustDomain.ID.UUID)
+	federation, err := e.federationCache.get(ctx.Request().Context(), harvesterTrustDomain.ID.UUID)
 	if err != nil {
-		e.handleTCPError(ctx, fmt.Sprintf("failed to fetch relationships: %v", err))
+		e.handleTCPError(ctx, fmt.Sprintf("failed to fetch federated bundles: %v", err))
 		return err
 	}
 
-	federatedTDs := getFederatedTrustDomains(relationships, harvesterTrustDomain.ID.UUID)
-
-	if len(federatedTDs) == 0 {
-		e.Logger.Debug("No federated trust domains yet")
-		return nil
-	}
-
-	federatedBundles, federatedBundlesDigests, err := e.getCurrentFederatedBundles(ctx.Request().Context(), federatedTDs)
-	if err != nil {
-		e.handleTCPError(ctx, fmt.Sprintf("failed to fetch bundles from DB: %v", err))
-		return err
-	}
-
-	if len(federatedBundles) == 0 {
+	if len(federation.bundles) == 0 {
 		e.Logger.Debug("No federated bundles yet")
-		return nil
 	}
 
-	bundlesUpdates, err := e.getFederatedBundlesUpdates(ctx.Request().Context(), harvesterBundleDigests, federatedBundles)
-	if err != nil {
-		e.handleTCPError(ctx, fmt.Sprintf("failed to fetch bundles from DB: %v", err))
+	// digests are compared with the algorithm of the harvester, so that harvesters computing them over the
+	// X.509 authorities only are not sent every bundle on each sync
+	digests := federation.digests
+	switch receivedHarvesterState.DigestAlgorithm {
+	case util.DigestAlgorithmCanonical:
+	case util.DigestAlgorithmX509, "":
+		digests = federation.x509Digests
+	default:
+		err := fmt.Errorf("unsupported digest algorithm %q", receivedHarvesterState.DigestAlgorithm)
+		e.handleTCPError(ctx, err.Error())
 		return err
 	}
 
 	response := common.SyncBundleResponse{
-		Updates: bundlesUpdates,
-		State:   federatedBundlesDigests,
+		Updates: getFederatedBundlesUpdates(receivedHarvesterState.State, federation.bundles, digests),
+		State:   digests,
 	}
 
 	responseBytes, err := json.Marshal(response)
@@ -209,66 +299,30 @@ func (e *Endpoints) syncFederatedBundleHandler(ctx echo.Context) error {
 	return nil
 }
 
-func getFederatedTrustDomains(relationships []*entity.Relationship, tdID uuid.UUID) []uuid.UUID {
-	var federatedTrustDomains []uuid.UUID
-
-	for _, r := range relationships {
-		ma := r.TrustDomainAID
-		mb := r.TrustDomainBID
-
-		if tdID == ma {
-			federatedTrustDomains = append(federatedTrustDomains, mb)
-		} else {
-			federatedTrustDomains = append(federatedTrustDomains, ma)
-		}
-	}
-	return federatedTrustDomains
-}
-
-func (e *Endpoints) getFederatedBundlesUpdates(ctx context.Context, harvesterBundlesDigests common.BundlesDigests, federatedBundles []*entity.Bundle) (common.BundleUpdates, error) {
+func getFederatedBundlesUpdates(harvesterBundlesDigests common.BundlesDigests, federatedBundles []*entity.Bundle, serverDigests common.BundlesDigests) common.BundleUpdates {
 	response := make(common.BundleUpdates)
 
 	for _, b := range federatedBundles {
-		td, err := e.Datastore.FindTrustDomainByID(ctx, b.TrustDomainID)
-		if err != nil {
-			return nil, err
-		}
-
-		serverDigest := b.Digest
-		harvesterDigest := harvesterBundlesDigests[td.Name]
+		serverDigest := serverDigests[b.TrustDomainName]
+		harvesterDigest := harvesterBundlesDigests[b.TrustDomainName]
 
 		// If the bundle digest received from a federated trust domain of the calling harvester is not the same as the
 		// digest the server has, the harvester needs to be updated of the new bundle. This also covers the case of
 		// the harvester not being aware of any bundles. The update represents a newly federated trustDomain's bundle.
 		if !bytes.Equal(harvesterDigest, serverDigest) {
-			response[td.Name] = b
+			response[b.TrustDomainName] = b
 		}
 	}
 
-	return response, nil
+	return response
 }
 
-func (e *Endpoints) getCurrentFederatedBundles(ctx context.Context, federatedTDs []uuid.UUID) ([]*entity.Bundle, common.BundlesDigests, error) {
-	var bundles []*entity.Bundle
-	bundlesDigests := map[spiffeid.TrustDomain][]byte{}
-
-	for _, id := range federatedTDs {
-		b, err := e.Datastore.FindBundleByTrustDomainID(ctx, id)
-		if err != nil {
-			return nil, nil, err
-		}
-		td, err := e.Datastore.FindTrustDomainByID(ctx, id)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		if b != nil {
-			bundles = append(bundles, b)
-			bundlesDigests[td.Name] = b.Digest
-		}
+func (e *Endpoints) handleTCPDatastoreError(ctx echo.Context, err error) {
+	if errors.Is(err, datastore.ErrConflict) {
+		ctx.Response().WriteHeader(http.StatusConflict)
 	}
 
-	return bundles, bundlesDigests, nil
+	e.handleTCPError(ctx, err.Error())
 }
 
 func (e *Endpoints) handleTCPError(ctx echo.Context, errMsg string) {
@@ -0,0 +1,184 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnboardHandlerRecordsHarvesterSession(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	jt, err := ds.CreateJoinToken(ctx, &entity.JoinToken{Token: "token", TrustDomainID: td.ID.UUID, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	server := echo.New()
+	server.Use(middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		return e.validateToken(c, key)
+	}))
+	server.CONNECT("/onboard", e.onboardHandler)
+
+	onboard := func(harvesterVersion, spireVersion string) {
+		req := httptest.NewRequest(http.MethodConnect, "/onboard", nil)
+		req.RemoteAddr = "10.0.0.1:4321"
+		req.Header.Set("Authorization", "Bearer "+jt.Token)
+		req.Header.Set(common.HarvesterVersionHeader, harvesterVersion)
+		req.Header.Set(common.SPIREVersionHeader, spireVersion)
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	onboard("0.1.0", "1.5.0")
+
+	// the token is marked as used by the first call authenticated with it
+	used, err := ds.FindJoinTokensByID(ctx, jt.ID.UUID)
+	require.NoError(t, err)
+	assert.True(t, used.Used)
+
+	session, err := ds.FindHarvesterSessionByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	assert.False(t, session.LastOnboardAt.IsZero())
+	assert.True(t, session.LastPostAt.IsZero())
+	assert.True(t, session.LastSyncAt.IsZero())
+	assert.Equal(t, "10.0.0.1", session.RemoteAddress)
+	assert.Equal(t, "0.1.0", session.HarvesterVersion)
+	assert.Equal(t, "1.5.0", session.SPIREVersion)
+
+	// the session is updated by the next calls
+	onboard("0.2.0", "1.6.0")
+
+	updated, err := ds.FindHarvesterSessionByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, updated.ID)
+	assert.False(t, updated.LastOnboardAt.Before(session.LastOnboardAt))
+	assert.Equal(t, "0.2.0", updated.HarvesterVersion)
+	assert.Equal(t, "1.6.0", updated.SPIREVersion)
+}
+
+func TestValidateTokenExpiry(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+
+	server := echo.New()
+	server.Use(middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		return e.validateToken(c, key)
+	}))
+	server.CONNECT("/onboard", e.onboardHandler)
+
+	onboard := func(token string) int {
+		req := httptest.NewRequest(http.MethodConnect, "/onboard", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// an expired token which was never used cannot be used, and is not marked as used
+	expired, err := ds.CreateJoinToken(ctx, &entity.JoinToken{Token: "expired", TrustDomainID: td.ID.UUID, ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, onboard(expired.Token))
+
+	stored, err := ds.FindJoinTokensByID(ctx, expired.ID.UUID)
+	require.NoError(t, err)
+	assert.False(t, stored.Used)
+
+	// a harvester keeps authenticating with the token it onboarded with once it expires
+	onboarded, err := ds.CreateJoinToken(ctx, &entity.JoinToken{Token: "onboarded", TrustDomainID: td.ID.UUID, ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = ds.UpdateJoinToken(ctx, onboarded.ID.UUID, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, onboard(onboarded.Token))
+}
+
+func TestPostBundleImportedTrustDomain(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{
+		Name:   spiffeid.RequireTrustDomainFromString("a.test"),
+		PeerID: peer.ID,
+	})
+	require.NoError(t, err)
+	jt, err := ds.CreateJoinToken(ctx, &entity.JoinToken{Token: "token", TrustDomainID: td.ID.UUID, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	server := echo.New()
+	server.Use(middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		return e.validateToken(c, key)
+	}))
+	server.POST("/bundle", e.postBundleHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/bundle", nil)
+	req.Header.Set("Authorization", "Bearer "+jt.Token)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	// the bundle of a trust domain imported from a peer is only synced from the peer
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "is imported from a peer")
+
+	bundle, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, bundle)
+}
+
+func TestRecordHarvesterCallThrottled(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	jt := &entity.JoinToken{TrustDomainID: td.ID.UUID}
+
+	record := func(call harvesterCall, harvesterVersion string) *entity.HarvesterSession {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(common.HarvesterVersionHeader, harvesterVersion)
+		e.recordHarvesterCall(echo.New().NewContext(req, httptest.NewRecorder()), jt, call)
+
+		session, err := ds.FindHarvesterSessionByTrustDomainID(ctx, td.ID.UUID)
+		require.NoError(t, err)
+		return session
+	}
+
+	first := record(syncCall, "0.1.0")
+
+	// the calls of the same kind are not recorded again within the interval
+	session := record(syncCall, "0.1.0")
+	assert.Equal(t, first.LastSyncAt, session.LastSyncAt)
+	assert.Equal(t, first.UpdatedAt, session.UpdatedAt)
+
+	// unless they change the session
+	session = record(postCall, "0.1.0")
+	assert.False(t, session.LastPostAt.IsZero())
+	session = record(syncCall, "0.2.0")
+	assert.Equal(t, "0.2.0", session.HarvesterVersion)
+	assert.True(t, session.LastSyncAt.After(first.LastSyncAt))
+}
@@ -0,0 +1,21 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// listJobsHandler lists the status of the last run of each maintenance job, whichever replica of the server ran
+// it. The jobs which did not run yet are not listed.
+func (e *Endpoints) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	statuses, err := e.Datastore.ListJobStatuses(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing job statuses: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.writeResponse(w, statuses)
+}
@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListJobsHandler(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	startedAt := time.Now().UTC().Truncate(time.Second)
+	_, err := ds.RecordJobStatus(ctx, &entity.JobStatus{
+		Name:       "prune_join_tokens",
+		Schedule:   "1h0m0s",
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(time.Second),
+		NextRunAt:  startedAt.Add(time.Hour),
+		Result:     "deleted 2 join tokens",
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	e.listJobsHandler(rec, httptest.NewRequest(http.MethodGet, "/listJobs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var statuses []*entity.JobStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "prune_join_tokens", statuses[0].Name)
+	assert.Equal(t, "deleted 2 join tokens", statuses[0].Result)
+	assert.True(t, startedAt.Add(time.Hour).Equal(statuses[0].NextRunAt))
+}
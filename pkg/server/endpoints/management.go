@@ -3,13 +3,21 @@ package endpoints
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/apply"
+	"github.com/HewlettPackard/galadriel/pkg/server/backup"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/util"
 )
@@ -31,26 +39,158 @@ func (e *Endpoints) createTrustDomainHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	td, err := e.Datastore.FindTrustDomainByName(ctx, trustDomainReq.Name)
+	if err := entity.ValidateLabels(trustDomainReq.Labels); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the trust domains imported from a peer are only created by the peer sync
+	if trustDomainReq.PeerID.Valid {
+		errMsg := fmt.Sprintf("trust domain %q cannot be created with a peer, the trust domains of a peer are imported from the peer", trustDomainReq.Name)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	var m *entity.TrustDomain
+	err = e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		td, err := tx.FindTrustDomainByName(ctx, trustDomainReq.Name)
+		if err != nil {
+			return fmt.Errorf("failed looking up trust domain: %w", err)
+		}
+		if td != nil {
+			return fmt.Errorf("trust domain already exists: %q: %w", trustDomainReq.Name, datastore.ErrConflict)
+		}
+
+		m, err = tx.CreateOrUpdateTrustDomain(ctx, &trustDomainReq)
+		if err != nil {
+			return fmt.Errorf("failed creating trustDomain: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		errMsg := fmt.Sprintf("failed looking up trust domain: %v", err)
+		e.handleDatastoreError(w, err)
+		return
+	}
+
+	e.Logger.Printf("Created trustDomain for trust domain: %s", trustDomainReq.Name)
+
+	trustDomainBytes, err := json.Marshal(m)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling trustDomain entity: %v", err)
 		e.handleError(w, errMsg)
 		return
 	}
-	if td != nil {
-		errMsg := fmt.Sprintf("trust domain already exists: %q", trustDomainReq.Name)
+
+	_, err = w.Write(trustDomainBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
 		e.handleError(w, errMsg)
 		return
 	}
 
-	m, err := e.Datastore.CreateOrUpdateTrustDomain(ctx, &trustDomainReq)
+}
+
+// UpdateTrustDomainRequest updates the trust domain with the given name. Fields that are not set are left unchanged.
+type UpdateTrustDomainRequest struct {
+	Name             spiffeid.TrustDomain `json:"name"`
+	Description      *string              `json:"description,omitempty"`
+	PublishBundle    *bool                `json:"publish_bundle,omitempty"`
+	PublishOIDC      *bool                `json:"publish_oidc,omitempty"`
+	OnboardingBundle []byte               `json:"onboarding_bundle,omitempty"`
+
+	// Labels replaces the labels of the trust domain. Empty labels remove them all.
+	Labels *map[string]string `json:"labels,omitempty"`
+
+	// BundleEndpoint sets the SPIFFE bundle endpoint the bundle of the trust domain is fetched from.
+	// An endpoint with an empty URL removes it.
+	BundleEndpoint *BundleEndpoint `json:"bundle_endpoint,omitempty"`
+}
+
+type BundleEndpoint struct {
+	URL      string      `json:"url"`
+	Profile  string      `json:"profile"`
+	SPIFFEID spiffeid.ID `json:"spiffe_id"`
+}
+
+func (e *Endpoints) updateTrustDomainHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		errMsg := fmt.Sprintf("failed creating trustDomain: %v", err)
+		errMsg := fmt.Sprintf("failed reading request body: %v", err)
 		e.handleError(w, errMsg)
 		return
 	}
 
-	e.Logger.Printf("Created trustDomain for trust domain: %s", trustDomainReq.Name)
+	var req UpdateTrustDomainRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		errMsg := fmt.Sprintf("failed unmarshalling request: %v", err)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	var m *entity.TrustDomain
+	err = e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		td, err := tx.FindTrustDomainByName(ctx, req.Name)
+		if err != nil {
+			return fmt.Errorf("failed looking up trust domain: %w", err)
+		}
+		if td == nil {
+			return fmt.Errorf("trust domain %q not found", req.Name)
+		}
+
+		if req.Description != nil {
+			td.Description = *req.Description
+		}
+		if req.PublishBundle != nil {
+			td.PublishBundle = *req.PublishBundle
+		}
+		if req.PublishOIDC != nil {
+			td.PublishOIDC = *req.PublishOIDC
+		}
+		if req.OnboardingBundle != nil {
+			td.OnboardingBundle = req.OnboardingBundle
+		}
+		if req.BundleEndpoint != nil {
+			td.BundleEndpointURL = req.BundleEndpoint.URL
+			td.BundleEndpointProfile = req.BundleEndpoint.Profile
+			td.BundleEndpointSPIFFEID = req.BundleEndpoint.SPIFFEID
+		}
+		if req.Labels != nil {
+			if err := entity.ValidateLabels(*req.Labels); err != nil {
+				return &badRequestError{err: err}
+			}
+			td.Labels = *req.Labels
+		}
+
+		if err := bundles.ValidateEndpoint(td); err != nil {
+			return &badRequestError{err: err}
+		}
+
+		m, err = tx.CreateOrUpdateTrustDomain(ctx, td)
+		if err != nil {
+			return fmt.Errorf("failed updating trust domain: %w", err)
+		}
+
+		return nil
+	})
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, badRequest.Error())
+		return
+	}
+	if err != nil {
+		e.handleDatastoreError(w, err)
+		return
+	}
+
+	// the labels select the peers of the federation policies
+	if req.Labels != nil {
+		e.federationCache.invalidate()
+	}
+
+	e.Logger.Printf("Updated trust domain: %s", req.Name)
 
 	trustDomainBytes, err := json.Marshal(m)
 	if err != nil {
@@ -65,19 +205,37 @@ func (e *Endpoints) createTrustDomainHandler(w http.ResponseWriter, r *http.Requ
 		e.handleError(w, errMsg)
 		return
 	}
-
 }
 
 func (e *Endpoints) listTrustDomainsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	ms, err := e.Datastore.ListTrustDomains(ctx)
+	if !e.setResourceVersion(ctx, w) {
+		return
+	}
+
+	ms, err := e.BundleMonitor.TrustDomains(ctx, time.Now())
 	if err != nil {
 		errMsg := fmt.Sprintf("failed listing trustDomains: %v", err)
 		e.handleError(w, errMsg)
 		return
 	}
 
+	sessions, err := e.Datastore.ListHarvesterSessions(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing harvester sessions: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	byTrustDomain := make(map[uuid.UUID]*entity.HarvesterSession, len(sessions))
+	for _, s := range sessions {
+		byTrustDomain[s.TrustDomainID] = s
+	}
+	for _, m := range ms {
+		m.HarvesterSession = byTrustDomain[m.ID.UUID]
+	}
+
 	trustDomainsBytes, err := json.Marshal(ms)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed marshalling trustDomains entities: %v", err)
@@ -111,29 +269,60 @@ func (e *Endpoints) createRelationshipHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	tdaID, err := e.Datastore.FindTrustDomainByName(ctx, relationshipReq.TrustDomainAName)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed creating relationship: %v", err)
-		e.handleError(w, errMsg)
+	if err := relationshipReq.Direction.Validate(); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	relationshipReq.TrustDomainAID = tdaID.ID.UUID
-
-	tdbID, err := e.Datastore.FindTrustDomainByName(ctx, relationshipReq.TrustDomainBName)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed creating relationship: %v", err)
-		e.handleError(w, errMsg)
+	if err := validateValidity(&relationshipReq); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	relationshipReq.TrustDomainBID = tdbID.ID.UUID
+	relationshipReq.ExpiredAt = time.Time{}
 
-	rel, err := e.Datastore.CreateOrUpdateRelationship(ctx, &relationshipReq)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed creating relationship: %v", err)
-		e.handleError(w, errMsg)
+	var rel *entity.Relationship
+	err = e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		tda, err := tx.FindTrustDomainByName(ctx, relationshipReq.TrustDomainAName)
+		if err != nil {
+			return fmt.Errorf("failed creating relationship: %w", err)
+		}
+		if tda == nil {
+			return fmt.Errorf("failed creating relationship: trust domain %q not found", relationshipReq.TrustDomainAName)
+		}
+		relationshipReq.TrustDomainAID = tda.ID.UUID
+
+		tdb, err := tx.FindTrustDomainByName(ctx, relationshipReq.TrustDomainBName)
+		if err != nil {
+			return fmt.Errorf("failed creating relationship: %w", err)
+		}
+		if tdb == nil {
+			return fmt.Errorf("failed creating relationship: trust domain %q not found", relationshipReq.TrustDomainBName)
+		}
+		relationshipReq.TrustDomainBID = tdb.ID.UUID
+
+		if err := e.checkRelationshipRules(tda, tdb); err != nil {
+			return err
+		}
+
+		rel, err = tx.CreateOrUpdateRelationship(ctx, &relationshipReq)
+		if err != nil {
+			return fmt.Errorf("failed creating relationship: %w", err)
+		}
+		rel.TrustDomainAName, rel.TrustDomainBName = tda.Name, tdb.Name
+
+		return nil
+	})
+	var denied *relationshipDeniedError
+	switch {
+	case errors.As(err, &denied):
+		e.handleErrorWithStatus(w, http.StatusForbidden, denied.Error())
+		return
+	case err != nil:
+		e.handleDatastoreError(w, err)
 		return
 	}
 
+	e.federationCache.invalidate()
+
 	e.Logger.Printf("Created relationship between trust domains %s and %s", rel.TrustDomainAID, rel.TrustDomainBID)
 
 	relBytes, err := json.Marshal(rel)
@@ -151,19 +340,113 @@ func (e *Endpoints) createRelationshipHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (e *Endpoints) listRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
+// renewRelationshipHandler sets the end of the validity of the relationship between the trust domains named in
+// the request, which brings an expired relationship back into effect.
+func (e *Endpoints) renewRelationshipHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	rels, err := e.Datastore.ListRelationships(context.TODO())
+	var req entity.Relationship
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+	if req.NotAfter.IsZero() {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, "the end of the validity of the relationship is required")
+		return
+	}
+	if !req.NotAfter.After(time.Now()) {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, "the end of the validity of the relationship must be in the future")
+		return
+	}
+
+	var rel *entity.Relationship
+	err := e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		rel, err = findRelationshipByNames(ctx, tx, req.TrustDomainAName, req.TrustDomainBName)
+		if err != nil || rel == nil {
+			return err
+		}
+
+		rel.NotAfter = req.NotAfter
+		if err := validateValidity(rel); err != nil {
+			return &badRequestError{err: err}
+		}
+		rel.ExpiredAt = time.Time{}
+
+		rel, err = tx.CreateOrUpdateRelationship(ctx, rel)
+		if err != nil {
+			return fmt.Errorf("failed renewing relationship: %w", err)
+		}
+		rel.TrustDomainAName, rel.TrustDomainBName = req.TrustDomainAName, req.TrustDomainBName
+
+		return nil
+	})
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		e.handleErrorWithStatus(w, http.StatusBadRequest, badRequest.Error())
+		return
+	case err != nil:
+		e.handleDatastoreError(w, err)
+		return
+	case rel == nil:
+		errMsg := fmt.Sprintf("relationship between trust domains %q and %q does not exist", req.TrustDomainAName, req.TrustDomainBName)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	e.Logger.Infof("Relationship between trust domains %q and %q renewed until %s", req.TrustDomainAName, req.TrustDomainBName, rel.NotAfter.UTC().Format(time.RFC3339))
+
+	e.writeResponse(w, rel)
+}
+
+// findRelationshipByNames returns the relationship between the named trust domains, in either order, or nil if
+// there is none.
+func findRelationshipByNames(ctx context.Context, ds datastore.Datastore, a, b spiffeid.TrustDomain) (*entity.Relationship, error) {
+	tdA, err := ds.FindTrustDomainByName(ctx, a)
 	if err != nil {
-		errMsg := fmt.Sprintf("failed listing relationships: %v", err)
-		e.handleError(w, errMsg)
+		return nil, fmt.Errorf("failed looking up trust domain: %w", err)
+	}
+	tdB, err := ds.FindTrustDomainByName(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up trust domain: %w", err)
+	}
+	if tdA == nil || tdB == nil {
+		return nil, nil
+	}
+
+	rels, err := ds.FindRelationshipsByTrustDomainID(ctx, tdA.ID.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up relationships: %w", err)
+	}
+	for _, r := range rels {
+		if r.TrustDomainAID == tdB.ID.UUID || r.TrustDomainBID == tdB.ID.UUID {
+			return r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// validateValidity checks that the validity of a relationship does not end before it starts.
+func validateValidity(rel *entity.Relationship) error {
+	if !rel.NotBefore.IsZero() && !rel.NotAfter.IsZero() && !rel.NotAfter.After(rel.NotBefore) {
+		return errors.New("the validity of the relationship must end after it starts")
+	}
+	return nil
+}
+
+func (e *Endpoints) listRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !e.setResourceVersion(ctx, w) {
 		return
 	}
 
-	rels, err = e.populateTrustDomainNames(ctx, rels)
+	rels, err := e.Datastore.ListRelationshipsWithTrustDomainNames(ctx)
 	if err != nil {
-		errMsg := fmt.Sprintf("failed populating relationships entities: %v", err)
+		errMsg := fmt.Sprintf("failed listing relationships: %v", err)
 		e.handleError(w, errMsg)
 		return
 	}
@@ -183,21 +466,114 @@ func (e *Endpoints) listRelationshipsHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-func (e *Endpoints) populateTrustDomainNames(ctx context.Context, relationships []*entity.Relationship) ([]*entity.Relationship, error) {
-	for _, r := range relationships {
-		tda, err := e.Datastore.FindTrustDomainByID(ctx, r.TrustDomainAID)
-		if err != nil {
-			return nil, err
-		}
-		r.TrustDomainAName = tda.Name
+func (e *Endpoints) listBundleRejectionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-		tdb, err := e.Datastore.FindTrustDomainByID(ctx, r.TrustDomainBID)
-		if err != nil {
-			return nil, err
-		}
-		r.TrustDomainBName = tdb.Name
+	rejections, err := e.Datastore.ListBundleRejections(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing bundle rejections: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	rejectionsBytes, err := json.Marshal(rejections)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling bundle rejections: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	_, err = w.Write(rejectionsBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+}
+
+func (e *Endpoints) listQuarantinedBundlesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quarantined, err := e.Datastore.ListQuarantinedBundles(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing quarantined bundles: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	quarantinedBytes, err := json.Marshal(quarantined)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling quarantined bundles: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	_, err = w.Write(quarantinedBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+}
+
+func (e *Endpoints) approveBundleHandler(w http.ResponseWriter, r *http.Request) {
+	if !e.reviewQuarantinedBundle(w, r, "approved", bundles.Approve) {
+		return
 	}
-	return relationships, nil
+
+	// the approved bundle is redistributed right away
+	e.federationCache.invalidate()
+}
+
+func (e *Endpoints) rejectBundleHandler(w http.ResponseWriter, r *http.Request) {
+	e.reviewQuarantinedBundle(w, r, "rejected", bundles.Reject)
+}
+
+// reviewQuarantinedBundle approves or rejects the quarantined bundle of the trust domain named in the request,
+// and returns whether it succeeded.
+func (e *Endpoints) reviewQuarantinedBundle(w http.ResponseWriter, r *http.Request, action string, review func(context.Context, datastore.Datastore, uuid.UUID) error) bool {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed reading request body: %v", err)
+		e.handleError(w, errMsg)
+		return false
+	}
+
+	var trustDomain entity.TrustDomain
+	if err = json.Unmarshal(body, &trustDomain); err != nil {
+		errMsg := fmt.Sprintf("failed unmarshalling request: %v", err)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return false
+	}
+
+	td, err := e.Datastore.FindTrustDomainByName(ctx, trustDomain.Name)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed looking up trust domain: %v", err)
+		e.handleError(w, errMsg)
+		return false
+	}
+	if td == nil {
+		errMsg := fmt.Sprintf("trust domain %q does not exist", trustDomain.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return false
+	}
+
+	err = review(ctx, e.Datastore, td.ID.UUID)
+	switch {
+	case errors.Is(err, bundles.ErrNotQuarantined):
+		e.handleErrorWithStatus(w, http.StatusNotFound, err.Error())
+		return false
+	case err != nil:
+		errMsg := fmt.Sprintf("quarantined bundle could not be %s: %v", action, err)
+		e.handleError(w, errMsg)
+		return false
+	}
+
+	e.Logger.Infof("Quarantined bundle of trust domain %s %s", td.Name, action)
+
+	return true
 }
 
 func (e *Endpoints) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -223,6 +599,11 @@ func (e *Endpoints) generateTokenHandler(w http.ResponseWriter, r *http.Request)
 		e.handleError(w, errMsg)
 		return
 	}
+	if tdID != nil && tdID.PeerID.Valid {
+		errMsg := fmt.Sprintf("trust domain %q is imported from a peer, its bundle is synced from the peer", trustDomain.Name)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return
+	}
 
 	token, err := util.GenerateToken()
 	if err != nil {
@@ -259,8 +640,141 @@ func (e *Endpoints) generateTokenHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (e *Endpoints) exportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	opts := backup.ExportOptions{
+		ExcludeJoinTokens: r.URL.Query().Get("exclude_tokens") == "true",
+	}
+
+	doc, err := backup.Export(ctx, e.Datastore, opts)
+	if err != nil {
+		e.handleError(w, err.Error())
+		return
+	}
+
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling backup document: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	_, err = w.Write(docBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+}
+
+func (e *Endpoints) importHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed reading request body: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	var doc backup.Document
+	if err = json.Unmarshal(body, &doc); err != nil {
+		errMsg := fmt.Sprintf("failed unmarshalling backup document: %v", err)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	result, err := backup.Import(ctx, e.Datastore, &doc)
+	if err != nil {
+		e.handleDatastoreError(w, err)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	e.Logger.Infof("Imported backup: %d entities created, %d updated", result.Created, result.Updated)
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling import result: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	_, err = w.Write(resultBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+}
+
+func (e *Endpoints) applyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed reading request body: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	var state apply.State
+	if err = json.Unmarshal(body, &state); err != nil {
+		errMsg := fmt.Sprintf("failed unmarshalling state: %v", err)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err = state.Validate(); err != nil {
+		errMsg := fmt.Sprintf("invalid state: %v", err)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	if err = e.checkStateRules(&state); err != nil {
+		e.handleErrorWithStatus(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	plan, err := apply.Apply(ctx, e.Datastore, &state, r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		e.handleDatastoreError(w, err)
+		return
+	}
+
+	if plan.Applied && len(plan.Changes) > 0 {
+		e.federationCache.invalidate()
+		e.Logger.Infof("Applied state: %d changes made", len(plan.Changes))
+	}
+
+	planBytes, err := json.Marshal(plan)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling plan: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	_, err = w.Write(planBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+}
+
 func (e *Endpoints) onboardHandler(c echo.Context) error {
-	e.Logger.Info("Harvester connected")
+	jt, ok := c.Get(tokenKey).(*entity.JoinToken)
+	if !ok {
+		err := errors.New("error parsing join token")
+		e.handleTCPError(c, err.Error())
+		return err
+	}
+
+	e.Logger.Infof("Harvester of trust domain %q connected from %s", jt.TrustDomainID, c.Request().RemoteAddr)
+	e.recordHarvesterCall(c, jt, onboardCall)
+
 	return nil
 }
 
@@ -270,20 +784,108 @@ func (e *Endpoints) validateToken(ctx echo.Context, token string) (bool, error)
 		e.Logger.Errorf("Invalid Token: %s\n", token)
 		return false, err
 	}
+	if t == nil {
+		return false, nil
+	}
+
+	// the expiry of a token bounds when a harvester can onboard with it: a harvester keeps authenticating with the
+	// token it onboarded with, which is marked as used the first time it authenticates
+	if !t.Used && time.Now().After(t.ExpiresAt) {
+		e.Logger.Warnf("Expired join token used for trust domain: %s", t.TrustDomainID)
+		return false, nil
+	}
 
 	e.Logger.Debugf("Token valid for trust domain: %s\n", t.TrustDomainID)
 
-	ctx.Set("token", t)
+	// the last use of the token is recorded in its update time, the tokens not used within the retention of the
+	// prune_join_tokens job are deleted
+	if !t.Used || time.Since(t.UpdatedAt) > entity.JoinTokenUseInterval {
+		if _, err := e.Datastore.UpdateJoinToken(ctx.Request().Context(), t.ID.UUID, true); err != nil {
+			e.Logger.WithError(err).Warn("Failed to record join token use")
+		} else {
+			t.Used = true
+		}
+	}
+
+	ctx.Set(tokenKey, t)
 
 	return true, nil
 }
 
+// handleDatastoreError writes err as a 409 Conflict response if it is caused by a conflict with
+// the stored state, and as a 500 Internal Server Error response otherwise.
+func (e *Endpoints) handleDatastoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, datastore.ErrConflict) {
+		e.handleErrorWithStatus(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	e.handleError(w, err.Error())
+}
+
+// badRequestError is returned from within a transaction when the request is invalid.
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+// relationshipDeniedError is returned when the relationship rules do not allow two trust domains to be federated.
+type relationshipDeniedError struct {
+	a, b     spiffeid.TrustDomain
+	decision *relationships.Decision
+}
+
+func (e *relationshipDeniedError) Error() string {
+	return fmt.Sprintf("relationship between trust domains %q and %q is not allowed: %s (relationship rules version %s)", e.a, e.b, e.decision.Reason, e.decision.Version)
+}
+
+// checkRelationshipRules returns a relationshipDeniedError if the relationship rules do not allow the trust
+// domains to be federated.
+func (e *Endpoints) checkRelationshipRules(a, b *entity.TrustDomain) error {
+	if decision := e.RelationshipRules.Evaluate(a, b); !decision.Allowed {
+		return &relationshipDeniedError{a: a.Name, b: b.Name, decision: decision}
+	}
+	return nil
+}
+
+// checkStateRules checks the relationships declared in a validated state against the relationship rules, with
+// the trust domains as declared in the state.
+func (e *Endpoints) checkStateRules(state *apply.State) error {
+	trustDomains := make(map[string]*entity.TrustDomain, len(state.TrustDomains))
+	for _, td := range state.TrustDomains {
+		name, err := spiffeid.TrustDomainFromString(td.Name)
+		if err != nil {
+			return err
+		}
+		trustDomains[td.Name] = &entity.TrustDomain{Name: name, Labels: td.Labels}
+	}
+
+	for _, r := range state.Relationships {
+		a, b := trustDomains[r.TrustDomainA], trustDomains[r.TrustDomainB]
+		if a == nil || b == nil {
+			continue
+		}
+		if err := e.checkRelationshipRules(a, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (e *Endpoints) handleError(w http.ResponseWriter, errMsg string) {
+	e.handleErrorWithStatus(w, http.StatusInternalServerError, errMsg)
+}
+
+func (e *Endpoints) handleErrorWithStatus(w http.ResponseWriter, status int, errMsg string) {
 	errMsg = util.LogSanitize(errMsg)
 	e.Logger.Errorf(errMsg)
 
 	errBytes := []byte(errMsg)
-	w.WriteHeader(500)
+	w.WriteHeader(status)
 	_, err := w.Write(errBytes)
 	if err != nil {
 		e.Logger.Errorf("Failed to write error response: %v", err)
@@ -0,0 +1,50 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// metricsContentType is the content type of the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func (e *Endpoints) runMetricsServer(ctx context.Context) error {
+	server := echo.New()
+	server.HideBanner = true
+	server.HidePort = true
+
+	server.GET("/metrics", e.metricsHandler)
+
+	e.Logger.Infof("Starting Metrics Server on %s", e.MetricsAddress.String())
+	errChan := make(chan error)
+	go func() {
+		errChan <- server.Start(e.MetricsAddress.String())
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+		e.Logger.WithError(err).Error("Metrics Server stopped prematurely")
+		return err
+	case <-ctx.Done():
+		e.Logger.Info("Stopping Metrics Server")
+		server.Close()
+		<-errChan
+		e.Logger.Info("Metrics Server stopped")
+		return nil
+	}
+}
+
+func (e *Endpoints) metricsHandler(ctx echo.Context) error {
+	var buf bytes.Buffer
+	if err := e.BundleMonitor.WriteMetrics(ctx.Request().Context(), &buf, time.Now()); err != nil {
+		e.Logger.Errorf("Failed writing metrics: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return ctx.Blob(http.StatusOK, metricsContentType, buf.Bytes())
+}
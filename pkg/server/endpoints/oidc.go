@@ -0,0 +1,121 @@
+package endpoints
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/labstack/echo/v4"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// openIDConfiguration is the OIDC discovery document of a trust domain. Only the fields needed to validate
+// JWT-SVIDs are meaningful, the others are required by the specification.
+type openIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// oidcDiscoveryHandler writes the OIDC discovery document of a trust domain. The issuer is the URL the
+// document is served under, which is also the issuer the SPIRE server of the trust domain has to set in
+// the JWT-SVIDs it signs.
+func (e *Endpoints) oidcDiscoveryHandler(ctx echo.Context) error {
+	td, _, err := e.findOIDCTrustDomain(ctx)
+	if err != nil {
+		return err
+	}
+
+	issuer := e.oidcIssuer(ctx, td.Name)
+
+	return ctx.JSON(http.StatusOK, &openIDConfiguration{
+		Issuer:                           issuer,
+		JWKSURI:                          issuer + "/keys",
+		AuthorizationEndpoint:            "",
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{"RS256", "ES256", "ES384"},
+	})
+}
+
+// oidcKeysHandler writes the JWT authorities of a trust domain as a JWKS document.
+func (e *Endpoints) oidcKeysHandler(ctx echo.Context) error {
+	_, bundle, err := e.findOIDCTrustDomain(ctx)
+	if err != nil {
+		return err
+	}
+
+	authorities := bundle.JWTAuthorities()
+
+	keyIDs := make([]string, 0, len(authorities))
+	for keyID := range authorities {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, keyID := range keyIDs {
+		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
+			Key:   authorities[keyID],
+			KeyID: keyID,
+			Use:   "sig",
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, jwks)
+}
+
+// findOIDCTrustDomain looks up the trust domain of the request and its bundle. As with the federation bundle
+// endpoint, trust domains that are unknown, not allowed to publish their JWT authorities or without a bundle
+// are all reported as not found.
+func (e *Endpoints) findOIDCTrustDomain(ctx echo.Context) (*entity.TrustDomain, *spiffebundle.Bundle, error) {
+	notFound := echo.NewHTTPError(http.StatusNotFound, "trust domain not found")
+
+	trustDomain, err := spiffeid.TrustDomainFromString(ctx.Param("trustDomain"))
+	if err != nil {
+		return nil, nil, notFound
+	}
+
+	td, err := e.Datastore.FindTrustDomainByName(ctx.Request().Context(), trustDomain)
+	if err != nil {
+		e.Logger.Errorf("Failed looking up trust domain %q: %v", trustDomain, err)
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if td == nil || !td.PublishOIDC {
+		return nil, nil, notFound
+	}
+
+	stored, err := e.Datastore.FindBundleByTrustDomainID(ctx.Request().Context(), td.ID.UUID)
+	if err != nil {
+		e.Logger.Errorf("Failed looking up bundle for trust domain %q: %v", trustDomain, err)
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if stored == nil {
+		return nil, nil, notFound
+	}
+
+	bundle, err := spiffebundle.Parse(trustDomain, stored.Data)
+	if err != nil {
+		e.Logger.Errorf("Failed parsing bundle for trust domain %q: %v", trustDomain, err)
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return td, bundle, nil
+}
+
+// oidcIssuer returns the issuer of a trust domain: its path under the public URL of the federation endpoint,
+// or under the host of the request if no public URL is configured.
+func (e *Endpoints) oidcIssuer(ctx echo.Context, td spiffeid.TrustDomain) string {
+	base := e.FederationPublicURL
+	if base == "" {
+		base = "https://" + ctx.Request().Host
+	}
+
+	return strings.TrimSuffix(base, "/") + "/oidc/" + td.String()
+}
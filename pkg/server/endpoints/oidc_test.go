@@ -0,0 +1,92 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestOIDCHandlers(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tdName := spiffeid.RequireTrustDomainFromString("a.test")
+	bundle := spiffebundle.New(tdName)
+	require.NoError(t, bundle.AddJWTAuthority("key-1", key.Public()))
+	data, err := bundle.Marshal()
+	require.NoError(t, err)
+
+	published, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: tdName, PublishOIDC: true})
+	require.NoError(t, err)
+	private, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+
+	for _, td := range []*entity.TrustDomain{published, private} {
+		_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: data, Digest: []byte("digest"), TrustDomainID: td.ID.UUID})
+		require.NoError(t, err)
+	}
+
+	server := echo.New()
+	server.GET("/oidc/:trustDomain/.well-known/openid-configuration", e.oidcDiscoveryHandler)
+	server.GET("/oidc/:trustDomain/keys", e.oidcKeysHandler)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = "galadriel.test:8443"
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/oidc/a.test/.well-known/openid-configuration")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var config openIDConfiguration
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &config))
+	assert.Equal(t, "https://galadriel.test:8443/oidc/a.test", config.Issuer)
+	assert.Equal(t, "https://galadriel.test:8443/oidc/a.test/keys", config.JWKSURI)
+
+	rec = get("/oidc/a.test/keys")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var jwks jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "key-1", jwks.Keys[0].KeyID)
+	assert.Equal(t, "sig", jwks.Keys[0].Use)
+	assert.Equal(t, key.Public(), jwks.Keys[0].Key)
+
+	// the issuer is based on the public URL when it is configured
+	e.FederationPublicURL = "https://galadriel.example.org/"
+	rec = get("/oidc/a.test/.well-known/openid-configuration")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &config))
+	assert.Equal(t, "https://galadriel.example.org/oidc/a.test", config.Issuer)
+
+	for _, path := range []string{
+		"/oidc/b.test/.well-known/openid-configuration",
+		"/oidc/b.test/keys",
+		"/oidc/c.test/keys",
+	} {
+		assert.Equal(t, http.StatusNotFound, get(path).Code, path)
+	}
+}
@@ -0,0 +1,72 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/HewlettPackard/galadriel/pkg/server/peering"
+	"github.com/labstack/echo/v4"
+)
+
+// runPeeringServer serves the peering endpoint over mutual TLS. Peers are authenticated with a certificate
+// issued by the peering CA and valid for the host of their URL, and are served the bundles of the trust
+// domains shared with them.
+func (e *Endpoints) runPeeringServer(ctx context.Context) error {
+	server := echo.New()
+	server.HideBanner = true
+	server.HidePort = true
+
+	server.GET(peering.BundlesPath, e.peeringBundlesHandler)
+
+	tlsConfig := e.PeeringTLSConfig.Clone()
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	server.TLSServer.Addr = e.PeeringAddress.String()
+	server.TLSServer.TLSConfig = tlsConfig
+
+	e.Logger.Infof("Starting Peering Server on %s", e.PeeringAddress.String())
+	errChan := make(chan error)
+	go func() {
+		errChan <- server.StartServer(server.TLSServer)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+		e.Logger.WithError(err).Error("Peering Server stopped prematurely")
+		return err
+	case <-ctx.Done():
+		e.Logger.Info("Stopping Peering Server")
+		server.Close()
+		<-errChan
+		e.Logger.Info("Peering Server stopped")
+		return nil
+	}
+}
+
+// peeringBundlesHandler writes the trust domains shared with the calling peer, along with their bundles.
+func (e *Endpoints) peeringBundlesHandler(ctx echo.Context) error {
+	state := ctx.Request().TLS
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "client certificate is required")
+	}
+	cert := state.VerifiedChains[0][0]
+
+	peer, err := peering.FindPeerByCertificate(ctx.Request().Context(), e.Datastore, cert)
+	if err != nil {
+		e.Logger.Errorf("Failed looking up peer: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if peer == nil {
+		e.Logger.Warnf("Peering request from unknown peer %q rejected", cert.Subject.String())
+		return echo.NewHTTPError(http.StatusForbidden, "unknown peer")
+	}
+
+	resp, err := peering.SharedBundles(ctx.Request().Context(), e.Datastore, peer.ID.UUID)
+	if err != nil {
+		e.Logger.Errorf("Failed looking up the bundles shared with peer %q: %v", peer.Name, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
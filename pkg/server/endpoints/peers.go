@@ -0,0 +1,224 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/peering"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+func (e *Endpoints) createPeerHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.Peer
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, "peer name is required")
+		return
+	}
+	if err := peering.ValidateURL(req.URL); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	peer, err := e.Datastore.CreatePeer(ctx, &req)
+	if err != nil {
+		e.handleDatastoreError(w, fmt.Errorf("failed creating peer: %w", err))
+		return
+	}
+
+	e.Logger.Infof("Peer %q created with URL %s", peer.Name, peer.URL)
+
+	e.writeResponse(w, peer)
+}
+
+// listPeersHandler returns the peers along with the trust domains shared with them and imported from them.
+func (e *Endpoints) listPeersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	peers, err := e.Datastore.ListPeers(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing peers: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	trustDomains, err := e.Datastore.ListTrustDomains(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing trust domains: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	for _, peer := range peers {
+		shared, err := e.Datastore.ListPeerSharedTrustDomains(ctx, peer.ID.UUID)
+		if err != nil {
+			errMsg := fmt.Sprintf("failed listing trust domains shared with peer %q: %v", peer.Name, err)
+			e.handleError(w, errMsg)
+			return
+		}
+		for _, td := range shared {
+			peer.SharedTrustDomains = append(peer.SharedTrustDomains, td.Name)
+		}
+
+		for _, td := range trustDomains {
+			if td.PeerID == peer.ID {
+				peer.TrustDomains = append(peer.TrustDomains, td.Name)
+			}
+		}
+	}
+
+	e.writeResponse(w, peers)
+}
+
+// deletePeerHandler deletes the peer along with the trust domains imported from it and their relationships.
+func (e *Endpoints) deletePeerHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.Peer
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	var peer *entity.Peer
+	err := e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		peer, err = tx.FindPeerByName(ctx, req.Name)
+		if err != nil || peer == nil {
+			return err
+		}
+
+		trustDomains, err := tx.ListTrustDomains(ctx)
+		if err != nil {
+			return fmt.Errorf("failed listing trust domains: %w", err)
+		}
+		for _, td := range trustDomains {
+			if td.PeerID != peer.ID {
+				continue
+			}
+
+			relationships, err := tx.FindRelationshipsByTrustDomainID(ctx, td.ID.UUID)
+			if err != nil {
+				return fmt.Errorf("failed looking up relationships of trust domain %q: %w", td.Name, err)
+			}
+			for _, rel := range relationships {
+				if err := tx.DeleteRelationship(ctx, rel.ID.UUID); err != nil {
+					return fmt.Errorf("failed deleting relationship of trust domain %q: %w", td.Name, err)
+				}
+			}
+
+			// the imported trust domains would be deleted along with the peer, they are deleted beforehand so
+			// their deletion, and the deletion of their bundles, is recorded like any other
+			if err := tx.DeleteTrustDomain(ctx, td.ID.UUID); err != nil {
+				return fmt.Errorf("failed deleting trust domain %q: %w", td.Name, err)
+			}
+		}
+
+		if err := tx.DeletePeer(ctx, peer.ID.UUID); err != nil {
+			return fmt.Errorf("failed deleting peer: %w", err)
+		}
+
+		return nil
+	})
+	switch {
+	case err != nil:
+		e.handleDatastoreError(w, err)
+		return
+	case peer == nil:
+		errMsg := fmt.Sprintf("peer %q does not exist", req.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	e.Logger.Infof("Peer %q deleted", peer.Name)
+}
+
+// sharePeerTrustDomainHandler shares the trust domains listed as shared trust domains in the request with the
+// named peer.
+func (e *Endpoints) sharePeerTrustDomainHandler(w http.ResponseWriter, r *http.Request) {
+	e.updatePeerSharedTrustDomains(w, r, "shared with", func(ctx context.Context, tx datastore.Datastore, peer *entity.Peer, td *entity.TrustDomain) error {
+		return tx.AddPeerSharedTrustDomain(ctx, peer.ID.UUID, td.ID.UUID)
+	})
+}
+
+// unsharePeerTrustDomainHandler stops sharing the trust domains listed as shared trust domains in the request
+// with the named peer.
+func (e *Endpoints) unsharePeerTrustDomainHandler(w http.ResponseWriter, r *http.Request) {
+	e.updatePeerSharedTrustDomains(w, r, "no longer shared with", func(ctx context.Context, tx datastore.Datastore, peer *entity.Peer, td *entity.TrustDomain) error {
+		return tx.RemovePeerSharedTrustDomain(ctx, peer.ID.UUID, td.ID.UUID)
+	})
+}
+
+func (e *Endpoints) updatePeerSharedTrustDomains(w http.ResponseWriter, r *http.Request, verb string, update func(ctx context.Context, tx datastore.Datastore, peer *entity.Peer, td *entity.TrustDomain) error) {
+	ctx := r.Context()
+
+	var req entity.Peer
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	var peer *entity.Peer
+	err := e.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		peer, err = tx.FindPeerByName(ctx, req.Name)
+		if err != nil || peer == nil {
+			return err
+		}
+
+		for _, name := range req.SharedTrustDomains {
+			td, err := findSharedTrustDomain(ctx, tx, name)
+			if err != nil {
+				return err
+			}
+
+			if err := update(ctx, tx, peer, td); err != nil {
+				return fmt.Errorf("failed updating the trust domains shared with peer %q: %w", peer.Name, err)
+			}
+		}
+
+		return nil
+	})
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		e.handleErrorWithStatus(w, http.StatusBadRequest, badRequest.Error())
+		return
+	case err != nil:
+		e.handleDatastoreError(w, err)
+		return
+	case peer == nil:
+		errMsg := fmt.Sprintf("peer %q does not exist", req.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	for _, name := range req.SharedTrustDomains {
+		e.Logger.Infof("Trust domain %q %s peer %q", name, verb, peer.Name)
+	}
+}
+
+// findSharedTrustDomain looks up a trust domain to share with a peer named in a request, which is a bad request
+// if the trust domain does not exist or is imported from a peer.
+func findSharedTrustDomain(ctx context.Context, ds datastore.Datastore, name spiffeid.TrustDomain) (*entity.TrustDomain, error) {
+	td, err := ds.FindTrustDomainByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up trust domain: %w", err)
+	}
+	if td == nil {
+		return nil, &badRequestError{err: fmt.Errorf("trust domain %q does not exist", name)}
+	}
+	if td.PeerID.Valid {
+		return nil, &badRequestError{err: fmt.Errorf("trust domain %q is imported from a peer, only the trust domains of this server can be shared", name)}
+	}
+
+	return td, nil
+}
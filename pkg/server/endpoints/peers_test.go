@@ -0,0 +1,216 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/peering"
+	"github.com/HewlettPackard/galadriel/pkg/server/watch"
+	"github.com/HewlettPackard/galadriel/pkg/server/webhooks"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerHandlers(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{
+		Datastore:       ds,
+		Logger:          logrus.New(),
+		federationCache: newFederationCache(ds, nil, logrus.New(), federationCacheTTL),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/createPeer", e.createPeerHandler)
+	mux.HandleFunc("/listPeers", e.listPeersHandler)
+	mux.HandleFunc("/deletePeer", e.deletePeerHandler)
+	mux.HandleFunc("/sharePeerTrustDomain", e.sharePeerTrustDomainHandler)
+	mux.HandleFunc("/unsharePeerTrustDomain", e.unsharePeerTrustDomainHandler)
+	mux.HandleFunc("/createTrustDomain", e.createTrustDomainHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	post := func(path string, peer *entity.Peer) (int, string) {
+		body, err := json.Marshal(peer)
+		require.NoError(t, err)
+
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	status, body := post("/createPeer", &entity.Peer{Name: "acme", URL: "http://galadriel.acme.test:8444"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "invalid peer URL: scheme must be https")
+
+	status, _ = post("/createPeer", &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.Equal(t, http.StatusOK, status)
+	status, _ = post("/createPeer", &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	assert.Equal(t, http.StatusConflict, status)
+
+	peer, err := ds.FindPeerByName(ctx, "acme")
+	require.NoError(t, err)
+
+	local, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test")})
+	require.NoError(t, err)
+	imported, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("acme.test"), PeerID: peer.ID})
+	require.NoError(t, err)
+
+	// the trust domains of a peer are only imported from the peer
+	tdBody, err := json.Marshal(&entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("bar.test"), PeerID: peer.ID})
+	require.NoError(t, err)
+	resp, err := http.Post(server.URL+"/createTrustDomain", "application/json", bytes.NewReader(tdBody))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	td, err := ds.FindTrustDomainByName(ctx, spiffeid.RequireTrustDomainFromString("bar.test"))
+	require.NoError(t, err)
+	assert.Nil(t, td)
+
+	// only the trust domains of this server can be shared
+	status, body = post("/sharePeerTrustDomain", &entity.Peer{Name: "acme", SharedTrustDomains: []spiffeid.TrustDomain{imported.Name}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, `trust domain "acme.test" is imported from a peer`)
+
+	status, _ = post("/sharePeerTrustDomain", &entity.Peer{Name: "acme", SharedTrustDomains: []spiffeid.TrustDomain{local.Name}})
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = post("/sharePeerTrustDomain", &entity.Peer{Name: "unknown", SharedTrustDomains: []spiffeid.TrustDomain{local.Name}})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	resp, err = http.Get(server.URL + "/listPeers")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var peers []*entity.Peer
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&peers))
+	require.Len(t, peers, 1)
+	assert.Equal(t, []spiffeid.TrustDomain{local.Name}, peers[0].SharedTrustDomains)
+	assert.Equal(t, []spiffeid.TrustDomain{imported.Name}, peers[0].TrustDomains)
+
+	status, _ = post("/unsharePeerTrustDomain", &entity.Peer{Name: "acme", SharedTrustDomains: []spiffeid.TrustDomain{local.Name}})
+	assert.Equal(t, http.StatusOK, status)
+
+	shared, err := ds.ListPeerSharedTrustDomains(ctx, peer.ID.UUID)
+	require.NoError(t, err)
+	assert.Empty(t, shared)
+
+	// deleting a peer deletes the trust domains imported from it along with their relationships
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: local.ID.UUID, TrustDomainBID: imported.ID.UUID})
+	require.NoError(t, err)
+
+	status, _ = post("/deletePeer", &entity.Peer{Name: "acme"})
+	assert.Equal(t, http.StatusOK, status)
+
+	td, err = ds.FindTrustDomainByName(ctx, imported.Name)
+	require.NoError(t, err)
+	assert.Nil(t, td)
+	rels, err := ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rels)
+
+	status, _ = post("/deletePeer", &entity.Peer{Name: "acme"})
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestDeletePeerRecordsDeletions(t *testing.T) {
+	ctx := context.Background()
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := watch.NewDatastore(webhooks.NewDatastore(memory))
+	e := &Endpoints{
+		Datastore:       ds,
+		Logger:          logrus.New(),
+		federationCache: newFederationCache(ds, nil, logrus.New(), federationCacheTTL),
+	}
+
+	_, err := webhooks.Register(ctx, ds, "https://hooks.example.org/deleted", []string{string(webhooks.TrustDomainDeleted)})
+	require.NoError(t, err)
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	local, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test")})
+	require.NoError(t, err)
+	imported, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("acme.test"), PeerID: peer.ID})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{TrustDomainID: imported.ID.UUID, Data: []byte("bundle"), Digest: []byte("digest")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: local.ID.UUID, TrustDomainBID: imported.ID.UUID})
+	require.NoError(t, err)
+
+	version, err := memory.FindResourceVersion(ctx)
+	require.NoError(t, err)
+
+	body, err := json.Marshal(&entity.Peer{Name: "acme"})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	e.deletePeerHandler(rec, httptest.NewRequest(http.MethodPost, "/deletePeer", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// the deletions of the imported trust domain and of its bundle are recorded for the watches
+	events, err := memory.ListWatchEvents(ctx, version, 100)
+	require.NoError(t, err)
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event.Kind+"."+event.Type)
+	}
+	assert.Equal(t, []string{
+		watch.KindRelationship + "." + watch.Deleted,
+		watch.KindBundle + "." + watch.Deleted,
+		watch.KindTrustDomain + "." + watch.Deleted,
+	}, kinds)
+
+	// and notified to the webhooks
+	deliveries, err := memory.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, string(webhooks.TrustDomainDeleted), deliveries[0].EventType)
+	assert.Contains(t, string(deliveries[0].Payload), `"acme.test"`)
+}
+
+func TestPeeringBundlesHandler(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	e := &Endpoints{Datastore: ds, Logger: logrus.New()}
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("foo.test")})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddPeerSharedTrustDomain(ctx, peer.ID.UUID, td.ID.UUID))
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("bar.test")})
+	require.NoError(t, err)
+
+	call := func(dnsName string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, peering.BundlesPath, nil)
+		cert := &x509.Certificate{DNSNames: []string{dnsName}}
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+		rec := httptest.NewRecorder()
+		return rec, e.peeringBundlesHandler(echo.New().NewContext(req, rec))
+	}
+
+	// only the trust domains shared with the peer are served
+	rec, err := call("galadriel.acme.test")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"trust_domains": [{"name": "foo.test"}]}`, rec.Body.String())
+
+	_, err = call("galadriel.unknown.test")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
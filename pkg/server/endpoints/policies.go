@@ -0,0 +1,111 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+func (e *Endpoints) createFederationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.FederationPolicy
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, "federation policy name is required")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	policy, err := e.Datastore.CreateFederationPolicy(ctx, &req)
+	if err != nil {
+		e.handleDatastoreError(w, fmt.Errorf("failed creating federation policy: %w", err))
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	e.Logger.Infof("Federation policy %q created, federating %s with %s", policy.Name, policy.Selector, policy.PeerSelector)
+
+	e.writeResponse(w, policy)
+}
+
+func (e *Endpoints) listFederationPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	policies, err := e.Datastore.ListFederationPolicies(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing federation policies: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.writeResponse(w, policies)
+}
+
+func (e *Endpoints) deleteFederationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.FederationPolicy
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	policy, err := e.Datastore.FindFederationPolicyByName(ctx, req.Name)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed looking up federation policy: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+	if policy == nil {
+		errMsg := fmt.Sprintf("federation policy %q does not exist", req.Name)
+		e.handleErrorWithStatus(w, http.StatusNotFound, errMsg)
+		return
+	}
+
+	if err := e.Datastore.DeleteFederationPolicy(ctx, policy.ID.UUID); err != nil {
+		errMsg := fmt.Sprintf("failed deleting federation policy: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.federationCache.invalidate()
+
+	e.Logger.Infof("Federation policy %q deleted", policy.Name)
+}
+
+// testRelationshipHandler evaluates the relationship rules for the trust domains of a proposed relationship,
+// without creating it.
+func (e *Endpoints) testRelationshipHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.Relationship
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	var trustDomains []*entity.TrustDomain
+	for _, name := range []spiffeid.TrustDomain{req.TrustDomainAName, req.TrustDomainBName} {
+		td, err := e.Datastore.FindTrustDomainByName(ctx, name)
+		if err != nil {
+			errMsg := fmt.Sprintf("failed looking up trust domain: %v", err)
+			e.handleError(w, errMsg)
+			return
+		}
+		if td == nil {
+			errMsg := fmt.Sprintf("trust domain %q does not exist", name)
+			e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+			return
+		}
+		trustDomains = append(trustDomains, td)
+	}
+
+	e.writeResponse(w, e.RelationshipRules.Evaluate(trustDomains[0], trustDomains[1]))
+}
@@ -0,0 +1,94 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationshipRulesHandlers(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	rules, err := relationships.NewRuleSet("v1", []relationships.RuleConfig{
+		{Name: "prod-dev", Condition: `a.labels.env == 'prod' && b.labels.env == 'dev'`},
+	})
+	require.NoError(t, err)
+	e := &Endpoints{
+		Datastore:         ds,
+		Logger:            logrus.New(),
+		RelationshipRules: rules,
+		federationCache:   newFederationCache(ds, rules, logrus.New(), federationCacheTTL),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/createRelationship", e.createRelationshipHandler)
+	mux.HandleFunc("/testRelationship", e.testRelationshipHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for name, env := range map[string]string{"prod.test": "prod", "dev.test": "dev", "staging.test": "staging"} {
+		_, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString(name), Labels: map[string]string{"env": env}})
+		require.NoError(t, err)
+	}
+
+	post := func(path, a, b string) (int, string) {
+		body, err := json.Marshal(&entity.Relationship{
+			TrustDomainAName: spiffeid.RequireTrustDomainFromString(a),
+			TrustDomainBName: spiffeid.RequireTrustDomainFromString(b),
+		})
+		require.NoError(t, err)
+
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	// testing a relationship evaluates the rules without creating it
+	status, body := post("/testRelationship", "dev.test", "prod.test")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"allowed": false, "rule": "prod-dev", "version": "v1", "reason": "denied by relationship rule \"prod-dev\""}`, body)
+
+	status, body = post("/testRelationship", "staging.test", "prod.test")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"allowed": true, "version": "v1", "reason": "no deny relationship rule matches"}`, body)
+
+	status, _ = post("/testRelationship", "staging.test", "unknown.test")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	rels, err := ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rels)
+
+	// a denied relationship is not created
+	status, body = post("/createRelationship", "dev.test", "prod.test")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, `relationship between trust domains "dev.test" and "prod.test" is not allowed: denied by relationship rule "prod-dev" (relationship rules version v1)`)
+
+	status, body = post("/createRelationship", "staging.test", "prod.test")
+	assert.Equal(t, http.StatusOK, status)
+	var created entity.Relationship
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	assert.Equal(t, "staging.test", created.TrustDomainAName.String())
+	assert.Equal(t, "prod.test", created.TrustDomainBName.String())
+
+	rels, err = ds.ListRelationships(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rels, 1)
+}
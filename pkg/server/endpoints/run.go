@@ -2,12 +2,19 @@ package endpoints
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/changes"
 	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/HewlettPackard/galadriel/pkg/server/watch"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/sirupsen/logrus"
@@ -25,6 +32,27 @@ type Endpoints struct {
 	LocalAddr  net.Addr
 	Datastore  datastore.Datastore
 	Logger     logrus.FieldLogger
+
+	FederationAddress   *net.TCPAddr
+	FederationCertFile  string
+	FederationKeyFile   string
+	FederationPublicURL string
+
+	PeeringAddress   *net.TCPAddr
+	PeeringTLSConfig *tls.Config
+
+	BundlePolicy  *bundles.Policy
+	BundleMonitor *bundles.Monitor
+
+	RelationshipRules *relationships.RuleSet
+
+	Changes *changes.Listener
+
+	MetricsAddress *net.TCPAddr
+
+	federationCache   *federationCache
+	watchInterval     time.Duration
+	harvesterSessions harvesterSessionCache
 }
 
 func New(c *Config) (*Endpoints, error) {
@@ -32,24 +60,75 @@ func New(c *Config) (*Endpoints, error) {
 		return nil, err
 	}
 
-	ds, err := datastore.NewSQLDatastore(c.Logger, c.DatastoreConnString)
-	if err != nil {
-		return nil, err
+	if c.FederationAddress != nil && (c.FederationCertFile == "" || c.FederationKeyFile == "") {
+		return nil, errors.New("the federation endpoint requires a certificate and a private key")
+	}
+
+	if c.PeeringAddress != nil && c.PeeringTLSConfig == nil {
+		return nil, errors.New("the peering endpoint requires a TLS configuration")
+	}
+
+	bundlePolicy := c.BundlePolicy
+	if bundlePolicy == nil {
+		bundlePolicy = bundles.DefaultPolicy()
+	}
+
+	if c.RelationshipRules != nil {
+		c.Logger.Infof("Loaded %d relationship rules, version %s", len(c.RelationshipRules.Rules()), c.RelationshipRules.Version())
+	}
+
+	bundleMonitor := c.BundleMonitor
+	if bundleMonitor == nil {
+		bundleMonitor = bundles.NewMonitor(&bundles.MonitorConfig{Datastore: c.Datastore, Logger: c.Logger})
 	}
 
 	return &Endpoints{
 		TCPAddress: c.TCPAddress,
 		LocalAddr:  c.LocalAddress,
-		Datastore:  ds,
+		Datastore:  c.Datastore,
 		Logger:     c.Logger,
+
+		FederationAddress:   c.FederationAddress,
+		FederationCertFile:  c.FederationCertFile,
+		FederationKeyFile:   c.FederationKeyFile,
+		FederationPublicURL: c.FederationPublicURL,
+
+		PeeringAddress:   c.PeeringAddress,
+		PeeringTLSConfig: c.PeeringTLSConfig,
+
+		BundlePolicy:  bundlePolicy,
+		BundleMonitor: bundleMonitor,
+
+		RelationshipRules: c.RelationshipRules,
+
+		Changes: c.Changes,
+
+		MetricsAddress: c.MetricsAddress,
+
+		federationCache: newFederationCache(c.Datastore, c.RelationshipRules, c.Logger, federationCacheTTL),
+		watchInterval:   watch.DefaultPollInterval,
 	}, nil
 }
 
 func (e *Endpoints) ListenAndServe(ctx context.Context) error {
-	err := util.RunTasks(ctx,
+	tasks := []util.RunnableTask{
 		e.runTCPServer,
 		e.runUDSServer,
-	)
+	}
+	if e.FederationAddress != nil {
+		tasks = append(tasks, e.runFederationServer)
+	}
+	if e.PeeringAddress != nil {
+		tasks = append(tasks, e.runPeeringServer)
+	}
+	if e.MetricsAddress != nil {
+		tasks = append(tasks, e.runMetricsServer)
+	}
+	if e.Changes != nil {
+		tasks = append(tasks, e.invalidateOnChanges)
+	}
+
+	err := util.RunTasks(ctx, tasks...)
 	if err != nil {
 		return err
 	}
@@ -120,10 +199,40 @@ func (e *Endpoints) runUDSServer(ctx context.Context) error {
 
 func (e *Endpoints) addHandlers() {
 	http.HandleFunc("/createTrustDomain", e.createTrustDomainHandler)
+	http.HandleFunc("/updateTrustDomain", e.updateTrustDomainHandler)
 	http.HandleFunc("/listTrustDomains", e.listTrustDomainsHandler)
 	http.HandleFunc("/createRelationship", e.createRelationshipHandler)
 	http.HandleFunc("/listRelationships", e.listRelationshipsHandler)
+	http.HandleFunc("/renewRelationship", e.renewRelationshipHandler)
+	http.HandleFunc("/createFederationGroup", e.createFederationGroupHandler)
+	http.HandleFunc("/listFederationGroups", e.listFederationGroupsHandler)
+	http.HandleFunc("/deleteFederationGroup", e.deleteFederationGroupHandler)
+	http.HandleFunc("/addFederationGroupMember", e.addFederationGroupMemberHandler)
+	http.HandleFunc("/removeFederationGroupMember", e.removeFederationGroupMemberHandler)
+	http.HandleFunc("/createFederationPolicy", e.createFederationPolicyHandler)
+	http.HandleFunc("/listFederationPolicies", e.listFederationPoliciesHandler)
+	http.HandleFunc("/deleteFederationPolicy", e.deleteFederationPolicyHandler)
+	http.HandleFunc("/testRelationship", e.testRelationshipHandler)
+	http.HandleFunc("/createPeer", e.createPeerHandler)
+	http.HandleFunc("/listPeers", e.listPeersHandler)
+	http.HandleFunc("/deletePeer", e.deletePeerHandler)
+	http.HandleFunc("/sharePeerTrustDomain", e.sharePeerTrustDomainHandler)
+	http.HandleFunc("/unsharePeerTrustDomain", e.unsharePeerTrustDomainHandler)
+	http.HandleFunc("/listBundleRejections", e.listBundleRejectionsHandler)
+	http.HandleFunc("/listQuarantinedBundles", e.listQuarantinedBundlesHandler)
+	http.HandleFunc("/approveBundle", e.approveBundleHandler)
+	http.HandleFunc("/rejectBundle", e.rejectBundleHandler)
+	http.HandleFunc("/createWebhook", e.createWebhookHandler)
+	http.HandleFunc("/listWebhooks", e.listWebhooksHandler)
+	http.HandleFunc("/deleteWebhook", e.deleteWebhookHandler)
+	http.HandleFunc("/listDeadWebhookDeliveries", e.listDeadWebhookDeliveriesHandler)
+	http.HandleFunc("/redeliverWebhookDelivery", e.redeliverWebhookDeliveryHandler)
+	http.HandleFunc("/listJobs", e.listJobsHandler)
+	http.HandleFunc("/watch", e.watchHandler)
 	http.HandleFunc("/generateToken", e.generateTokenHandler)
+	http.HandleFunc("/export", e.exportHandler)
+	http.HandleFunc("/import", e.importHandler)
+	http.HandleFunc("/apply", e.applyHandler)
 }
 
 func (e *Endpoints) addTCPHandlers(server *echo.Echo) {
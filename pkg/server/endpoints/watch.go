@@ -0,0 +1,156 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/watch"
+)
+
+// watchHeartbeatInterval is how often a comment is sent to idle watches, so proxies and clients do not drop them.
+const watchHeartbeatInterval = 15 * time.Second
+
+// watchHandler streams the changes of the watched resources as server-sent events, each with the resource version
+// of the change as its ID. The watch starts after the version given by the Last-Event-ID header, which is sent by
+// reconnecting event sources, or by the resourceVersion query parameter, and from the current version otherwise.
+// Only the kinds given by the comma separated kinds query parameter are sent, or all of them if it is not set.
+func (e *Endpoints) watchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		e.handleError(w, "streaming is not supported")
+		return
+	}
+
+	from, err := e.watchFrom(ctx, r)
+	if err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var kinds []string
+	if k := r.URL.Query().Get("kinds"); k != "" {
+		kinds = strings.Split(k, ",")
+	}
+	if err := watch.ValidateKinds(kinds); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the watch is woken by the changes committed by every replica, and only polls as a fallback
+	var wake <-chan struct{}
+	if e.Changes != nil {
+		changed, unsubscribe := e.Changes.Subscribe()
+		defer unsubscribe()
+		wake = changed
+	}
+
+	started := false
+	lastWrite := time.Now()
+	err = watch.Watch(ctx, e.Datastore, from, e.watchInterval, wake, func(events []*entity.WatchEvent) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		} else if len(events) == 0 && time.Since(lastWrite) < watchHeartbeatInterval {
+			return nil
+		}
+
+		if err := writeEvents(w, events, kinds); err != nil {
+			return err
+		}
+
+		flusher.Flush()
+		lastWrite = time.Now()
+		return nil
+	})
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+	case started:
+		// the status was already sent, so the client only sees the stream ending
+		e.Logger.WithError(err).Warn("Watch stopped")
+	case errors.Is(err, watch.ErrVersionExpired):
+		e.handleErrorWithStatus(w, http.StatusGone, err.Error())
+	case errors.Is(err, watch.ErrInvalidVersion):
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+	default:
+		e.handleError(w, fmt.Sprintf("failed watching: %v", err))
+	}
+}
+
+// watchFrom returns the resource version the watch starts after.
+func (e *Endpoints) watchFrom(ctx context.Context, r *http.Request) (int64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("resourceVersion")
+	}
+	if v == "" {
+		return e.Datastore.FindResourceVersion(ctx)
+	}
+
+	from, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource version %q", v)
+	}
+	return from, nil
+}
+
+// setResourceVersion sets the resource version of a list, which is read before listing so a watch starting from
+// it misses no change. Changes made while listing may be sent again by the watch.
+func (e *Endpoints) setResourceVersion(ctx context.Context, w http.ResponseWriter) bool {
+	version, err := e.Datastore.FindResourceVersion(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed getting resource version: %v", err)
+		e.handleError(w, errMsg)
+		return false
+	}
+
+	w.Header().Set(common.ResourceVersionHeader, strconv.FormatInt(version, 10))
+	return true
+}
+
+// writeEvents writes the events of the given kinds, or a comment if there are none so the connection is kept
+// alive.
+func writeEvents(w http.ResponseWriter, events []*entity.WatchEvent, kinds []string) error {
+	written := false
+	for _, we := range events {
+		if len(kinds) > 0 && !contains(kinds, we.Kind) {
+			continue
+		}
+
+		data, err := json.Marshal(we)
+		if err != nil {
+			return fmt.Errorf("failed marshalling watch event: %w", err)
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", we.Version, data); err != nil {
+			return err
+		}
+		written = true
+	}
+
+	if !written {
+		_, err := fmt.Fprint(w, ": heartbeat\n\n")
+		return err
+	}
+
+	return nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
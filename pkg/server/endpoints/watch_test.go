@@ -0,0 +1,130 @@
+package endpoints
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/watch"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := watch.NewDatastore(memory)
+	e := &Endpoints{
+		Datastore:     ds,
+		Logger:        logrus.New(),
+		BundleMonitor: bundles.NewMonitor(&bundles.MonitorConfig{Datastore: ds, Logger: logrus.New()}),
+		watchInterval: 10 * time.Millisecond,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/watch", e.watchHandler)
+	mux.HandleFunc("/listTrustDomains", e.listTrustDomainsHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+
+	// lists report the resource version to start watching from
+	resp, err := http.Get(server.URL + "/listTrustDomains")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "1", resp.Header.Get(common.ResourceVersionHeader))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch?resourceVersion=0&kinds=trust_domain", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan *entity.WatchEvent)
+	go readEvents(t, resp, events)
+
+	// changes are streamed from the requested version, filtered by kind
+	first := <-events
+	assert.Equal(t, int64(1), first.Version)
+	assert.Equal(t, watch.KindTrustDomain, first.Kind)
+	assert.Equal(t, watch.Created, first.Type)
+
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+	tdB.Description = "B"
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, tdB)
+	require.NoError(t, err)
+
+	second := <-events
+	assert.Equal(t, int64(2), second.Version)
+	third := <-events
+	assert.Equal(t, int64(4), third.Version)
+	assert.Equal(t, watch.Updated, third.Type)
+
+	var td entity.TrustDomain
+	require.NoError(t, json.Unmarshal(third.Object, &td))
+	assert.Equal(t, "B", td.Description)
+
+	// watches are resumed after the last event ID, and cannot be resumed once the events are pruned
+	assert.Equal(t, http.StatusOK, watchStatus(t, server.URL+"/watch", "3"))
+	assert.Equal(t, http.StatusBadRequest, watchStatus(t, server.URL+"/watch", "5"))
+	assert.Equal(t, http.StatusBadRequest, watchStatus(t, server.URL+"/watch?kinds=foo", ""))
+
+	require.NoError(t, memory.DeleteWatchEventsBefore(ctx, time.Now().Add(time.Second)))
+	assert.Equal(t, http.StatusGone, watchStatus(t, server.URL+"/watch", "3"))
+	assert.Equal(t, http.StatusOK, watchStatus(t, server.URL+"/watch", "4"))
+}
+
+// readEvents sends the events of the stream to the channel.
+func readEvents(t *testing.T, resp *http.Response, events chan<- *entity.WatchEvent) {
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var e entity.WatchEvent
+		if !assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)) {
+			return
+		}
+		events <- &e
+	}
+}
+
+// watchStatus returns the status of a watch resumed after the given event ID.
+func watchStatus(t *testing.T, url, lastEventID string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	return resp.StatusCode
+}
@@ -0,0 +1,146 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/webhooks"
+)
+
+func (e *Endpoints) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.Webhook
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := webhooks.ValidateEventTypes(req.EventTypes); err != nil {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	webhook, err := webhooks.Register(ctx, e.Datastore, req.URL, req.EventTypes)
+	if err != nil {
+		e.handleDatastoreError(w, fmt.Errorf("failed creating webhook: %w", err))
+		return
+	}
+
+	e.Logger.Infof("Webhook %s created for %v events", webhook.URL, webhook.EventTypes)
+
+	// the secret is only returned on creation
+	e.writeResponse(w, webhook)
+}
+
+func (e *Endpoints) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	list, err := e.Datastore.ListWebhooks(ctx)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing webhooks: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	for _, webhook := range list {
+		webhook.Secret = ""
+	}
+
+	e.writeResponse(w, list)
+}
+
+func (e *Endpoints) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.Webhook
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+	if !req.ID.Valid {
+		e.handleErrorWithStatus(w, http.StatusBadRequest, "webhook ID is required")
+		return
+	}
+
+	if err := e.Datastore.DeleteWebhook(ctx, req.ID.UUID); err != nil {
+		errMsg := fmt.Sprintf("failed deleting webhook: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.Logger.Infof("Webhook %s deleted", req.ID.UUID)
+}
+
+func (e *Endpoints) listDeadWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deliveries, err := e.Datastore.ListWebhookDeliveries(ctx, true)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed listing dead webhook deliveries: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.writeResponse(w, deliveries)
+}
+
+func (e *Endpoints) redeliverWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req entity.WebhookDelivery
+	if !e.readRequest(w, r, &req) {
+		return
+	}
+
+	err := webhooks.Redeliver(ctx, e.Datastore, req.ID.UUID)
+	switch {
+	case errors.Is(err, webhooks.ErrNotDead):
+		e.handleErrorWithStatus(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		errMsg := fmt.Sprintf("failed redelivering webhook delivery: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	e.Logger.Infof("Webhook delivery %s scheduled for redelivery", req.ID.UUID)
+}
+
+// readRequest unmarshals the body of the request into v, and returns whether it succeeded.
+func (e *Endpoints) readRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed reading request body: %v", err)
+		e.handleError(w, errMsg)
+		return false
+	}
+
+	if err = json.Unmarshal(body, v); err != nil {
+		errMsg := fmt.Sprintf("failed unmarshalling request: %v", err)
+		e.handleErrorWithStatus(w, http.StatusBadRequest, errMsg)
+		return false
+	}
+
+	return true
+}
+
+func (e *Endpoints) writeResponse(w http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed marshalling response: %v", err)
+		e.handleError(w, errMsg)
+		return
+	}
+
+	if _, err = w.Write(b); err != nil {
+		errMsg := fmt.Sprintf("failed writing response: %v", err)
+		e.handleError(w, errMsg)
+	}
+}
@@ -0,0 +1,140 @@
+// Package jobs runs the maintenance jobs of the server on their schedule, and records the status of their last run
+// in the datastore, where the management API lists them from.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a maintenance job run on a schedule.
+type Job struct {
+	Name string
+
+	// Schedule is how long the runner waits after a run of the job before running it again.
+	Schedule time.Duration
+
+	// Run runs the job at the given time, and returns a summary of what it did.
+	Run func(ctx context.Context, now time.Time) (string, error)
+}
+
+// RunnerConfig conveys the configuration of a Runner.
+type RunnerConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	Jobs []*Job
+}
+
+// Runner runs the jobs on their schedule, and records the status of each run.
+type Runner struct {
+	ds     datastore.Datastore
+	logger logrus.FieldLogger
+	jobs   []*Job
+}
+
+func NewRunner(c *RunnerConfig) *Runner {
+	return &Runner{
+		ds:     c.Datastore,
+		logger: c.Logger,
+		jobs:   c.Jobs,
+	}
+}
+
+// Run runs the jobs until the context is canceled. The runs are scheduled after the last run of each job, which
+// might have been run by another replica of the server, so the jobs are not run more often when the leader changes.
+// A failing run is logged and recorded in the status of its job, and the job is run again on its schedule.
+func (r *Runner) Run(ctx context.Context) error {
+	statuses, err := r.ds.ListJobStatuses(ctx)
+	if err != nil {
+		r.logger.WithError(err).Warn("Failed to list job statuses, running every job now")
+	}
+
+	lastRuns := make(map[string]*entity.JobStatus, len(statuses))
+	for _, js := range statuses {
+		lastRuns[js.Name] = js
+	}
+
+	tasks := make([]util.RunnableTask, len(r.jobs))
+	for i, job := range r.jobs {
+		job := job
+		next := firstRun(job, lastRuns[job.Name], time.Now())
+		tasks[i] = func(ctx context.Context) error {
+			r.schedule(ctx, job, next)
+			return nil
+		}
+	}
+
+	err = util.RunTasks(ctx, tasks...)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// schedule runs the job at the given time and then on its schedule, until the context is canceled.
+func (r *Runner) schedule(ctx context.Context, job *Job, next time.Time) {
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		next = r.run(ctx, job)
+	}
+}
+
+// run runs the job once and records its status, unless the run was interrupted by the context being canceled.
+// It returns when the job is run next.
+func (r *Runner) run(ctx context.Context, job *Job) time.Time {
+	startedAt := time.Now()
+	result, err := job.Run(ctx, startedAt)
+	finishedAt := time.Now()
+	nextRunAt := finishedAt.Add(job.Schedule)
+	if ctx.Err() != nil {
+		return nextRunAt
+	}
+
+	logger := r.logger.WithField("job", job.Name)
+	status := &entity.JobStatus{
+		Name:       job.Name,
+		Schedule:   job.Schedule.String(),
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		NextRunAt:  nextRunAt,
+		Result:     result,
+	}
+	if err != nil {
+		logger.WithError(err).Error("Job failed")
+		status.Error = err.Error()
+	} else {
+		logger.Debugf("Job finished: %s", result)
+	}
+
+	if _, err := r.ds.RecordJobStatus(ctx, status); err != nil && ctx.Err() == nil {
+		logger.WithError(err).Warn("Failed to record job status")
+	}
+
+	return nextRunAt
+}
+
+// firstRun returns when the job is first run, which is when its last run scheduled it, if it did, but no later
+// than its current schedule from now, as the schedule might have been shortened since.
+func firstRun(job *Job, lastRun *entity.JobStatus, now time.Time) time.Time {
+	if lastRun == nil || lastRun.NextRunAt.Before(now) {
+		return now
+	}
+	if latest := now.Add(job.Schedule); lastRun.NextRunAt.After(latest) {
+		return latest
+	}
+	return lastRun.NextRunAt
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchedule = 10 * time.Millisecond
+
+func TestRunner(t *testing.T) {
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	var runs, failures atomic.Int32
+	runner := NewRunner(&RunnerConfig{
+		Datastore: ds,
+		Logger:    logrus.New(),
+		Jobs: []*Job{
+			{
+				Name:     "succeeding",
+				Schedule: testSchedule,
+				Run: func(ctx context.Context, now time.Time) (string, error) {
+					runs.Add(1)
+					return "done", nil
+				},
+			},
+			{
+				Name:     "failing",
+				Schedule: testSchedule,
+				Run: func(ctx context.Context, now time.Time) (string, error) {
+					failures.Add(1)
+					return "", errors.New("oops")
+				},
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runner.Run(ctx)
+	}()
+
+	// a failing job does not stop the runner, and is run again on its schedule
+	require.Eventually(t, func() bool {
+		return runs.Load() > 2 && failures.Load() > 2
+	}, time.Second, testSchedule)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	statuses, err := ds.ListJobStatuses(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+
+	assert.Equal(t, "failing", statuses[0].Name)
+	assert.Equal(t, "10ms", statuses[0].Schedule)
+	assert.Empty(t, statuses[0].Result)
+	assert.Equal(t, "oops", statuses[0].Error)
+
+	assert.Equal(t, "succeeding", statuses[1].Name)
+	assert.Equal(t, "done", statuses[1].Result)
+	assert.Empty(t, statuses[1].Error)
+	assert.False(t, statuses[1].FinishedAt.Before(statuses[1].StartedAt))
+	assert.True(t, statuses[1].FinishedAt.Add(testSchedule).Equal(statuses[1].NextRunAt))
+}
+
+func TestFirstRun(t *testing.T) {
+	now := time.Now()
+	job := &Job{Name: "job", Schedule: time.Hour}
+
+	// a job which never ran, or whose run is overdue, is run now
+	assert.Equal(t, now, firstRun(job, nil, now))
+	assert.Equal(t, now, firstRun(job, &entity.JobStatus{NextRunAt: now.Add(-time.Minute)}, now))
+
+	// a job is run when its last run scheduled it, even if it was run by another replica
+	assert.Equal(t, now.Add(time.Minute), firstRun(job, &entity.JobStatus{NextRunAt: now.Add(time.Minute)}, now))
+
+	// but no later than its current schedule
+	assert.Equal(t, now.Add(time.Hour), firstRun(job, &entity.JobStatus{NextRunAt: now.Add(24 * time.Hour)}, now))
+}
@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/relationships"
+	"github.com/HewlettPackard/galadriel/pkg/server/watch"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// The maintenance jobs of the server.
+const (
+	// ExpireRelationships records the expiry of the relationships whose validity ended.
+	ExpireRelationships = "expire_relationships"
+
+	// PruneWatchEvents deletes the watch events older than the retention, after which a watch can no longer
+	// be resumed.
+	PruneWatchEvents = "prune_watch_events"
+
+	// PruneJoinTokens deletes the join tokens which expired without being used, and the used ones not used within
+	// the retention. The harvesters keep authenticating with the token they onboarded with, so a harvester whose
+	// token is deleted must be onboarded again.
+	PruneJoinTokens = "prune_join_tokens"
+
+	// PruneBundles deletes the bundles not updated within the retention whose X.509 authorities all expired, as
+	// they can no longer authenticate anything.
+	PruneBundles = "prune_bundles"
+
+	// PruneBundleRejections deletes the records of the rejected bundles older than the retention.
+	PruneBundleRejections = "prune_bundle_rejections"
+)
+
+// Settings configure a maintenance job.
+type Settings struct {
+	// Schedule is how long the job waits after a run before running again.
+	Schedule time.Duration
+
+	// Retention is how long the records pruned by the job are kept. It is zero for the jobs not pruning records
+	// by age.
+	Retention time.Duration
+}
+
+// defaultSettings holds the settings of each maintenance job, unless configured otherwise.
+var defaultSettings = map[string]Settings{
+	ExpireRelationships:   {Schedule: 30 * time.Second},
+	PruneWatchEvents:      {Schedule: 5 * time.Minute, Retention: watch.DefaultRetention},
+	PruneJoinTokens:       {Schedule: time.Hour, Retention: 30 * 24 * time.Hour},
+	PruneBundles:          {Schedule: time.Hour, Retention: 30 * 24 * time.Hour},
+	PruneBundleRejections: {Schedule: time.Hour, Retention: 30 * 24 * time.Hour},
+}
+
+// Names returns the names of the maintenance jobs, sorted.
+func Names() []string {
+	names := make([]string, 0, len(defaultSettings))
+	for name := range defaultSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateSettings returns an error if the settings cannot configure the named maintenance job. Zero durations
+// keep the default settings of the job.
+func ValidateSettings(name string, s Settings) error {
+	defaults, ok := defaultSettings[name]
+	if !ok {
+		return fmt.Errorf("unknown job %q, expected one of: %s", name, strings.Join(Names(), ", "))
+	}
+	if s.Schedule < 0 {
+		return fmt.Errorf("schedule of job %q must be positive", name)
+	}
+	if s.Retention < 0 {
+		return fmt.Errorf("retention of job %q must be positive", name)
+	}
+	if s.Retention != 0 && defaults.Retention == 0 {
+		return fmt.Errorf("job %q does not prune records by age, it has no retention", name)
+	}
+	// the use of a join token is only recorded once per interval, so a shorter retention would delete tokens in use
+	if name == PruneJoinTokens && s.Retention != 0 && s.Retention <= entity.JoinTokenUseInterval {
+		return fmt.Errorf("retention of job %q must be longer than %s", name, entity.JoinTokenUseInterval)
+	}
+	return nil
+}
+
+// MaintenanceConfig conveys the configuration of the maintenance jobs.
+type MaintenanceConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	// Settings override the default settings of the jobs, by job name. Zero durations keep the defaults.
+	Settings map[string]Settings
+}
+
+// MaintenanceJobs returns the maintenance jobs of the server, sorted by name.
+func MaintenanceJobs(c *MaintenanceConfig) []*Job {
+	m := &maintenance{
+		ds: c.Datastore,
+		expirer: relationships.NewExpirer(&relationships.ExpirerConfig{
+			Datastore: c.Datastore,
+			Logger:    c.Logger,
+		}),
+		settings: make(map[string]Settings, len(defaultSettings)),
+	}
+
+	runs := map[string]func(ctx context.Context, now time.Time) (string, error){
+		ExpireRelationships:   m.expireRelationships,
+		PruneWatchEvents:      m.pruneWatchEvents,
+		PruneJoinTokens:       m.pruneJoinTokens,
+		PruneBundles:          m.pruneBundles,
+		PruneBundleRejections: m.pruneBundleRejections,
+	}
+
+	var jobs []*Job
+	for _, name := range Names() {
+		m.settings[name] = settings(name, c.Settings[name])
+		jobs = append(jobs, &Job{
+			Name:     name,
+			Schedule: m.settings[name].Schedule,
+			Run:      runs[name],
+		})
+	}
+
+	return jobs
+}
+
+// settings returns the given settings of the named job, with its defaults in place of the zero durations.
+func settings(name string, s Settings) Settings {
+	defaults := defaultSettings[name]
+	if s.Schedule == 0 {
+		s.Schedule = defaults.Schedule
+	}
+	if s.Retention == 0 {
+		s.Retention = defaults.Retention
+	}
+	return s
+}
+
+type maintenance struct {
+	ds       datastore.Datastore
+	expirer  *relationships.Expirer
+	settings map[string]Settings
+}
+
+func (m *maintenance) expireRelationships(ctx context.Context, now time.Time) (string, error) {
+	count, err := m.expirer.ExpireDue(ctx, now)
+	return fmt.Sprintf("expired %d relationships", count), err
+}
+
+func (m *maintenance) pruneWatchEvents(ctx context.Context, now time.Time) (string, error) {
+	before := now.Add(-m.settings[PruneWatchEvents].Retention)
+	if err := m.ds.DeleteWatchEventsBefore(ctx, before); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted the watch events created before %s", before.UTC().Format(time.RFC3339)), nil
+}
+
+// pruneJoinTokens deletes the tokens one at a time, so their deletion is recorded for the watches like any other.
+// The last use of a used token is its update time.
+func (m *maintenance) pruneJoinTokens(ctx context.Context, now time.Time) (string, error) {
+	tokens, err := m.ds.ListJoinTokens(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list join tokens: %w", err)
+	}
+
+	lastUse := now.Add(-m.settings[PruneJoinTokens].Retention)
+	count := 0
+	for _, jt := range tokens {
+		if jt.Used && !jt.UpdatedAt.Before(lastUse) || !jt.Used && now.Before(jt.ExpiresAt) {
+			continue
+		}
+		if err := m.ds.DeleteJoinToken(ctx, jt.ID.UUID); err != nil {
+			return fmt.Sprintf("deleted %d join tokens", count), fmt.Errorf("failed to delete join token %q: %w", jt.ID.UUID, err)
+		}
+		count++
+	}
+
+	return fmt.Sprintf("deleted %d join tokens", count), nil
+}
+
+// pruneBundles deletes the bundles one at a time, so their deletion is recorded for the watches like any other.
+// The bundles which cannot be parsed, or have no X.509 authorities, are kept.
+func (m *maintenance) pruneBundles(ctx context.Context, now time.Time) (string, error) {
+	trustDomains, err := m.ds.ListTrustDomains(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list trust domains: %w", err)
+	}
+	names := make(map[uuid.UUID]spiffeid.TrustDomain, len(trustDomains))
+	for _, td := range trustDomains {
+		names[td.ID.UUID] = td.Name
+	}
+
+	bundles, err := m.ds.ListBundles(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list bundles: %w", err)
+	}
+
+	before := now.Add(-m.settings[PruneBundles].Retention)
+	count := 0
+	for _, b := range bundles {
+		if !b.UpdatedAt.Before(before) || !expired(names[b.TrustDomainID], b, now) {
+			continue
+		}
+		if err := m.ds.DeleteBundle(ctx, b.ID.UUID); err != nil {
+			return fmt.Sprintf("deleted %d bundles", count), fmt.Errorf("failed to delete bundle of trust domain %q: %w", names[b.TrustDomainID], err)
+		}
+		count++
+	}
+
+	return fmt.Sprintf("deleted %d bundles", count), nil
+}
+
+// expired returns whether every X.509 authority of the bundle expired at the given time.
+func expired(td spiffeid.TrustDomain, b *entity.Bundle, now time.Time) bool {
+	bundle, err := spiffebundle.Parse(td, b.Data)
+	if err != nil {
+		return false
+	}
+
+	authorities := bundle.X509Authorities()
+	for _, cert := range authorities {
+		if now.Before(cert.NotAfter) {
+			return false
+		}
+	}
+	return len(authorities) > 0
+}
+
+func (m *maintenance) pruneBundleRejections(ctx context.Context, now time.Time) (string, error) {
+	before := now.Add(-m.settings[PruneBundleRejections].Retention)
+	if err := m.ds.DeleteBundleRejectionsBefore(ctx, before); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted the bundle rejections created before %s", before.UTC().Format(time.RFC3339)), nil
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceJobs(t *testing.T) {
+	jobs := MaintenanceJobs(&MaintenanceConfig{
+		Datastore: datastore.NewMemoryDatastore(logrus.New()),
+		Logger:    logrus.New(),
+		Settings: map[string]Settings{
+			PruneWatchEvents: {Schedule: time.Minute},
+		},
+	})
+
+	schedules := make(map[string]time.Duration)
+	for _, job := range jobs {
+		schedules[job.Name] = job.Schedule
+	}
+	assert.Equal(t, map[string]time.Duration{
+		ExpireRelationships:   30 * time.Second,
+		PruneBundleRejections: time.Hour,
+		PruneBundles:          time.Hour,
+		PruneJoinTokens:       time.Hour,
+		PruneWatchEvents:      time.Minute,
+	}, schedules)
+}
+
+func TestPruneJoinTokens(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	m := &maintenance{ds: ds, settings: map[string]Settings{PruneJoinTokens: {Retention: 24 * time.Hour}}}
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+
+	now := time.Now()
+	createToken := func(token string, expiresAt time.Time) *entity.JoinToken {
+		jt, err := ds.CreateJoinToken(ctx, &entity.JoinToken{Token: token, TrustDomainID: td.ID.UUID, ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		return jt
+	}
+	createToken("expired", now.Add(-time.Minute))
+	used := createToken("used", now.Add(-time.Minute))
+	valid := createToken("valid", now.Add(time.Hour))
+
+	_, err = ds.UpdateJoinToken(ctx, used.ID.UUID, true)
+	require.NoError(t, err)
+
+	// the expired token which was never used is deleted, the harvesters authenticate with the used ones
+	result, err := m.pruneJoinTokens(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, "deleted 1 join tokens", result)
+
+	tokens, err := ds.ListJoinTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.ElementsMatch(t, []string{used.Token, valid.Token}, []string{tokens[0].Token, tokens[1].Token})
+
+	// the used token is deleted once it was not used within the retention, along with the valid token once expired
+	result, err = m.pruneJoinTokens(ctx, now.Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "deleted 2 join tokens", result)
+
+	tokens, err = ds.ListJoinTokens(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestPruneBundles(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	m := &maintenance{ds: ds, settings: map[string]Settings{PruneBundles: {Retention: 24 * time.Hour}}}
+
+	now := time.Now()
+	createBundle := func(name string, notAfter time.Time) {
+		td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString(name)})
+		require.NoError(t, err)
+
+		data := certtest.MarshalBundle(t, certtest.CreateBundle(t, td.Name, certtest.WithNotAfter(notAfter)))
+		_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{TrustDomainID: td.ID.UUID, Data: data, Digest: []byte(name)})
+		require.NoError(t, err)
+	}
+	createBundle("expired.test", now.Add(time.Hour))
+	createBundle("valid.test", now.Add(72*time.Hour))
+
+	// the bundles are kept for the retention, even when expired
+	result, err := m.pruneBundles(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "deleted 0 bundles", result)
+
+	// the bundle not updated within the retention whose authorities expired is deleted
+	result, err = m.pruneBundles(ctx, now.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "deleted 1 bundles", result)
+
+	bundles, err := ds.ListBundles(ctx)
+	require.NoError(t, err)
+	require.Len(t, bundles, 1)
+	assert.Equal(t, []byte("valid.test"), bundles[0].Digest)
+}
+
+func TestPruneBundleRejections(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+	m := &maintenance{ds: ds, settings: map[string]Settings{PruneBundleRejections: {Retention: 24 * time.Hour}}}
+
+	td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	_, err = ds.CreateBundleRejection(ctx, &entity.BundleRejection{TrustDomainID: td.ID.UUID, Digest: []byte{1}})
+	require.NoError(t, err)
+
+	// the rejections are kept for the retention
+	_, err = m.pruneBundleRejections(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	rejections, err := ds.ListBundleRejections(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rejections, 1)
+
+	_, err = m.pruneBundleRejections(ctx, time.Now().Add(25*time.Hour))
+	require.NoError(t, err)
+	rejections, err = ds.ListBundleRejections(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rejections)
+}
+
+func TestValidateSettings(t *testing.T) {
+	assert.NoError(t, ValidateSettings(PruneWatchEvents, Settings{}))
+	assert.NoError(t, ValidateSettings(PruneWatchEvents, Settings{Schedule: time.Minute, Retention: 2 * time.Hour}))
+	assert.EqualError(t, ValidateSettings("prune_everything", Settings{}), `unknown job "prune_everything", expected one of: expire_relationships, prune_bundle_rejections, prune_bundles, prune_join_tokens, prune_watch_events`)
+	assert.EqualError(t, ValidateSettings(ExpireRelationships, Settings{Retention: time.Hour}), `job "expire_relationships" does not prune records by age, it has no retention`)
+	assert.EqualError(t, ValidateSettings(PruneJoinTokens, Settings{Retention: time.Hour}), `retention of job "prune_join_tokens" must be longer than 1h0m0s`)
+	assert.EqualError(t, ValidateSettings(ExpireRelationships, Settings{Schedule: -time.Second}), `schedule of job "expire_relationships" must be positive`)
+}
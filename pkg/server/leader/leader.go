@@ -0,0 +1,129 @@
+// Package leader elects, among the replicas of the server sharing a datastore, the one running the singleton
+// tasks, such as the bundle fetcher or the webhook dispatcher, which must not run on several replicas at once.
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is how often a replica campaigns for leadership, and how often the leader checks that it still
+// holds the leader lock.
+const DefaultInterval = 5 * time.Second
+
+// lockKey identifies the advisory lock held by the leader.
+const lockKey int64 = 0x47616c6164726965
+
+// releaseTimeout bounds how long releasing the leader lock can take once the tasks stopped.
+const releaseTimeout = 5 * time.Second
+
+// Config conveys the configuration of an Elector.
+type Config struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	// Tasks are run by the leader only.
+	Tasks []util.RunnableTask
+
+	// Interval is how often the replica campaigns for leadership. If not set, DefaultInterval is used.
+	Interval time.Duration
+}
+
+// Elector campaigns for leadership by acquiring the leader lock, which is held until the replica stops or loses
+// its connection to the datastore, and runs the tasks while it leads.
+type Elector struct {
+	ds       datastore.Datastore
+	logger   logrus.FieldLogger
+	tasks    []util.RunnableTask
+	interval time.Duration
+}
+
+func New(c *Config) *Elector {
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	return &Elector{
+		ds:       c.Datastore,
+		logger:   c.Logger,
+		tasks:    c.Tasks,
+		interval: interval,
+	}
+}
+
+// Run campaigns for leadership until the context is canceled, running the tasks whenever the replica leads.
+// It returns the error of a task failing, as they would if they ran on every replica.
+func (e *Elector) Run(ctx context.Context) error {
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+
+	for {
+		lock, err := e.ds.TryLock(ctx, lockKey)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			e.logger.WithError(err).Error("Failed to campaign for leadership")
+		case lock != nil:
+			if err := e.lead(ctx, lock); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// lead runs the tasks until the context is canceled or the lock is lost, and releases the lock once they stopped.
+func (e *Elector) lead(ctx context.Context, lock datastore.Lock) error {
+	e.logger.Info("Elected leader, starting the singleton tasks")
+	defer e.release(lock)
+
+	tasksCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- util.RunTasks(tasksCtx, e.tasks...)
+	}()
+
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case err := <-errCh:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			cancel()
+			<-errCh
+			return nil
+		case <-t.C:
+			if err := lock.Check(ctx); err != nil && ctx.Err() == nil {
+				e.logger.WithError(err).Warn("Lost leadership, stopping the singleton tasks")
+				cancel()
+				<-errCh
+				return nil
+			}
+		}
+	}
+}
+
+func (e *Elector) release(lock datastore.Lock) {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	if err := lock.Release(ctx); err != nil {
+		e.logger.WithError(err).Warn("Failed to release the leader lock")
+	}
+}
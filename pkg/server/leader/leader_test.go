@@ -0,0 +1,81 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInterval = 10 * time.Millisecond
+
+func TestElector(t *testing.T) {
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	var running [2]atomic.Bool
+	var cancels [2]context.CancelFunc
+	var done [2]chan error
+	for i := range running {
+		i := i
+		task := func(ctx context.Context) error {
+			running[i].Store(true)
+			<-ctx.Done()
+			running[i].Store(false)
+			return nil
+		}
+		elector := New(&Config{Datastore: ds, Logger: logrus.New(), Tasks: []util.RunnableTask{task}, Interval: testInterval})
+
+		var ctx context.Context
+		ctx, cancels[i] = context.WithCancel(context.Background())
+		done[i] = make(chan error, 1)
+		go func() {
+			done[i] <- elector.Run(ctx)
+		}()
+	}
+
+	// a single replica runs the tasks
+	require.Eventually(t, func() bool {
+		return running[0].Load() || running[1].Load()
+	}, time.Second, testInterval)
+	time.Sleep(5 * testInterval)
+	require.NotEqual(t, running[0].Load(), running[1].Load())
+
+	leader, follower := 0, 1
+	if running[1].Load() {
+		leader, follower = 1, 0
+	}
+
+	// the other replica takes over once the leader stops
+	cancels[leader]()
+	require.NoError(t, <-done[leader])
+	assert.False(t, running[leader].Load())
+
+	require.Eventually(t, running[follower].Load, time.Second, testInterval)
+
+	cancels[follower]()
+	require.NoError(t, <-done[follower])
+}
+
+func TestElectorTaskFailure(t *testing.T) {
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	failing := func(ctx context.Context) error {
+		return errors.New("oops")
+	}
+	elector := New(&Config{Datastore: ds, Logger: logrus.New(), Tasks: []util.RunnableTask{failing}, Interval: testInterval})
+
+	err := elector.Run(context.Background())
+	require.EqualError(t, err, "oops")
+
+	// the lock is released, so another replica can lead
+	lock, err := ds.TryLock(context.Background(), lockKey)
+	require.NoError(t, err)
+	assert.NotNil(t, lock)
+}
@@ -0,0 +1,123 @@
+// Package peering exchanges the bundles of trust domains with peer Galadriel Servers. Peers authenticate each
+// other with mutual TLS: each server serves the bundles of the trust domains its admin shares with the calling
+// peer, and imports the trust domains the peer shares with it. An imported trust domain is federated with local
+// trust domains through relationships like any other, so an exchange takes the approval of the admins of both
+// sides.
+package peering
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// BundlesPath is the path of the peering endpoint serving the bundles shared with the calling peer.
+const BundlesPath = "/peering/bundles"
+
+// BundlesResponse lists the trust domains a server shares with a peer, along with their bundles.
+type BundlesResponse struct {
+	TrustDomains []*SharedTrustDomain `json:"trust_domains"`
+}
+
+// SharedTrustDomain is a trust domain shared with a peer. Bundle is the SPIFFE bundle of the trust domain, it is
+// empty if the trust domain has no bundle yet.
+type SharedTrustDomain struct {
+	Name   spiffeid.TrustDomain `json:"name"`
+	Bundle []byte               `json:"bundle,omitempty"`
+}
+
+// LoadTLSConfig loads the certificate the server presents to its peers and the CA certificates the certificates
+// of the peers are verified with, which are used both to serve the peering endpoint and to call the peers.
+func LoadTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peering certificate: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peering CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no CA certificates found in %s", caFile)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ValidateURL returns an error if the URL of a peering endpoint is not an https URL with a host.
+func ValidateURL(peerURL string) error {
+	u, err := url.Parse(peerURL)
+	if err != nil {
+		return fmt.Errorf("invalid peer URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return errors.New("invalid peer URL: scheme must be https")
+	}
+	if u.Hostname() == "" {
+		return errors.New("invalid peer URL: host is missing")
+	}
+
+	return nil
+}
+
+// FindPeerByCertificate returns the peer a verified client certificate belongs to, i.e., the peer whose URL host
+// the certificate is valid for, or nil if there is none.
+func FindPeerByCertificate(ctx context.Context, ds datastore.Datastore, cert *x509.Certificate) (*entity.Peer, error) {
+	peers, err := ds.ListPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range peers {
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			continue
+		}
+		if cert.VerifyHostname(u.Hostname()) == nil {
+			return p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SharedBundles returns the trust domains shared with the peer, along with their bundles.
+func SharedBundles(ctx context.Context, ds datastore.Datastore, peerID uuid.UUID) (*BundlesResponse, error) {
+	trustDomains, err := ds.ListPeerSharedTrustDomains(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &BundlesResponse{TrustDomains: make([]*SharedTrustDomain, 0, len(trustDomains))}
+	for _, td := range trustDomains {
+		shared := &SharedTrustDomain{Name: td.Name}
+
+		bundle, err := ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+		if err != nil {
+			return nil, err
+		}
+		if bundle != nil {
+			shared.Bundle = bundle.Data
+		}
+
+		resp.TrustDomains = append(resp.TrustDomains, shared)
+	}
+
+	return resp, nil
+}
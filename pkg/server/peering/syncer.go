@@ -0,0 +1,194 @@
+package peering
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+const (
+	// syncInterval is how often the bundles shared by the peers are synced.
+	syncInterval = 30 * time.Second
+
+	syncTimeout = 30 * time.Second
+
+	// maxResponseSize bounds the size of the responses of the peers.
+	maxResponseSize = 10 << 20
+)
+
+// SyncerConfig conveys the configuration of a Syncer.
+type SyncerConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	// TLSConfig authenticates the server to its peers, and the peers to the server.
+	TLSConfig *tls.Config
+
+	// Policy validates the bundles shared by the peers. If not set, the bundles are not validated.
+	Policy *bundles.Policy
+}
+
+// Syncer imports the trust domains shared by the peers and stores their bundles, the same way the bundles
+// posted by harvesters are stored.
+type Syncer struct {
+	ds     datastore.Datastore
+	logger logrus.FieldLogger
+	policy *bundles.Policy
+	client *http.Client
+}
+
+func NewSyncer(c *SyncerConfig) *Syncer {
+	return &Syncer{
+		ds:     c.Datastore,
+		logger: c.Logger,
+		policy: c.Policy,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: c.TLSConfig},
+			Timeout:   syncTimeout,
+		},
+	}
+}
+
+// Run syncs the bundles shared by the peers until the context is canceled.
+func (s *Syncer) Run(ctx context.Context) error {
+	t := time.NewTicker(syncInterval)
+	defer t.Stop()
+
+	for {
+		s.syncAll(ctx)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *Syncer) syncAll(ctx context.Context) {
+	peers, err := s.ds.ListPeers(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list peers")
+		return
+	}
+
+	for _, p := range peers {
+		if err := s.sync(ctx, p); err != nil {
+			s.logger.WithError(err).Warnf("Failed to sync bundles shared by peer %q from %s", p.Name, p.URL)
+		}
+	}
+}
+
+// sync imports the trust domains shared by the peer and stores their bundles. The bundles of the trust domains
+// the peer no longer shares are deleted, so they are no longer federated, while the trust domains are kept along
+// with their relationships in case the peer shares them again.
+func (s *Syncer) sync(ctx context.Context, peer *entity.Peer) error {
+	resp, err := s.fetch(ctx, peer)
+	if err != nil {
+		return err
+	}
+
+	trustDomains, err := s.ds.ListTrustDomains(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[spiffeid.TrustDomain]*entity.TrustDomain, len(trustDomains))
+	for _, td := range trustDomains {
+		byName[td.Name] = td
+	}
+
+	shared := make(map[spiffeid.TrustDomain]bool, len(resp.TrustDomains))
+	for _, st := range resp.TrustDomains {
+		shared[st.Name] = true
+
+		td := byName[st.Name]
+		switch {
+		case td == nil:
+			td, err = s.ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: st.Name, PeerID: peer.ID})
+			if err != nil {
+				return fmt.Errorf("failed to import trust domain %q: %w", st.Name, err)
+			}
+			s.logger.Infof("Trust domain %q imported from peer %q", st.Name, peer.Name)
+		case td.PeerID != peer.ID:
+			// a peer cannot override the trust domains of this server, nor those imported from another peer
+			s.logger.Warnf("Trust domain %q shared by peer %q not imported: it is already managed by this server or imported from another peer", st.Name, peer.Name)
+			continue
+		}
+
+		if len(st.Bundle) == 0 {
+			continue
+		}
+
+		outcome, err := bundles.Ingest(ctx, s.ds, s.policy, td, st.Bundle)
+		if err != nil {
+			s.logger.WithError(err).Warnf("Failed to store bundle of trust domain %q shared by peer %q", st.Name, peer.Name)
+			continue
+		}
+		switch outcome {
+		case bundles.Stored:
+			s.logger.Infof("Bundle of trust domain %q synced from peer %q", st.Name, peer.Name)
+		case bundles.Quarantined:
+			s.logger.Warnf("Bundle of trust domain %q synced from peer %q breaks the continuity with the stored bundle and was quarantined", st.Name, peer.Name)
+		}
+	}
+
+	for _, td := range trustDomains {
+		if td.PeerID != peer.ID || shared[td.Name] {
+			continue
+		}
+
+		bundle, err := s.ds.FindBundleByTrustDomainID(ctx, td.ID.UUID)
+		if err != nil {
+			return err
+		}
+		if bundle == nil {
+			continue
+		}
+		if err := s.ds.DeleteBundle(ctx, bundle.ID.UUID); err != nil {
+			return fmt.Errorf("failed to delete bundle of trust domain %q: %w", td.Name, err)
+		}
+		s.logger.Warnf("Trust domain %q is no longer shared by peer %q, its bundle was deleted", td.Name, peer.Name)
+	}
+
+	return nil
+}
+
+// fetch calls the peering endpoint of the peer for the trust domains it shares with this server.
+func (s *Syncer) fetch(ctx context.Context, peer *entity.Peer) (*BundlesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(peer.URL, "/")+BundlesPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer responded with status %d: %s", r.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	resp := &BundlesResponse{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return resp, nil
+}
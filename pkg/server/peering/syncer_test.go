@@ -0,0 +1,162 @@
+package peering
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/certtest"
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	td1 = spiffeid.RequireTrustDomainFromString("foo.test")
+	td2 = spiffeid.RequireTrustDomainFromString("bar.test")
+	td3 = spiffeid.RequireTrustDomainFromString("baz.test")
+)
+
+func TestSyncer(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	bundle1 := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1))
+	bundle2 := certtest.MarshalBundle(t, certtest.CreateBundle(t, td2))
+	shared := &BundlesResponse{TrustDomains: []*SharedTrustDomain{
+		{Name: td1, Bundle: bundle1},
+		{Name: td2, Bundle: bundle2},
+		{Name: td3},
+	}}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BundlesPath, r.URL.Path)
+		require.NoError(t, json.NewEncoder(w).Encode(shared))
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: server.URL})
+	require.NoError(t, err)
+
+	// the trust domains of this server are not overridden by the peer
+	local, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: td2})
+	require.NoError(t, err)
+
+	s := NewSyncer(&SyncerConfig{Datastore: ds, Logger: logrus.New(), TLSConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}})
+	require.NoError(t, s.sync(ctx, peer))
+
+	imported, err := ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	require.NotNil(t, imported)
+	assert.Equal(t, peer.ID, imported.PeerID)
+
+	stored, err := ds.FindBundleByTrustDomainID(ctx, imported.ID.UUID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, bundle1, stored.Data)
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, local.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// trust domains without a bundle are imported all the same
+	imported3, err := ds.FindTrustDomainByName(ctx, td3)
+	require.NoError(t, err)
+	require.NotNil(t, imported3)
+	assert.Equal(t, peer.ID, imported3.PeerID)
+
+	// the bundles of the trust domains the peer no longer shares are deleted, the trust domains are kept
+	shared.TrustDomains = shared.TrustDomains[1:]
+	require.NoError(t, s.sync(ctx, peer))
+
+	stored, err = ds.FindBundleByTrustDomainID(ctx, imported.ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	kept, err := ds.FindTrustDomainByName(ctx, td1)
+	require.NoError(t, err)
+	assert.NotNil(t, kept)
+}
+
+func TestSyncerPeerError(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown peer", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: server.URL})
+	require.NoError(t, err)
+
+	s := NewSyncer(&SyncerConfig{Datastore: ds, Logger: logrus.New(), TLSConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}})
+	assert.EqualError(t, s.sync(ctx, peer), "peer responded with status 403: unknown peer")
+}
+
+func TestFindPeerByCertificate(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	acme, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+	_, err = ds.CreatePeer(ctx, &entity.Peer{Name: "partner", URL: "https://galadriel.partner.test:8444"})
+	require.NoError(t, err)
+
+	peer, err := FindPeerByCertificate(ctx, ds, &x509.Certificate{DNSNames: []string{"galadriel.acme.test"}})
+	require.NoError(t, err)
+	assert.Equal(t, acme, peer)
+
+	peer, err = FindPeerByCertificate(ctx, ds, &x509.Certificate{DNSNames: []string{"galadriel.unknown.test"}})
+	require.NoError(t, err)
+	assert.Nil(t, peer)
+}
+
+func TestSharedBundles(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	peer, err := ds.CreatePeer(ctx, &entity.Peer{Name: "acme", URL: "https://galadriel.acme.test:8444"})
+	require.NoError(t, err)
+
+	bundle1 := certtest.MarshalBundle(t, certtest.CreateBundle(t, td1))
+	for _, name := range []spiffeid.TrustDomain{td1, td2, td3} {
+		td, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: name})
+		require.NoError(t, err)
+		if name == td3 {
+			// not shared with the peer
+			continue
+		}
+		require.NoError(t, ds.AddPeerSharedTrustDomain(ctx, peer.ID.UUID, td.ID.UUID))
+		if name == td1 {
+			_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{Data: bundle1, Digest: []byte("digest"), TrustDomainID: td.ID.UUID})
+			require.NoError(t, err)
+		}
+	}
+
+	resp, err := SharedBundles(ctx, ds, peer.ID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, &BundlesResponse{TrustDomains: []*SharedTrustDomain{
+		{Name: td2},
+		{Name: td1, Bundle: bundle1},
+	}}, resp)
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://galadriel.acme.test:8444"))
+	assert.EqualError(t, ValidateURL("http://galadriel.acme.test:8444"), "invalid peer URL: scheme must be https")
+	assert.EqualError(t, ValidateURL("https://:8444"), "invalid peer URL: host is missing")
+}
@@ -0,0 +1,109 @@
+// Package relationships processes the expiry of the federation relationships, and constrains which trust domains
+// can be federated with the relationship rules.
+package relationships
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// ExpirerConfig conveys the configuration of an Expirer.
+type ExpirerConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+}
+
+// Expirer records the expiry of the relationships whose validity ended, which notifies the webhooks of both of
+// their trust domains. Relationships stop being in effect at the end of their validity regardless of the Expirer,
+// and the harvesters remove the bundle of their former peer on their next sync.
+type Expirer struct {
+	ds     datastore.Datastore
+	logger logrus.FieldLogger
+}
+
+func NewExpirer(c *ExpirerConfig) *Expirer {
+	return &Expirer{
+		ds:     c.Datastore,
+		logger: c.Logger,
+	}
+}
+
+// ExpireDue expires the relationships whose validity ended at the given time, and returns how many it expired.
+// It is run by the expire_relationships maintenance job.
+func (e *Expirer) ExpireDue(ctx context.Context, now time.Time) (int, error) {
+	expired, err := e.ds.ListExpiredRelationships(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired relationships: %w", err)
+	}
+
+	count, failed := 0, 0
+	for _, r := range expired {
+		rel, err := e.expire(ctx, r.ID.UUID, now)
+		if err != nil {
+			e.logger.WithError(err).Errorf("Failed to expire relationship %q", r.ID.UUID)
+			failed++
+			continue
+		}
+		if rel != nil {
+			e.logger.Infof("Relationship between trust domains %q and %q expired", rel.TrustDomainAName, rel.TrustDomainBName)
+			count++
+		}
+	}
+
+	if failed > 0 {
+		return count, fmt.Errorf("failed to expire %d relationships", failed)
+	}
+
+	return count, nil
+}
+
+// expire records the expiry of a relationship if its validity ended at the given time, as it may have been renewed
+// or deleted since it was listed. It returns the expired relationship, or nil if it was not expired.
+func (e *Expirer) expire(ctx context.Context, relationshipID uuid.UUID, now time.Time) (*entity.Relationship, error) {
+	var rel *entity.Relationship
+	err := e.ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		r, err := tx.FindRelationshipByID(ctx, relationshipID)
+		if err != nil {
+			return err
+		}
+		if r == nil || !r.ExpiredAt.IsZero() || r.NotAfter.IsZero() || now.Before(r.NotAfter) {
+			return nil
+		}
+
+		r.ExpiredAt = now
+		r, err = tx.CreateOrUpdateRelationship(ctx, r)
+		if err != nil {
+			return err
+		}
+
+		if r.TrustDomainAName, err = trustDomainName(ctx, tx, r.TrustDomainAID); err != nil {
+			return err
+		}
+		if r.TrustDomainBName, err = trustDomainName(ctx, tx, r.TrustDomainBID); err != nil {
+			return err
+		}
+
+		rel = r
+		return nil
+	})
+
+	return rel, err
+}
+
+func trustDomainName(ctx context.Context, ds datastore.Datastore, trustDomainID uuid.UUID) (spiffeid.TrustDomain, error) {
+	td, err := ds.FindTrustDomainByID(ctx, trustDomainID)
+	if err != nil {
+		return spiffeid.TrustDomain{}, err
+	}
+	if td == nil {
+		return spiffeid.TrustDomain{}, fmt.Errorf("trust domain %q does not exist", trustDomainID)
+	}
+	return td.Name, nil
+}
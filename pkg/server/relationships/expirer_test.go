@@ -0,0 +1,76 @@
+package relationships
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/HewlettPackard/galadriel/pkg/server/webhooks"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpirer(t *testing.T) {
+	ctx := context.Background()
+	ds := webhooks.NewDatastore(datastore.NewMemoryDatastore(logrus.New()))
+	logger, hook := test.NewNullLogger()
+
+	e := NewExpirer(&ExpirerConfig{Datastore: ds, Logger: logger})
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	_, err = webhooks.Register(ctx, ds, "https://hooks.example.org", []string{string(webhooks.RelationshipExpired)})
+	require.NoError(t, err)
+
+	now := time.Now()
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{
+		TrustDomainAID: tdA.ID.UUID,
+		TrustDomainBID: tdB.ID.UUID,
+		NotAfter:       now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	count, err := e.ExpireDue(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Empty(t, hook.AllEntries())
+
+	// the expiry is recorded, logged and notified to both trust domains once
+	count, err = e.ExpireDue(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = e.ExpireDue(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, `Relationship between trust domains "a.test" and "b.test" expired`, hook.LastEntry().Message)
+
+	stored, err := ds.FindRelationshipByID(ctx, rel.ID.UUID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(2*time.Hour), stored.ExpiredAt, time.Millisecond)
+
+	deliveries, err := ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+
+	// a renewed relationship expires again at the end of its new validity
+	stored.NotAfter = now.Add(4 * time.Hour)
+	stored.ExpiredAt = time.Time{}
+	_, err = ds.CreateOrUpdateRelationship(ctx, stored)
+	require.NoError(t, err)
+
+	_, err = e.ExpireDue(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, hook.AllEntries(), 1)
+
+	_, err = e.ExpireDue(ctx, now.Add(5*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, hook.AllEntries(), 2)
+}
@@ -0,0 +1,504 @@
+package relationships
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+)
+
+// Effects of the relationship rules.
+const (
+	EffectDeny  = "deny"
+	EffectAllow = "allow"
+)
+
+// RuleSet constrains which trust domains can be federated with each other. It is evaluated when a relationship
+// is created, and on every sync for all the peers of a trust domain, whether they come from relationships,
+// federation groups or federation policies.
+//
+// A pair of trust domains is denied if any deny rule matches it. Otherwise it is allowed, unless the set has
+// allow rules and none of them matches it. A nil RuleSet allows every pair.
+type RuleSet struct {
+	version string
+	rules   []*Rule
+}
+
+// Rule is a named condition over two trust domains, e.g.:
+//
+//	a.labels.env == 'prod' && b.labels.env == 'dev'
+//
+// The trust domains are referred to as a and b, and their attributes as a.name and a.labels.<key>, a missing
+// label being an empty string. Attributes are compared to strings or to each other with == and !=, to glob
+// patterns with matches, e.g., b.name matches '*.partner.org', and to lists with in, e.g.,
+// a.labels.org in ['acme', 'initech']. Comparisons are combined with &&, || and !, and grouped with parentheses.
+//
+// Relationships are bidirectional, so a rule matches a pair of trust domains if its condition holds with
+// either of them as a.
+type Rule struct {
+	Name      string
+	Effect    string
+	Condition string
+
+	expr expr
+}
+
+// RuleConfig conveys the settings of a Rule.
+type RuleConfig struct {
+	Name string
+
+	// Effect is either deny, the default, or allow.
+	Effect string
+
+	Condition string
+}
+
+// Decision is the outcome of the evaluation of a RuleSet for a pair of trust domains.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+
+	// Rule is the name of the rule that denied or allowed the pair, if any.
+	Rule string `json:"rule,omitempty"`
+
+	// Version is the version of the rule set the pair was evaluated against.
+	Version string `json:"version,omitempty"`
+
+	Reason string `json:"reason"`
+}
+
+// NewRuleSet parses the rules of the given version of a rule set.
+func NewRuleSet(version string, configs []RuleConfig) (*RuleSet, error) {
+	if version == "" {
+		return nil, errors.New("the version of the relationship rules is required")
+	}
+
+	s := &RuleSet{version: version}
+	names := make(map[string]bool, len(configs))
+	for _, c := range configs {
+		if c.Name == "" {
+			return nil, errors.New("relationship rule name is required")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate relationship rule %q", c.Name)
+		}
+		names[c.Name] = true
+
+		effect := c.Effect
+		if effect == "" {
+			effect = EffectDeny
+		}
+		if effect != EffectDeny && effect != EffectAllow {
+			return nil, fmt.Errorf("invalid effect %q for relationship rule %q, must be %s or %s", c.Effect, c.Name, EffectDeny, EffectAllow)
+		}
+
+		e, err := parseCondition(c.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition for relationship rule %q: %w", c.Name, err)
+		}
+
+		s.rules = append(s.rules, &Rule{Name: c.Name, Effect: effect, Condition: c.Condition, expr: e})
+	}
+
+	// the rules are evaluated in a stable order, so the rule reported by a decision does not vary
+	sort.Slice(s.rules, func(i, j int) bool { return s.rules[i].Name < s.rules[j].Name })
+
+	return s, nil
+}
+
+// Version returns the version of the rule set, or an empty string for a nil RuleSet.
+func (s *RuleSet) Version() string {
+	if s == nil {
+		return ""
+	}
+	return s.version
+}
+
+// Rules returns the rules of the set.
+func (s *RuleSet) Rules() []*Rule {
+	if s == nil {
+		return nil
+	}
+	return s.rules
+}
+
+// Evaluate decides whether the trust domains can be federated with each other.
+func (s *RuleSet) Evaluate(a, b *entity.TrustDomain) *Decision {
+	if s == nil || len(s.rules) == 0 {
+		return &Decision{Allowed: true, Version: s.Version(), Reason: "no relationship rules are configured"}
+	}
+
+	var allow *Rule
+	hasAllowRules := false
+	for _, r := range s.rules {
+		if r.Effect == EffectAllow {
+			hasAllowRules = true
+		}
+		if !r.matches(a, b) {
+			continue
+		}
+
+		if r.Effect == EffectDeny {
+			return &Decision{
+				Rule:    r.Name,
+				Version: s.version,
+				Reason:  fmt.Sprintf("denied by relationship rule %q", r.Name),
+			}
+		}
+		if allow == nil {
+			allow = r
+		}
+	}
+
+	switch {
+	case allow != nil:
+		return &Decision{Allowed: true, Rule: allow.Name, Version: s.version, Reason: fmt.Sprintf("allowed by relationship rule %q", allow.Name)}
+	case hasAllowRules:
+		return &Decision{Version: s.version, Reason: "no allow relationship rule matches"}
+	default:
+		return &Decision{Allowed: true, Version: s.version, Reason: "no deny relationship rule matches"}
+	}
+}
+
+func (r *Rule) matches(a, b *entity.TrustDomain) bool {
+	return r.expr.eval(a, b) || r.expr.eval(b, a)
+}
+
+// expr is a node of a parsed condition, evaluated with the given trust domains as a and b.
+type expr interface {
+	eval(a, b *entity.TrustDomain) bool
+}
+
+type andExpr struct{ left, right expr }
+
+func (e *andExpr) eval(a, b *entity.TrustDomain) bool { return e.left.eval(a, b) && e.right.eval(a, b) }
+
+type orExpr struct{ left, right expr }
+
+func (e *orExpr) eval(a, b *entity.TrustDomain) bool { return e.left.eval(a, b) || e.right.eval(a, b) }
+
+type notExpr struct{ expr expr }
+
+func (e *notExpr) eval(a, b *entity.TrustDomain) bool { return !e.expr.eval(a, b) }
+
+type compareExpr struct {
+	op          string
+	left, right operand
+	list        []string
+}
+
+func (e *compareExpr) eval(a, b *entity.TrustDomain) bool {
+	left := e.left.value(a, b)
+	switch e.op {
+	case "==":
+		return left == e.right.value(a, b)
+	case "!=":
+		return left != e.right.value(a, b)
+	case "matches":
+		ok, _ := path.Match(e.right.value(a, b), left)
+		return ok
+	default: // in
+		for _, v := range e.list {
+			if left == v {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// operand is either a string literal or an attribute of one of the trust domains.
+type operand struct {
+	literal string
+
+	// subject is a or b for an attribute, and label the key of a label attribute, empty for the name
+	subject string
+	label   string
+}
+
+func (o operand) value(a, b *entity.TrustDomain) string {
+	td := a
+	switch o.subject {
+	case "":
+		return o.literal
+	case "b":
+		td = b
+	}
+
+	if o.label == "" {
+		return td.Name.String()
+	}
+	return td.Labels[o.label]
+}
+
+// token kinds of the conditions
+const (
+	tokenEOF = iota
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// parser parses a condition with a recursive descent over its tokens:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | "(" or ")" | compare
+//	compare = operand ( "==" | "!=" | "matches" ) operand | operand "in" "[" [ string { "," string } ] "]"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseCondition(condition string) (expr, error) {
+	if strings.TrimSpace(condition) == "" {
+		return nil, errors.New("condition is empty")
+	}
+
+	tokens, err := tokenize(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+	}
+
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(kind int, value string) bool {
+	if t := p.peek(); t.kind == kind && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind int, value string) error {
+	if !p.accept(kind, value) {
+		return unexpected(p.peek(), fmt.Sprintf("%q", value))
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.accept(tokenOp, "!") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr: e}, nil
+	}
+
+	if p.accept(tokenOp, "(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenOp, ")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	switch {
+	case t.kind == tokenOp && (t.value == "==" || t.value == "!="), t.kind == tokenIdent && t.value == "matches":
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpr{op: t.value, left: left, right: right}, nil
+
+	case t.kind == tokenIdent && t.value == "in":
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpr{op: t.value, left: left, list: list}, nil
+
+	default:
+		return nil, unexpected(t, "==, !=, matches or in")
+	}
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return operand{literal: t.value}, nil
+	case tokenIdent:
+		return parseAttribute(t)
+	default:
+		return operand{}, unexpected(t, "a string or an attribute")
+	}
+}
+
+func (p *parser) parseList() ([]string, error) {
+	if err := p.expect(tokenOp, "["); err != nil {
+		return nil, err
+	}
+
+	var list []string
+	if p.accept(tokenOp, "]") {
+		return list, nil
+	}
+	for {
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, unexpected(t, "a string")
+		}
+		list = append(list, t.value)
+
+		if p.accept(tokenOp, "]") {
+			return list, nil
+		}
+		if err := p.expect(tokenOp, ","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseAttribute parses an attribute of a trust domain: a.name, b.name, a.labels.<key> or b.labels.<key>.
+func parseAttribute(t token) (operand, error) {
+	subject, attribute, _ := strings.Cut(t.value, ".")
+	if subject != "a" && subject != "b" {
+		return operand{}, fmt.Errorf("unknown trust domain %q at position %d, must be a or b", subject, t.pos)
+	}
+
+	if attribute == "name" {
+		return operand{subject: subject}, nil
+	}
+	if key, ok := cutPrefix(attribute, "labels."); ok && key != "" {
+		return operand{subject: subject, label: key}, nil
+	}
+
+	return operand{}, fmt.Errorf("unknown attribute %q at position %d, must be %s.name or %s.labels.<key>", t.value, t.pos, subject, subject)
+}
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+func unexpected(t token, want string) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of condition, expecting %s", want)
+	}
+	return fmt.Errorf("unexpected %q at position %d, expecting %s", t.value, t.pos, want)
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: s[i+1 : i+1+end], pos: i})
+			i += end + 2
+
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!=") ||
+			strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, token{kind: tokenOp, value: s[i : i+2], pos: i})
+			i += 2
+
+		case strings.ContainsRune("!()[],", c):
+			tokens = append(tokens, token{kind: tokenOp, value: string(c), pos: i})
+			i++
+
+		case isIdentChar(c):
+			start := i
+			for i < len(s) && isIdentChar(rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: s[start:i], pos: start})
+
+		default:
+			return nil, fmt.Errorf("unexpected character %s at position %d", strconv.QuoteRune(c), i)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+// isIdentChar reports whether the character can be part of an attribute or a keyword, which include the
+// characters allowed in label keys.
+func isIdentChar(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("._/-", c))
+}
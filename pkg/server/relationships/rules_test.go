@@ -0,0 +1,123 @@
+package relationships
+
+import (
+	"testing"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trustDomain(name string, labels map[string]string) *entity.TrustDomain {
+	return &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString(name), Labels: labels}
+}
+
+func TestRuleSetEvaluate(t *testing.T) {
+	rules, err := NewRuleSet("v2", []RuleConfig{
+		{Name: "prod-dev", Condition: `a.labels.env == 'prod' && b.labels.env == 'dev'`},
+		{Name: "partners-via-dmz", Effect: EffectDeny, Condition: `a.labels.zone == "external" && b.name != 'dmz.example.org'`},
+	})
+	require.NoError(t, err)
+
+	prod := trustDomain("prod.example.org", map[string]string{"env": "prod"})
+	dev := trustDomain("dev.example.org", map[string]string{"env": "dev"})
+	dmz := trustDomain("dmz.example.org", nil)
+	partner := trustDomain("partner.org", map[string]string{"zone": "external"})
+
+	// the rules match the pair in both orders
+	assert.Equal(t, &Decision{Rule: "prod-dev", Version: "v2", Reason: `denied by relationship rule "prod-dev"`}, rules.Evaluate(prod, dev))
+	assert.Equal(t, &Decision{Rule: "prod-dev", Version: "v2", Reason: `denied by relationship rule "prod-dev"`}, rules.Evaluate(dev, prod))
+	assert.False(t, rules.Evaluate(prod, partner).Allowed)
+
+	assert.Equal(t, &Decision{Allowed: true, Version: "v2", Reason: "no deny relationship rule matches"}, rules.Evaluate(partner, dmz))
+	assert.True(t, rules.Evaluate(prod, dmz).Allowed)
+}
+
+func TestRuleSetEvaluateAllowRules(t *testing.T) {
+	rules, err := NewRuleSet("v1", []RuleConfig{
+		{Name: "acme", Effect: EffectAllow, Condition: `a.name matches '*.acme.org' && b.labels.org in ['acme', 'initech']`},
+		{Name: "no-legacy", Condition: `a.labels.legacy == 'true'`},
+	})
+	require.NoError(t, err)
+
+	foo := trustDomain("foo.acme.org", nil)
+	initech := trustDomain("initech.org", map[string]string{"org": "initech"})
+	legacy := trustDomain("legacy.acme.org", map[string]string{"org": "acme", "legacy": "true"})
+	other := trustDomain("other.org", nil)
+
+	assert.Equal(t, &Decision{Allowed: true, Rule: "acme", Version: "v1", Reason: `allowed by relationship rule "acme"`}, rules.Evaluate(initech, foo))
+	assert.Equal(t, &Decision{Version: "v1", Reason: "no allow relationship rule matches"}, rules.Evaluate(foo, other))
+
+	// deny rules override allow rules
+	assert.Equal(t, "no-legacy", rules.Evaluate(foo, legacy).Rule)
+	assert.False(t, rules.Evaluate(foo, legacy).Allowed)
+}
+
+func TestRuleSetEvaluateNil(t *testing.T) {
+	var rules *RuleSet
+	decision := rules.Evaluate(trustDomain("a.test", nil), trustDomain("b.test", nil))
+	assert.True(t, decision.Allowed)
+	assert.Empty(t, decision.Version)
+}
+
+func TestParseCondition(t *testing.T) {
+	a := trustDomain("a.test", map[string]string{"env": "prod", "team/name": "x"})
+	b := trustDomain("b.test", map[string]string{"env": "prod"})
+
+	for _, tt := range []struct {
+		condition string
+		want      bool
+	}{
+		{condition: `a.name == 'a.test'`, want: true},
+		{condition: `a.labels.env == b.labels.env`, want: true},
+		{condition: `a.labels.missing == ''`, want: true},
+		{condition: `a.labels.team/name == "x"`, want: true},
+		{condition: `!(a.labels.env == 'prod') || a.name matches '*.test'`, want: true},
+		{condition: `a.name in []`, want: false},
+		{condition: `a.name == 'a.test' && b.name == 'c.test' || a.name != a.name`, want: false},
+		{condition: `!a.name == 'a.test'`, want: false},
+	} {
+		t.Run(tt.condition, func(t *testing.T) {
+			e, err := parseCondition(tt.condition)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, e.eval(a, b))
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	for _, tt := range []struct {
+		condition string
+		err       string
+	}{
+		{condition: ` `, err: "condition is empty"},
+		{condition: `a.name`, err: "unexpected end of condition, expecting ==, !=, matches or in"},
+		{condition: `a.name == 'x' b.name`, err: `unexpected "b.name" at position 14`},
+		{condition: `c.name == 'x'`, err: `unknown trust domain "c" at position 0, must be a or b`},
+		{condition: `a.org == 'x'`, err: `unknown attribute "a.org" at position 0, must be a.name or a.labels.<key>`},
+		{condition: `(a.name == 'x'`, err: `unexpected end of condition, expecting ")"`},
+		{condition: `a.name in ['x' 'y']`, err: `unexpected "y" at position 15, expecting ","`},
+		{condition: `a.name == 'x`, err: "unterminated string at position 10"},
+		{condition: `a.name == 'x' & b.name == 'y'`, err: "unexpected character '&' at position 14"},
+	} {
+		t.Run(tt.condition, func(t *testing.T) {
+			_, err := parseCondition(tt.condition)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestNewRuleSetErrors(t *testing.T) {
+	_, err := NewRuleSet("", nil)
+	require.EqualError(t, err, "the version of the relationship rules is required")
+
+	_, err = NewRuleSet("v1", []RuleConfig{{Name: "x", Effect: "audit", Condition: `a.name == 'x'`}})
+	require.EqualError(t, err, `invalid effect "audit" for relationship rule "x", must be deny or allow`)
+
+	_, err = NewRuleSet("v1", []RuleConfig{{Name: "x", Condition: `a.name == 'x'`}, {Name: "x", Condition: `a.name == 'y'`}})
+	require.EqualError(t, err, `duplicate relationship rule "x"`)
+
+	_, err = NewRuleSet("v1", []RuleConfig{{Name: "x", Condition: `a.name =`}})
+	require.EqualError(t, err, `invalid condition for relationship rule "x": unexpected character '=' at position 7`)
+}
@@ -2,11 +2,20 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 
 	"github.com/HewlettPackard/galadriel/pkg/common/telemetry"
 	"github.com/HewlettPackard/galadriel/pkg/common/util"
+	"github.com/HewlettPackard/galadriel/pkg/server/bundles"
+	"github.com/HewlettPackard/galadriel/pkg/server/changes"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
 	"github.com/HewlettPackard/galadriel/pkg/server/endpoints"
+	"github.com/HewlettPackard/galadriel/pkg/server/jobs"
+	"github.com/HewlettPackard/galadriel/pkg/server/leader"
+	"github.com/HewlettPackard/galadriel/pkg/server/peering"
+	"github.com/HewlettPackard/galadriel/pkg/server/watch"
+	"github.com/HewlettPackard/galadriel/pkg/server/webhooks"
 )
 
 // Server represents a Galadriel Server.
@@ -28,25 +37,137 @@ func (s *Server) Run(ctx context.Context) error {
 }
 
 func (s *Server) run(ctx context.Context) error {
-	endpointsServer, err := s.newEndpointsServer()
+	ds, err := s.newDatastore()
 	if err != nil {
 		return err
 	}
+	defer ds.Close()
 
-	err = util.RunTasks(ctx, endpointsServer.ListenAndServe)
+	// the changes made by any component notify the webhooks
+	ds = webhooks.NewDatastore(ds)
+	// and are recorded for the watches
+	ds = watch.NewDatastore(ds)
+	// and are notified to every replica of the server
+	ds = changes.NewDatastore(ds)
+
+	listener := changes.NewListener(&changes.ListenerConfig{
+		Datastore: ds,
+		Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.ChangeListener),
+	})
+
+	monitor := bundles.NewMonitor(&bundles.MonitorConfig{
+		Datastore:        ds,
+		Logger:           s.config.Logger.WithField(telemetry.SubsystemName, telemetry.BundleMonitor),
+		ExpiryWarning:    s.config.BundleExpiryWarning,
+		StalenessWarning: s.config.BundleStalenessWarning,
+	})
+
+	var peeringTLSConfig *tls.Config
+	if s.config.PeeringAddress != nil {
+		peeringTLSConfig, err = peering.LoadTLSConfig(s.config.PeeringCertFile, s.config.PeeringKeyFile, s.config.PeeringCAFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	endpointsServer, err := s.newEndpointsServer(ds, monitor, listener, peeringTLSConfig)
+	if err != nil {
+		return err
+	}
+
+	fetcher := bundles.NewFetcher(&bundles.FetcherConfig{
+		Datastore: ds,
+		Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.BundleFetcher),
+		Policy:    s.bundlePolicy(),
+	})
+
+	releaser := bundles.NewReleaser(&bundles.ReleaserConfig{
+		Datastore: ds,
+		Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.BundleReleaser),
+	})
+
+	dispatcher := webhooks.NewDispatcher(&webhooks.DispatcherConfig{
+		Datastore: ds,
+		Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.WebhookDispatcher),
+	})
+
+	runner := jobs.NewRunner(&jobs.RunnerConfig{
+		Datastore: ds,
+		Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.JobRunner),
+		Jobs: jobs.MaintenanceJobs(&jobs.MaintenanceConfig{
+			Datastore: ds,
+			Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.JobRunner),
+			Settings:  s.config.Jobs,
+		}),
+	})
+
+	// the background tasks run on the leader only, so that they do not run on several replicas of the server at once
+	singletonTasks := []util.RunnableTask{fetcher.Run, releaser.Run, monitor.Run, dispatcher.Run, runner.Run}
+	if peeringTLSConfig != nil {
+		syncer := peering.NewSyncer(&peering.SyncerConfig{
+			Datastore: ds,
+			Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.PeeringSyncer),
+			TLSConfig: peeringTLSConfig,
+			Policy:    s.bundlePolicy(),
+		})
+		singletonTasks = append(singletonTasks, syncer.Run)
+	}
+
+	elector := leader.New(&leader.Config{
+		Datastore: ds,
+		Logger:    s.config.Logger.WithField(telemetry.SubsystemName, telemetry.LeaderElector),
+		Tasks:     singletonTasks,
+	})
+
+	err = util.RunTasks(ctx, endpointsServer.ListenAndServe, listener.Run, elector.Run)
 	if errors.Is(err, context.Canceled) {
 		err = nil
 	}
 	return err
 }
 
-func (s *Server) newEndpointsServer() (endpoints.Server, error) {
+func (s *Server) newDatastore() (datastore.Datastore, error) {
+	logger := s.config.Logger.WithField(telemetry.SubsystemName, telemetry.Datastore)
+
+	if s.config.DevMode {
+		logger.Warn("Running in development mode: using an in-memory datastore, data will not be persisted")
+		return datastore.NewMemoryDatastore(logger), nil
+	}
+
+	return datastore.NewSQLDatastore(logger, s.config.DBConnString, s.config.DBMigrationMode)
+}
+
+func (s *Server) newEndpointsServer(ds datastore.Datastore, monitor *bundles.Monitor, listener *changes.Listener, peeringTLSConfig *tls.Config) (endpoints.Server, error) {
 	config := &endpoints.Config{
-		TCPAddress:          s.config.TCPAddress,
-		LocalAddress:        s.config.LocalAddress,
-		DatastoreConnString: s.config.DBConnString,
-		Logger:              s.config.Logger.WithField(telemetry.SubsystemName, telemetry.Endpoints),
+		TCPAddress:   s.config.TCPAddress,
+		LocalAddress: s.config.LocalAddress,
+		Datastore:    ds,
+		Logger:       s.config.Logger.WithField(telemetry.SubsystemName, telemetry.Endpoints),
+
+		FederationAddress:   s.config.FederationAddress,
+		FederationCertFile:  s.config.FederationCertFile,
+		FederationKeyFile:   s.config.FederationKeyFile,
+		FederationPublicURL: s.config.FederationPublicURL,
+
+		PeeringAddress:   s.config.PeeringAddress,
+		PeeringTLSConfig: peeringTLSConfig,
+
+		BundlePolicy:  s.bundlePolicy(),
+		BundleMonitor: monitor,
+
+		RelationshipRules: s.config.RelationshipRules,
+
+		Changes: listener,
+
+		MetricsAddress: s.config.MetricsAddress,
 	}
 
 	return endpoints.New(config)
 }
+
+func (s *Server) bundlePolicy() *bundles.Policy {
+	if s.config.BundlePolicy != nil {
+		return s.config.BundlePolicy
+	}
+	return bundles.DefaultPolicy()
+}
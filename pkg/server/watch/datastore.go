@@ -0,0 +1,214 @@
+package watch
+
+import (
+	"context"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+)
+
+// watchDatastore is a Datastore recording the changes made through it. Each change and its event are stored within
+// the same transaction, so events are recorded in the order the changes are committed.
+type watchDatastore struct {
+	datastore.Datastore
+}
+
+// NewDatastore returns a Datastore recording the changes of the watched resources made through it, whichever
+// component makes them.
+func NewDatastore(ds datastore.Datastore) datastore.Datastore {
+	return &watchDatastore{Datastore: ds}
+}
+
+func (d *watchDatastore) WithTx(ctx context.Context, fn func(tx datastore.Datastore) error) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		return fn(&watchDatastore{Datastore: tx})
+	})
+}
+
+func (d *watchDatastore) CreateOrUpdateTrustDomain(ctx context.Context, req *entity.TrustDomain) (*entity.TrustDomain, error) {
+	var td *entity.TrustDomain
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		td, err = tx.CreateOrUpdateTrustDomain(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, KindTrustDomain, changeType(req.ID), td)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return td, nil
+}
+
+// DeleteTrustDomain records the deletion of the trust domain along with the deletions of its bundle and join tokens,
+// which are deleted with it.
+func (d *watchDatastore) DeleteTrustDomain(ctx context.Context, trustDomainID uuid.UUID) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		td, err := tx.FindTrustDomainByID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+
+		bundle, err := tx.FindBundleByTrustDomainID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+		joinTokens, err := tx.FindJoinTokensByTrustDomainID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteTrustDomain(ctx, trustDomainID); err != nil || td == nil {
+			return err
+		}
+
+		if bundle != nil {
+			if err := record(ctx, tx, KindBundle, Deleted, bundle); err != nil {
+				return err
+			}
+		}
+		for _, jt := range joinTokens {
+			if err := record(ctx, tx, KindJoinToken, Deleted, redact(jt)); err != nil {
+				return err
+			}
+		}
+
+		return record(ctx, tx, KindTrustDomain, Deleted, td)
+	})
+}
+
+func (d *watchDatastore) CreateOrUpdateBundle(ctx context.Context, req *entity.Bundle) (*entity.Bundle, error) {
+	var b *entity.Bundle
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		b, err = tx.CreateOrUpdateBundle(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, KindBundle, changeType(req.ID), b)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (d *watchDatastore) DeleteBundle(ctx context.Context, bundleID uuid.UUID) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		b, err := tx.FindBundleByID(ctx, bundleID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteBundle(ctx, bundleID); err != nil || b == nil {
+			return err
+		}
+
+		return record(ctx, tx, KindBundle, Deleted, b)
+	})
+}
+
+func (d *watchDatastore) CreateJoinToken(ctx context.Context, req *entity.JoinToken) (*entity.JoinToken, error) {
+	var jt *entity.JoinToken
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		jt, err = tx.CreateJoinToken(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, KindJoinToken, Created, redact(jt))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jt, nil
+}
+
+func (d *watchDatastore) UpdateJoinToken(ctx context.Context, joinTokenID uuid.UUID, used bool) (*entity.JoinToken, error) {
+	var jt *entity.JoinToken
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		jt, err = tx.UpdateJoinToken(ctx, joinTokenID, used)
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, KindJoinToken, Updated, redact(jt))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jt, nil
+}
+
+func (d *watchDatastore) DeleteJoinToken(ctx context.Context, joinTokenID uuid.UUID) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		jt, err := tx.FindJoinTokensByID(ctx, joinTokenID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteJoinToken(ctx, joinTokenID); err != nil || jt == nil {
+			return err
+		}
+
+		return record(ctx, tx, KindJoinToken, Deleted, redact(jt))
+	})
+}
+
+func (d *watchDatastore) CreateOrUpdateRelationship(ctx context.Context, req *entity.Relationship) (*entity.Relationship, error) {
+	var rel *entity.Relationship
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		rel, err = tx.CreateOrUpdateRelationship(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		return record(ctx, tx, KindRelationship, changeType(req.ID), rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rel, nil
+}
+
+func (d *watchDatastore) DeleteRelationship(ctx context.Context, relationshipID uuid.UUID) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		rel, err := tx.FindRelationshipByID(ctx, relationshipID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteRelationship(ctx, relationshipID); err != nil || rel == nil {
+			return err
+		}
+
+		return record(ctx, tx, KindRelationship, Deleted, rel)
+	})
+}
+
+// changeType returns whether a resource with the requested ID was created or updated.
+func changeType(id uuid.NullUUID) string {
+	if id.Valid {
+		return Updated
+	}
+	return Created
+}
+
+// redact returns the join token without its secret, which is only returned when it is generated.
+func redact(jt *entity.JoinToken) *entity.JoinToken {
+	c := *jt
+	c.Token = ""
+	return &c
+}
@@ -0,0 +1,115 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatastoreRecordsChanges(t *testing.T) {
+	ctx := context.Background()
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := NewDatastore(memory)
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+
+	tdA.Description = "A"
+	_, err = ds.CreateOrUpdateTrustDomain(ctx, tdA)
+	require.NoError(t, err)
+
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+	_, err = ds.CreateOrUpdateBundle(ctx, &entity.Bundle{TrustDomainID: tdB.ID.UUID, Data: []byte("1"), Digest: []byte("d1")})
+	require.NoError(t, err)
+	jt, err := ds.CreateJoinToken(ctx, &entity.JoinToken{Token: "secret", TrustDomainID: tdB.ID.UUID, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = ds.UpdateJoinToken(ctx, jt.ID.UUID, true)
+	require.NoError(t, err)
+
+	events := assertEvents(t, memory, 0,
+		KindTrustDomain+"."+Created,
+		KindTrustDomain+"."+Created,
+		KindTrustDomain+"."+Updated,
+		KindRelationship+"."+Created,
+		KindBundle+"."+Created,
+		KindJoinToken+"."+Created,
+		KindJoinToken+"."+Updated,
+	)
+
+	var td entity.TrustDomain
+	require.NoError(t, json.Unmarshal(events[2].Object, &td))
+	assert.Equal(t, "A", td.Description)
+
+	// join tokens are recorded without their secret
+	var token entity.JoinToken
+	require.NoError(t, json.Unmarshal(events[6].Object, &token))
+	assert.Equal(t, jt.ID, token.ID)
+	assert.True(t, token.Used)
+	assert.Empty(t, token.Token)
+
+	require.NoError(t, ds.DeleteRelationship(ctx, rel.ID.UUID))
+	events = assertEvents(t, memory, 7, KindRelationship+"."+Deleted)
+
+	var deleted entity.Relationship
+	require.NoError(t, json.Unmarshal(events[0].Object, &deleted))
+	assert.Equal(t, rel.ID, deleted.ID)
+
+	// deleting a trust domain records the deletions of the resources deleted with it
+	require.NoError(t, ds.DeleteTrustDomain(ctx, tdB.ID.UUID))
+	assertEvents(t, memory, 8,
+		KindBundle+"."+Deleted,
+		KindJoinToken+"."+Deleted,
+		KindTrustDomain+"."+Deleted,
+	)
+
+	// deleting what does not exist records nothing
+	require.NoError(t, ds.DeleteRelationship(ctx, rel.ID.UUID))
+	assertEvents(t, memory, 11)
+}
+
+func TestDatastoreDropsEventsOfRolledBackChanges(t *testing.T) {
+	ctx := context.Background()
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := NewDatastore(memory)
+
+	err := ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		_, err := tx.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+		require.NoError(t, err)
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	assertEvents(t, memory, 0)
+
+	version, err := memory.FindResourceVersion(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, version)
+}
+
+// assertEvents asserts the kinds and types of the events recorded after the given version, which are numbered
+// from it.
+func assertEvents(t *testing.T, ds datastore.Datastore, after int64, expected ...string) []*entity.WatchEvent {
+	events, err := ds.ListWatchEvents(context.Background(), after, 100)
+	require.NoError(t, err)
+
+	var actual []string
+	for i, e := range events {
+		actual = append(actual, e.Kind+"."+e.Type)
+		assert.Equal(t, after+int64(i)+1, e.Version)
+	}
+	assert.Equal(t, expected, actual)
+
+	return events
+}
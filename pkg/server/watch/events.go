@@ -0,0 +1,69 @@
+// Package watch records the changes of the trust domains, relationships, bundles and join tokens as events with
+// resource versions, and streams them to the management clients watching for changes.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+)
+
+// Kinds of the watched resources.
+const (
+	KindTrustDomain  = "trust_domain"
+	KindRelationship = "relationship"
+	KindBundle       = "bundle"
+	KindJoinToken    = "join_token"
+)
+
+// Kinds lists the kinds of the watched resources.
+var Kinds = []string{KindTrustDomain, KindRelationship, KindBundle, KindJoinToken}
+
+// Types of the changes.
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// ValidateKinds returns an error if any of the kinds is not the kind of a watched resource.
+func ValidateKinds(kinds []string) error {
+	for _, k := range kinds {
+		if !isKind(k) {
+			return fmt.Errorf("unknown kind %q", k)
+		}
+	}
+	return nil
+}
+
+// record stores the change of a resource with the next resource version. The object is the resource after the
+// change, or before it for deletions.
+func record(ctx context.Context, ds datastore.Datastore, kind, changeType string, object any) error {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return fmt.Errorf("failed marshalling %s: %w", kind, err)
+	}
+
+	_, err = ds.CreateWatchEvent(ctx, &entity.WatchEvent{
+		Kind:   kind,
+		Type:   changeType,
+		Object: b,
+	})
+	if err != nil {
+		return fmt.Errorf("failed recording watch event: %w", err)
+	}
+
+	return nil
+}
+
+func isKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
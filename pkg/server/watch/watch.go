@@ -0,0 +1,85 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+)
+
+// DefaultPollInterval is how often Watch polls for new events.
+const DefaultPollInterval = time.Second
+
+// DefaultRetention is how long the events are kept for the watches to resume from, before they are pruned by the
+// prune_watch_events maintenance job.
+const DefaultRetention = time.Hour
+
+// batchSize bounds the number of events read at once.
+const batchSize = 100
+
+var (
+	// ErrVersionExpired is returned when the events after the resource version to watch from were pruned,
+	// so the resources have to be listed again.
+	ErrVersionExpired = errors.New("resource version is too old")
+
+	// ErrInvalidVersion is returned when the resource version to watch from was not reached yet.
+	ErrInvalidVersion = errors.New("resource version is invalid")
+)
+
+// Watch calls fn with the events recorded after the resource version from, in version order, until the context
+// is canceled or fn fails. New events are polled every interval, or as soon as wake is signaled if it is not nil,
+// and fn is called with no events when there are none, so the caller can keep its connection alive.
+// ErrVersionExpired is returned if events after from were pruned, before or while watching.
+func Watch(ctx context.Context, ds datastore.Datastore, from int64, interval time.Duration, wake <-chan struct{}, fn func(events []*entity.WatchEvent) error) error {
+	current, err := ds.FindResourceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if from < 0 || from > current {
+		return fmt.Errorf("%w: %d is not between 0 and %d", ErrInvalidVersion, from, current)
+	}
+	if from < current {
+		// the events are recorded without gaps, so the next event is missing only if it was pruned
+		next, err := ds.ListWatchEvents(ctx, from, 1)
+		if err != nil {
+			return err
+		}
+		if len(next) == 0 || next[0].Version != from+1 {
+			return ErrVersionExpired
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		events, err := ds.ListWatchEvents(ctx, from, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) > 0 && events[0].Version != from+1 {
+			return ErrVersionExpired
+		}
+
+		if err := fn(events); err != nil {
+			return err
+		}
+
+		if len(events) > 0 {
+			from = events[len(events)-1].Version
+		}
+		if len(events) == batchSize {
+			continue
+		}
+
+		select {
+		case <-t.C:
+		case <-wake:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
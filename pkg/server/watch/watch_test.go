@@ -0,0 +1,73 @@
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	for i := 0; i < 3; i++ {
+		_, err := ds.CreateWatchEvent(ctx, &entity.WatchEvent{Kind: KindTrustDomain, Type: Created, Object: []byte("{}")})
+		require.NoError(t, err)
+	}
+
+	// a watch is resumed after the given version
+	var versions []int64
+	err := Watch(ctx, ds, 1, time.Millisecond, nil, func(events []*entity.WatchEvent) error {
+		for _, e := range events {
+			versions = append(versions, e.Version)
+		}
+		if len(versions) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, versions)
+
+	ctx = context.Background()
+	err = Watch(ctx, ds, 4, time.Millisecond, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+
+	// a watch cannot be resumed once the events after its version are pruned
+	require.NoError(t, ds.DeleteWatchEventsBefore(ctx, time.Now().Add(time.Second)))
+	err = Watch(ctx, ds, 1, time.Millisecond, nil, nil)
+	assert.ErrorIs(t, err, ErrVersionExpired)
+}
+
+func TestWatchWake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	// new events are read as soon as the watch is woken, rather than when it next polls
+	wake := make(chan struct{}, 1)
+	var versions []int64
+	err := Watch(ctx, ds, 0, time.Hour, wake, func(events []*entity.WatchEvent) error {
+		for _, e := range events {
+			versions = append(versions, e.Version)
+		}
+		if len(versions) == 1 {
+			cancel()
+			return nil
+		}
+
+		_, err := ds.CreateWatchEvent(ctx, &entity.WatchEvent{Kind: KindTrustDomain, Type: Created, Object: []byte("{}")})
+		require.NoError(t, err)
+		wake <- struct{}{}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, versions)
+}
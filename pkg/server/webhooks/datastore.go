@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+)
+
+// eventDatastore is a Datastore enqueueing the events caused by the changes made through it. Each change and its
+// events are stored within the same transaction, so no event is lost or sent for a change that was rolled back.
+type eventDatastore struct {
+	datastore.Datastore
+}
+
+// NewDatastore returns a Datastore enqueueing the federation events caused by the changes made through it,
+// whichever component makes them.
+func NewDatastore(ds datastore.Datastore) datastore.Datastore {
+	return &eventDatastore{Datastore: ds}
+}
+
+func (d *eventDatastore) WithTx(ctx context.Context, fn func(tx datastore.Datastore) error) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		return fn(&eventDatastore{Datastore: tx})
+	})
+}
+
+func (d *eventDatastore) CreateOrUpdateTrustDomain(ctx context.Context, req *entity.TrustDomain) (*entity.TrustDomain, error) {
+	var td *entity.TrustDomain
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var err error
+		td, err = tx.CreateOrUpdateTrustDomain(ctx, req)
+		if err != nil || req.ID.Valid {
+			return err
+		}
+
+		return Enqueue(ctx, tx, NewEvent(TrustDomainCreated, td.Name.String()))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return td, nil
+}
+
+func (d *eventDatastore) DeleteTrustDomain(ctx context.Context, trustDomainID uuid.UUID) error {
+	return d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		td, err := tx.FindTrustDomainByID(ctx, trustDomainID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteTrustDomain(ctx, trustDomainID); err != nil || td == nil {
+			return err
+		}
+
+		return Enqueue(ctx, tx, NewEvent(TrustDomainDeleted, td.Name.String()))
+	})
+}
+
+func (d *eventDatastore) CreateOrUpdateRelationship(ctx context.Context, req *entity.Relationship) (*entity.Relationship, error) {
+	var rel *entity.Relationship
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		var current *entity.Relationship
+		if req.ID.Valid {
+			var err error
+			current, err = tx.FindRelationshipByID(ctx, req.ID.UUID)
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		rel, err = tx.CreateOrUpdateRelationship(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		return enqueueRelationshipEvents(ctx, tx, current, rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rel, nil
+}
+
+func (d *eventDatastore) CreateOrUpdateBundle(ctx context.Context, req *entity.Bundle) (*entity.Bundle, error) {
+	var b *entity.Bundle
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		current, err := tx.FindBundleByTrustDomainID(ctx, req.TrustDomainID)
+		if err != nil {
+			return err
+		}
+
+		b, err = tx.CreateOrUpdateBundle(ctx, req)
+		if err != nil {
+			return err
+		}
+		if current != nil && bytes.Equal(current.Data, b.Data) {
+			return nil
+		}
+
+		return enqueueBundleEvent(ctx, tx, BundleRotated, b.TrustDomainID, b.Digest)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (d *eventDatastore) CreateOrUpdateQuarantinedBundle(ctx context.Context, req *entity.QuarantinedBundle) (*entity.QuarantinedBundle, error) {
+	var qb *entity.QuarantinedBundle
+	err := d.Datastore.WithTx(ctx, func(tx datastore.Datastore) error {
+		current, err := tx.FindQuarantinedBundleByTrustDomainID(ctx, req.TrustDomainID)
+		if err != nil {
+			return err
+		}
+
+		qb, err = tx.CreateOrUpdateQuarantinedBundle(ctx, req)
+		if err != nil {
+			return err
+		}
+		if current != nil && bytes.Equal(current.Digest, qb.Digest) {
+			return nil
+		}
+
+		return enqueueBundleEvent(ctx, tx, BundleQuarantined, qb.TrustDomainID, qb.Digest)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return qb, nil
+}
+
+// enqueueRelationshipEvents enqueues the creation of the relationship if there is no current relationship, and
+// the approvals and denials of its trust domains, and its expiry, otherwise.
+func enqueueRelationshipEvents(ctx context.Context, tx datastore.Datastore, current, rel *entity.Relationship) error {
+	tdA, err := findTrustDomain(ctx, tx, rel.TrustDomainAID)
+	if err != nil {
+		return err
+	}
+	tdB, err := findTrustDomain(ctx, tx, rel.TrustDomainBID)
+	if err != nil {
+		return err
+	}
+
+	relationship := &Relationship{
+		ID:           rel.ID.UUID,
+		TrustDomainA: tdA.Name.String(),
+		TrustDomainB: tdB.Name.String(),
+		Direction:    rel.Direction.OrDefault(),
+	}
+
+	var events []*Event
+	if current == nil {
+		events = append(events, NewEvent(RelationshipCreated, ""))
+	} else {
+		if e := consentEvent(current.TrustDomainAConsent, rel.TrustDomainAConsent, tdA); e != nil {
+			events = append(events, e)
+		}
+		if e := consentEvent(current.TrustDomainBConsent, rel.TrustDomainBConsent, tdB); e != nil {
+			events = append(events, e)
+		}
+		if current.ExpiredAt.IsZero() && !rel.ExpiredAt.IsZero() {
+			events = append(events, NewEvent(RelationshipExpired, tdA.Name.String()), NewEvent(RelationshipExpired, tdB.Name.String()))
+		}
+	}
+
+	for _, e := range events {
+		e.Relationship = relationship
+		if err := Enqueue(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// consentEvent returns the approval or the denial of a relationship by a trust domain whose consent changed.
+func consentEvent(was, is bool, td *entity.TrustDomain) *Event {
+	switch {
+	case is && !was:
+		return NewEvent(RelationshipApproved, td.Name.String())
+	case !is && was:
+		return NewEvent(RelationshipDenied, td.Name.String())
+	default:
+		return nil
+	}
+}
+
+func enqueueBundleEvent(ctx context.Context, tx datastore.Datastore, t EventType, trustDomainID uuid.UUID, digest []byte) error {
+	td, err := findTrustDomain(ctx, tx, trustDomainID)
+	if err != nil {
+		return err
+	}
+
+	e := NewEvent(t, td.Name.String())
+	e.BundleDigest = digest
+
+	return Enqueue(ctx, tx, e)
+}
+
+func findTrustDomain(ctx context.Context, ds datastore.Datastore, trustDomainID uuid.UUID) (*entity.TrustDomain, error) {
+	td, err := ds.FindTrustDomainByID(ctx, trustDomainID)
+	if err != nil {
+		return nil, err
+	}
+	if td == nil {
+		return nil, fmt.Errorf("trust domain %q does not exist", trustDomainID)
+	}
+	return td, nil
+}
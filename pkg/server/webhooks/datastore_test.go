@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatastoreEnqueuesEvents(t *testing.T) {
+	ctx := context.Background()
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := NewDatastore(memory)
+
+	all := make([]string, len(EventTypes))
+	for i, e := range EventTypes {
+		all[i] = string(e)
+	}
+	_, err := Register(ctx, ds, "https://hooks.example.org/all", all)
+	require.NoError(t, err)
+	_, err = Register(ctx, ds, "https://hooks.example.org/bundles", []string{string(BundleRotated)})
+	require.NoError(t, err)
+
+	tdA, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+	require.NoError(t, err)
+	tdB, err := ds.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("b.test")})
+	require.NoError(t, err)
+	assertEvents(t, memory, TrustDomainCreated, TrustDomainCreated)
+
+	// updates of the attributes of a trust domain are not notified
+	tdA.Description = "A"
+	tdA, err = ds.CreateOrUpdateTrustDomain(ctx, tdA)
+	require.NoError(t, err)
+	assertEvents(t, memory)
+
+	rel, err := ds.CreateOrUpdateRelationship(ctx, &entity.Relationship{TrustDomainAID: tdA.ID.UUID, TrustDomainBID: tdB.ID.UUID})
+	require.NoError(t, err)
+	events := assertEvents(t, memory, RelationshipCreated)
+	assert.Equal(t, &Relationship{ID: rel.ID.UUID, TrustDomainA: "a.test", TrustDomainB: "b.test", Direction: entity.RelationshipBidirectional}, events[0].Relationship)
+
+	rel.TrustDomainAConsent = true
+	rel, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+	events = assertEvents(t, memory, RelationshipApproved)
+	assert.Equal(t, "a.test", events[0].TrustDomain)
+
+	rel.TrustDomainAConsent = false
+	rel.TrustDomainBConsent = true
+	rel, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+	events = assertEvents(t, memory, RelationshipDenied, RelationshipApproved)
+	assert.Equal(t, "a.test", events[0].TrustDomain)
+	assert.Equal(t, "b.test", events[1].TrustDomain)
+
+	// the expiry of a relationship is notified to both of its trust domains
+	rel.NotAfter = time.Now()
+	rel.ExpiredAt = rel.NotAfter
+	_, err = ds.CreateOrUpdateRelationship(ctx, rel)
+	require.NoError(t, err)
+	events = assertEvents(t, memory, RelationshipExpired, RelationshipExpired)
+	assert.Equal(t, "a.test", events[0].TrustDomain)
+	assert.Equal(t, "b.test", events[1].TrustDomain)
+
+	// bundles are notified to both webhooks, unless they are unchanged
+	bundle, err := ds.CreateOrUpdateBundle(ctx, &entity.Bundle{TrustDomainID: tdA.ID.UUID, Data: []byte("1"), Digest: []byte("d1")})
+	require.NoError(t, err)
+	events = assertEvents(t, memory, BundleRotated, BundleRotated)
+	assert.Equal(t, "a.test", events[0].TrustDomain)
+	assert.Equal(t, []byte("d1"), events[0].BundleDigest)
+
+	_, err = ds.CreateOrUpdateBundle(ctx, bundle)
+	require.NoError(t, err)
+	assertEvents(t, memory)
+
+	_, err = ds.CreateOrUpdateQuarantinedBundle(ctx, &entity.QuarantinedBundle{TrustDomainID: tdA.ID.UUID, Data: []byte("2"), Digest: []byte("d2")})
+	require.NoError(t, err)
+	events = assertEvents(t, memory, BundleQuarantined)
+	assert.Equal(t, []byte("d2"), events[0].BundleDigest)
+
+	require.NoError(t, ds.DeleteRelationship(ctx, rel.ID.UUID))
+	require.NoError(t, ds.DeleteTrustDomain(ctx, tdB.ID.UUID))
+	events = assertEvents(t, memory, TrustDomainDeleted)
+	assert.Equal(t, "b.test", events[0].TrustDomain)
+}
+
+func TestDatastoreDropsEventsOfRolledBackChanges(t *testing.T) {
+	ctx := context.Background()
+	memory := datastore.NewMemoryDatastore(logrus.New())
+	ds := NewDatastore(memory)
+
+	_, err := Register(ctx, ds, "https://hooks.example.org/all", []string{string(TrustDomainCreated)})
+	require.NoError(t, err)
+
+	errRollback := errors.New("rollback")
+	err = ds.WithTx(ctx, func(tx datastore.Datastore) error {
+		_, err := tx.CreateOrUpdateTrustDomain(ctx, &entity.TrustDomain{Name: spiffeid.RequireTrustDomainFromString("a.test")})
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	assertEvents(t, memory)
+}
+
+// assertEvents asserts that the pending deliveries are those of the events of the given types, oldest first,
+// and returns the events after deleting the deliveries.
+func assertEvents(t *testing.T, ds datastore.Datastore, types ...EventType) []*Event {
+	ctx := context.Background()
+
+	deliveries, err := ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+
+	var events []*Event
+	var got []EventType
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		var e Event
+		require.NoError(t, json.Unmarshal(deliveries[i].Payload, &e))
+		assert.Equal(t, string(e.Type), deliveries[i].EventType)
+		events = append(events, &e)
+		got = append(got, e.Type)
+
+		require.NoError(t, ds.DeleteWebhookDelivery(ctx, deliveries[i].ID.UUID))
+	}
+
+	assert.Equal(t, types, got)
+	return events
+}
@@ -0,0 +1,236 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers of the deliveries.
+const (
+	EventHeader     = "X-Galadriel-Event"
+	DeliveryHeader  = "X-Galadriel-Delivery"
+	SignatureHeader = "X-Galadriel-Signature"
+)
+
+// Defaults of the retries of the Dispatcher.
+const (
+	DefaultMaxAttempts    = 8
+	DefaultInitialBackoff = 30 * time.Second
+	DefaultMaxBackoff     = time.Hour
+)
+
+const (
+	// dispatchInterval is how often the due deliveries are made.
+	dispatchInterval = 10 * time.Second
+
+	// deliveryTimeout bounds the time a webhook has to respond.
+	deliveryTimeout = 10 * time.Second
+
+	// maxErrorLength bounds the length of the response bodies recorded as delivery errors.
+	maxErrorLength = 256
+)
+
+// DispatcherConfig conveys the configuration of a Dispatcher.
+type DispatcherConfig struct {
+	Datastore datastore.Datastore
+	Logger    logrus.FieldLogger
+
+	// Client sends the deliveries. If not set, a client with a timeout of 10 seconds is used.
+	Client *http.Client
+
+	// MaxAttempts is the number of attempts after which a delivery is dead. If not set, DefaultMaxAttempts is used.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry of a failed delivery, doubled on each retry up to
+	// MaxBackoff. If not set, DefaultInitialBackoff and DefaultMaxBackoff are used.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Dispatcher delivers the enqueued events to the webhooks.
+type Dispatcher struct {
+	ds             datastore.Datastore
+	logger         logrus.FieldLogger
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func NewDispatcher(c *DispatcherConfig) *Dispatcher {
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: deliveryTimeout}
+	}
+	maxAttempts := c.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	initialBackoff := c.InitialBackoff
+	if initialBackoff == 0 {
+		initialBackoff = DefaultInitialBackoff
+	}
+	maxBackoff := c.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	return &Dispatcher{
+		ds:             c.Datastore,
+		logger:         c.Logger,
+		client:         client,
+		maxAttempts:    maxAttempts,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+	}
+}
+
+// Run makes the due deliveries until the context is canceled.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	t := time.NewTicker(dispatchInterval)
+	defer t.Stop()
+
+	for {
+		if err := d.dispatchDue(ctx, time.Now()); err != nil {
+			d.logger.WithError(err).Error("Failed to dispatch webhook deliveries")
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// dispatchDue makes the pending deliveries whose next attempt is due at the given time, oldest first.
+func (d *Dispatcher) dispatchDue(ctx context.Context, now time.Time) error {
+	deliveries, err := d.ds.ListWebhookDeliveries(ctx, false)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	webhooks, err := d.ds.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*entity.Webhook, len(webhooks))
+	for _, w := range webhooks {
+		byID[w.ID.UUID] = w
+	}
+
+	// deliveries are listed newest first
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		wd := deliveries[i]
+		w, ok := byID[wd.WebhookID]
+		if !ok || wd.NextAttemptAt.After(now) {
+			continue
+		}
+
+		if err := d.dispatch(ctx, w, wd, now); err != nil {
+			d.logger.WithError(err).Errorf("Failed to record delivery %s to webhook %s", wd.ID.UUID, w.URL)
+		}
+	}
+
+	return nil
+}
+
+// dispatch makes a delivery, which is deleted once delivered, and scheduled for a retry or dead otherwise.
+func (d *Dispatcher) dispatch(ctx context.Context, w *entity.Webhook, wd *entity.WebhookDelivery, now time.Time) error {
+	deliveryErr := d.deliver(ctx, w, wd)
+	if deliveryErr == nil {
+		d.logger.Debugf("Delivered %s event to webhook %s", wd.EventType, w.URL)
+		return d.ds.DeleteWebhookDelivery(ctx, wd.ID.UUID)
+	}
+
+	wd.Attempts++
+	wd.LastError = deliveryErr.Error()
+	if wd.Attempts >= d.maxAttempts {
+		wd.Dead = true
+		d.logger.WithError(deliveryErr).Warnf("Failed to deliver %s event to webhook %s after %d attempts, the delivery is dead", wd.EventType, w.URL, wd.Attempts)
+	} else {
+		wd.NextAttemptAt = now.Add(d.backoff(wd.Attempts))
+		d.logger.WithError(deliveryErr).Infof("Failed to deliver %s event to webhook %s, retrying at %s", wd.EventType, w.URL, wd.NextAttemptAt.UTC().Format(time.RFC3339))
+	}
+
+	_, err := d.ds.UpdateWebhookDelivery(ctx, wd)
+	return err
+}
+
+// deliver posts the payload of the delivery to the webhook. Any response other than a 2xx is a failure.
+func (d *Dispatcher) deliver(ctx context.Context, w *entity.Webhook, wd *entity.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(wd.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, wd.EventType)
+	req.Header.Set(DeliveryHeader, wd.ID.UUID.String())
+	req.Header.Set(SignatureHeader, Sign(w.Secret, wd.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorLength))
+		return fmt.Errorf("webhook responded with status %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt of a delivery that failed the given number of times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.initialBackoff
+	for i := 1; i < attempts && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.maxBackoff {
+		backoff = d.maxBackoff
+	}
+	return backoff
+}
+
+// Redeliver schedules a dead delivery for an immediate new series of attempts.
+func Redeliver(ctx context.Context, ds datastore.Datastore, deliveryID uuid.UUID) error {
+	wd, err := ds.FindWebhookDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	if wd == nil || !wd.Dead {
+		return ErrNotDead
+	}
+
+	wd.Attempts = 0
+	wd.Dead = false
+	wd.NextAttemptAt = time.Now()
+
+	_, err = ds.UpdateWebhookDelivery(ctx, wd)
+	return err
+}
+
+// Sign returns the signature of a payload sent in the SignatureHeader: the hex-encoded HMAC-SHA256 of the
+// payload keyed with the secret of the webhook, prefixed with "sha256=".
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,114 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMemoryDatastore(logrus.New())
+
+	status := http.StatusInternalServerError
+	var received []*http.Request
+	var payloads [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = append(received, r)
+		payloads = append(payloads, body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook, err := Register(ctx, ds, server.URL, []string{string(HarvesterStale)})
+	require.NoError(t, err)
+	require.NoError(t, Enqueue(ctx, ds, NewEvent(HarvesterStale, "a.test")))
+
+	d := NewDispatcher(&DispatcherConfig{
+		Datastore:      ds,
+		Logger:         logrus.New(),
+		MaxAttempts:    3,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     90 * time.Second,
+	})
+
+	now := time.Now()
+	require.NoError(t, d.dispatchDue(ctx, now))
+
+	// the payload is signed with the secret of the webhook
+	require.Len(t, received, 1)
+	assert.Equal(t, string(HarvesterStale), received[0].Header.Get(EventHeader))
+	assert.Equal(t, Sign(webhook.Secret, payloads[0]), received[0].Header.Get(SignatureHeader))
+	var event Event
+	require.NoError(t, json.Unmarshal(payloads[0], &event))
+	assert.Equal(t, HarvesterStale, event.Type)
+	assert.Equal(t, "a.test", event.TrustDomain)
+
+	// failed deliveries are retried with an exponential backoff
+	pending, err := ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Contains(t, pending[0].LastError, "status 500")
+	assert.WithinDuration(t, now.Add(time.Minute), pending[0].NextAttemptAt, time.Millisecond)
+
+	require.NoError(t, d.dispatchDue(ctx, now.Add(30*time.Second)))
+	assert.Len(t, received, 1)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, d.dispatchDue(ctx, now))
+	require.Len(t, received, 2)
+	assert.Equal(t, received[0].Header.Get(DeliveryHeader), received[1].Header.Get(DeliveryHeader))
+
+	pending, err = ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.WithinDuration(t, now.Add(90*time.Second), pending[0].NextAttemptAt, time.Millisecond)
+
+	// the delivery is dead after the last attempt
+	now = now.Add(90 * time.Second)
+	require.NoError(t, d.dispatchDue(ctx, now))
+	require.Len(t, received, 3)
+
+	pending, err = ds.ListWebhookDeliveries(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	dead, err := ds.ListWebhookDeliveries(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 3, dead[0].Attempts)
+
+	// a redelivered dead delivery is deleted once delivered
+	require.ErrorIs(t, Redeliver(ctx, ds, uuid.New()), ErrNotDead)
+	require.NoError(t, Redeliver(ctx, ds, dead[0].ID.UUID))
+
+	status = http.StatusNoContent
+	require.NoError(t, d.dispatchDue(ctx, time.Now()))
+	require.Len(t, received, 4)
+
+	stored, err := ds.FindWebhookDeliveryByID(ctx, dead[0].ID.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := NewDispatcher(&DispatcherConfig{Logger: logrus.New()})
+
+	assert.Equal(t, DefaultInitialBackoff, d.backoff(1))
+	assert.Equal(t, 2*DefaultInitialBackoff, d.backoff(2))
+	assert.Equal(t, 4*DefaultInitialBackoff, d.backoff(3))
+	assert.Equal(t, DefaultMaxBackoff, d.backoff(20))
+}
@@ -0,0 +1,149 @@
+// Package webhooks notifies HTTP endpoints registered by admins of the federation events they are subscribed to.
+//
+// Events are recorded as deliveries within the transaction of the change that causes them, and delivered by the
+// Dispatcher, which retries the failed deliveries with an exponential backoff until they are dead.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+	"github.com/google/uuid"
+)
+
+// EventType is the type of the federation events webhooks subscribe to.
+type EventType string
+
+const (
+	TrustDomainCreated   EventType = "trust_domain.created"
+	TrustDomainDeleted   EventType = "trust_domain.deleted"
+	RelationshipCreated  EventType = "relationship.created"
+	RelationshipApproved EventType = "relationship.approved"
+	RelationshipDenied   EventType = "relationship.denied"
+	RelationshipExpired  EventType = "relationship.expired"
+	BundleRotated        EventType = "bundle.rotated"
+	BundleQuarantined    EventType = "bundle.quarantined"
+	HarvesterStale       EventType = "harvester.stale"
+)
+
+// EventTypes are the types of the events webhooks can subscribe to.
+var EventTypes = []EventType{
+	TrustDomainCreated,
+	TrustDomainDeleted,
+	RelationshipCreated,
+	RelationshipApproved,
+	RelationshipDenied,
+	RelationshipExpired,
+	BundleRotated,
+	BundleQuarantined,
+	HarvesterStale,
+}
+
+// Event is the payload delivered to the webhooks.
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// TrustDomain is the trust domain the event is about. For the relationship approvals and denials, it is the
+	// trust domain that approved or denied the relationship. The expiry of a relationship is notified to each of
+	// its trust domains.
+	TrustDomain string `json:"trust_domain,omitempty"`
+
+	// Relationship is set for the relationship events.
+	Relationship *Relationship `json:"relationship,omitempty"`
+
+	// BundleDigest is set for the bundle events.
+	BundleDigest []byte `json:"bundle_digest,omitempty"`
+}
+
+// Relationship identifies the relationship of a relationship event.
+type Relationship struct {
+	ID           uuid.UUID `json:"id"`
+	TrustDomainA string    `json:"trust_domain_a"`
+	TrustDomainB string    `json:"trust_domain_b"`
+
+	Direction entity.RelationshipDirection `json:"direction"`
+}
+
+// NewEvent creates an event of the given type about a trust domain.
+func NewEvent(t EventType, trustDomain string) *Event {
+	return &Event{
+		ID:          uuid.New(),
+		Type:        t,
+		CreatedAt:   time.Now().UTC(),
+		TrustDomain: trustDomain,
+	}
+}
+
+// ValidateEventTypes checks that webhooks can subscribe to the event types.
+func ValidateEventTypes(types []string) error {
+	if len(types) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+
+	for _, t := range types {
+		if !isEventType(t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+
+	return nil
+}
+
+// Enqueue records a delivery of the event for each of the webhooks subscribed to its type. The deliveries are
+// made by the Dispatcher.
+func Enqueue(ctx context.Context, ds datastore.Datastore, e *Event) error {
+	webhooks, err := ds.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	var payload []byte
+	for _, w := range webhooks {
+		if !subscribed(w, e.Type) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(e)
+			if err != nil {
+				return fmt.Errorf("failed to marshal event: %w", err)
+			}
+		}
+
+		_, err := ds.CreateWebhookDelivery(ctx, &entity.WebhookDelivery{
+			WebhookID:     w.ID.UUID,
+			EventType:     string(e.Type),
+			Payload:       payload,
+			NextAttemptAt: e.CreatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func subscribed(w *entity.Webhook, t EventType) bool {
+	for _, s := range w.EventTypes {
+		if s == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEventType(t string) bool {
+	for _, e := range EventTypes {
+		if string(e) == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/HewlettPackard/galadriel/pkg/common/entity"
+	"github.com/HewlettPackard/galadriel/pkg/server/datastore"
+)
+
+// secretSize is the size in bytes of the generated webhook secrets.
+const secretSize = 32
+
+// ErrNotDead is returned when redelivering a delivery that does not exist or is not dead.
+var ErrNotDead = errors.New("the webhook delivery does not exist or is not dead")
+
+// Register creates a webhook notified of the events of the given types at the URL. The secret the payloads
+// are signed with is generated, and only returned by Register.
+func Register(ctx context.Context, ds datastore.Datastore, webhookURL string, eventTypes []string) (*entity.Webhook, error) {
+	if err := ValidateURL(webhookURL); err != nil {
+		return nil, err
+	}
+	if err := ValidateEventTypes(eventTypes); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return ds.CreateWebhook(ctx, &entity.Webhook{
+		URL:        webhookURL,
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+	})
+}
+
+// ValidateURL checks that the URL of a webhook is an http or https URL.
+func ValidateURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("webhook URL %q must be an http or https URL", webhookURL)
+	}
+	return nil
+}